@@ -96,7 +96,15 @@ func AdminDeleteUser(c echo.Context) error {
 	} else if err != nil {
 		panic(err)
 	}
-	utils.PanicIfDBError(base.DB.Delete(&user), "could not delete user")
+	err = base.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.PersonalAccessToken{}, "user_id = ?", user.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&user).Error
+	})
+	if err != nil {
+		panic(errors.Wrap(err, "could not delete user"))
+	}
 	return c.JSON(http.StatusOK, response.Response{
 		Message: "SUCCESS",
 		Error:   nil,
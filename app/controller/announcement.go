@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func GetAnnouncements(c echo.Context) error {
+	class := models.Class{}
+	if err := base.DB.First(&class, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not get class for getting announcements"))
+	}
+	user := c.Get("user").(models.User)
+	if !user.Can("read_class_secrets", class) && !user.Can("read_class_secrets") &&
+		base.DB.Model(&class).Where("id = ?", user.ID).Association("Students").Count() == 0 {
+		return c.JSON(http.StatusForbidden, response.ErrorResp("PERMISSION_DENIED", nil))
+	}
+	var announcements []*models.Announcement
+	utils.PanicIfDBError(base.DB.Preload("User").Order("pinned desc, id desc").
+		Find(&announcements, "class_id = ?", class.ID), "could not get announcements")
+	return c.JSON(http.StatusOK, response.GetAnnouncementsResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			Announcements []resource.Announcement `json:"announcements"`
+		}{
+			resource.GetAnnouncementSlice(announcements),
+		},
+	})
+}
+
+func CreateAnnouncement(c echo.Context) error {
+	req := request.CreateAnnouncementRequest{}
+	err, ok := utils.BindAndValidate(&req, c)
+	if !ok {
+		return err
+	}
+	class := models.Class{}
+	if err := base.DB.Preload("Students").Preload("Assistants").First(&class, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not get class for creating announcement"))
+	}
+	user := c.Get("user").(models.User)
+	announcement := models.Announcement{
+		ClassID: class.ID,
+		UserID:  user.ID,
+		User:    &user,
+		Title:   req.Title,
+		Content: req.Content,
+		Pinned:  req.Pinned,
+	}
+	utils.PanicIfDBError(base.DB.Omit("User").Create(&announcement), "could not create announcement")
+	var userIDs []uint
+	for _, u := range append(class.Students, class.Assistants...) {
+		if u.ID != user.ID {
+			userIDs = append(userIDs, u.ID)
+		}
+	}
+	if err := utils.CreateNotifications(userIDs, models.Notification{
+		Type:     models.NotificationTypeAnnouncement,
+		Title:    fmt.Sprintf("[%s] %s", class.Name, announcement.Title),
+		Content:  announcement.Content,
+		ClassID:  class.ID,
+		TargetID: announcement.ID,
+	}); err != nil {
+		panic(errors.Wrap(err, "could not notify announcement"))
+	}
+	return c.JSON(http.StatusCreated, response.CreateAnnouncementResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.Announcement `json:"announcement"`
+		}{
+			resource.GetAnnouncement(&announcement),
+		},
+	})
+}
+
+func UpdateAnnouncement(c echo.Context) error {
+	req := request.UpdateAnnouncementRequest{}
+	err, ok := utils.BindAndValidate(&req, c)
+	if !ok {
+		return err
+	}
+	announcement := models.Announcement{}
+	if err := base.DB.Preload("User").
+		First(&announcement, "id = ? and class_id = ?", c.Param("announcement_id"), c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not get announcement for updating"))
+	}
+	announcement.Title = req.Title
+	announcement.Content = req.Content
+	announcement.Pinned = req.Pinned
+	utils.PanicIfDBError(base.DB.Omit("User").Save(&announcement), "could not update announcement")
+	return c.JSON(http.StatusOK, response.UpdateAnnouncementResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.Announcement `json:"announcement"`
+		}{
+			resource.GetAnnouncement(&announcement),
+		},
+	})
+}
+
+func DeleteAnnouncement(c echo.Context) error {
+	announcement := models.Announcement{}
+	if err := base.DB.First(&announcement, "id = ? and class_id = ?", c.Param("announcement_id"), c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not get announcement for deleting"))
+	}
+	utils.PanicIfDBError(base.DB.Delete(&announcement), "could not delete announcement")
+	return c.JSON(http.StatusOK, response.Response{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data:    nil,
+	})
+}
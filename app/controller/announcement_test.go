@@ -0,0 +1,281 @@
+package controller_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func createAnnouncementForTest(t *testing.T, name string, id int, class *models.Class, user *models.User, pinned bool) models.Announcement {
+	announcement := models.Announcement{
+		ClassID: class.ID,
+		UserID:  user.ID,
+		User:    user,
+		Title:   fmt.Sprintf("test_%s_%d_title", name, id),
+		Content: fmt.Sprintf("test_%s_%d_content", name, id),
+		Pinned:  pinned,
+	}
+	assert.NoError(t, base.DB.Omit("User").Create(&announcement).Error)
+	return announcement
+}
+
+func TestGetAnnouncements(t *testing.T) {
+	t.Parallel()
+	manager := createUserForTest(t, "get_announcements", 0)
+	student := createUserForTest(t, "get_announcements", 1)
+	class := createClassForTest(t, "get_announcements", 0, []*models.User{&manager}, []*models.User{&student})
+	announcement1 := createAnnouncementForTest(t, "get_announcements", 1, &class, &manager, true)
+	announcement2 := createAnnouncementForTest(t, "get_announcements", 2, &class, &manager, false)
+	announcement3 := createAnnouncementForTest(t, "get_announcements", 3, &class, &manager, false)
+
+	failTests := []failTest{
+		{
+			name:   "NonExistingClass",
+			method: "GET",
+			path:   base.Echo.Reverse("class.getAnnouncements", -1),
+			req:    request.GetAnnouncementsRequest{},
+			reqOptions: []reqOption{
+				applyAdminUser,
+			},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:   "PermissionDenied",
+			method: "GET",
+			path:   base.Echo.Reverse("class.getAnnouncements", class.ID),
+			req:    request.GetAnnouncementsRequest{},
+			reqOptions: []reqOption{
+				applyNormalUser,
+			},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "GetAnnouncements")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("class.getAnnouncements", class.ID),
+			request.GetAnnouncementsRequest{}, applyUser(student)))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.GetAnnouncementsResponse{}
+		mustJsonDecode(httpResp, &resp)
+		expected := resource.GetAnnouncementSlice([]*models.Announcement{&announcement1, &announcement3, &announcement2})
+		assert.Len(t, resp.Data.Announcements, 3)
+		for i := range expected {
+			assert.Equal(t, expected[i].ID, resp.Data.Announcements[i].ID)
+			assert.Equal(t, expected[i].Title, resp.Data.Announcements[i].Title)
+			assert.Equal(t, expected[i].Content, resp.Data.Announcements[i].Content)
+			assert.Equal(t, expected[i].Pinned, resp.Data.Announcements[i].Pinned)
+			assert.Equal(t, expected[i].User, resp.Data.Announcements[i].User)
+		}
+	})
+}
+
+func TestCreateAnnouncement(t *testing.T) {
+	t.Parallel()
+	manager := createUserForTest(t, "create_announcement", 0)
+	student1 := createUserForTest(t, "create_announcement", 1)
+	student2 := createUserForTest(t, "create_announcement", 2)
+	class := createClassForTest(t, "create_announcement", 0, []*models.User{&manager}, []*models.User{&student1, &student2})
+	manager.GrantRole("class_creator", class)
+
+	failTests := []failTest{
+		{
+			name:   "NonExistingClass",
+			method: "POST",
+			path:   base.Echo.Reverse("class.createAnnouncement", -1),
+			req: request.CreateAnnouncementRequest{
+				Title:   "test_create_announcement_title",
+				Content: "test_create_announcement_content",
+			},
+			reqOptions: []reqOption{
+				applyAdminUser,
+			},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:   "PermissionDenied",
+			method: "POST",
+			path:   base.Echo.Reverse("class.createAnnouncement", class.ID),
+			req: request.CreateAnnouncementRequest{
+				Title:   "test_create_announcement_title",
+				Content: "test_create_announcement_content",
+			},
+			reqOptions: []reqOption{
+				applyUser(student1),
+			},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "CreateAnnouncement")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("class.createAnnouncement", class.ID), request.CreateAnnouncementRequest{
+			Title:   "test_create_announcement_title",
+			Content: "test_create_announcement_content",
+			Pinned:  true,
+		}, applyUser(manager)))
+		assert.Equal(t, http.StatusCreated, httpResp.StatusCode)
+		resp := response.CreateAnnouncementResponse{}
+		mustJsonDecode(httpResp, &resp)
+
+		announcement := models.Announcement{}
+		assert.NoError(t, base.DB.Preload("User").First(&announcement, resp.Data.Announcement.ID).Error)
+		assert.Equal(t, class.ID, announcement.ClassID)
+		assert.Equal(t, manager.ID, announcement.UserID)
+		assert.Equal(t, "test_create_announcement_title", announcement.Title)
+		assert.Equal(t, "test_create_announcement_content", announcement.Content)
+		assert.True(t, announcement.Pinned)
+		assert.Equal(t, resource.GetUser(&manager), resp.Data.User)
+
+		var notifications []models.Notification
+		assert.NoError(t, base.DB.Order("user_id").Find(&notifications, "type = ? and target_id = ?",
+			models.NotificationTypeAnnouncement, announcement.ID).Error)
+		assert.Len(t, notifications, 2)
+		for i, u := range []models.User{student1, student2} {
+			assert.Equal(t, u.ID, notifications[i].UserID)
+			assert.Equal(t, class.ID, notifications[i].ClassID)
+			assert.Equal(t, fmt.Sprintf("[%s] %s", class.Name, announcement.Title), notifications[i].Title)
+			assert.False(t, notifications[i].Read)
+		}
+	})
+}
+
+func TestUpdateAnnouncement(t *testing.T) {
+	t.Parallel()
+	manager := createUserForTest(t, "update_announcement", 0)
+	class := createClassForTest(t, "update_announcement", 0, []*models.User{&manager}, nil)
+	otherClass := createClassForTest(t, "update_announcement", 1, nil, nil)
+	announcement := createAnnouncementForTest(t, "update_announcement", 0, &class, &manager, false)
+
+	failTests := []failTest{
+		{
+			name:   "NonExistingAnnouncement",
+			method: "PUT",
+			path:   base.Echo.Reverse("class.updateAnnouncement", class.ID, -1),
+			req: request.UpdateAnnouncementRequest{
+				Title:   "test_update_announcement_title",
+				Content: "test_update_announcement_content",
+			},
+			reqOptions: []reqOption{
+				applyAdminUser,
+			},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:   "AnnouncementNotInClass",
+			method: "PUT",
+			path:   base.Echo.Reverse("class.updateAnnouncement", otherClass.ID, announcement.ID),
+			req: request.UpdateAnnouncementRequest{
+				Title:   "test_update_announcement_title",
+				Content: "test_update_announcement_content",
+			},
+			reqOptions: []reqOption{
+				applyAdminUser,
+			},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:   "PermissionDenied",
+			method: "PUT",
+			path:   base.Echo.Reverse("class.updateAnnouncement", class.ID, announcement.ID),
+			req: request.UpdateAnnouncementRequest{
+				Title:   "test_update_announcement_title",
+				Content: "test_update_announcement_content",
+			},
+			reqOptions: []reqOption{
+				applyNormalUser,
+			},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "UpdateAnnouncement")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "PUT", base.Echo.Reverse("class.updateAnnouncement", class.ID, announcement.ID), request.UpdateAnnouncementRequest{
+			Title:   "test_update_announcement_title",
+			Content: "test_update_announcement_content",
+			Pinned:  true,
+		}, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.UpdateAnnouncementResponse{}
+		mustJsonDecode(httpResp, &resp)
+
+		databaseAnnouncement := models.Announcement{}
+		assert.NoError(t, base.DB.First(&databaseAnnouncement, announcement.ID).Error)
+		assert.Equal(t, "test_update_announcement_title", databaseAnnouncement.Title)
+		assert.Equal(t, "test_update_announcement_content", databaseAnnouncement.Content)
+		assert.True(t, databaseAnnouncement.Pinned)
+		assert.Equal(t, databaseAnnouncement.Title, resp.Data.Title)
+		assert.Equal(t, resource.GetUser(&manager), resp.Data.User)
+	})
+}
+
+func TestDeleteAnnouncement(t *testing.T) {
+	t.Parallel()
+	manager := createUserForTest(t, "delete_announcement", 0)
+	class := createClassForTest(t, "delete_announcement", 0, []*models.User{&manager}, nil)
+	announcement := createAnnouncementForTest(t, "delete_announcement", 0, &class, &manager, false)
+
+	failTests := []failTest{
+		{
+			name:   "NonExistingAnnouncement",
+			method: "DELETE",
+			path:   base.Echo.Reverse("class.deleteAnnouncement", class.ID, -1),
+			req:    request.DeleteAnnouncementRequest{},
+			reqOptions: []reqOption{
+				applyAdminUser,
+			},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:   "PermissionDenied",
+			method: "DELETE",
+			path:   base.Echo.Reverse("class.deleteAnnouncement", class.ID, announcement.ID),
+			req:    request.DeleteAnnouncementRequest{},
+			reqOptions: []reqOption{
+				applyNormalUser,
+			},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "DeleteAnnouncement")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "DELETE", base.Echo.Reverse("class.deleteAnnouncement", class.ID, announcement.ID),
+			request.DeleteAnnouncementRequest{}, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.Response{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, response.Response{
+			Message: "SUCCESS",
+			Error:   nil,
+			Data:    nil,
+		}, resp)
+		assert.ErrorIs(t, base.DB.First(&models.Announcement{}, announcement.ID).Error, gorm.ErrRecordNotFound)
+	})
+}
@@ -2,7 +2,11 @@ package controller
 
 import (
 	"bytes"
+	"fmt"
+	"math"
 	"net/http"
+	"regexp"
+	"strconv"
 	"time"
 
 	"github.com/EduOJ/backend/base/log"
@@ -23,16 +27,22 @@ import (
 
 // @summary      Login into an account using email/username and password.
 // @description  Login into an account using email/username and password. A token will be returned, together with the
-// @description  user's personal data.
+// @description  user's personal data. If LDAP authentication is enabled, users in the directory could login with
+// @description  their directory passwords too, and are created on their first login. If two-factor authentication
+// @description  is enabled, a challenge is returned instead of a token, which should be finished with a code at
+// @description  /auth/login/two_factor. Failed attempts are delayed with exponential backoff, and the account or the ip
+// @description  is locked out temporarily after too many failures.
 // @router       /auth/login [POST]
 // @produce      json
 // @tags         Auth
 // @param        request  body      request.LoginRequest  true  "The login request."
 // @success      200      {object}  response.LoginResponse
+// @success      202      {object}  response.TwoFactorChallengeResponse  "Two-factor authentication enabled, with message `TWO_FACTOR_REQUIRED`"
 // @failure      500      {object}  response.Response
 // @failure      400      {object}  response.Response{data=[]response.ValidationError}  "Validation error"
 // @failure      404      {object}  response.Response                                   "Wrong username, with message `WRONG_USERNAME`"
 // @failure      403      {object}  response.Response                                   "Wrong password, with message `WRONG_PASSWORD`"
+// @failure      429      {object}  response.Response{error=response.RateLimitError}    "Too many failures of the account or the ip, with message `LOGIN_THROTTLED`"
 func Login(c echo.Context) error {
 	req := request.LoginRequest{}
 	if err, ok := utils.BindAndValidate(&req, c); !ok {
@@ -40,22 +50,77 @@ func Login(c echo.Context) error {
 	}
 	user := models.User{}
 	err := base.DB.Where("email = ? or username = ?", req.UsernameOrEmail, req.UsernameOrEmail).First(&user).Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		panic(errors.Wrap(err, "could not query username or email"))
+	}
+	found := err == nil
+	if err, ok := checkLoginThrottle(c, user.ID, req.UsernameOrEmail); !ok {
+		return err
+	}
+	if !found || !utils.VerifyPassword(req.Password, user.Password) {
+		// Local accounts are tried first, and the LDAP directory is tried then if enabled.
+		ldapUser := loginLDAPUser(req.UsernameOrEmail, req.Password)
+		switch {
+		case ldapUser != nil:
+			user = *ldapUser
+		case !found:
+			recordLoginFailure(c, 0, req.UsernameOrEmail)
 			return c.JSON(http.StatusNotFound, response.ErrorResp("WRONG_USERNAME", nil))
-		} else {
-			panic(errors.Wrap(err, "could not query username or email"))
+		default:
+			recordLoginFailure(c, user.ID, user.Username)
+			return c.JSON(http.StatusForbidden, response.ErrorResp("WRONG_PASSWORD", nil))
 		}
 	}
-	if !utils.VerifyPassword(req.Password, user.Password) {
-		return c.JSON(http.StatusForbidden, response.ErrorResp("WRONG_PASSWORD", nil))
+	return loginOrChallenge(c, user, req.RememberMe)
+}
+
+// checkLoginThrottle rejects the login attempt to the user from the ip of the request if it is throttled.
+// The user id is 0 if the account does not exist locally, in which case the submitted username is used.
+func checkLoginThrottle(c echo.Context, userID uint, username string) (err error, ok bool) {
+	limit, retryAfter, err := utils.CheckLoginThrottle(userID, username, c.RealIP())
+	if err != nil {
+		panic(errors.Wrap(err, "could not check login throttle"))
+	}
+	if limit == "" {
+		return nil, true
+	}
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	c.Response().Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	return c.JSON(http.StatusTooManyRequests, response.ErrorResp("LOGIN_THROTTLED", response.RateLimitError{
+		Limit:      limit,
+		RetryAfter: seconds,
+	})), false
+}
+
+func recordLoginFailure(c echo.Context, userID uint, username string) {
+	if err := utils.RecordLoginFailure(userID, username, c.RealIP()); err != nil {
+		panic(errors.Wrap(err, "could not record login failure"))
+	}
+}
+
+// loginOrChallenge logs the user in after the first factor, or responds a two-factor challenge if the user
+// enabled two-factor authentication. Every login with the first factor should end here.
+// The login failures of the account are cleared only after the last factor succeeds.
+func loginOrChallenge(c echo.Context, user models.User, rememberMe bool) error {
+	credential, err := utils.GetTOTPCredential(user.ID)
+	if err != nil {
+		panic(err)
+	}
+	if credential != nil {
+		return beginTwoFactorLogin(c, user, rememberMe)
 	}
-	token := models.Token{
-		Token:      utils.RandStr(32),
-		User:       user,
-		RememberMe: req.RememberMe,
+	if err := utils.ResetLoginFailures(user.ID); err != nil {
+		panic(err)
+	}
+	return loginResponse(c, user, rememberMe)
+}
+
+// loginResponse creates a token for the user, and responds it together with the user's personal data.
+func loginResponse(c echo.Context, user models.User, rememberMe bool) error {
+	token, err := utils.NewToken(user, rememberMe, c.Request().UserAgent(), c.RealIP())
+	if err != nil {
+		panic(errors.Wrap(err, "could not create token for users"))
 	}
-	utils.PanicIfDBError(base.DB.Create(&token), "could not create token for users")
 	if !user.RoleLoaded {
 		user.LoadRoles()
 	}
@@ -110,11 +175,10 @@ func Register(c echo.Context) error {
 	if _, err := event.FireEvent("register", &user); err != nil {
 		panic(err)
 	}
-	token := models.Token{
-		Token: utils.RandStr(32),
-		User:  user,
+	token, err := utils.NewToken(user, false, c.Request().UserAgent(), c.RealIP())
+	if err != nil {
+		panic(errors.Wrap(err, "could not create token for user"))
 	}
-	utils.PanicIfDBError(base.DB.Create(&token), "could not create token for user")
 	return c.JSON(http.StatusCreated, response.RegisterResponse{
 		Message: "SUCCESS",
 		Error:   nil,
@@ -273,6 +337,7 @@ func ResendEmailVerification(c echo.Context) error {
 // @success      408      {object}  response.Response                                   "the verification code is expired, with message `CODE_EXPIRED`"
 // @success      408      {object}  response.Response                                   "the verification code is used, with message `CODE_USED`"
 // @success      404      {object}  response.Response                                   "user not found, with message `NOT_FOUND`"
+// @failure      429      {object}  response.Response{error=response.RateLimitError}    "Too many failures of the account or the ip, with message `LOGIN_THROTTLED`"
 // @security     ApiKeyAuth
 func DoResetPassword(c echo.Context) error {
 	req := request.DoResetPasswordRequest{}
@@ -289,10 +354,14 @@ func DoResetPassword(c echo.Context) error {
 			panic(errors.Wrap(err, "could not query username or email"))
 		}
 	}
+	if err, ok := checkLoginThrottle(c, user.ID, user.Username); !ok {
+		return err
+	}
 	var code models.EmailVerificationToken
 	err = base.DB.Where("user_id = ? and token = ? and email = ?", user.ID, req.Token, user.Email).First(&code).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			recordLoginFailure(c, user.ID, user.Username)
 			return c.JSON(http.StatusUnauthorized, response.ErrorResp("WRONG_CODE", nil))
 		} else {
 			panic(err)
@@ -309,6 +378,9 @@ func DoResetPassword(c echo.Context) error {
 	user.Password = utils.HashPassword(req.Password)
 	utils.PanicIfDBError(base.DB.Save(&user), "could not save user")
 	base.DB.Where("user_id = ?", user.ID).Delete(models.Token{}) // logout existing user
+	if err := utils.ResetLoginFailures(user.ID); err != nil {
+		panic(err)
+	}
 	return c.JSON(http.StatusOK, response.EmailVerificationResponse{
 		Message: "SUCCESS",
 		Error:   nil,
@@ -411,3 +483,40 @@ func UpdateEmail(c echo.Context) error {
 		},
 	})
 }
+
+var invalidUsernameChars = regexp.MustCompile("[^a-zA-Z0-9_]")
+
+// provisionUser creates a user for an external identity. The username is sanitized, and is made unique
+// with a number suffix. The password is random, so the user could only login with the identity
+// until a password is set by resetting it.
+func provisionUser(username, nickname, email string) models.User {
+	prefix := invalidUsernameChars.ReplaceAllString(username, "_")
+	if len(prefix) > 24 {
+		prefix = prefix[:24]
+	}
+	for len(prefix) < 5 {
+		prefix += "_"
+	}
+	username = prefix
+	for i := 1; ; i++ {
+		var count int64
+		utils.PanicIfDBError(base.DB.Model(&models.User{}).Where("username = ?", username).Count(&count),
+			"could not query user count")
+		if count == 0 {
+			break
+		}
+		username = fmt.Sprintf("%s_%d", prefix, i)
+	}
+	if nickname == "" {
+		nickname = username
+	}
+	user := models.User{
+		Username:      username,
+		Nickname:      nickname,
+		Email:         email,
+		Password:      utils.HashPassword(utils.RandStr(32)),
+		EmailVerified: true,
+	}
+	utils.PanicIfDBError(base.DB.Create(&user), "could not create user for external identity")
+	return user
+}
@@ -0,0 +1,219 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/log"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// getClarificationProblemSet finds the problem set in the path and checks if the user
+// is a manager of the clarifications or a student of the class.
+func getClarificationProblemSet(c echo.Context) (problemSet *models.ProblemSet, isManager bool, err error, ok bool) {
+	problemSet = &models.ProblemSet{}
+	if err := base.DB.First(problemSet, "class_id = ? and id = ?", c.Param("class_id"), c.Param("problem_set_id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, c.JSON(http.StatusNotFound, response.ErrorResp("PROBLEM_SET_NOT_FOUND", nil)), false
+		}
+		panic(errors.Wrap(err, "could not find problem set for clarifications"))
+	}
+	user := c.Get("user").(models.User)
+	class := models.Class{ID: problemSet.ClassID}
+	if user.Can("manage_clarifications", class) || user.Can("manage_clarifications") {
+		return problemSet, true, nil, true
+	}
+	if base.DB.Model(&class).Where("id = ?", user.ID).Association("Students").Count() == 0 {
+		return nil, false, c.JSON(http.StatusForbidden, response.ErrorResp("PERMISSION_DENIED", nil)), false
+	}
+	return problemSet, false, nil, true
+}
+
+// getClarifications returns the clarifications in the problem set which could be seen by the user.
+func getClarifications(problemSet *models.ProblemSet, user *models.User, isManager bool) (clarifications []*models.Clarification) {
+	query := base.DB.Preload("User").Preload("Replies.User").Order("id").Where("problem_set_id = ?", problemSet.ID)
+	if !isManager {
+		query = query.Where("user_id = ? or public = ?", user.ID, true)
+	}
+	utils.PanicIfDBError(query.Find(&clarifications), "could not get clarifications")
+	return
+}
+
+func notifyClarificationUpdate(problemSetID uint) {
+	if !inTest {
+		base.Redis.Publish(context.Background(), fmt.Sprintf("clarification_update:%d", problemSetID), nil)
+	}
+}
+
+func GetClarifications(c echo.Context) error {
+	var startedAt time.Time
+	poll := false
+	if c.QueryParam("poll") == "1" {
+		poll = true
+	}
+	if err := echo.QueryParamsBinder(c).Time("before", &startedAt, time.RFC3339).BindError(); err != nil {
+		// Ignore error.
+		log.Error(err)
+	}
+	problemSet, isManager, err, ok := getClarificationProblemSet(c)
+	if !ok {
+		return err
+	}
+	var timer *time.Timer
+	var sub *redis.PubSub
+	if poll {
+		timer = time.NewTimer(viper.GetDuration("polling_timeout"))
+		sub = base.Redis.Subscribe(c.Request().Context(), fmt.Sprintf("clarification_update:%d", problemSet.ID))
+		defer sub.Close()
+		defer timer.Stop()
+	}
+	user := c.Get("user").(models.User)
+	updated := func(clarifications []*models.Clarification) bool {
+		for _, clarification := range clarifications {
+			if clarification.UpdatedAt.After(startedAt) {
+				return true
+			}
+		}
+		return false
+	}
+	clarifications := getClarifications(problemSet, &user, isManager)
+	if poll && !updated(clarifications) {
+		timeout := false
+		for !timeout {
+			select {
+			case <-sub.Channel():
+				clarifications = getClarifications(problemSet, &user, isManager)
+			case <-c.Request().Context().Done():
+				// context cancelled
+				return nil
+			case <-timer.C:
+				timeout = true
+			}
+			if updated(clarifications) {
+				break
+			}
+		}
+	}
+	return c.JSON(http.StatusOK, response.GetClarificationsResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			Clarifications []resource.Clarification `json:"clarifications"`
+		}{
+			resource.GetClarificationSlice(clarifications),
+		},
+	})
+}
+
+func CreateClarification(c echo.Context) error {
+	req := request.CreateClarificationRequest{}
+	err, ok := utils.BindAndValidate(&req, c)
+	if !ok {
+		return err
+	}
+	problemSet, _, err, ok := getClarificationProblemSet(c)
+	if !ok {
+		return err
+	}
+	if req.ProblemID != 0 &&
+		base.DB.Model(problemSet).Where("id = ?", req.ProblemID).Association("Problems").Count() == 0 {
+		return c.JSON(http.StatusNotFound, response.ErrorResp("PROBLEM_NOT_FOUND", nil))
+	}
+	user := c.Get("user").(models.User)
+	clarification := models.Clarification{
+		ProblemSetID: problemSet.ID,
+		ProblemID:    req.ProblemID,
+		UserID:       user.ID,
+		User:         &user,
+		Content:      req.Content,
+		Replies:      []models.ClarificationReply{},
+	}
+	utils.PanicIfDBError(base.DB.Omit(clause.Associations).Create(&clarification), "could not create clarification")
+	notifyClarificationUpdate(problemSet.ID)
+	return c.JSON(http.StatusCreated, response.CreateClarificationResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.Clarification `json:"clarification"`
+		}{
+			resource.GetClarification(&clarification),
+		},
+	})
+}
+
+func ReplyClarification(c echo.Context) error {
+	req := request.ReplyClarificationRequest{}
+	err, ok := utils.BindAndValidate(&req, c)
+	if !ok {
+		return err
+	}
+	problemSet, isManager, err, ok := getClarificationProblemSet(c)
+	if !ok {
+		return err
+	}
+	user := c.Get("user").(models.User)
+	clarification := models.Clarification{}
+	if err := base.DB.First(&clarification, "problem_set_id = ? and id = ?", problemSet.ID, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not find clarification for replying"))
+	}
+	if !isManager && (clarification.UserID != user.ID || req.Public) {
+		return c.JSON(http.StatusForbidden, response.ErrorResp("PERMISSION_DENIED", nil))
+	}
+	reply := models.ClarificationReply{
+		ClarificationID: clarification.ID,
+		UserID:          user.ID,
+		Content:         req.Content,
+	}
+	utils.PanicIfDBError(base.DB.Create(&reply), "could not create clarification reply")
+	if req.Public {
+		clarification.Public = true
+	}
+	// Saving the clarification updates its UpdatedAt, which is used by polling.
+	utils.PanicIfDBError(base.DB.Omit(clause.Associations).Save(&clarification), "could not update clarification")
+	utils.PanicIfDBError(base.DB.Preload("User").Preload("Replies.User").First(&clarification, clarification.ID),
+		"could not reload clarification")
+
+	if isManager {
+		recipients := []uint{clarification.UserID}
+		if req.Public {
+			recipients = nil
+			utils.PanicIfDBError(base.DB.Table("user_in_classes").Where("class_id = ? and user_id <> ?", problemSet.ClassID, user.ID).
+				Pluck("user_id", &recipients), "could not get students for notifying clarification")
+		}
+		if err := utils.CreateNotifications(recipients, models.Notification{
+			Type:     models.NotificationTypeClarification,
+			Title:    fmt.Sprintf("Clarification in problem set %s is answered", problemSet.Name),
+			Content:  fmt.Sprintf("%s\n\n%s", clarification.Content, reply.Content),
+			ClassID:  problemSet.ClassID,
+			TargetID: clarification.ID,
+		}); err != nil {
+			panic(errors.Wrap(err, "could not notify clarification"))
+		}
+	}
+	notifyClarificationUpdate(problemSet.ID)
+	return c.JSON(http.StatusOK, response.ReplyClarificationResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.Clarification `json:"clarification"`
+		}{
+			resource.GetClarification(&clarification),
+		},
+	})
+}
@@ -0,0 +1,254 @@
+package controller_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func createClarificationForTest(t *testing.T, name string, id int, problemSet *models.ProblemSet, user *models.User, public bool) models.Clarification {
+	clarification := models.Clarification{
+		ProblemSetID: problemSet.ID,
+		UserID:       user.ID,
+		Content:      fmt.Sprintf("test_%s_%d_content", name, id),
+		Public:       public,
+	}
+	assert.NoError(t, base.DB.Create(&clarification).Error)
+	return clarification
+}
+
+func TestGetClarifications(t *testing.T) {
+	t.Parallel()
+	student1 := createUserForTest(t, "get_clarifications", 1)
+	student2 := createUserForTest(t, "get_clarifications", 2)
+	class := createClassForTest(t, "get_clarifications", 0, nil, []*models.User{&student1, &student2})
+	problemSet := createProblemSetForTest(t, "get_clarifications", 0, &class, nil, inProgress)
+	notStartedProblemSet := createProblemSetForTest(t, "get_clarifications", 1, &class, nil, notStartYet)
+	own := createClarificationForTest(t, "get_clarifications", 1, problemSet, &student1, false)
+	private := createClarificationForTest(t, "get_clarifications", 2, problemSet, &student2, false)
+	public := createClarificationForTest(t, "get_clarifications", 3, problemSet, &student2, true)
+
+	failTests := []failTest{
+		{
+			name:   "NonExistingProblemSet",
+			method: "GET",
+			path:   base.Echo.Reverse("problemSet.getClarifications", class.ID, -1),
+			req:    request.GetClarificationsRequest{},
+			reqOptions: []reqOption{
+				applyAdminUser,
+			},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("PROBLEM_SET_NOT_FOUND", nil),
+		},
+		{
+			name:   "NotStudent",
+			method: "GET",
+			path:   base.Echo.Reverse("problemSet.getClarifications", class.ID, problemSet.ID),
+			req:    request.GetClarificationsRequest{},
+			reqOptions: []reqOption{
+				applyNormalUser,
+			},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+		{
+			name:   "NotStarted",
+			method: "GET",
+			path:   base.Echo.Reverse("problemSet.getClarifications", class.ID, notStartedProblemSet.ID),
+			req:    request.GetClarificationsRequest{},
+			reqOptions: []reqOption{
+				applyUser(student1),
+			},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "GetClarifications")
+
+	getIDs := func(resp response.GetClarificationsResponse) (ids []uint) {
+		for _, clarification := range resp.Data.Clarifications {
+			ids = append(ids, clarification.ID)
+		}
+		return
+	}
+	t.Run("Student", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("problemSet.getClarifications", class.ID, problemSet.ID),
+			request.GetClarificationsRequest{}, applyUser(student1)))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.GetClarificationsResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, []uint{own.ID, public.ID}, getIDs(resp))
+	})
+	t.Run("Manager", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("problemSet.getClarifications", class.ID, problemSet.ID),
+			request.GetClarificationsRequest{}, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.GetClarificationsResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, []uint{own.ID, private.ID, public.ID}, getIDs(resp))
+	})
+}
+
+func TestCreateClarification(t *testing.T) {
+	t.Parallel()
+	student := createUserForTest(t, "create_clarification", 0)
+	class := createClassForTest(t, "create_clarification", 0, nil, []*models.User{&student})
+	problem := createProblemForTest(t, "create_clarification", 0, nil, student)
+	otherProblem := createProblemForTest(t, "create_clarification", 1, nil, student)
+	problemSet := createProblemSetForTest(t, "create_clarification", 0, &class, []models.Problem{problem}, inProgress)
+
+	failTests := []failTest{
+		{
+			name:   "NotStudent",
+			method: "POST",
+			path:   base.Echo.Reverse("problemSet.createClarification", class.ID, problemSet.ID),
+			req: request.CreateClarificationRequest{
+				Content: "test_create_clarification_content",
+			},
+			reqOptions: []reqOption{
+				applyNormalUser,
+			},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+		{
+			name:   "ProblemNotInProblemSet",
+			method: "POST",
+			path:   base.Echo.Reverse("problemSet.createClarification", class.ID, problemSet.ID),
+			req: request.CreateClarificationRequest{
+				ProblemID: otherProblem.ID,
+				Content:   "test_create_clarification_content",
+			},
+			reqOptions: []reqOption{
+				applyUser(student),
+			},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("PROBLEM_NOT_FOUND", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "CreateClarification")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("problemSet.createClarification", class.ID, problemSet.ID), request.CreateClarificationRequest{
+			ProblemID: problem.ID,
+			Content:   "test_create_clarification_content",
+		}, applyUser(student)))
+		assert.Equal(t, http.StatusCreated, httpResp.StatusCode)
+		resp := response.CreateClarificationResponse{}
+		mustJsonDecode(httpResp, &resp)
+
+		clarification := models.Clarification{}
+		assert.NoError(t, base.DB.First(&clarification, resp.Data.Clarification.ID).Error)
+		assert.Equal(t, problemSet.ID, clarification.ProblemSetID)
+		assert.Equal(t, problem.ID, clarification.ProblemID)
+		assert.Equal(t, student.ID, clarification.UserID)
+		assert.Equal(t, "test_create_clarification_content", clarification.Content)
+		assert.False(t, clarification.Public)
+	})
+}
+
+func TestReplyClarification(t *testing.T) {
+	t.Parallel()
+	student1 := createUserForTest(t, "reply_clarification", 1)
+	student2 := createUserForTest(t, "reply_clarification", 2)
+	class := createClassForTest(t, "reply_clarification", 0, nil, []*models.User{&student1, &student2})
+	problemSet := createProblemSetForTest(t, "reply_clarification", 0, &class, nil, inProgress)
+	clarification1 := createClarificationForTest(t, "reply_clarification", 1, problemSet, &student1, false)
+	clarification2 := createClarificationForTest(t, "reply_clarification", 2, problemSet, &student1, false)
+
+	failTests := []failTest{
+		{
+			name:   "NonExistingClarification",
+			method: "POST",
+			path:   base.Echo.Reverse("problemSet.replyClarification", class.ID, problemSet.ID, -1),
+			req: request.ReplyClarificationRequest{
+				Content: "test_reply_clarification_content",
+			},
+			reqOptions: []reqOption{
+				applyAdminUser,
+			},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:   "OtherStudent",
+			method: "POST",
+			path:   base.Echo.Reverse("problemSet.replyClarification", class.ID, problemSet.ID, clarification1.ID),
+			req: request.ReplyClarificationRequest{
+				Content: "test_reply_clarification_content",
+			},
+			reqOptions: []reqOption{
+				applyUser(student2),
+			},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+		{
+			name:   "StudentBroadcast",
+			method: "POST",
+			path:   base.Echo.Reverse("problemSet.replyClarification", class.ID, problemSet.ID, clarification1.ID),
+			req: request.ReplyClarificationRequest{
+				Content: "test_reply_clarification_content",
+				Public:  true,
+			},
+			reqOptions: []reqOption{
+				applyUser(student1),
+			},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "ReplyClarification")
+
+	t.Run("PrivateAnswer", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("problemSet.replyClarification", class.ID, problemSet.ID, clarification1.ID), request.ReplyClarificationRequest{
+			Content: "test_reply_clarification_private_answer",
+		}, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.ReplyClarificationResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.False(t, resp.Data.Public)
+		assert.Len(t, resp.Data.Replies, 1)
+		assert.Equal(t, "test_reply_clarification_private_answer", resp.Data.Replies[0].Content)
+
+		var notifications []models.Notification
+		assert.NoError(t, base.DB.Find(&notifications, "type = ? and target_id = ?",
+			models.NotificationTypeClarification, clarification1.ID).Error)
+		assert.Len(t, notifications, 1)
+		assert.Equal(t, student1.ID, notifications[0].UserID)
+	})
+	t.Run("Broadcast", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("problemSet.replyClarification", class.ID, problemSet.ID, clarification2.ID), request.ReplyClarificationRequest{
+			Content: "test_reply_clarification_public_answer",
+			Public:  true,
+		}, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.ReplyClarificationResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.True(t, resp.Data.Public)
+
+		clarification := models.Clarification{}
+		assert.NoError(t, base.DB.First(&clarification, clarification2.ID).Error)
+		assert.True(t, clarification.Public)
+		var notifications []models.Notification
+		assert.NoError(t, base.DB.Order("user_id").Find(&notifications, "type = ? and target_id = ?",
+			models.NotificationTypeClarification, clarification2.ID).Error)
+		assert.Len(t, notifications, 2)
+		assert.Equal(t, student1.ID, notifications[0].UserID)
+		assert.Equal(t, student2.ID, notifications[1].UserID)
+	})
+}
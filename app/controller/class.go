@@ -1,16 +1,24 @@
 package controller
 
 import (
+	"bytes"
+	"encoding/csv"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/EduOJ/backend/app/request"
 	"github.com/EduOJ/backend/app/response"
 	"github.com/EduOJ/backend/app/response/resource"
 	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/log"
 	"github.com/EduOJ/backend/base/utils"
+	validator2 "github.com/EduOJ/backend/base/validator"
 	"github.com/EduOJ/backend/database/models"
+	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 	"gorm.io/gorm"
 )
 
@@ -44,9 +52,48 @@ func CreateClass(c echo.Context) error {
 	})
 }
 
+func CloneClass(c echo.Context) error {
+	user := c.Get("user").(models.User)
+	req := request.CloneClassRequest{}
+	err, ok := utils.BindAndValidate(&req, c)
+	if !ok {
+		return err
+	}
+	source := models.Class{}
+	if err := base.DB.Preload("ProblemSets").First(&source, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not get class for cloning class"))
+	}
+	offset := time.Duration(req.TimeOffset) * time.Second
+	if req.StartTime != nil && len(source.ProblemSets) > 0 {
+		termStart := source.ProblemSets[0].StartTime
+		for _, problemSet := range source.ProblemSets {
+			if problemSet.StartTime.Before(termStart) {
+				termStart = problemSet.StartTime
+			}
+		}
+		offset = req.StartTime.Sub(termStart)
+	}
+	class, err := utils.CloneClass(&source, req.Name, offset, &user)
+	if err != nil {
+		panic(err)
+	}
+	return c.JSON(http.StatusCreated, response.CloneClassResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.ClassDetail `json:"class"`
+		}{
+			resource.GetClassDetail(class),
+		},
+	})
+}
+
 func GetClass(c echo.Context) error {
 	class := models.Class{}
-	if err := base.DB.Preload("Managers").Preload("Students").Preload("ProblemSets").
+	if err := base.DB.Preload("Managers").Preload("Students").Preload("Assistants").Preload("ProblemSets").
 		First(&class, c.Param("id")).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
@@ -121,6 +168,7 @@ func RefreshInviteCode(c echo.Context) error {
 		}
 	}
 	class.InviteCode = utils.GenerateInviteCode()
+	class.InviteCodeUses = 0
 	utils.PanicIfDBError(base.DB.Save(&class), "could not update class for refreshing invite code")
 	return c.JSON(http.StatusOK, response.RefreshInviteCodeResponse{
 		Message: "SUCCESS",
@@ -133,6 +181,34 @@ func RefreshInviteCode(c echo.Context) error {
 	})
 }
 
+func UpdateInviteCodeSettings(c echo.Context) error {
+	req := request.UpdateInviteCodeSettingsRequest{}
+	err, ok := utils.BindAndValidate(&req, c)
+	if !ok {
+		return err
+	}
+	class := models.Class{}
+	if err := base.DB.Preload("Managers").Preload("Students").Preload("Assistants").First(&class, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not find class for updating invite code settings"))
+	}
+	class.InviteCodeExpireAt = req.ExpireAt
+	class.InviteCodeMaxUses = req.MaxUses
+	class.RequireApproval = req.RequireApproval
+	utils.PanicIfDBError(base.DB.Save(&class), "could not update invite code settings")
+	return c.JSON(http.StatusOK, response.UpdateInviteCodeSettingsResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.ClassDetail `json:"class"`
+		}{
+			resource.GetClassDetail(&class),
+		},
+	})
+}
+
 func AddStudents(c echo.Context) error {
 	req := request.AddStudentsRequest{}
 	err, ok := utils.BindAndValidate(&req, c)
@@ -150,6 +226,9 @@ func AddStudents(c echo.Context) error {
 	if err := class.AddStudents(req.UserIds); err != nil {
 		panic(errors.Wrap(err, "could not add students"))
 	}
+	if err := utils.RecalculateStudentGrades(class.ID, req.UserIds); err != nil {
+		panic(errors.Wrap(err, "could not recalculate grades for adding students"))
+	}
 	return c.JSON(http.StatusOK, response.AddStudentsResponse{
 		Message: "SUCCESS",
 		Error:   nil,
@@ -161,6 +240,167 @@ func AddStudents(c echo.Context) error {
 	})
 }
 
+func ImportStudents(c echo.Context) error {
+	file, err := c.FormFile("file")
+	if err != nil && err != http.ErrMissingFile && err.Error() != "request Content-Type isn't multipart/form-data" {
+		panic(errors.Wrap(err, "could not read file"))
+	}
+	if file == nil {
+		return c.JSON(http.StatusBadRequest, response.ErrorResp("INVALID_FILE", nil))
+	}
+	class := models.Class{}
+	if err := base.DB.Preload("Managers").Preload("Students").First(&class, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		} else {
+			panic(errors.Wrap(err, "could not find class for importing students"))
+		}
+	}
+	src, err := file.Open()
+	if err != nil {
+		panic(errors.Wrap(err, "could not open file for importing students"))
+	}
+	defer src.Close()
+	records, err := csv.NewReader(src).ReadAll()
+	if err != nil || len(records) == 0 {
+		return c.JSON(http.StatusBadRequest, response.ErrorResp("INVALID_FILE", nil))
+	}
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	usernameColumn, ok1 := columns["username"]
+	nicknameColumn, ok2 := columns["nickname"]
+	emailColumn, ok3 := columns["email"]
+	if !ok1 || !ok2 || !ok3 {
+		return c.JSON(http.StatusBadRequest, response.ErrorResp("INVALID_FILE", nil))
+	}
+
+	inClass := make(map[uint]bool)
+	for _, s := range class.Students {
+		inClass[s.ID] = true
+	}
+	rows := make([]response.ImportStudentsRow, 0, len(records)-1)
+	var ids []uint
+	// The credentials are only mailed after the whole roster is saved.
+	var created []*models.User
+	var passwords []string
+	err = base.DB.Transaction(func(tx *gorm.DB) error {
+		for i, record := range records[1:] {
+			row := response.ImportStudentsRow{
+				Line:     i + 2,
+				Username: strings.TrimSpace(record[usernameColumn]),
+				Nickname: strings.TrimSpace(record[nicknameColumn]),
+				Email:    strings.TrimSpace(record[emailColumn]),
+			}
+			// Empty columns are not used for matching, or they would match users without the field.
+			var conditions []string
+			var args []interface{}
+			if row.Email != "" {
+				conditions = append(conditions, "email = ?")
+				args = append(args, row.Email)
+			}
+			if row.Username != "" {
+				conditions = append(conditions, "username = ?")
+				args = append(args, row.Username)
+			}
+			var users []models.User
+			if len(conditions) != 0 {
+				if err := tx.Where(strings.Join(conditions, " or "), args...).Find(&users).Error; err != nil {
+					return errors.Wrap(err, "could not find users for importing students")
+				}
+			}
+			switch {
+			case len(users) > 1:
+				row.Status = "CONFLICT"
+			case len(users) == 1:
+				row.UserID = users[0].ID
+				if inClass[row.UserID] {
+					row.Status = "ALREADY_IN_CLASS"
+				} else {
+					row.Status = "ADDED"
+				}
+			default:
+				newUser := request.ImportStudentsRow{
+					Username: row.Username,
+					Nickname: row.Nickname,
+					Email:    row.Email,
+				}
+				if err := c.Validate(&newUser); err != nil {
+					e, ok := err.(validator.ValidationErrors)
+					if !ok {
+						return errors.Wrap(err, "could not validate row for importing students")
+					}
+					row.Status = "INVALID"
+					row.Errors = make([]response.ValidationError, len(e))
+					for j, v := range e {
+						row.Errors[j] = response.ValidationError{
+							Field:       v.Field(),
+							Reason:      v.Tag(),
+							Translation: v.Translate(validator2.Trans),
+						}
+					}
+					break
+				}
+				password := utils.RandStr(16)
+				user := models.User{
+					Username: newUser.Username,
+					Nickname: newUser.Nickname,
+					Email:    newUser.Email,
+					Password: utils.HashPassword(password),
+					// The credentials are sent to this address.
+					EmailVerified: true,
+				}
+				if err := tx.Create(&user).Error; err != nil {
+					return errors.Wrap(err, "could not create user for importing students")
+				}
+				created = append(created, &user)
+				passwords = append(passwords, password)
+				row.UserID = user.ID
+				row.Status = "CREATED"
+			}
+			if row.Status == "ADDED" || row.Status == "CREATED" {
+				inClass[row.UserID] = true
+				ids = append(ids, row.UserID)
+			}
+			rows = append(rows, row)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		var students []models.User
+		if err := tx.Find(&students, ids).Error; err != nil {
+			return errors.Wrap(err, "could not find students for importing students")
+		}
+		if err := tx.Model(&class).Association("Students").Append(&students); err != nil {
+			return errors.Wrap(err, "could not add students for importing students")
+		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	for i, user := range created {
+		sendAccountCreatedEmail(user, passwords[i], &class)
+	}
+	if err := utils.RecalculateStudentGrades(class.ID, ids); err != nil {
+		panic(errors.Wrap(err, "could not recalculate grades for importing students"))
+	}
+	utils.PanicIfDBError(base.DB.Preload("Managers").Preload("Students").First(&class, class.ID),
+		"could not reload class for importing students")
+	return c.JSON(http.StatusOK, response.ImportStudentsResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.ClassDetail `json:"class"`
+			Rows                  []response.ImportStudentsRow `json:"rows"`
+		}{
+			resource.GetClassDetail(&class),
+			rows,
+		},
+	})
+}
+
 func DeleteStudents(c echo.Context) error {
 	req := request.DeleteStudentsRequest{}
 	err, ok := utils.BindAndValidate(&req, c)
@@ -189,6 +429,62 @@ func DeleteStudents(c echo.Context) error {
 	})
 }
 
+func AddAssistants(c echo.Context) error {
+	req := request.AddAssistantsRequest{}
+	err, ok := utils.BindAndValidate(&req, c)
+	if !ok {
+		return err
+	}
+	class := models.Class{}
+	if err := base.DB.Preload("Managers").Preload("Students").Preload("Assistants").First(&class, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		} else {
+			panic(errors.Wrap(err, "could not find class for adding assistants"))
+		}
+	}
+	if err := class.AddAssistants(req.UserIds); err != nil {
+		panic(errors.Wrap(err, "could not add assistants"))
+	}
+	return c.JSON(http.StatusOK, response.AddAssistantsResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.ClassDetail `json:"class"`
+		}{
+			resource.GetClassDetail(&class),
+		},
+	})
+}
+
+func DeleteAssistants(c echo.Context) error {
+	req := request.DeleteAssistantsRequest{}
+	err, ok := utils.BindAndValidate(&req, c)
+	if !ok {
+		return err
+	}
+	class := models.Class{}
+	if err := base.DB.Preload("Managers").Preload("Students").Preload("Assistants").First(&class, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		} else {
+			panic(errors.Wrap(err, "could not find class for deleting assistants"))
+		}
+	}
+	if err := class.DeleteAssistants(req.UserIds); err != nil {
+		panic(errors.Wrap(err, "could not delete assistants"))
+	}
+	return c.JSON(http.StatusOK, response.DeleteAssistantsResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.ClassDetail `json:"class"`
+		}{
+			resource.GetClassDetail(&class),
+		},
+	})
+}
+
 func JoinClass(c echo.Context) error {
 	req := request.JoinClassRequest{}
 	err, ok := utils.BindAndValidate(&req, c)
@@ -206,14 +502,50 @@ func JoinClass(c echo.Context) error {
 	if class.InviteCode != req.InviteCode {
 		return c.JSON(http.StatusForbidden, response.ErrorResp("WRONG_INVITE_CODE", nil))
 	}
+	if class.InviteCodeExpireAt != nil && time.Now().After(*class.InviteCodeExpireAt) {
+		return c.JSON(http.StatusForbidden, response.ErrorResp("INVITE_CODE_EXPIRED", nil))
+	}
 	user := c.Get("user").(models.User)
 	count := base.DB.Model(&class).Where("id = ?", user.ID).Association("Students").Count()
 	if count > 0 {
 		return c.JSON(http.StatusBadRequest, response.ErrorResp("ALREADY_IN_CLASS", nil))
 	}
+	if class.RequireApproval {
+		var pending int64
+		utils.PanicIfDBError(base.DB.Model(&models.ClassJoinRequest{}).
+			Where("class_id = ? and user_id = ? and status = ?", class.ID, user.ID, models.ClassJoinRequestStatusPending).
+			Count(&pending), "could not count pending join requests")
+		if pending > 0 {
+			return c.JSON(http.StatusBadRequest, response.ErrorResp("ALREADY_REQUESTED", nil))
+		}
+	}
+	if !useInviteCode(&class) {
+		return c.JSON(http.StatusForbidden, response.ErrorResp("INVITE_CODE_USED_UP", nil))
+	}
+	if class.RequireApproval {
+		joinRequest := models.ClassJoinRequest{
+			ClassID: class.ID,
+			UserID:  user.ID,
+			User:    &user,
+			Status:  models.ClassJoinRequestStatusPending,
+		}
+		utils.PanicIfDBError(base.DB.Omit("User").Create(&joinRequest), "could not create join request")
+		return c.JSON(http.StatusAccepted, response.RequestJoinClassResponse{
+			Message: "SUCCESS",
+			Error:   nil,
+			Data: struct {
+				*resource.ClassJoinRequest `json:"join_request"`
+			}{
+				resource.GetClassJoinRequest(&joinRequest),
+			},
+		})
+	}
 	if err := base.DB.Model(&class).Association("Students").Append(&user); err != nil {
 		panic(errors.Wrap(err, "could not add student for joining class"))
 	}
+	if err := utils.RecalculateStudentGrades(class.ID, []uint{user.ID}); err != nil {
+		panic(errors.Wrap(err, "could not recalculate grades for joining class"))
+	}
 	return c.JSON(http.StatusOK, response.JoinClassResponse{
 		Message: "SUCCESS",
 		Error:   nil,
@@ -225,6 +557,111 @@ func JoinClass(c echo.Context) error {
 	})
 }
 
+// useInviteCode counts a use of the invite code of the class, and returns false if it is used up.
+func useInviteCode(class *models.Class) bool {
+	result := base.DB.Model(&models.Class{}).
+		Where("id = ? and (invite_code_max_uses = 0 or invite_code_uses < invite_code_max_uses)", class.ID).
+		Update("invite_code_uses", gorm.Expr("invite_code_uses + 1"))
+	utils.PanicIfDBError(result, "could not use invite code")
+	return result.RowsAffected > 0
+}
+
+func GetClassJoinRequests(c echo.Context) error {
+	req := request.GetClassJoinRequestsRequest{}
+	err, ok := utils.BindAndValidate(&req, c)
+	if !ok {
+		return err
+	}
+	class := models.Class{}
+	if err := base.DB.First(&class, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not find class for getting join requests"))
+	}
+	query := base.DB.Preload("User").Preload("Operator").Where("class_id = ?", class.ID).Order("id desc")
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+	var joinRequests []*models.ClassJoinRequest
+	utils.PanicIfDBError(query.Find(&joinRequests), "could not get join requests")
+	return c.JSON(http.StatusOK, response.GetClassJoinRequestsResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			JoinRequests []resource.ClassJoinRequest `json:"join_requests"`
+		}{
+			resource.GetClassJoinRequestSlice(joinRequests),
+		},
+	})
+}
+
+// getPendingClassJoinRequest finds the pending join request in the path.
+func getPendingClassJoinRequest(c echo.Context) (joinRequest *models.ClassJoinRequest, err error, ok bool) {
+	joinRequest = &models.ClassJoinRequest{}
+	if err := base.DB.Preload("User").
+		First(joinRequest, "id = ? and class_id = ?", c.Param("request_id"), c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil)), false
+		}
+		panic(errors.Wrap(err, "could not find join request"))
+	}
+	if joinRequest.Status != models.ClassJoinRequestStatusPending {
+		return nil, c.JSON(http.StatusBadRequest, response.ErrorResp("ALREADY_HANDLED", nil)), false
+	}
+	return joinRequest, nil, true
+}
+
+func ApproveClassJoinRequest(c echo.Context) error {
+	joinRequest, err, ok := getPendingClassJoinRequest(c)
+	if !ok {
+		return err
+	}
+	class := models.Class{}
+	utils.PanicIfDBError(base.DB.Preload("Students").First(&class, joinRequest.ClassID), "could not find class for approving join request")
+	if err := class.AddStudents([]uint{joinRequest.UserID}); err != nil {
+		panic(errors.Wrap(err, "could not add student for approving join request"))
+	}
+	if err := utils.RecalculateStudentGrades(class.ID, []uint{joinRequest.UserID}); err != nil {
+		panic(errors.Wrap(err, "could not recalculate grades for approving join request"))
+	}
+	user := c.Get("user").(models.User)
+	joinRequest.Status = models.ClassJoinRequestStatusApproved
+	joinRequest.OperatorID = user.ID
+	joinRequest.Operator = &user
+	utils.PanicIfDBError(base.DB.Omit("User", "Operator").Save(joinRequest), "could not approve join request")
+	return c.JSON(http.StatusOK, response.ApproveClassJoinRequestResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.ClassJoinRequest `json:"join_request"`
+		}{
+			resource.GetClassJoinRequest(joinRequest),
+		},
+	})
+}
+
+func RejectClassJoinRequest(c echo.Context) error {
+	joinRequest, err, ok := getPendingClassJoinRequest(c)
+	if !ok {
+		return err
+	}
+	user := c.Get("user").(models.User)
+	joinRequest.Status = models.ClassJoinRequestStatusRejected
+	joinRequest.OperatorID = user.ID
+	joinRequest.Operator = &user
+	utils.PanicIfDBError(base.DB.Omit("User", "Operator").Save(joinRequest), "could not reject join request")
+	return c.JSON(http.StatusOK, response.RejectClassJoinRequestResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.ClassJoinRequest `json:"join_request"`
+		}{
+			resource.GetClassJoinRequest(joinRequest),
+		},
+	})
+}
+
 func DeleteClass(c echo.Context) error {
 	class := models.Class{}
 	if err := base.DB.First(&class, c.Param("id")).Error; err != nil {
@@ -240,3 +677,30 @@ func DeleteClass(c echo.Context) error {
 		Data:    nil,
 	})
 }
+
+func sendAccountCreatedEmail(user *models.User, password string, class *models.Class) {
+	action := func() {
+		if viper.GetBool("email.inTest") {
+			return
+		}
+		buf := new(bytes.Buffer)
+		if err := base.Template.ExecuteTemplate(buf, "account_created.html", map[string]string{
+			"Nickname": user.Nickname,
+			"Username": user.Username,
+			"Password": password,
+			"Class":    class.Name,
+		}); err != nil {
+			log.Errorf("%+v\n", err)
+			return
+		}
+		if err := utils.SendMail(user.Email, "Your EduOJ account", buf.String()); err != nil {
+			log.Errorf("%+v\n", err)
+			return
+		}
+	}
+	if viper.GetBool("email.inTest") {
+		action()
+	} else {
+		go action()
+	}
+}
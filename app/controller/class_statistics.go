@@ -0,0 +1,275 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+const scoreDistributionBuckets = 10
+
+// submissionDayExpression formats the creation date of a submission as YYYY-MM-DD in the database.
+func submissionDayExpression() string {
+	switch viper.GetString("database.dialect") {
+	case "sqlite":
+		return "strftime('%Y-%m-%d', created_at)"
+	case "mysql":
+		return "date_format(created_at, '%Y-%m-%d')"
+	default:
+		return "to_char(created_at, 'YYYY-MM-DD')"
+	}
+}
+
+type problemSetStatisticsQuery struct {
+	problemSetIDs []uint
+	students      *gorm.DB
+}
+
+// submissions returns a query of the submissions of the students in the problem sets.
+func (q *problemSetStatisticsQuery) submissions() *gorm.DB {
+	return base.DB.Model(&models.Submission{}).
+		Where("problem_set_id in (?) and user_id in (?)", q.problemSetIDs, q.students)
+}
+
+// problemCounts returns a query of the number of problems and the total full mark of each problem set.
+func (q *problemSetStatisticsQuery) problemCounts() *gorm.DB {
+	return base.DB.Table("problems_in_problem_sets").
+		Select("problem_set_id, count(*) as problem_count, sum(full_mark) as full_mark").
+		Group("problem_set_id")
+}
+
+func (q *problemSetStatisticsQuery) dailySubmissions() (result map[uint][]resource.DailySubmissionCount) {
+	var rows []struct {
+		ProblemSetID uint
+		Day          string
+		Count        uint
+	}
+	day := submissionDayExpression()
+	utils.PanicIfDBError(q.submissions().Select(fmt.Sprintf("problem_set_id, %s as day, count(*) as count", day)).
+		Group("problem_set_id, "+day).Order(day).Scan(&rows), "could not count daily submissions")
+	result = make(map[uint][]resource.DailySubmissionCount)
+	for _, row := range rows {
+		result[row.ProblemSetID] = append(result[row.ProblemSetID], resource.DailySubmissionCount{
+			Date:  row.Day,
+			Count: row.Count,
+		})
+	}
+	return
+}
+
+func (q *problemSetStatisticsQuery) scoreDistributions() (result map[uint][]uint) {
+	selects := []string{"grades.problem_set_id"}
+	for i := 0; i < scoreDistributionBuckets; i++ {
+		condition := fmt.Sprintf("grades.total * %d >= f.full_mark * %d", scoreDistributionBuckets, i)
+		if i != scoreDistributionBuckets-1 {
+			condition += fmt.Sprintf(" and grades.total * %d < f.full_mark * %d", scoreDistributionBuckets, i+1)
+		}
+		selects = append(selects, fmt.Sprintf("sum(case when %s then 1 else 0 end)", condition))
+	}
+	rows, err := base.DB.Table("grades").Select(strings.Join(selects, ", ")).
+		Joins("join (?) f on f.problem_set_id = grades.problem_set_id", q.problemCounts()).
+		Where("grades.problem_set_id in (?) and grades.user_id in (?)", q.problemSetIDs, q.students).
+		Group("grades.problem_set_id").Rows()
+	if err != nil {
+		panic(errors.Wrap(err, "could not count score distributions"))
+	}
+	defer rows.Close()
+	result = make(map[uint][]uint)
+	for rows.Next() {
+		var problemSetID uint
+		buckets := make([]uint, scoreDistributionBuckets)
+		dest := []interface{}{&problemSetID}
+		for i := range buckets {
+			dest = append(dest, &buckets[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			panic(errors.Wrap(err, "could not scan score distributions"))
+		}
+		result[problemSetID] = buckets
+	}
+	return
+}
+
+// completedCounts counts the students who solved all the problems in each problem set before it ends.
+func (q *problemSetStatisticsQuery) completedCounts() (result map[uint]uint) {
+	completed := base.DB.Table("submissions s").Select("s.problem_set_id, s.user_id").
+		Joins("join problems_in_problem_sets p on p.problem_set_id = s.problem_set_id and p.problem_id = s.problem_id").
+		Joins("join (?) f on f.problem_set_id = s.problem_set_id", q.problemCounts()).
+		Where("s.deleted_at is null and s.status = ? and s.upsolve = ? and s.problem_set_id in (?) and s.user_id in (?)",
+			"ACCEPTED", false, q.problemSetIDs, q.students).
+		Group("s.problem_set_id, s.user_id, f.problem_count").
+		Having("count(distinct s.problem_id) = f.problem_count")
+	var rows []struct {
+		ProblemSetID uint
+		Count        uint
+	}
+	utils.PanicIfDBError(base.DB.Table("(?) as c", completed).Select("problem_set_id, count(*) as count").
+		Group("problem_set_id").Scan(&rows), "could not count students completed problem sets")
+	result = make(map[uint]uint)
+	for _, row := range rows {
+		result[row.ProblemSetID] = row.Count
+	}
+	return
+}
+
+type problemStatisticsKey struct {
+	problemSetID uint
+	problemID    uint
+}
+
+func (q *problemSetStatisticsQuery) problemStatistics() (result map[problemStatisticsKey]*resource.ProblemStatistics) {
+	result = make(map[problemStatisticsKey]*resource.ProblemStatistics)
+	get := func(problemSetID, problemID uint) *resource.ProblemStatistics {
+		key := problemStatisticsKey{problemSetID, problemID}
+		if result[key] == nil {
+			result[key] = &resource.ProblemStatistics{
+				FailureStatuses: []resource.StatusCount{},
+			}
+		}
+		return result[key]
+	}
+
+	var statusRows []struct {
+		ProblemSetID uint
+		ProblemID    uint
+		Status       string
+		Count        uint
+	}
+	utils.PanicIfDBError(q.submissions().Select("problem_set_id, problem_id, status, count(*) as count").
+		Group("problem_set_id, problem_id, status").Scan(&statusRows), "could not count submission statuses")
+	for _, row := range statusRows {
+		statistics := get(row.ProblemSetID, row.ProblemID)
+		statistics.SubmissionCount += row.Count
+		if row.Status != "ACCEPTED" && row.Status != "PENDING" {
+			statistics.FailureStatuses = append(statistics.FailureStatuses, resource.StatusCount{
+				Status: row.Status,
+				Count:  row.Count,
+			})
+		}
+	}
+	for _, statistics := range result {
+		sort.SliceStable(statistics.FailureStatuses, func(i, j int) bool {
+			return statistics.FailureStatuses[i].Count > statistics.FailureStatuses[j].Count
+		})
+	}
+
+	// Solving a problem by upsolving is not counted.
+	firstAccepted := q.submissions().Select("problem_set_id, problem_id, user_id, min(id) as first_accepted").
+		Where("status = ? and upsolve = ?", "ACCEPTED", false).Group("problem_set_id, problem_id, user_id")
+	var attemptRows []struct {
+		ProblemSetID uint
+		ProblemID    uint
+		Accepted     uint
+		Attempts     uint
+	}
+	utils.PanicIfDBError(base.DB.Table("(?) as a", firstAccepted).
+		Select("a.problem_set_id, a.problem_id, count(distinct a.user_id) as accepted, count(s.id) as attempts").
+		Joins("join submissions s on s.problem_set_id = a.problem_set_id and s.problem_id = a.problem_id "+
+			"and s.user_id = a.user_id and s.id <= a.first_accepted and s.upsolve = ? and s.deleted_at is null", false).
+		Group("a.problem_set_id, a.problem_id").Scan(&attemptRows), "could not count attempts until accepted")
+	for _, row := range attemptRows {
+		statistics := get(row.ProblemSetID, row.ProblemID)
+		statistics.AcceptedCount = row.Accepted
+		if row.Accepted > 0 {
+			statistics.AverageAttempts = float64(row.Attempts) / float64(row.Accepted)
+		}
+	}
+	return
+}
+
+func GetClassStatistics(c echo.Context) error {
+	class := models.Class{}
+	if err := base.DB.Preload("ProblemSets", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Preload("ProblemSets.Problems").Preload("ProblemSets.ProblemEntries").First(&class, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not get class for getting statistics"))
+	}
+	students := base.DB.Table("user_in_classes").Select("user_id").Where("class_id = ?", class.ID)
+	var studentCount int64
+	utils.PanicIfDBError(base.DB.Table("user_in_classes").Where("class_id = ?", class.ID).Count(&studentCount),
+		"could not count students")
+	q := problemSetStatisticsQuery{
+		problemSetIDs: make([]uint, len(class.ProblemSets)),
+		students:      students,
+	}
+	for i, problemSet := range class.ProblemSets {
+		q.problemSetIDs[i] = problemSet.ID
+	}
+
+	statistics := resource.ClassStatistics{
+		StudentCount: uint(studentCount),
+		ProblemSets:  make([]resource.ProblemSetStatistics, len(class.ProblemSets)),
+	}
+	if len(class.ProblemSets) > 0 {
+		dailySubmissions := q.dailySubmissions()
+		scoreDistributions := q.scoreDistributions()
+		completedCounts := q.completedCounts()
+		problemStatistics := q.problemStatistics()
+		for i, problemSet := range class.ProblemSets {
+			s := &statistics.ProblemSets[i]
+			s.ID = problemSet.ID
+			s.Name = problemSet.Name
+			s.DailySubmissions = dailySubmissions[problemSet.ID]
+			if s.DailySubmissions == nil {
+				s.DailySubmissions = []resource.DailySubmissionCount{}
+			}
+			for _, day := range s.DailySubmissions {
+				s.SubmissionCount += day.Count
+			}
+			s.ScoreDistribution = scoreDistributions[problemSet.ID]
+			if s.ScoreDistribution == nil {
+				s.ScoreDistribution = make([]uint, scoreDistributionBuckets)
+			}
+			// Students without a grade score 0.
+			graded := uint(0)
+			for _, count := range s.ScoreDistribution {
+				graded += count
+			}
+			s.ScoreDistribution[0] += statistics.StudentCount - graded
+			s.CompletedCount = completedCounts[problemSet.ID]
+			if statistics.StudentCount > 0 {
+				s.CompletionRate = float64(s.CompletedCount) / float64(statistics.StudentCount)
+			}
+			s.Problems = make([]resource.ProblemStatistics, len(problemSet.Problems))
+			for j, problem := range problemSet.Problems {
+				if p, ok := problemStatistics[problemStatisticsKey{problemSet.ID, problem.ID}]; ok {
+					s.Problems[j] = *p
+				} else {
+					s.Problems[j].FailureStatuses = []resource.StatusCount{}
+				}
+				s.Problems[j].ID = problem.ID
+				s.Problems[j].Name = problem.Name
+			}
+		}
+	}
+
+	var inactiveStudents []*models.User
+	utils.PanicIfDBError(base.DB.Where("id in (?) and id not in (?)", students,
+		base.DB.Model(&models.Submission{}).Select("user_id").Where("problem_set_id in (?)", q.problemSetIDs)).
+		Order("id").Find(&inactiveStudents), "could not get inactive students")
+	statistics.InactiveStudents = resource.GetUserSlice(inactiveStudents)
+
+	return c.JSON(http.StatusOK, response.GetClassStatisticsResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.ClassStatistics `json:"statistics"`
+		}{
+			&statistics,
+		},
+	})
+}
@@ -0,0 +1,136 @@
+package controller_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetClassStatistics(t *testing.T) {
+	t.Parallel()
+
+	student1 := createUserForTest(t, "get_class_statistics", 0)
+	student2 := createUserForTest(t, "get_class_statistics", 1)
+	student3 := createUserForTest(t, "get_class_statistics", 2)
+	teacher := createUserForTest(t, "get_class_statistics", 3)
+	problem1 := createProblemForTest(t, "get_class_statistics", 0, nil, teacher)
+	problem2 := createProblemForTest(t, "get_class_statistics", 1, nil, teacher)
+	class := createClassForTest(t, "get_class_statistics", 0, nil, []*models.User{&student1, &student2, &student3})
+	problemSet := createProblemSetForTest(t, "get_class_statistics", 0, &class, []models.Problem{problem1, problem2}, inProgress)
+	emptyClass := createClassForTest(t, "get_class_statistics", 1, nil, []*models.User{&student1})
+
+	createSubmission := func(id int, problem *models.Problem, user *models.User, status string) {
+		submission := createSubmissionForTest(t, "get_class_statistics", id, problem, user, nil, 0, status)
+		submission.ProblemSetID = problemSet.ID
+		assert.NoError(t, base.DB.Save(&submission).Error)
+	}
+	createSubmission(0, &problem1, &student1, "WRONG_ANSWER")
+	createSubmission(1, &problem1, &student1, "ACCEPTED")
+	createSubmission(2, &problem2, &student1, "ACCEPTED")
+	createSubmission(3, &problem1, &student2, "WRONG_ANSWER")
+	createSubmission(4, &problem1, &student2, "TIME_LIMIT_EXCEEDED")
+	createSubmission(5, &problem1, &student2, "WRONG_ANSWER")
+	// Submissions of users not in the class are ignored.
+	createSubmission(6, &problem1, &teacher, "ACCEPTED")
+	// Upsolve submissions do not count as solving the problems.
+	for i, problem := range []*models.Problem{&problem1, &problem2} {
+		submission := createSubmissionForTest(t, "get_class_statistics", 7+i, problem, &student2, nil, 0, "ACCEPTED")
+		submission.ProblemSetID = problemSet.ID
+		submission.Upsolve = true
+		assert.NoError(t, base.DB.Save(&submission).Error)
+	}
+	assert.NoError(t, base.DB.Create(&models.Grade{
+		UserID:       student1.ID,
+		ProblemSetID: problemSet.ID,
+		ClassID:      class.ID,
+		Detail:       []byte(fmt.Sprintf(`{"%d":100,"%d":100}`, problem1.ID, problem2.ID)),
+	}).Error)
+	assert.NoError(t, base.DB.Create(&models.Grade{
+		UserID:       student2.ID,
+		ProblemSetID: problemSet.ID,
+		ClassID:      class.ID,
+		Detail:       []byte(fmt.Sprintf(`{"%d":50}`, problem1.ID)),
+	}).Error)
+
+	failTests := []failTest{
+		{
+			name:       "NonExisting",
+			method:     "GET",
+			path:       base.Echo.Reverse("class.getClassStatistics", -1),
+			req:        nil,
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:       "PermissionDenied",
+			method:     "GET",
+			path:       base.Echo.Reverse("class.getClassStatistics", class.ID),
+			req:        nil,
+			reqOptions: []reqOption{applyUser(student1)},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "GetClassStatistics")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("class.getClassStatistics", class.ID), nil, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.GetClassStatisticsResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, uint(3), resp.Data.StudentCount)
+		if assert.Len(t, resp.Data.InactiveStudents, 1) {
+			assert.Equal(t, student3.ID, resp.Data.InactiveStudents[0].ID)
+		}
+		if !assert.Len(t, resp.Data.ProblemSets, 1) {
+			return
+		}
+		s := resp.Data.ProblemSets[0]
+		assert.Equal(t, problemSet.ID, s.ID)
+		assert.Equal(t, uint(8), s.SubmissionCount)
+		if assert.Len(t, s.DailySubmissions, 1) {
+			assert.Regexp(t, `^\d{4}-\d{2}-\d{2}$`, s.DailySubmissions[0].Date)
+			assert.Equal(t, uint(8), s.DailySubmissions[0].Count)
+		}
+		assert.Equal(t, []uint{1, 0, 1, 0, 0, 0, 0, 0, 0, 1}, s.ScoreDistribution)
+		assert.Equal(t, uint(1), s.CompletedCount)
+		assert.InDelta(t, 1.0/3, s.CompletionRate, 1e-9)
+		if assert.Len(t, s.Problems, 2) {
+			for _, p := range s.Problems {
+				switch p.ID {
+				case problem1.ID:
+					assert.Equal(t, uint(6), p.SubmissionCount)
+					assert.Equal(t, uint(1), p.AcceptedCount)
+					assert.Equal(t, 2.0, p.AverageAttempts)
+					assert.Equal(t, "WRONG_ANSWER", p.FailureStatuses[0].Status)
+					assert.Equal(t, uint(3), p.FailureStatuses[0].Count)
+					assert.Equal(t, "TIME_LIMIT_EXCEEDED", p.FailureStatuses[1].Status)
+				case problem2.ID:
+					assert.Equal(t, uint(2), p.SubmissionCount)
+					assert.Equal(t, uint(1), p.AcceptedCount)
+					assert.Equal(t, 1.0, p.AverageAttempts)
+					assert.Empty(t, p.FailureStatuses)
+				default:
+					t.Errorf("unexpected problem %d", p.ID)
+				}
+			}
+		}
+	})
+	t.Run("EmptyClass", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("class.getClassStatistics", emptyClass.ID), nil, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.GetClassStatisticsResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Empty(t, resp.Data.ProblemSets)
+		assert.Len(t, resp.Data.InactiveStudents, 1)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/EduOJ/backend/app/request"
 	"github.com/EduOJ/backend/app/response"
@@ -132,6 +133,128 @@ func TestCreateClass(t *testing.T) {
 	}, resp)
 }
 
+func TestCloneClass(t *testing.T) {
+	t.Parallel()
+
+	manager := createUserForTest(t, "clone_class", 0)
+	student := createUserForTest(t, "clone_class", 1)
+	problem := createProblemForTest(t, "clone_class", 0, nil, manager)
+	source := createClassForTest(t, "clone_class", 0, []*models.User{&manager}, []*models.User{&student})
+	manager.GrantRole("class_creator", source)
+	problemSet1 := createProblemSetForTest(t, "clone_class", 0, &source, []models.Problem{problem})
+	problemSet2 := createProblemSetForTest(t, "clone_class", 1, &source, nil)
+	assert.NoError(t, problemSet1.SetProblemEntries(base.DB, []*models.ProblemInProblemSet{
+		{ProblemID: problem.ID, Order: 0, Label: "X", FullMark: 40},
+	}))
+	firstStart := problemSet1.StartTime
+	if problemSet2.StartTime.Before(firstStart) {
+		firstStart = problemSet2.StartTime
+	}
+	assert.NoError(t, base.DB.Create(&models.GradeWeight{
+		ClassID:      source.ID,
+		ProblemSetID: problemSet1.ID,
+		Weight:       2,
+	}).Error)
+	assert.NoError(t, base.DB.Create(&models.Grade{
+		UserID:       student.ID,
+		ProblemSetID: problemSet1.ID,
+		ClassID:      source.ID,
+		Detail:       []byte("{}"),
+	}).Error)
+
+	failTests := []failTest{
+		{
+			name:   "NonExisting",
+			method: "POST",
+			path:   base.Echo.Reverse("class.cloneClass", -1),
+			req: request.CloneClassRequest{
+				Name: "test_clone_class_name",
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:   "PermissionDenied",
+			method: "POST",
+			path:   base.Echo.Reverse("class.cloneClass", source.ID),
+			req: request.CloneClassRequest{
+				Name: "test_clone_class_name",
+			},
+			reqOptions: []reqOption{applyNormalUser},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "CloneClass")
+
+	getClone := func(t *testing.T, id uint) models.Class {
+		class := models.Class{}
+		assert.NoError(t, base.DB.Preload("Managers").Preload("Students").Preload("ProblemSets", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).Preload("ProblemSets.Problems").Preload("ProblemSets.ProblemEntries").First(&class, id).Error)
+		return class
+	}
+
+	t.Run("Offset", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("class.cloneClass", source.ID), request.CloneClassRequest{
+			Name:       "test_clone_class_offset_name",
+			TimeOffset: 3600,
+		}, applyAdminUser))
+		assert.Equal(t, http.StatusCreated, httpResp.StatusCode)
+		resp := response.CloneClassResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, "test_clone_class_offset_name", resp.Data.Name)
+		assert.Equal(t, source.CourseName, resp.Data.CourseName)
+		assert.NotEqual(t, source.InviteCode, resp.Data.InviteCode)
+		checkInviteCode(t, resp.Data.InviteCode)
+
+		class := getClone(t, resp.Data.ClassDetail.ID)
+		assert.Empty(t, class.Students)
+		assert.Len(t, class.Managers, 2)
+		manager.LoadRoles()
+		assert.True(t, manager.Can("manage_class", class))
+		if assert.Len(t, class.ProblemSets, 2) {
+			assert.Equal(t, problemSet1.Name, class.ProblemSets[0].Name)
+			assert.True(t, problemSet1.StartTime.Add(time.Hour).Equal(class.ProblemSets[0].StartTime))
+			assert.True(t, problemSet1.EndTime.Add(time.Hour).Equal(class.ProblemSets[0].EndTime))
+			if assert.Len(t, class.ProblemSets[0].Problems, 1) {
+				assert.Equal(t, problem.ID, class.ProblemSets[0].Problems[0].ID)
+			}
+			assert.Equal(t, []*models.ProblemInProblemSet{
+				{ProblemSetID: class.ProblemSets[0].ID, ProblemID: problem.ID, Order: 0, Label: "X", FullMark: 40},
+			}, class.ProblemSets[0].ProblemEntries)
+			weight := models.GradeWeight{}
+			assert.NoError(t, base.DB.First(&weight, "class_id = ?", class.ID).Error)
+			assert.Equal(t, class.ProblemSets[0].ID, weight.ProblemSetID)
+			assert.Equal(t, 2.0, weight.Weight)
+		}
+		var grades int64
+		assert.NoError(t, base.DB.Model(&models.Grade{}).Where("class_id = ?", class.ID).Count(&grades).Error)
+		assert.Equal(t, int64(0), grades)
+	})
+	t.Run("StartTime", func(t *testing.T) {
+		t.Parallel()
+		termStart := time.Date(2030, 9, 1, 8, 0, 0, 0, time.UTC)
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("class.cloneClass", source.ID), request.CloneClassRequest{
+			Name:      "test_clone_class_start_time_name",
+			StartTime: &termStart,
+		}, applyAdminUser))
+		assert.Equal(t, http.StatusCreated, httpResp.StatusCode)
+		resp := response.CloneClassResponse{}
+		mustJsonDecode(httpResp, &resp)
+
+		class := getClone(t, resp.Data.ClassDetail.ID)
+		offset := termStart.Sub(firstStart)
+		if assert.Len(t, class.ProblemSets, 2) {
+			assert.True(t, problemSet1.StartTime.Add(offset).Equal(class.ProblemSets[0].StartTime))
+			assert.True(t, problemSet2.EndTime.Add(offset).Equal(class.ProblemSets[1].EndTime))
+		}
+	})
+}
+
 func TestGetClass(t *testing.T) {
 	t.Parallel()
 
@@ -596,11 +719,338 @@ func TestAddAndDeleteStudents(t *testing.T) {
 	})
 }
 
+func TestImportStudents(t *testing.T) {
+	t.Parallel()
+
+	class := createClassForTest(t, "import_students", 0, nil, nil)
+	failTests := []failTest{
+		{
+			name:   "NonExist",
+			method: "POST",
+			path:   base.Echo.Reverse("class.importStudents", -1),
+			req: []reqContent{
+				newFileContent("file", "roster.csv", b64Encode("username,nickname,email\n")),
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:   "PermissionDenied",
+			method: "POST",
+			path:   base.Echo.Reverse("class.importStudents", class.ID),
+			req: []reqContent{
+				newFileContent("file", "roster.csv", b64Encode("username,nickname,email\n")),
+			},
+			reqOptions: []reqOption{applyNormalUser},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+		{
+			name:       "WithoutFile",
+			method:     "POST",
+			path:       base.Echo.Reverse("class.importStudents", class.ID),
+			req:        request.ImportStudentsRequest{},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusBadRequest,
+			resp:       response.ErrorResp("INVALID_FILE", nil),
+		},
+		{
+			name:   "MissingColumn",
+			method: "POST",
+			path:   base.Echo.Reverse("class.importStudents", class.ID),
+			req: []reqContent{
+				newFileContent("file", "roster.csv", b64Encode("username,email\nstudent,student@e.e\n")),
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusBadRequest,
+			resp:       response.ErrorResp("INVALID_FILE", nil),
+		},
+	}
+	runFailTests(t, failTests, "ImportStudents")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+
+		user1 := createUserForTest(t, "import_students", 1)
+		user2 := createUserForTest(t, "import_students", 2)
+		user3 := createUserForTest(t, "import_students", 3)
+		class := createClassForTest(t, "import_students", 1, nil, []*models.User{&user2})
+		manager := createUserForTest(t, "import_students", 0)
+		manager.GrantRole("class_creator", class)
+		roster := "Email, Username ,Nickname\n" +
+			user1.Email + ",whatever_username,whatever\n" +
+			"whatever@e.e," + user2.Username + ",whatever\n" +
+			"test_import_students_new@e.e,test_import_students_new,new student\n" +
+			"invalid_email,test_import_students_invalid,invalid student\n" +
+			user1.Email + "," + user3.Username + ",conflict\n"
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("class.importStudents", class.ID), []reqContent{
+			newFileContent("file", "roster.csv", b64Encode(roster)),
+		}, applyUser(manager)))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+
+		newUser := models.User{}
+		assert.NoError(t, base.DB.First(&newUser, "username = ?", "test_import_students_new").Error)
+		assert.Equal(t, "new student", newUser.Nickname)
+		assert.Equal(t, "test_import_students_new@e.e", newUser.Email)
+		assert.True(t, newUser.EmailVerified)
+		assert.ErrorIs(t, base.DB.First(&models.User{}, "username = ?", "test_import_students_invalid").Error, gorm.ErrRecordNotFound)
+
+		databaseClass := models.Class{}
+		assert.NoError(t, base.DB.Preload("Managers").Preload("Students").
+			First(&databaseClass, class.ID).Error)
+		expectedClass := models.Class{
+			ID:          databaseClass.ID,
+			Name:        "test_import_students_1_name",
+			CourseName:  "test_import_students_1_course_name",
+			Description: "test_import_students_1_description",
+			InviteCode:  databaseClass.InviteCode,
+			Managers:    []*models.User{},
+			Students: []*models.User{
+				&user1,
+				&user2,
+				&newUser,
+			},
+			CreatedAt: databaseClass.CreatedAt,
+			UpdatedAt: databaseClass.UpdatedAt,
+			DeletedAt: gorm.DeletedAt{},
+		}
+		assert.Equal(t, expectedClass, databaseClass)
+		resp := response.ImportStudentsResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, response.ImportStudentsResponse{
+			Message: "SUCCESS",
+			Error:   nil,
+			Data: struct {
+				*resource.ClassDetail `json:"class"`
+				Rows                  []response.ImportStudentsRow `json:"rows"`
+			}{
+				resource.GetClassDetail(&expectedClass),
+				[]response.ImportStudentsRow{
+					{
+						Line:     2,
+						Username: "whatever_username",
+						Nickname: "whatever",
+						Email:    user1.Email,
+						Status:   "ADDED",
+						UserID:   user1.ID,
+					},
+					{
+						Line:     3,
+						Username: user2.Username,
+						Nickname: "whatever",
+						Email:    "whatever@e.e",
+						Status:   "ALREADY_IN_CLASS",
+						UserID:   user2.ID,
+					},
+					{
+						Line:     4,
+						Username: "test_import_students_new",
+						Nickname: "new student",
+						Email:    "test_import_students_new@e.e",
+						Status:   "CREATED",
+						UserID:   newUser.ID,
+					},
+					{
+						Line:     5,
+						Username: "test_import_students_invalid",
+						Nickname: "invalid student",
+						Email:    "invalid_email",
+						Status:   "INVALID",
+						Errors: []response.ValidationError{
+							{
+								Field:       "Email",
+								Reason:      "email",
+								Translation: "邮箱必须是一个有效的邮箱",
+							},
+						},
+					},
+					{
+						Line:     6,
+						Username: user3.Username,
+						Nickname: "conflict",
+						Email:    user1.Email,
+						Status:   "CONFLICT",
+					},
+				},
+			},
+		}, resp)
+	})
+	t.Run("EmptyColumn", func(t *testing.T) {
+		t.Parallel()
+
+		// Users from the directory may have no email, and must not be matched by an empty column.
+		user := models.User{
+			Username: "test_import_students_empty_email",
+			Nickname: "test_import_students_empty_email_nick",
+			Password: utils.HashPassword("test_import_students_empty_email_pwd"),
+		}
+		assert.NoError(t, base.DB.Create(&user).Error)
+		class := createClassForTest(t, "import_students", 2, nil, nil)
+		roster := "username,nickname,email\n" +
+			"test_import_students_empty_column,empty email,\n"
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("class.importStudents", class.ID), []reqContent{
+			newFileContent("file", "roster.csv", b64Encode(roster)),
+		}, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.ImportStudentsResponse{}
+		mustJsonDecode(httpResp, &resp)
+		if assert.Len(t, resp.Data.Rows, 1) {
+			assert.Equal(t, "INVALID", resp.Data.Rows[0].Status)
+			assert.Zero(t, resp.Data.Rows[0].UserID)
+		}
+		assert.Equal(t, int64(0), base.DB.Model(&class).Association("Students").Count())
+	})
+}
+
+func TestAddAndDeleteAssistants(t *testing.T) {
+	t.Parallel()
+
+	class := createClassForTest(t, "add_and_delete_assistants_permission_denied", 0, nil, nil)
+	failTests := []failTest{
+		{
+			name:       "AddWithoutParams",
+			method:     "POST",
+			path:       base.Echo.Reverse("class.addAssistants", class.ID),
+			req:        request.AddAssistantsRequest{},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusBadRequest,
+			resp: response.ErrorResp("VALIDATION_ERROR", []interface{}{
+				map[string]interface{}{
+					"field":       "UserIds",
+					"reason":      "required",
+					"translation": "用户ID数组为必填字段",
+				},
+			}),
+		},
+		{
+			name:   "AddNonExist",
+			method: "POST",
+			path:   base.Echo.Reverse("class.addAssistants", -1),
+			req: request.AddAssistantsRequest{
+				UserIds: []uint{0},
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:   "AddPermissionDenied",
+			method: "POST",
+			path:   base.Echo.Reverse("class.addAssistants", class.ID),
+			req: request.AddAssistantsRequest{
+				UserIds: []uint{0},
+			},
+			reqOptions: []reqOption{applyNormalUser},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+		{
+			name:   "DeleteNonExist",
+			method: "DELETE",
+			path:   base.Echo.Reverse("class.deleteAssistants", -1),
+			req: request.DeleteAssistantsRequest{
+				UserIds: []uint{0},
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:   "DeletePermissionDenied",
+			method: "DELETE",
+			path:   base.Echo.Reverse("class.deleteAssistants", class.ID),
+			req: request.DeleteAssistantsRequest{
+				UserIds: []uint{0},
+			},
+			reqOptions: []reqOption{applyNormalUser},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+	}
+	runFailTests(t, failTests, "AddAndDeleteAssistants")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+
+		assistant1 := createUserForTest(t, "add_and_delete_assistants", 1)
+		assistant2 := createUserForTest(t, "add_and_delete_assistants", 2)
+		manager := createUserForTest(t, "add_and_delete_assistants", 0)
+		class := createClassForTest(t, "add_and_delete_assistants", 0, []*models.User{&manager}, nil)
+		manager.GrantRole("class_creator", class)
+
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("class.addAssistants", class.ID),
+			request.AddAssistantsRequest{
+				UserIds: []uint{assistant1.ID, assistant2.ID},
+			}, applyUser(manager)))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		databaseClass := models.Class{}
+		assert.NoError(t, base.DB.Preload("Managers").Preload("Students").Preload("Assistants").
+			First(&databaseClass, class.ID).Error)
+		assert.Equal(t, resource.GetUserSlice([]*models.User{&assistant1, &assistant2}), resource.GetUserSlice(databaseClass.Assistants))
+		resp := response.AddAssistantsResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, response.AddAssistantsResponse{
+			Message: "SUCCESS",
+			Error:   nil,
+			Data: struct {
+				*resource.ClassDetail `json:"class"`
+			}{
+				resource.GetClassDetail(&databaseClass),
+			},
+		}, resp)
+		assert.True(t, assistant1.HasRole("class_ta", class))
+		assert.True(t, assistant2.HasRole("class_ta", class))
+
+		// assistants can read grades, but can't manage the class.
+		httpResp = makeResp(makeReq(t, "GET", base.Echo.Reverse("class.getClassGrades", class.ID), nil, applyUser(assistant1)))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		httpResp = makeResp(makeReq(t, "DELETE", base.Echo.Reverse("class.deleteClass", class.ID), nil, applyUser(assistant1)))
+		assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+		httpResp = makeResp(makeReq(t, "POST", base.Echo.Reverse("class.addAssistants", class.ID),
+			request.AddAssistantsRequest{
+				UserIds: []uint{assistant1.ID},
+			}, applyUser(assistant1)))
+		assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+
+		httpResp = makeResp(makeReq(t, "DELETE", base.Echo.Reverse("class.deleteAssistants", class.ID),
+			request.DeleteAssistantsRequest{
+				UserIds: []uint{assistant1.ID},
+			}, applyUser(manager)))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		databaseClass = models.Class{}
+		assert.NoError(t, base.DB.Preload("Managers").Preload("Students").Preload("Assistants").
+			First(&databaseClass, class.ID).Error)
+		assert.Equal(t, resource.GetUserSlice([]*models.User{&assistant2}), resource.GetUserSlice(databaseClass.Assistants))
+		deleteResp := response.DeleteAssistantsResponse{}
+		mustJsonDecode(httpResp, &deleteResp)
+		assert.Equal(t, response.DeleteAssistantsResponse{
+			Message: "SUCCESS",
+			Error:   nil,
+			Data: struct {
+				*resource.ClassDetail `json:"class"`
+			}{
+				resource.GetClassDetail(&databaseClass),
+			},
+		}, deleteResp)
+		assert.False(t, assistant1.HasRole("class_ta", class))
+		assert.True(t, assistant2.HasRole("class_ta", class))
+	})
+}
+
 func TestJoinClass(t *testing.T) {
 	t.Parallel()
 	user := createUserForTest(t, "test_join_class_already_in_class", 0)
 	class1 := createClassForTest(t, "test_join_class_wrong_invite_code", 1, nil, nil)
 	class2 := createClassForTest(t, "test_join_class_already_in_class", 2, nil, []*models.User{&user})
+	class3 := createClassForTest(t, "test_join_class_expired", 3, nil, nil)
+	expireAt := time.Now().Add(-time.Minute)
+	class3.InviteCodeExpireAt = &expireAt
+	assert.NoError(t, base.DB.Save(&class3).Error)
+	class4 := createClassForTest(t, "test_join_class_used_up", 4, nil, nil)
+	class4.InviteCodeMaxUses = 1
+	class4.InviteCodeUses = 1
+	assert.NoError(t, base.DB.Save(&class4).Error)
 	failTests := []failTest{
 		{
 			// testJoinClassWithoutParams
@@ -654,6 +1104,28 @@ func TestJoinClass(t *testing.T) {
 			statusCode: http.StatusBadRequest,
 			resp:       response.ErrorResp("ALREADY_IN_CLASS", nil),
 		},
+		{
+			name:   "InviteCodeExpired",
+			method: "POST",
+			path:   base.Echo.Reverse("class.joinClass", class3.ID),
+			req: request.JoinClassRequest{
+				InviteCode: class3.InviteCode,
+			},
+			reqOptions: []reqOption{applyNormalUser},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("INVITE_CODE_EXPIRED", nil),
+		},
+		{
+			name:   "InviteCodeUsedUp",
+			method: "POST",
+			path:   base.Echo.Reverse("class.joinClass", class4.ID),
+			req: request.JoinClassRequest{
+				InviteCode: class4.InviteCode,
+			},
+			reqOptions: []reqOption{applyNormalUser},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("INVITE_CODE_USED_UP", nil),
+		},
 	}
 
 	runFailTests(t, failTests, "JoinClass")
@@ -686,9 +1158,10 @@ func TestJoinClass(t *testing.T) {
 			Students: []*models.User{
 				&user,
 			},
-			CreatedAt: databaseClass.CreatedAt,
-			UpdatedAt: databaseClass.UpdatedAt,
-			DeletedAt: gorm.DeletedAt{},
+			InviteCodeUses: 1,
+			CreatedAt:      databaseClass.CreatedAt,
+			UpdatedAt:      databaseClass.UpdatedAt,
+			DeletedAt:      gorm.DeletedAt{},
 		}
 		assert.Equal(t, expectedClass, databaseClass)
 		resp := response.JoinClassResponse{}
@@ -705,6 +1178,172 @@ func TestJoinClass(t *testing.T) {
 	})
 }
 
+func TestUpdateInviteCodeSettings(t *testing.T) {
+	t.Parallel()
+
+	class := createClassForTest(t, "update_invite_code_settings", 0, nil, nil)
+	class.InviteCodeUses = 3
+	assert.NoError(t, base.DB.Save(&class).Error)
+	failTests := []failTest{
+		{
+			name:       "NonExist",
+			method:     "PUT",
+			path:       base.Echo.Reverse("class.updateInviteCodeSettings", -1),
+			req:        request.UpdateInviteCodeSettingsRequest{},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:       "PermissionDenied",
+			method:     "PUT",
+			path:       base.Echo.Reverse("class.updateInviteCodeSettings", class.ID),
+			req:        request.UpdateInviteCodeSettingsRequest{},
+			reqOptions: []reqOption{applyNormalUser},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "UpdateInviteCodeSettings")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		expireAt := time.Now().Add(time.Hour).Truncate(time.Second)
+		httpResp := makeResp(makeReq(t, "PUT", base.Echo.Reverse("class.updateInviteCodeSettings", class.ID), request.UpdateInviteCodeSettingsRequest{
+			ExpireAt:        &expireAt,
+			MaxUses:         10,
+			RequireApproval: true,
+		}, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.UpdateInviteCodeSettingsResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.True(t, expireAt.Equal(*resp.Data.InviteCodeExpireAt))
+		assert.Equal(t, uint(10), resp.Data.InviteCodeMaxUses)
+		assert.Equal(t, uint(3), resp.Data.InviteCodeUses)
+		assert.True(t, resp.Data.RequireApproval)
+
+		databaseClass := models.Class{}
+		assert.NoError(t, base.DB.First(&databaseClass, class.ID).Error)
+		assert.Equal(t, uint(10), databaseClass.InviteCodeMaxUses)
+		assert.True(t, databaseClass.RequireApproval)
+	})
+}
+
+func TestClassJoinRequests(t *testing.T) {
+	t.Parallel()
+
+	user1 := createUserForTest(t, "class_join_requests", 0)
+	user2 := createUserForTest(t, "class_join_requests", 1)
+	class := createClassForTest(t, "class_join_requests", 0, nil, nil)
+	class.RequireApproval = true
+	assert.NoError(t, base.DB.Save(&class).Error)
+	otherClass := createClassForTest(t, "class_join_requests", 1, nil, nil)
+	handled := models.ClassJoinRequest{
+		ClassID: class.ID,
+		UserID:  user2.ID,
+		Status:  models.ClassJoinRequestStatusRejected,
+	}
+	assert.NoError(t, base.DB.Create(&handled).Error)
+
+	join := func(t *testing.T, user models.User) *response.RequestJoinClassResponse {
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("class.joinClass", class.ID), request.JoinClassRequest{
+			InviteCode: class.InviteCode,
+		}, applyUser(user)))
+		assert.Equal(t, http.StatusAccepted, httpResp.StatusCode)
+		resp := response.RequestJoinClassResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, models.ClassJoinRequestStatusPending, resp.Data.Status)
+		return &resp
+	}
+	isStudent := func(t *testing.T, user models.User) bool {
+		return base.DB.Model(&class).Where("id = ?", user.ID).Association("Students").Count() > 0
+	}
+
+	failTests := []failTest{
+		{
+			name:       "HandledRequest",
+			method:     "POST",
+			path:       base.Echo.Reverse("class.approveJoinRequest", class.ID, handled.ID),
+			req:        request.ApproveClassJoinRequestRequest{},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusBadRequest,
+			resp:       response.ErrorResp("ALREADY_HANDLED", nil),
+		},
+		{
+			name:       "RequestInOtherClass",
+			method:     "POST",
+			path:       base.Echo.Reverse("class.rejectJoinRequest", otherClass.ID, handled.ID),
+			req:        request.RejectClassJoinRequestRequest{},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:       "PermissionDenied",
+			method:     "GET",
+			path:       base.Echo.Reverse("class.getJoinRequests", class.ID),
+			req:        request.GetClassJoinRequestsRequest{},
+			reqOptions: []reqOption{applyNormalUser},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "ClassJoinRequests")
+
+	t.Run("Approve", func(t *testing.T) {
+		t.Parallel()
+		joinResp := join(t, user1)
+		assert.False(t, isStudent(t, user1))
+
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("class.joinClass", class.ID), request.JoinClassRequest{
+			InviteCode: class.InviteCode,
+		}, applyUser(user1)))
+		assert.Equal(t, http.StatusBadRequest, httpResp.StatusCode)
+		resp := response.Response{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, response.ErrorResp("ALREADY_REQUESTED", nil), resp)
+
+		httpResp = makeResp(makeReq(t, "GET", base.Echo.Reverse("class.getJoinRequests", class.ID)+"?status=PENDING",
+			nil, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		listResp := response.GetClassJoinRequestsResponse{}
+		mustJsonDecode(httpResp, &listResp)
+		found := false
+		for _, joinRequest := range listResp.Data.JoinRequests {
+			assert.Equal(t, models.ClassJoinRequestStatusPending, joinRequest.Status)
+			if joinRequest.ID == joinResp.Data.ClassJoinRequest.ID {
+				found = true
+				assert.Equal(t, user1.Username, joinRequest.User.Username)
+			}
+		}
+		assert.True(t, found)
+
+		httpResp = makeResp(makeReq(t, "POST", base.Echo.Reverse("class.approveJoinRequest", class.ID, joinResp.Data.ClassJoinRequest.ID),
+			request.ApproveClassJoinRequestRequest{}, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		approveResp := response.ApproveClassJoinRequestResponse{}
+		mustJsonDecode(httpResp, &approveResp)
+		assert.Equal(t, models.ClassJoinRequestStatusApproved, approveResp.Data.Status)
+		assert.NotZero(t, approveResp.Data.OperatorID)
+		assert.True(t, isStudent(t, user1))
+	})
+	t.Run("Reject", func(t *testing.T) {
+		t.Parallel()
+		user := createUserForTest(t, "class_join_requests", 2)
+		joinResp := join(t, user)
+
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("class.rejectJoinRequest", class.ID, joinResp.Data.ClassJoinRequest.ID),
+			request.RejectClassJoinRequestRequest{}, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		rejectResp := response.RejectClassJoinRequestResponse{}
+		mustJsonDecode(httpResp, &rejectResp)
+		assert.Equal(t, models.ClassJoinRequestStatusRejected, rejectResp.Data.Status)
+		assert.False(t, isStudent(t, user))
+	})
+}
+
 func TestDeleteClass(t *testing.T) {
 	t.Parallel()
 
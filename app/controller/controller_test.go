@@ -234,6 +234,14 @@ func (q queryOption) make(r *http.Request) {
 
 var _ = queryOption{} // explictly mark this type used
 
+// remoteAddrOption sets the address of the connection, which is 192.0.2.1 by default
+// and trusted as a proxy setting X-Forwarded-For.
+type remoteAddrOption string
+
+func (a remoteAddrOption) make(r *http.Request) {
+	r.RemoteAddr = string(a)
+}
+
 type reqContent interface {
 	add(r *multipart.Writer) error
 }
@@ -354,6 +362,8 @@ server:
   port: 8080
   origin:
     - http://127.0.0.1:8000
+  trusted_proxies:
+    - 192.0.2.1
 judger:
   token: judger_token
 email:
@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func UpdateGradeWeights(c echo.Context) error {
+	req := request.UpdateGradeWeightsRequest{}
+	err, ok := utils.BindAndValidate(&req, c)
+	if !ok {
+		return err
+	}
+	class := models.Class{}
+	if err := base.DB.Preload("ProblemSets.Problems").First(&class, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not get class for updating grade weights"))
+	}
+	problemSets := make(map[uint]*models.ProblemSet)
+	for _, problemSet := range class.ProblemSets {
+		problemSets[problemSet.ID] = problemSet
+	}
+	var weights []models.GradeWeight
+	for _, ps := range req.ProblemSets {
+		problemSet, ok := problemSets[ps.ProblemSetID]
+		if !ok {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("PROBLEM_SET_NOT_FOUND", nil))
+		}
+		weights = append(weights, models.GradeWeight{
+			ProblemSetID: ps.ProblemSetID,
+			Weight:       ps.Weight,
+		})
+		for _, p := range ps.Problems {
+			found := false
+			for _, problem := range problemSet.Problems {
+				if problem.ID == p.ProblemID {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return c.JSON(http.StatusNotFound, response.ErrorResp("PROBLEM_NOT_FOUND", nil))
+			}
+			weights = append(weights, models.GradeWeight{
+				ProblemSetID: ps.ProblemSetID,
+				ProblemID:    p.ProblemID,
+				Weight:       p.Weight,
+			})
+		}
+	}
+	if err := utils.SetGradeWeights(&class, req.DropLowest, weights); err != nil {
+		panic(errors.Wrap(err, "could not set grade weights"))
+	}
+	return c.JSON(http.StatusOK, response.UpdateGradeWeightsResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			DropLowest   uint                   `json:"drop_lowest"`
+			GradeWeights []resource.GradeWeight `json:"grade_weights"`
+			CourseGrades []resource.CourseGrade `json:"course_grades"`
+		}{
+			DropLowest:   class.DropLowest,
+			GradeWeights: resource.GetGradeWeightSlice(weights),
+			CourseGrades: resource.GetCourseGradeSlice(getCourseGrades(&class)),
+		},
+	})
+}
+
+func GetCourseGrades(c echo.Context) error {
+	class := models.Class{}
+	if err := base.DB.Preload("Students").First(&class, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not get class for getting course grades"))
+	}
+	courseGrades := getCourseGrades(&class)
+	if len(courseGrades) < len(class.Students) {
+		// Students who have never got a grade don't have a course grade yet.
+		if err := utils.RefreshCourseGrades(class.ID); err != nil {
+			panic(errors.Wrap(err, "could not refresh course grades"))
+		}
+		courseGrades = getCourseGrades(&class)
+	}
+	var weights []models.GradeWeight
+	utils.PanicIfDBError(base.DB.Find(&weights, "class_id = ?", class.ID), "could not get grade weights")
+	return c.JSON(http.StatusOK, response.GetCourseGradesResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			DropLowest   uint                   `json:"drop_lowest"`
+			GradeWeights []resource.GradeWeight `json:"grade_weights"`
+			CourseGrades []resource.CourseGrade `json:"course_grades"`
+		}{
+			DropLowest:   class.DropLowest,
+			GradeWeights: resource.GetGradeWeightSlice(weights),
+			CourseGrades: resource.GetCourseGradeSlice(courseGrades),
+		},
+	})
+}
+
+// getCourseGrades returns the course grades of the current students in a class.
+func getCourseGrades(class *models.Class) (courseGrades []*models.CourseGrade) {
+	var studentIDs []uint
+	utils.PanicIfDBError(base.DB.Table("user_in_classes").Where("class_id = ?", class.ID).
+		Pluck("user_id", &studentIDs), "could not get students for getting course grades")
+	if len(studentIDs) == 0 {
+		return
+	}
+	utils.PanicIfDBError(base.DB.Preload("User").Order("user_id").
+		Find(&courseGrades, "class_id = ? and user_id in (?)", class.ID, studentIDs), "could not get course grades")
+	return
+}
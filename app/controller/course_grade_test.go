@@ -0,0 +1,238 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateGradeWeights(t *testing.T) {
+	t.Parallel()
+	user1 := createUserForTest(t, "update_grade_weights", 1)
+	user2 := createUserForTest(t, "update_grade_weights", 2)
+	class := createClassForTest(t, "update_grade_weights", 0, nil, []*models.User{&user1, &user2})
+	otherClass := createClassForTest(t, "update_grade_weights", 1, nil, nil)
+	problem1 := createProblemForTest(t, "update_grade_weights", 1, nil, user1)
+	problem2 := createProblemForTest(t, "update_grade_weights", 2, nil, user1)
+	problemSet1 := createProblemSetForTest(t, "update_grade_weights", 1, &class, []models.Problem{problem1, problem2}, inProgress)
+	problemSet2 := createProblemSetForTest(t, "update_grade_weights", 2, &class, []models.Problem{problem1}, inProgress)
+	otherProblemSet := createProblemSetForTest(t, "update_grade_weights", 3, &otherClass, []models.Problem{problem1}, inProgress)
+	detail, err := json.Marshal(map[uint]uint{
+		problem1.ID: 100,
+		problem2.ID: 40,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, base.DB.Create(&models.Grade{
+		UserID:       user1.ID,
+		ProblemSetID: problemSet1.ID,
+		ClassID:      class.ID,
+		Detail:       detail,
+	}).Error)
+
+	failTests := []failTest{
+		{
+			name:   "NonExistingClass",
+			method: "PUT",
+			path:   base.Echo.Reverse("class.updateGradeWeights", -1),
+			req:    request.UpdateGradeWeightsRequest{},
+			reqOptions: []reqOption{
+				applyAdminUser,
+			},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:   "PermissionDenied",
+			method: "PUT",
+			path:   base.Echo.Reverse("class.updateGradeWeights", class.ID),
+			req:    request.UpdateGradeWeightsRequest{},
+			reqOptions: []reqOption{
+				applyNormalUser,
+			},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+		{
+			name:   "ProblemSetNotInClass",
+			method: "PUT",
+			path:   base.Echo.Reverse("class.updateGradeWeights", class.ID),
+			req: request.UpdateGradeWeightsRequest{
+				ProblemSets: []request.ProblemSetGradeWeight{
+					{ProblemSetID: otherProblemSet.ID, Weight: 1},
+				},
+			},
+			reqOptions: []reqOption{
+				applyAdminUser,
+			},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("PROBLEM_SET_NOT_FOUND", nil),
+		},
+		{
+			name:   "ProblemNotInProblemSet",
+			method: "PUT",
+			path:   base.Echo.Reverse("class.updateGradeWeights", class.ID),
+			req: request.UpdateGradeWeightsRequest{
+				ProblemSets: []request.ProblemSetGradeWeight{
+					{
+						ProblemSetID: problemSet2.ID,
+						Weight:       1,
+						Problems: []request.ProblemGradeWeight{
+							{ProblemID: problem2.ID, Weight: 1},
+						},
+					},
+				},
+			},
+			reqOptions: []reqOption{
+				applyAdminUser,
+			},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("PROBLEM_NOT_FOUND", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "UpdateGradeWeights")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "PUT", base.Echo.Reverse("class.updateGradeWeights", class.ID), request.UpdateGradeWeightsRequest{
+			DropLowest: 0,
+			ProblemSets: []request.ProblemSetGradeWeight{
+				{
+					ProblemSetID: problemSet1.ID,
+					Weight:       3,
+					Problems: []request.ProblemGradeWeight{
+						{ProblemID: problem2.ID, Weight: 3},
+					},
+				},
+			},
+		}, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.UpdateGradeWeightsResponse{}
+		mustJsonDecode(httpResp, &resp)
+
+		databaseClass := models.Class{}
+		assert.NoError(t, base.DB.First(&databaseClass, class.ID).Error)
+		assert.Equal(t, uint(0), databaseClass.DropLowest)
+		var weights []models.GradeWeight
+		assert.NoError(t, base.DB.Find(&weights, "class_id = ?", class.ID).Error)
+		assert.Equal(t, []resource.GradeWeight{
+			{ProblemSetID: problemSet1.ID, ProblemID: 0, Weight: 3},
+			{ProblemSetID: problemSet1.ID, ProblemID: problem2.ID, Weight: 3},
+		}, resource.GetGradeWeightSlice(weights))
+		assert.Equal(t, resp.Data.GradeWeights, resource.GetGradeWeightSlice(weights))
+
+		assert.Equal(t, []resource.CourseGrade{
+			{
+				ID:      resp.Data.CourseGrades[0].ID,
+				UserID:  user1.ID,
+				User:    resource.GetUser(&user1),
+				ClassID: class.ID,
+				Detail: []resource.CourseGradeItem{
+					{ProblemSetID: problemSet1.ID, Score: 55, Weight: 3},
+					{ProblemSetID: problemSet2.ID, Score: 0, Weight: 1},
+				},
+				Total: 41.25,
+			},
+			{
+				ID:      resp.Data.CourseGrades[1].ID,
+				UserID:  user2.ID,
+				User:    resource.GetUser(&user2),
+				ClassID: class.ID,
+				Detail: []resource.CourseGradeItem{
+					{ProblemSetID: problemSet1.ID, Score: 0, Weight: 3},
+					{ProblemSetID: problemSet2.ID, Score: 0, Weight: 1},
+				},
+				Total: 0,
+			},
+		}, resp.Data.CourseGrades)
+	})
+}
+
+func TestGetCourseGrades(t *testing.T) {
+	t.Parallel()
+	user1 := createUserForTest(t, "get_course_grades", 1)
+	user2 := createUserForTest(t, "get_course_grades", 2)
+	class := createClassForTest(t, "get_course_grades", 0, nil, []*models.User{&user1, &user2})
+	problem := createProblemForTest(t, "get_course_grades", 1, nil, user1)
+	problemSet1 := createProblemSetForTest(t, "get_course_grades", 1, &class, []models.Problem{problem}, inProgress)
+	problemSet2 := createProblemSetForTest(t, "get_course_grades", 2, &class, []models.Problem{problem}, inProgress)
+	detail, err := json.Marshal(map[uint]uint{
+		problem.ID: 60,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, base.DB.Create(&models.Grade{
+		UserID:       user2.ID,
+		ProblemSetID: problemSet2.ID,
+		ClassID:      class.ID,
+		Detail:       detail,
+	}).Error)
+	assert.NoError(t, base.DB.Model(&class).Update("drop_lowest", 1).Error)
+
+	failTests := []failTest{
+		{
+			name:   "NonExistingClass",
+			method: "GET",
+			path:   base.Echo.Reverse("class.getCourseGrades", -1),
+			req:    request.GetCourseGradesRequest{},
+			reqOptions: []reqOption{
+				applyAdminUser,
+			},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:   "PermissionDenied",
+			method: "GET",
+			path:   base.Echo.Reverse("class.getCourseGrades", class.ID),
+			req:    request.GetCourseGradesRequest{},
+			reqOptions: []reqOption{
+				applyNormalUser,
+			},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "GetCourseGrades")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("class.getCourseGrades", class.ID), nil, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.GetCourseGradesResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, uint(1), resp.Data.DropLowest)
+		assert.Equal(t, []resource.GradeWeight{}, resp.Data.GradeWeights)
+		assert.Equal(t, []resource.CourseGrade{
+			{
+				ID:      resp.Data.CourseGrades[0].ID,
+				UserID:  user1.ID,
+				User:    resource.GetUser(&user1),
+				ClassID: class.ID,
+				Detail: []resource.CourseGradeItem{
+					{ProblemSetID: problemSet1.ID, Score: 0, Weight: 1, Dropped: true},
+					{ProblemSetID: problemSet2.ID, Score: 0, Weight: 1},
+				},
+				Total: 0,
+			},
+			{
+				ID:      resp.Data.CourseGrades[1].ID,
+				UserID:  user2.ID,
+				User:    resource.GetUser(&user2),
+				ClassID: class.ID,
+				Detail: []resource.CourseGradeItem{
+					{ProblemSetID: problemSet1.ID, Score: 0, Weight: 1, Dropped: true},
+					{ProblemSetID: problemSet2.ID, Score: 60, Weight: 1},
+				},
+				Total: 60,
+			},
+		}, resp.Data.CourseGrades)
+	})
+}
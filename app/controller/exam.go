@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func UpdateExamSettings(c echo.Context) error {
+	req := request.UpdateExamSettingsRequest{}
+	if err, ok := utils.BindAndValidate(&req, c); !ok {
+		return err
+	}
+	problemSet := models.ProblemSet{}
+	if err := base.DB.Preload("Problems").Preload("ProblemEntries").
+		First(&problemSet, "id = ? and class_id = ?", c.Param("id"), c.Param("class_id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not get problem set for updating exam settings"))
+	}
+	problemSet.ExamMode = req.ExamMode
+	problemSet.ExamIPRanges = strings.Join(req.AllowedIPRanges, ",")
+	utils.PanicIfDBError(base.DB.Model(&problemSet).Updates(map[string]interface{}{
+		"exam_mode":      problemSet.ExamMode,
+		"exam_ip_ranges": problemSet.ExamIPRanges,
+	}), "could not update exam settings")
+	return c.JSON(http.StatusOK, response.UpdateExamSettingsResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.ProblemSetDetail `json:"problem_set"`
+		}{
+			resource.GetProblemSetDetail(&problemSet),
+		},
+	})
+}
+
+func GetExamViolations(c echo.Context) error {
+	req := request.GetExamViolationsRequest{}
+	if err, ok := utils.BindAndValidate(&req, c); !ok {
+		return err
+	}
+	problemSet := models.ProblemSet{}
+	if err := base.DB.First(&problemSet, "id = ? and class_id = ?", c.Param("id"), c.Param("class_id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not get problem set for getting exam violations"))
+	}
+	query := base.DB.Preload("User").Where("problem_set_id = ?", problemSet.ID).Order("id")
+	if req.UserID != 0 {
+		query = query.Where("user_id = ?", req.UserID)
+	}
+	var violations []*models.ExamViolation
+	utils.PanicIfDBError(query.Find(&violations), "could not get exam violations")
+	return c.JSON(http.StatusOK, response.GetExamViolationsResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			ExamViolations []resource.ExamViolation `json:"exam_violations"`
+		}{
+			resource.GetExamViolationSlice(violations),
+		},
+	})
+}
+
+func ResetExamSession(c echo.Context) error {
+	req := request.ResetExamSessionRequest{}
+	if err, ok := utils.BindAndValidate(&req, c); !ok {
+		return err
+	}
+	problemSet := models.ProblemSet{}
+	if err := base.DB.First(&problemSet, "id = ? and class_id = ?", c.Param("id"), c.Param("class_id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not get problem set for resetting exam session"))
+	}
+	result := base.DB.Delete(&models.ExamSession{}, "problem_set_id = ? and user_id = ?", problemSet.ID, req.UserID)
+	utils.PanicIfDBError(result, "could not reset exam session")
+	if result.RowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, response.ErrorResp("SESSION_NOT_FOUND", nil))
+	}
+	return c.JSON(http.StatusOK, response.ResetExamSessionResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data:    nil,
+	})
+}
@@ -0,0 +1,404 @@
+package controller_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func createExamForTest(t *testing.T, name string, id int, class *models.Class, problems []models.Problem, ipRanges string) *models.ProblemSet {
+	problemSet := createProblemSetForTest(t, name, id, class, problems, inProgress)
+	problemSet.ExamMode = true
+	problemSet.ExamIPRanges = ipRanges
+	assert.NoError(t, base.DB.Save(problemSet).Error)
+	return problemSet
+}
+
+func createTokenForTest(t *testing.T, user models.User) models.Token {
+	token := models.Token{
+		Token: utils.RandStr(32),
+		User:  user,
+	}
+	assert.NoError(t, base.DB.Create(&token).Error)
+	return token
+}
+
+func applyTokenAndIP(token models.Token, ip string) headerOption {
+	return headerOption{
+		"Authorization":   {token.Token},
+		"X-Forwarded-For": {ip},
+	}
+}
+
+func TestUpdateExamSettings(t *testing.T) {
+	t.Parallel()
+	student := createUserForTest(t, "update_exam_settings", 0)
+	class := createClassForTest(t, "update_exam_settings", 0, nil, []*models.User{&student})
+	problemSet := createProblemSetForTest(t, "update_exam_settings", 0, &class, nil, inProgress)
+
+	failTests := []failTest{
+		{
+			name:   "NonExistingProblemSet",
+			method: "PUT",
+			path:   base.Echo.Reverse("problemSet.updateExamSettings", class.ID, -1),
+			req: request.UpdateExamSettingsRequest{
+				ExamMode: true,
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:   "PermissionDenied",
+			method: "PUT",
+			path:   base.Echo.Reverse("problemSet.updateExamSettings", class.ID, problemSet.ID),
+			req: request.UpdateExamSettingsRequest{
+				ExamMode: true,
+			},
+			reqOptions: []reqOption{applyUser(student)},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+		{
+			name:   "InvalidIPRange",
+			method: "PUT",
+			path:   base.Echo.Reverse("problemSet.updateExamSettings", class.ID, problemSet.ID),
+			req: request.UpdateExamSettingsRequest{
+				ExamMode:        true,
+				AllowedIPRanges: []string{"10.0.0.1"},
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusBadRequest,
+			resp: response.ErrorResp("VALIDATION_ERROR", []interface{}{
+				map[string]interface{}{
+					"field":       "AllowedIPRanges[0]",
+					"reason":      "cidr",
+					"translation": "必须是一个有效的无类别域间路由(CIDR)",
+				},
+			}),
+		},
+	}
+
+	runFailTests(t, failTests, "UpdateExamSettings")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "PUT", base.Echo.Reverse("problemSet.updateExamSettings", class.ID, problemSet.ID),
+			request.UpdateExamSettingsRequest{
+				ExamMode:        true,
+				AllowedIPRanges: []string{"10.0.0.0/8", "192.168.1.0/24"},
+			}, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.UpdateExamSettingsResponse{}
+		mustJsonDecode(httpResp, &resp)
+		databaseProblemSet := models.ProblemSet{}
+		assert.NoError(t, base.DB.Preload("Problems").Preload("ProblemEntries").First(&databaseProblemSet, problemSet.ID).Error)
+		assert.True(t, databaseProblemSet.ExamMode)
+		assert.Equal(t, "10.0.0.0/8,192.168.1.0/24", databaseProblemSet.ExamIPRanges)
+		assert.Equal(t, response.UpdateExamSettingsResponse{
+			Message: "SUCCESS",
+			Error:   nil,
+			Data: struct {
+				*resource.ProblemSetDetail `json:"problem_set"`
+			}{
+				resource.GetProblemSetDetail(&databaseProblemSet),
+			},
+		}, resp)
+		assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.0/24"}, resp.Data.AllowedIPRanges)
+	})
+}
+
+func TestGetExamViolations(t *testing.T) {
+	t.Parallel()
+	student1 := createUserForTest(t, "get_exam_violations", 0)
+	student2 := createUserForTest(t, "get_exam_violations", 1)
+	class := createClassForTest(t, "get_exam_violations", 0, nil, []*models.User{&student1, &student2})
+	problemSet := createExamForTest(t, "get_exam_violations", 0, &class, nil, "")
+	assert.NoError(t, utils.LogExamViolation(problemSet.ID, student1.ID, models.ExamViolationIPNotAllowed, "192.168.0.1", "/api/test"))
+	assert.NoError(t, utils.LogExamViolation(problemSet.ID, student2.ID, models.ExamViolationOutOfExamAccess, "10.0.0.1", "/api/test"))
+
+	failTests := []failTest{
+		{
+			name:       "NonExistingProblemSet",
+			method:     "GET",
+			path:       base.Echo.Reverse("problemSet.getExamViolations", class.ID, -1),
+			req:        request.GetExamViolationsRequest{},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:       "PermissionDenied",
+			method:     "GET",
+			path:       base.Echo.Reverse("problemSet.getExamViolations", class.ID, problemSet.ID),
+			req:        request.GetExamViolationsRequest{},
+			reqOptions: []reqOption{applyUser(student1)},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "GetExamViolations")
+
+	t.Run("All", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("problemSet.getExamViolations", class.ID, problemSet.ID),
+			nil, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.GetExamViolationsResponse{}
+		mustJsonDecode(httpResp, &resp)
+		if assert.Len(t, resp.Data.ExamViolations, 2) {
+			assert.Equal(t, student1.ID, resp.Data.ExamViolations[0].UserID)
+			assert.Equal(t, models.ExamViolationIPNotAllowed, resp.Data.ExamViolations[0].Type)
+			assert.Equal(t, "192.168.0.1", resp.Data.ExamViolations[0].IP)
+			assert.Equal(t, resource.GetUser(&student1), resp.Data.ExamViolations[0].User)
+			assert.Equal(t, student2.ID, resp.Data.ExamViolations[1].UserID)
+			assert.Equal(t, models.ExamViolationOutOfExamAccess, resp.Data.ExamViolations[1].Type)
+		}
+	})
+	t.Run("FilterByUser", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("problemSet.getExamViolations", class.ID, problemSet.ID),
+			nil, applyAdminUser, queryOption{
+				"user_id": {fmt.Sprint(student2.ID)},
+			}))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.GetExamViolationsResponse{}
+		mustJsonDecode(httpResp, &resp)
+		if assert.Len(t, resp.Data.ExamViolations, 1) {
+			assert.Equal(t, student2.ID, resp.Data.ExamViolations[0].UserID)
+		}
+	})
+}
+
+func TestResetExamSession(t *testing.T) {
+	t.Parallel()
+	student := createUserForTest(t, "reset_exam_session", 0)
+	class := createClassForTest(t, "reset_exam_session", 0, nil, []*models.User{&student})
+	problemSet := createExamForTest(t, "reset_exam_session", 0, &class, nil, "")
+	token1 := createTokenForTest(t, student)
+	token2 := createTokenForTest(t, student)
+
+	failTests := []failTest{
+		{
+			name:   "NonExistingProblemSet",
+			method: "DELETE",
+			path:   base.Echo.Reverse("problemSet.resetExamSession", class.ID, -1),
+			req: request.ResetExamSessionRequest{
+				UserID: student.ID,
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:   "NonExistingSession",
+			method: "DELETE",
+			path:   base.Echo.Reverse("problemSet.resetExamSession", class.ID, problemSet.ID),
+			req: request.ResetExamSessionRequest{
+				UserID: 0xffffff,
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("SESSION_NOT_FOUND", nil),
+		},
+		{
+			name:   "PermissionDenied",
+			method: "DELETE",
+			path:   base.Echo.Reverse("problemSet.resetExamSession", class.ID, problemSet.ID),
+			req: request.ResetExamSessionRequest{
+				UserID: student.ID,
+			},
+			reqOptions: []reqOption{applyUser(student)},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "ResetExamSession")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		getProblemSet := func(token models.Token) *http.Response {
+			return makeResp(makeReq(t, "GET", base.Echo.Reverse("problemSet.getProblemSet", class.ID, problemSet.ID),
+				nil, applyTokenAndIP(token, "10.0.0.1")))
+		}
+		assert.Equal(t, http.StatusOK, getProblemSet(token1).StatusCode)
+		assert.Equal(t, http.StatusForbidden, getProblemSet(token2).StatusCode)
+
+		httpResp := makeResp(makeReq(t, "DELETE", base.Echo.Reverse("problemSet.resetExamSession", class.ID, problemSet.ID),
+			request.ResetExamSessionRequest{
+				UserID: student.ID,
+			}, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.ResetExamSessionResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, response.ResetExamSessionResponse{
+			Message: "SUCCESS",
+			Error:   nil,
+			Data:    nil,
+		}, resp)
+
+		// The student could continue the exam with the other token.
+		assert.Equal(t, http.StatusOK, getProblemSet(token2).StatusCode)
+		assert.Equal(t, http.StatusForbidden, getProblemSet(token1).StatusCode)
+	})
+}
+
+func TestExamRestriction(t *testing.T) {
+	t.Parallel()
+
+	type examForTest struct {
+		student    models.User
+		token      models.Token
+		class      models.Class
+		problem    models.Problem
+		exam       *models.ProblemSet
+		problemSet *models.ProblemSet
+	}
+	createExam := func(t *testing.T, id int) examForTest {
+		e := examForTest{}
+		e.student = createUserForTest(t, "exam_restriction", id)
+		e.token = createTokenForTest(t, e.student)
+		e.class = createClassForTest(t, "exam_restriction", id, nil, []*models.User{&e.student})
+		e.problem = createProblemForTest(t, "exam_restriction", id, nil, e.student)
+		e.exam = createExamForTest(t, "exam_restriction", id, &e.class, []models.Problem{e.problem}, "10.0.0.0/8")
+		e.problemSet = createProblemSetForTest(t, "exam_restriction_other", id, &e.class, []models.Problem{e.problem}, inProgress)
+		return e
+	}
+	violationTypes := func(t *testing.T, e examForTest) (types []string) {
+		var violations []models.ExamViolation
+		assert.NoError(t, base.DB.Order("id").Find(&violations, "problem_set_id = ? and user_id = ?", e.exam.ID, e.student.ID).Error)
+		for _, violation := range violations {
+			types = append(types, violation.Type)
+		}
+		return
+	}
+
+	t.Run("Allowed", func(t *testing.T) {
+		t.Parallel()
+		e := createExam(t, 0)
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("problemSet.getProblemSet", e.class.ID, e.exam.ID),
+			nil, applyTokenAndIP(e.token, "10.1.2.3")))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		httpResp = makeResp(makeReq(t, "GET", base.Echo.Reverse("problemSet.getProblemSetProblem", e.class.ID, e.exam.ID, e.problem.ID),
+			nil, applyTokenAndIP(e.token, "10.1.2.3")))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		session := models.ExamSession{}
+		assert.NoError(t, base.DB.First(&session, "problem_set_id = ? and user_id = ?", e.exam.ID, e.student.ID).Error)
+		assert.Equal(t, e.token.ID, session.TokenID)
+		assert.Equal(t, "10.1.2.3", session.IP)
+		assert.Empty(t, violationTypes(t, e))
+	})
+	t.Run("OutOfExamAccess", func(t *testing.T) {
+		t.Parallel()
+		e := createExam(t, 1)
+		for _, path := range []string{
+			base.Echo.Reverse("problemSet.getProblemSet", e.class.ID, e.problemSet.ID),
+			base.Echo.Reverse("problemSet.getSubmissions", e.class.ID, e.problemSet.ID),
+			base.Echo.Reverse("problem.getProblem", e.problem.ID),
+			base.Echo.Reverse("submission.getSubmissions"),
+		} {
+			httpResp := makeResp(makeReq(t, "GET", path, nil, applyTokenAndIP(e.token, "10.1.2.3")))
+			assert.Equal(t, http.StatusForbidden, httpResp.StatusCode, path)
+			resp := response.Response{}
+			mustJsonDecode(httpResp, &resp)
+			assert.Equal(t, response.ErrorResp("EXAM_IN_PROGRESS", nil), resp, path)
+		}
+		assert.Equal(t, []string{
+			models.ExamViolationOutOfExamAccess,
+			models.ExamViolationOutOfExamAccess,
+			models.ExamViolationOutOfExamAccess,
+			models.ExamViolationOutOfExamAccess,
+		}, violationTypes(t, e))
+	})
+	t.Run("IPNotAllowed", func(t *testing.T) {
+		t.Parallel()
+		e := createExam(t, 2)
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("problemSet.getProblemSet", e.class.ID, e.exam.ID),
+			nil, applyTokenAndIP(e.token, "192.168.1.1")))
+		assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+		resp := response.Response{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, response.ErrorResp("EXAM_IP_NOT_ALLOWED", nil), resp)
+		assert.Equal(t, []string{models.ExamViolationIPNotAllowed}, violationTypes(t, e))
+	})
+	t.Run("SpoofedIP", func(t *testing.T) {
+		t.Parallel()
+		e := createExam(t, 5)
+		// X-Forwarded-For is ignored for requests not from a trusted proxy.
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("problemSet.getProblemSet", e.class.ID, e.exam.ID),
+			nil, applyTokenAndIP(e.token, "10.1.2.3"), remoteAddrOption("203.0.113.1:1234")))
+		assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+		resp := response.Response{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, response.ErrorResp("EXAM_IP_NOT_ALLOWED", nil), resp)
+		violation := models.ExamViolation{}
+		assert.NoError(t, base.DB.First(&violation, "problem_set_id = ? and user_id = ?", e.exam.ID, e.student.ID).Error)
+		assert.Equal(t, models.ExamViolationIPNotAllowed, violation.Type)
+		assert.Equal(t, "203.0.113.1", violation.IP)
+	})
+	t.Run("SessionConflict", func(t *testing.T) {
+		t.Parallel()
+		e := createExam(t, 3)
+		otherToken := createTokenForTest(t, e.student)
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("problemSet.getProblemSet", e.class.ID, e.exam.ID),
+			nil, applyTokenAndIP(e.token, "10.1.2.3")))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		httpResp = makeResp(makeReq(t, "POST", base.Echo.Reverse("problemSet.createSubmission", e.class.ID, e.exam.ID, e.problem.ID),
+			addFieldContentSlice([]reqContent{
+				newFileContent("code", "code_file_name", b64Encode("test code content")),
+			}, map[string]string{"language": "test_language"}), applyTokenAndIP(otherToken, "10.1.2.4")))
+		assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+		resp := response.Response{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, response.ErrorResp("EXAM_SESSION_CONFLICT", nil), resp)
+		assert.Equal(t, []string{models.ExamViolationSessionConflict}, violationTypes(t, e))
+	})
+	t.Run("PersonalAccessToken", func(t *testing.T) {
+		t.Parallel()
+		e := createExam(t, 6)
+		pat := createPersonalAccessTokenForTest(t, e.student, "test_exam_restriction_pat", "submission:write").Data
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("problemSet.createSubmission", e.class.ID, e.exam.ID, e.problem.ID),
+			addFieldContentSlice([]reqContent{
+				newFileContent("code", "code_file_name", b64Encode("test code content")),
+			}, map[string]string{"language": "test_language"}), headerOption{
+				"Authorization":   {pat.Token},
+				"X-Forwarded-For": {"10.1.2.3"},
+			}))
+		assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+		resp := response.Response{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, response.ErrorResp("EXAM_SESSION_CONFLICT", nil), resp)
+		assert.Equal(t, []string{models.ExamViolationSessionConflict}, violationTypes(t, e))
+
+		// The personal access token is not bound to the student, so the session token still works.
+		httpResp = makeResp(makeReq(t, "GET", base.Echo.Reverse("problemSet.getProblemSet", e.class.ID, e.exam.ID),
+			nil, applyTokenAndIP(e.token, "10.1.2.3")))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		session := models.ExamSession{}
+		assert.NoError(t, base.DB.First(&session, "problem_set_id = ? and user_id = ?", e.exam.ID, e.student.ID).Error)
+		assert.Equal(t, e.token.ID, session.TokenID)
+	})
+	t.Run("NotInExam", func(t *testing.T) {
+		t.Parallel()
+		e := createExam(t, 4)
+		e.exam.ExamMode = false
+		assert.NoError(t, base.DB.Save(e.exam).Error)
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("problem.getProblem", e.problem.ID),
+			nil, applyTokenAndIP(e.token, "192.168.1.1")))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		httpResp = makeResp(makeReq(t, "GET", base.Echo.Reverse("problemSet.getProblemSet", e.class.ID, e.problemSet.ID),
+			nil, applyTokenAndIP(e.token, "192.168.1.1")))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		assert.Empty(t, violationTypes(t, e))
+	})
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func GetGradeChanges(c echo.Context) error {
+	req := request.GetGradeChangesRequest{}
+	if err, ok := utils.BindAndValidate(&req, c); !ok {
+		return err
+	}
+	problemSet := models.ProblemSet{}
+	if err := base.DB.First(&problemSet, "id = ? and class_id = ?", c.Param("id"), c.Param("class_id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not get problem set for getting grade changes"))
+	}
+	query := base.DB.Preload("User").Where("problem_set_id = ?", problemSet.ID).Order("id")
+	if req.UserID != 0 {
+		query = query.Where("user_id = ?", req.UserID)
+	}
+	if req.ProblemID != 0 {
+		query = query.Where("problem_id = ?", req.ProblemID)
+	}
+	var changes []*models.GradeChange
+	utils.PanicIfDBError(query.Find(&changes), "could not get grade changes")
+	return c.JSON(http.StatusOK, response.GetGradeChangesResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			GradeChanges []resource.GradeChange `json:"grade_changes"`
+		}{
+			resource.GetGradeChangeSlice(changes),
+		},
+	})
+}
+
+func GetMyGradeChanges(c echo.Context) error {
+	req := request.GetMyGradeChangesRequest{}
+	if err, ok := utils.BindAndValidate(&req, c); !ok {
+		return err
+	}
+	user := c.Get("user").(models.User)
+	query := base.DB.Preload("User").Where("user_id = ?", user.ID).Order("id")
+	if req.ClassID != 0 {
+		query = query.Where("class_id = ?", req.ClassID)
+	}
+	if req.ProblemSetID != 0 {
+		query = query.Where("problem_set_id = ?", req.ProblemSetID)
+	}
+	var changes []*models.GradeChange
+	utils.PanicIfDBError(query.Find(&changes), "could not get grade changes")
+	return c.JSON(http.StatusOK, response.GetMyGradeChangesResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			GradeChanges []resource.GradeChange `json:"grade_changes"`
+		}{
+			resource.GetGradeChangeSlice(changes),
+		},
+	})
+}
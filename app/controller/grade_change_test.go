@@ -0,0 +1,91 @@
+package controller_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetGradeChanges(t *testing.T) {
+	t.Parallel()
+	student1 := createUserForTest(t, "get_grade_changes", 0)
+	student2 := createUserForTest(t, "get_grade_changes", 1)
+	problem := createProblemForTest(t, "get_grade_changes", 0, nil, student1)
+	class := createClassForTest(t, "get_grade_changes", 0, nil, []*models.User{&student1, &student2})
+	problemSet := createProblemSetForTest(t, "get_grade_changes", 0, &class, []models.Problem{problem}, inProgress)
+	for _, student := range []models.User{student1, student2} {
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("problemSet.overrideGrade", class.ID, problemSet.ID), request.OverrideGradeRequest{
+			UserID:    student.ID,
+			ProblemID: problem.ID,
+			Score:     60,
+			Reason:    "test_get_grade_changes_reason",
+		}, applyAdminUser))
+		assert.Equal(t, http.StatusCreated, httpResp.StatusCode)
+	}
+	var changes []*models.GradeChange
+	assert.NoError(t, base.DB.Preload("User").Order("id").Find(&changes, "problem_set_id = ?", problemSet.ID).Error)
+	assert.Len(t, changes, 2)
+
+	failTests := []failTest{
+		{
+			name:       "NonExistingProblemSet",
+			method:     "GET",
+			path:       base.Echo.Reverse("problemSet.getGradeChanges", class.ID, -1),
+			req:        request.GetGradeChangesRequest{},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:       "PermissionDenied",
+			method:     "GET",
+			path:       base.Echo.Reverse("problemSet.getGradeChanges", class.ID, problemSet.ID),
+			req:        request.GetGradeChangesRequest{},
+			reqOptions: []reqOption{applyUser(student1)},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+	}
+	runFailTests(t, failTests, "GetGradeChanges")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("problemSet.getGradeChanges", class.ID, problemSet.ID),
+			nil, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.GetGradeChangesResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, resource.GetGradeChangeSlice(changes), resp.Data.GradeChanges)
+		assert.Equal(t, models.GradeChangeCauseOverride, resp.Data.GradeChanges[0].Cause)
+		assert.Equal(t, uint(60), resp.Data.GradeChanges[0].NewScore)
+	})
+	t.Run("FilterByUser", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("problemSet.getGradeChanges", class.ID, problemSet.ID),
+			nil, applyAdminUser, queryOption{
+				"user_id": {fmt.Sprint(student2.ID)},
+			}))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.GetGradeChangesResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, resource.GetGradeChangeSlice(changes[1:]), resp.Data.GradeChanges)
+	})
+	t.Run("Mine", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("user.getMyGradeChanges"),
+			nil, applyUser(student1), queryOption{
+				"problem_set_id": {fmt.Sprint(problemSet.ID)},
+			}))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.GetMyGradeChangesResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, resource.GetGradeChangeSlice(changes[:1]), resp.Data.GradeChanges)
+	})
+}
@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// getProblemSetForGradeOverride finds the problem set in the path, and checks if the student
+// is in the class and the problem is in the problem set.
+func getProblemSetForGradeOverride(c echo.Context, userID, problemID uint) (problemSet *models.ProblemSet, err error, ok bool) {
+	problemSet = &models.ProblemSet{}
+	if err := base.DB.First(problemSet, "id = ? and class_id = ?", c.Param("id"), c.Param("class_id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil)), false
+		}
+		panic(errors.Wrap(err, "could not get problem set for overriding grade"))
+	}
+	if base.DB.Model(&models.Class{ID: problemSet.ClassID}).Where("id = ?", userID).Association("Students").Count() == 0 {
+		return nil, c.JSON(http.StatusNotFound, response.ErrorResp("USER_NOT_FOUND", nil)), false
+	}
+	if base.DB.Model(problemSet).Where("id = ?", problemID).Association("Problems").Count() == 0 {
+		return nil, c.JSON(http.StatusNotFound, response.ErrorResp("PROBLEM_NOT_FOUND", nil)), false
+	}
+	return problemSet, nil, true
+}
+
+func GetGradeOverrides(c echo.Context) error {
+	problemSet := models.ProblemSet{}
+	if err := base.DB.First(&problemSet, "id = ? and class_id = ?", c.Param("id"), c.Param("class_id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not get problem set for getting grade overrides"))
+	}
+	var overrides []*models.GradeOverride
+	utils.PanicIfDBError(base.DB.Preload("User").Preload("Operator").Order("id").
+		Find(&overrides, "problem_set_id = ?", problemSet.ID), "could not get grade overrides")
+	return c.JSON(http.StatusOK, response.GetGradeOverridesResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			GradeOverrides []resource.GradeOverride `json:"grade_overrides"`
+		}{
+			resource.GetGradeOverrideSlice(overrides),
+		},
+	})
+}
+
+func OverrideGrade(c echo.Context) error {
+	req := request.OverrideGradeRequest{}
+	err, ok := utils.BindAndValidate(&req, c)
+	if !ok {
+		return err
+	}
+	problemSet, err, ok := getProblemSetForGradeOverride(c, req.UserID, req.ProblemID)
+	if !ok {
+		return err
+	}
+	if err := problemSet.LoadProblemEntries(); err != nil {
+		panic(errors.Wrap(err, "could not get problems for overriding grade"))
+	}
+	if req.Score > problemSet.FullMark(req.ProblemID) {
+		return c.JSON(http.StatusBadRequest, response.ErrorResp("SCORE_EXCEEDS_FULL_MARK", nil))
+	}
+	user := c.Get("user").(models.User)
+	override, err := utils.OverrideGrade(problemSet, req.UserID, req.ProblemID, req.Score, req.Reason, &user)
+	if err != nil {
+		panic(errors.Wrap(err, "could not override grade"))
+	}
+	utils.PanicIfDBError(base.DB.Preload("User").Preload("Operator").First(override, override.ID),
+		"could not get grade override")
+	return c.JSON(http.StatusCreated, response.OverrideGradeResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.GradeOverride `json:"grade_override"`
+		}{
+			resource.GetGradeOverride(override),
+		},
+	})
+}
+
+func RemoveGradeOverride(c echo.Context) error {
+	req := request.RemoveGradeOverrideRequest{}
+	err, ok := utils.BindAndValidate(&req, c)
+	if !ok {
+		return err
+	}
+	problemSet, err, ok := getProblemSetForGradeOverride(c, req.UserID, req.ProblemID)
+	if !ok {
+		return err
+	}
+	overridden, err := utils.IsGradeOverridden(problemSet.ID, req.UserID, req.ProblemID)
+	if err != nil {
+		panic(err)
+	}
+	if !overridden {
+		return c.JSON(http.StatusNotFound, response.ErrorResp("OVERRIDE_NOT_FOUND", nil))
+	}
+	user := c.Get("user").(models.User)
+	override, err := utils.RemoveGradeOverride(problemSet, req.UserID, req.ProblemID, req.Reason, &user)
+	if err != nil {
+		panic(errors.Wrap(err, "could not remove grade override"))
+	}
+	utils.PanicIfDBError(base.DB.Preload("User").Preload("Operator").First(override, override.ID),
+		"could not get grade override")
+	return c.JSON(http.StatusOK, response.RemoveGradeOverrideResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.GradeOverride `json:"grade_override"`
+		}{
+			resource.GetGradeOverride(override),
+		},
+	})
+}
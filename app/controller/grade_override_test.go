@@ -0,0 +1,197 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func getGradeDetailForTest(t *testing.T, problemSet *models.ProblemSet, user *models.User) map[uint]uint {
+	grade := models.Grade{}
+	assert.NoError(t, base.DB.First(&grade, "problem_set_id = ? and user_id = ?", problemSet.ID, user.ID).Error)
+	detail := make(map[uint]uint)
+	assert.NoError(t, json.Unmarshal(grade.Detail, &detail))
+	return detail
+}
+
+func TestOverrideGrade(t *testing.T) {
+	t.Parallel()
+	student := createUserForTest(t, "override_grade", 0)
+	otherUser := createUserForTest(t, "override_grade", 1)
+	problem := createProblemForTest(t, "override_grade", 0, nil, student)
+	otherProblem := createProblemForTest(t, "override_grade", 1, nil, student)
+	class := createClassForTest(t, "override_grade", 0, nil, []*models.User{&student})
+	problemSet := createProblemSetForTest(t, "override_grade", 0, &class, []models.Problem{problem}, inProgress)
+	submission := createSubmissionForTest(t, "override_grade", 0, &problem, &student, nil, 0)
+	submission.ProblemSetID = problemSet.ID
+	submission.Score = 100
+	assert.NoError(t, base.DB.Save(&submission).Error)
+
+	failTests := []failTest{
+		{
+			name:   "NonExistingProblemSet",
+			method: "POST",
+			path:   base.Echo.Reverse("problemSet.overrideGrade", class.ID, -1),
+			req: request.OverrideGradeRequest{
+				UserID:    student.ID,
+				ProblemID: problem.ID,
+				Score:     60,
+				Reason:    "test_override_grade_reason",
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:   "PermissionDenied",
+			method: "POST",
+			path:   base.Echo.Reverse("problemSet.overrideGrade", class.ID, problemSet.ID),
+			req: request.OverrideGradeRequest{
+				UserID:    student.ID,
+				ProblemID: problem.ID,
+				Score:     60,
+				Reason:    "test_override_grade_reason",
+			},
+			reqOptions: []reqOption{applyUser(student)},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+		{
+			name:   "UserNotInClass",
+			method: "POST",
+			path:   base.Echo.Reverse("problemSet.overrideGrade", class.ID, problemSet.ID),
+			req: request.OverrideGradeRequest{
+				UserID:    otherUser.ID,
+				ProblemID: problem.ID,
+				Score:     60,
+				Reason:    "test_override_grade_reason",
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("USER_NOT_FOUND", nil),
+		},
+		{
+			name:   "ProblemNotInProblemSet",
+			method: "POST",
+			path:   base.Echo.Reverse("problemSet.overrideGrade", class.ID, problemSet.ID),
+			req: request.OverrideGradeRequest{
+				UserID:    student.ID,
+				ProblemID: otherProblem.ID,
+				Score:     60,
+				Reason:    "test_override_grade_reason",
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("PROBLEM_NOT_FOUND", nil),
+		},
+		{
+			name:   "ScoreExceedsFullMark",
+			method: "POST",
+			path:   base.Echo.Reverse("problemSet.overrideGrade", class.ID, problemSet.ID),
+			req: request.OverrideGradeRequest{
+				UserID:    student.ID,
+				ProblemID: problem.ID,
+				Score:     101,
+				Reason:    "test_override_grade_reason",
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusBadRequest,
+			resp:       response.ErrorResp("SCORE_EXCEEDS_FULL_MARK", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "OverrideGrade")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("problemSet.overrideGrade", class.ID, problemSet.ID), request.OverrideGradeRequest{
+			UserID:    student.ID,
+			ProblemID: problem.ID,
+			Score:     60,
+			Reason:    "test_override_grade_reason",
+		}, applyAdminUser))
+		assert.Equal(t, http.StatusCreated, httpResp.StatusCode)
+		resp := response.OverrideGradeResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, uint(60), resp.Data.Score)
+		assert.Equal(t, "test_override_grade_reason", resp.Data.Reason)
+		assert.False(t, resp.Data.Removed)
+		assert.NotNil(t, resp.Data.Operator)
+		assert.Equal(t, map[uint]uint{problem.ID: 60}, getGradeDetailForTest(t, problemSet, &student))
+
+		// The override survives refreshing grades.
+		httpResp = makeResp(makeReq(t, "POST", base.Echo.Reverse("problemSet.RefreshGrades", class.ID, problemSet.ID),
+			request.RefreshGradesRequest{}, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		assert.Equal(t, map[uint]uint{problem.ID: 60}, getGradeDetailForTest(t, problemSet, &student))
+	})
+}
+
+func TestRemoveGradeOverride(t *testing.T) {
+	t.Parallel()
+	student := createUserForTest(t, "remove_grade_override", 0)
+	problem := createProblemForTest(t, "remove_grade_override", 0, nil, student)
+	class := createClassForTest(t, "remove_grade_override", 0, nil, []*models.User{&student})
+	problemSet := createProblemSetForTest(t, "remove_grade_override", 0, &class, []models.Problem{problem}, inProgress)
+	notOverriddenProblemSet := createProblemSetForTest(t, "remove_grade_override", 1, &class, []models.Problem{problem}, inProgress)
+	submission := createSubmissionForTest(t, "remove_grade_override", 0, &problem, &student, nil, 0)
+	submission.ProblemSetID = problemSet.ID
+	submission.Score = 50
+	assert.NoError(t, base.DB.Save(&submission).Error)
+	assert.NoError(t, base.DB.Create(&models.GradeOverride{
+		ClassID:      class.ID,
+		ProblemSetID: problemSet.ID,
+		ProblemID:    problem.ID,
+		UserID:       student.ID,
+		Score:        90,
+		Reason:       "test_remove_grade_override_reason",
+	}).Error)
+
+	failTests := []failTest{
+		{
+			name:   "NotOverridden",
+			method: "DELETE",
+			path:   base.Echo.Reverse("problemSet.removeGradeOverride", class.ID, notOverriddenProblemSet.ID),
+			req: request.RemoveGradeOverrideRequest{
+				UserID:    student.ID,
+				ProblemID: problem.ID,
+				Reason:    "test_remove_grade_override_reason",
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("OVERRIDE_NOT_FOUND", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "RemoveGradeOverride")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "DELETE", base.Echo.Reverse("problemSet.removeGradeOverride", class.ID, problemSet.ID), request.RemoveGradeOverrideRequest{
+			UserID:    student.ID,
+			ProblemID: problem.ID,
+			Reason:    "test_remove_grade_override_reason",
+		}, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.RemoveGradeOverrideResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.True(t, resp.Data.Removed)
+		assert.Equal(t, map[uint]uint{problem.ID: 50}, getGradeDetailForTest(t, problemSet, &student))
+
+		httpResp = makeResp(makeReq(t, "GET", base.Echo.Reverse("problemSet.getGradeOverrides", class.ID, problemSet.ID),
+			request.GetGradeOverridesRequest{}, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		getResp := response.GetGradeOverridesResponse{}
+		mustJsonDecode(httpResp, &getResp)
+		assert.Len(t, getResp.Data.GradeOverrides, 2)
+		assert.False(t, getResp.Data.GradeOverrides[0].Removed)
+		assert.Equal(t, uint(90), getResp.Data.GradeOverrides[0].Score)
+		assert.True(t, getResp.Data.GradeOverrides[1].Removed)
+	})
+}
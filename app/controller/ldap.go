@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/log"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// loginLDAPUser authenticates a user against the LDAP directory, and returns the user linked to the entry.
+// The user with the same verified email is linked on the first login, or a new user is created if there is no user
+// with the email.
+// Nil is returned if LDAP authentication is disabled or fails.
+func loginLDAPUser(username, password string) *models.User {
+	config, err := utils.GetLDAPConfig()
+	if err != nil {
+		panic(err)
+	}
+	if config == nil {
+		return nil
+	}
+	ldapUser, err := config.Authenticate(username, password)
+	if err != nil {
+		if !errors.Is(err, utils.ErrLDAPInvalidCredentials) {
+			log.Errorf("could not authenticate %s with ldap: %v", username, err)
+		}
+		return nil
+	}
+
+	identity := models.UserIdentity{}
+	err = base.DB.Preload("User").
+		First(&identity, "provider = ? and subject = ?", models.UserIdentityProviderLDAP, ldapUser.DN).Error
+	if err == nil {
+		user := *identity.User
+		if config.SyncProfile {
+			syncLDAPUser(&user, ldapUser)
+		}
+		return &user
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		panic(errors.Wrap(err, "could not find ldap user identity"))
+	}
+
+	if ldapUser.Email == "" {
+		log.Errorf("could not create user for ldap entry %s without email", ldapUser.DN)
+		return nil
+	}
+	user := models.User{}
+	err = base.DB.First(&user, "email = ?", ldapUser.Email).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if ldapUser.Username == "" {
+			ldapUser.Username = username
+		}
+		user = provisionUser(ldapUser.Username, ldapUser.Nickname, ldapUser.Email)
+	} else if err != nil {
+		panic(errors.Wrap(err, "could not find user by email for ldap login"))
+	} else if !user.EmailVerified {
+		// The email may be registered by someone else, who would take over the directory account.
+		log.Errorf("could not link ldap entry %s to user %s with unverified email", ldapUser.DN, user.Username)
+		return nil
+	}
+	identity = models.UserIdentity{
+		UserID:   user.ID,
+		Provider: models.UserIdentityProviderLDAP,
+		Subject:  ldapUser.DN,
+		Email:    ldapUser.Email,
+	}
+	utils.PanicIfDBError(base.DB.Omit("User").Create(&identity), "could not create ldap user identity")
+	return &user
+}
+
+// syncLDAPUser updates the nickname and the email of a user from the LDAP entry.
+// The email is not updated if it is used by another user.
+func syncLDAPUser(user *models.User, ldapUser *utils.LDAPUser) {
+	updates := make(map[string]interface{})
+	if ldapUser.Nickname != "" && ldapUser.Nickname != user.Nickname {
+		updates["nickname"] = ldapUser.Nickname
+	}
+	if ldapUser.Email != "" && ldapUser.Email != user.Email {
+		var count int64
+		utils.PanicIfDBError(base.DB.Model(&models.User{}).Where("email = ?", ldapUser.Email).Count(&count),
+			"could not query user count")
+		if count == 0 {
+			updates["email"] = ldapUser.Email
+			updates["email_verified"] = true
+		} else {
+			log.Errorf("could not sync email of user %s from ldap: %s is used by another user", user.Username, ldapUser.Email)
+		}
+	}
+	if len(updates) == 0 {
+		return
+	}
+	utils.PanicIfDBError(base.DB.Model(user).Updates(updates), "could not sync ldap user")
+}
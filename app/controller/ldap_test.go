@@ -0,0 +1,273 @@
+package controller_test
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockLDAPEntry struct {
+	password string
+	cn       string
+	mail     string
+}
+
+// mockLDAP is an LDAP server supporting simple binds as uid=<uid>,ou=people,dc=test and searches by uid.
+type mockLDAP struct {
+	listener net.Listener
+	mu       sync.Mutex
+	entries  map[string]mockLDAPEntry
+}
+
+func newMockLDAP(t *testing.T) *mockLDAP {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s := &mockLDAP{
+		listener: listener,
+		entries:  make(map[string]mockLDAPEntry),
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *mockLDAP) set(uid string, entry mockLDAPEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[uid] = entry
+}
+
+func (s *mockLDAP) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			code := ldap.LDAPResultInvalidCredentials
+			s.mu.Lock()
+			for uid, entry := range s.entries {
+				if op.Children[1].Value == "uid="+uid+",ou=people,dc=test" && op.Children[2].Data.String() == entry.password {
+					code = ldap.LDAPResultSuccess
+				}
+			}
+			s.mu.Unlock()
+			s.write(conn, messageID, s.result(ldap.ApplicationBindResponse, code))
+		case ldap.ApplicationSearchRequest:
+			filter, _ := ldap.DecompileFilter(op.Children[6])
+			s.mu.Lock()
+			for uid, entry := range s.entries {
+				if filter == "(uid="+uid+")" {
+					s.write(conn, messageID, s.entry(uid, entry))
+				}
+			}
+			s.mu.Unlock()
+			s.write(conn, messageID, s.result(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+		default:
+			return
+		}
+	}
+}
+
+func (s *mockLDAP) write(conn net.Conn, messageID int64, op *ber.Packet) {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, ""))
+	packet.AppendChild(op)
+	_, _ = conn.Write(packet.Bytes())
+}
+
+func (s *mockLDAP) result(tag ber.Tag, code int) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), ""))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	return op
+}
+
+func (s *mockLDAP) entry(uid string, entry mockLDAPEntry) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "uid="+uid+",ou=people,dc=test", ""))
+	attributes := ber.NewSequence("")
+	for name, value := range map[string]string{"uid": uid, "cn": entry.cn, "mail": entry.mail} {
+		attribute := ber.NewSequence("")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, ""))
+		values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+		values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, ""))
+		attribute.AppendChild(values)
+		attributes.AppendChild(attribute)
+	}
+	op.AppendChild(attributes)
+	return op
+}
+
+func TestLDAPLogin(t *testing.T) {
+	directory := newMockLDAP(t)
+	t.Cleanup(func() {
+		_ = directory.listener.Close()
+	})
+	// The test is not parallel as enabling ldap changes the login of all users.
+	// Its subtests finish before the other parallel tests start.
+	ldapConfig := viper.Get("auth.ldap")
+	t.Cleanup(func() {
+		viper.Set("auth.ldap", ldapConfig)
+	})
+	viper.Set("auth.ldap", map[string]interface{}{
+		"enabled":       true,
+		"url":           "ldap://" + directory.listener.Addr().String(),
+		"bind_dn":       "uid={username},ou=people,dc=test",
+		"search_base":   "ou=people,dc=test",
+		"search_filter": "(uid={username})",
+		"sync_profile":  true,
+	})
+
+	login := func(t *testing.T, username, password string) *http.Response {
+		return makeResp(makeReq(t, "POST", base.Echo.Reverse("auth.login"), request.LoginRequest{
+			UsernameOrEmail: username,
+			Password:        password,
+		}))
+	}
+	loggedInUser := func(t *testing.T, httpResp *http.Response) models.User {
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.LoginResponse{}
+		mustJsonDecode(httpResp, &resp)
+		user := models.User{}
+		assert.NoError(t, base.DB.First(&user, resp.Data.User.ID).Error)
+		return user
+	}
+
+	t.Run("LocalUser", func(t *testing.T) {
+		t.Parallel()
+		user := createUserForTest(t, "ldap_local", 0)
+		assert.Equal(t, user.ID, loggedInUser(t, login(t, user.Username, "test_ldap_local_user_0_pwd")).ID)
+
+		httpResp := login(t, user.Username, "wrong_password")
+		assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+		resp := response.Response{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, response.ErrorResp("WRONG_PASSWORD", nil), resp)
+	})
+	t.Run("WrongPassword", func(t *testing.T) {
+		t.Parallel()
+		directory.set("test_ldap_wrong_password", mockLDAPEntry{
+			password: "ldap_password",
+			cn:       "test_ldap_wrong_password_nick",
+			mail:     "test_ldap_wrong_password@e.e",
+		})
+		httpResp := login(t, "test_ldap_wrong_password", "wrong_password")
+		assert.Equal(t, http.StatusNotFound, httpResp.StatusCode)
+		resp := response.Response{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, response.ErrorResp("WRONG_USERNAME", nil), resp)
+	})
+	t.Run("CreateOnFirstLogin", func(t *testing.T) {
+		t.Parallel()
+		directory.set("test_ldap_create", mockLDAPEntry{
+			password: "ldap_password",
+			cn:       "test_ldap_create_nick",
+			mail:     "test_ldap_create@e.e",
+		})
+		user := loggedInUser(t, login(t, "test_ldap_create", "ldap_password"))
+		assert.Equal(t, "test_ldap_create", user.Username)
+		assert.Equal(t, "test_ldap_create_nick", user.Nickname)
+		assert.Equal(t, "test_ldap_create@e.e", user.Email)
+		assert.True(t, user.EmailVerified)
+		identity := models.UserIdentity{}
+		assert.NoError(t, base.DB.First(&identity, "provider = ? and subject = ?",
+			models.UserIdentityProviderLDAP, "uid=test_ldap_create,ou=people,dc=test").Error)
+		assert.Equal(t, user.ID, identity.UserID)
+
+		assert.Equal(t, user.ID, loggedInUser(t, login(t, "test_ldap_create", "ldap_password")).ID)
+	})
+	t.Run("LinkByEmail", func(t *testing.T) {
+		t.Parallel()
+		user := createUserForTest(t, "ldap_link", 0)
+		verifyEmailForTest(t, &user)
+		directory.set("test_ldap_link_directory", mockLDAPEntry{
+			password: "ldap_password",
+			cn:       "test_ldap_link_nick",
+			mail:     user.Email,
+		})
+		assert.Equal(t, user.ID, loggedInUser(t, login(t, "test_ldap_link_directory", "ldap_password")).ID)
+		// The local password keeps working.
+		assert.Equal(t, user.ID, loggedInUser(t, login(t, user.Username, "test_ldap_link_user_0_pwd")).ID)
+	})
+	t.Run("UnverifiedEmail", func(t *testing.T) {
+		t.Parallel()
+		// The email of the local user may be registered by someone else.
+		user := createUserForTest(t, "ldap_unverified", 0)
+		directory.set("test_ldap_unverified_directory", mockLDAPEntry{
+			password: "ldap_password",
+			cn:       "test_ldap_unverified_nick",
+			mail:     user.Email,
+		})
+		httpResp := login(t, "test_ldap_unverified_directory", "ldap_password")
+		assert.Equal(t, http.StatusNotFound, httpResp.StatusCode)
+		var count int64
+		assert.NoError(t, base.DB.Model(&models.UserIdentity{}).Where("user_id = ?", user.ID).Count(&count).Error)
+		assert.Equal(t, int64(0), count)
+	})
+	t.Run("UsernameConflict", func(t *testing.T) {
+		t.Parallel()
+		local := createUserForTest(t, "ldapc", 0)
+		directory.set(local.Username, mockLDAPEntry{
+			password: "ldap_password",
+			cn:       "test_ldap_conflict_nick",
+			mail:     "test_ldap_conflict_directory@e.e",
+		})
+		user := loggedInUser(t, login(t, local.Username, "ldap_password"))
+		assert.NotEqual(t, local.ID, user.ID)
+		assert.Equal(t, local.Username+"_1", user.Username)
+		assert.Equal(t, user.ID, loggedInUser(t, login(t, local.Username, "ldap_password")).ID)
+		assert.Equal(t, local.ID, loggedInUser(t, login(t, local.Username, "test_ldapc_user_0_pwd")).ID)
+	})
+	t.Run("SyncProfile", func(t *testing.T) {
+		t.Parallel()
+		directory.set("test_ldap_sync", mockLDAPEntry{
+			password: "ldap_password",
+			cn:       "test_ldap_sync_nick",
+			mail:     "test_ldap_sync@e.e",
+		})
+		user := loggedInUser(t, login(t, "test_ldap_sync", "ldap_password"))
+		assert.Equal(t, "test_ldap_sync_nick", user.Nickname)
+
+		directory.set("test_ldap_sync", mockLDAPEntry{
+			password: "ldap_password",
+			cn:       "test_ldap_sync_nick_changed",
+			mail:     "test_ldap_sync_changed@e.e",
+		})
+		user = loggedInUser(t, login(t, "test_ldap_sync", "ldap_password"))
+		assert.Equal(t, "test_ldap_sync_nick_changed", user.Nickname)
+		assert.Equal(t, "test_ldap_sync_changed@e.e", user.Email)
+
+		// Emails used by other users are not synced.
+		other := createUserForTest(t, "ldap_sync", 0)
+		directory.set("test_ldap_sync", mockLDAPEntry{
+			password: "ldap_password",
+			cn:       "test_ldap_sync_nick_changed",
+			mail:     other.Email,
+		})
+		user = loggedInUser(t, login(t, "test_ldap_sync", "ldap_password"))
+		assert.Equal(t, "test_ldap_sync_changed@e.e", user.Email)
+	})
+}
@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// @summary      Get login lockouts.
+// @description  Get the records of accounts and ips locked out from logging in after repeated failures, including
+// @description  expired and unlocked ones, newest first.
+// @router       /admin/login_lockouts [GET]
+// @produce      json
+// @tags         Admin
+// @param        request  query     request.AdminGetLoginLockoutsRequest  true  "The filter and pagination of lockouts."
+// @success      200      {object}  response.AdminGetLoginLockoutsResponse
+// @failure      400      {object}  response.Response{data=[]response.ValidationError}  "Validation error"
+// @failure      403      {object}  response.Response                                   "Permission denied, with message `PERMISSION_DENIED`"
+// @failure      500      {object}  response.Response
+// @security     ApiKeyAuth
+func AdminGetLoginLockouts(c echo.Context) error {
+	req := request.AdminGetLoginLockoutsRequest{}
+	if err, ok := utils.BindAndValidate(&req, c); !ok {
+		return err
+	}
+	query := base.DB.Model(&models.LoginLockout{}).Preload("User").Order("id desc")
+	if req.UserID != 0 {
+		query = query.Where("user_id = ?", req.UserID)
+	}
+	var lockouts []models.LoginLockout
+	total, prevUrl, nextUrl, err := utils.Paginator(query, req.Limit, req.Offset, c.Request().URL, &lockouts)
+	if err != nil {
+		if herr, ok := err.(utils.HttpError); ok {
+			return herr.Response(c)
+		}
+		panic(err)
+	}
+	return c.JSON(http.StatusOK, response.AdminGetLoginLockoutsResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			LoginLockouts []resource.LoginLockout `json:"login_lockouts"`
+			Total         int                     `json:"total"`
+			Count         int                     `json:"count"`
+			Offset        int                     `json:"offset"`
+			Prev          *string                 `json:"prev"`
+			Next          *string                 `json:"next"`
+		}{
+			resource.GetLoginLockoutSlice(lockouts),
+			total,
+			len(lockouts),
+			req.Offset,
+			prevUrl,
+			nextUrl,
+		},
+	})
+}
+
+// @summary      Unlock a user.
+// @description  Remove the login lockout and the login failures of a user, so that the user could login at once.
+// @router       /admin/user/{id}/login_lockout [DELETE]
+// @produce      json
+// @tags         Admin
+// @param        id   path      string  true  "The id or username of the user."
+// @success      200  {object}  response.AdminUnlockUserResponse
+// @failure      404  {object}  response.Response  "User not found, with message `NOT_FOUND`"
+// @failure      403  {object}  response.Response  "Permission denied, with message `PERMISSION_DENIED`"
+// @failure      500  {object}  response.Response
+// @security     ApiKeyAuth
+func AdminUnlockUser(c echo.Context) error {
+	admin := c.Get("user").(models.User)
+	user, err := utils.FindUser(c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+	} else if err != nil {
+		panic(err)
+	}
+	if err := utils.UnlockAccount(user, admin.ID); err != nil {
+		panic(err)
+	}
+	return c.JSON(http.StatusOK, response.AdminUnlockUserResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data:    nil,
+	})
+}
+
+// @summary      Unlock a login lockout.
+// @description  Remove the login lockout and the login failures of the account or the ip of a lockout record,
+// @description  e.g. an ip or a username not existing locally, so that logging in from it is allowed at once.
+// @router       /admin/login_lockout/{id} [DELETE]
+// @produce      json
+// @tags         Admin
+// @param        id   path      uint  true  "The id of the lockout record."
+// @success      200  {object}  response.AdminUnlockLoginLockoutResponse
+// @failure      404  {object}  response.Response  "Lockout not found, with message `NOT_FOUND`"
+// @failure      403  {object}  response.Response  "Permission denied, with message `PERMISSION_DENIED`"
+// @failure      500  {object}  response.Response
+// @security     ApiKeyAuth
+func AdminUnlockLoginLockout(c echo.Context) error {
+	admin := c.Get("user").(models.User)
+	lockout := models.LoginLockout{}
+	if err := base.DB.First(&lockout, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not find login lockout for unlocking"))
+	}
+	if err := utils.UnlockLoginLockout(&lockout, admin.ID); err != nil {
+		panic(err)
+	}
+	return c.JSON(http.StatusOK, response.AdminUnlockLoginLockoutResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data:    nil,
+	})
+}
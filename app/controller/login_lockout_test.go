@@ -0,0 +1,218 @@
+package controller_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func loginFromIPForTest(t *testing.T, username, password, ip string) *http.Response {
+	return makeResp(makeReq(t, "POST", base.Echo.Reverse("auth.login"), request.LoginRequest{
+		UsernameOrEmail: username,
+		Password:        password,
+	}, headerOption{
+		"X-Forwarded-For": {ip},
+	}))
+}
+
+func assertLoginThrottled(t *testing.T, httpResp *http.Response, limit string) {
+	assert.Equal(t, http.StatusTooManyRequests, httpResp.StatusCode)
+	assert.NotEmpty(t, httpResp.Header.Get("Retry-After"))
+	resp := response.Response{}
+	mustJsonDecode(httpResp, &resp)
+	assert.Equal(t, "LOGIN_THROTTLED", resp.Message)
+	assert.Equal(t, limit, resp.Error.(map[string]interface{})["limit"])
+}
+
+func TestLoginBackoff(t *testing.T) {
+	t.Parallel()
+	user := createUserForTest(t, "login_backoff", 0)
+	for i := 0; i < 4; i++ {
+		httpResp := loginFromIPForTest(t, user.Username, "wrong_password", "198.51.100.1")
+		assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+	}
+	// The account is throttled from any ip, even with the right password.
+	assertLoginThrottled(t, loginFromIPForTest(t, user.Username, "test_login_backoff_user_0_pwd", "198.51.100.2"),
+		utils.LoginLimitBackoff)
+}
+
+func TestLoginBackoffByUsername(t *testing.T) {
+	t.Parallel()
+	// Users only in the LDAP directory do not exist locally, and are counted by the username.
+	for i := 0; i < 4; i++ {
+		httpResp := loginFromIPForTest(t, "test_login_backoff_by_username", "wrong_password", fmt.Sprintf("198.51.103.%d", i))
+		assert.Equal(t, http.StatusNotFound, httpResp.StatusCode)
+	}
+	assertLoginThrottled(t, loginFromIPForTest(t, "Test_Login_Backoff_By_Username", "wrong_password", "198.51.103.100"),
+		utils.LoginLimitBackoff)
+}
+
+func TestTwoFactorLoginBackoff(t *testing.T) {
+	t.Parallel()
+	user := createUserForTest(t, "tfa_backoff", 0)
+	enableTOTPForTest(t, user)
+	login := func(t *testing.T) *http.Response {
+		return loginFromIPForTest(t, user.Username, "test_tfa_backoff_user_0_pwd", "198.51.100.4")
+	}
+	finish := func(t *testing.T, challenge string) *http.Response {
+		return makeResp(makeReq(t, "POST", base.Echo.Reverse("auth.twoFactor.finishLogin"), request.FinishTwoFactorLoginRequest{
+			Challenge: challenge,
+			Code:      "wrong_code",
+		}, headerOption{
+			"X-Forwarded-For": {"198.51.100.4"},
+		}))
+	}
+	// The failures are not cleared by the password, and each challenge adds to them.
+	for i := 0; i < 4; i++ {
+		httpResp := login(t)
+		assert.Equal(t, http.StatusAccepted, httpResp.StatusCode)
+		resp := response.TwoFactorChallengeResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, http.StatusForbidden, finish(t, resp.Data.Challenge).StatusCode)
+	}
+	assertLoginThrottled(t, login(t), utils.LoginLimitBackoff)
+}
+
+func TestDoResetPasswordBackoff(t *testing.T) {
+	t.Parallel()
+	user := createUserForTest(t, "reset_backoff", 0)
+	doReset := func(token string) *http.Response {
+		return makeResp(makeReq(t, "PUT", base.Echo.Reverse("auth.doResetPassword"), request.DoResetPasswordRequest{
+			UsernameOrEmail: user.Username,
+			Token:           token,
+			Password:        "test_reset_backoff_new_pwd",
+		}, headerOption{
+			"X-Forwarded-For": {"198.51.100.3"},
+		}))
+	}
+	for i := 0; i < 4; i++ {
+		httpResp := doReset(fmt.Sprintf("wron%d", i))
+		assert.Equal(t, http.StatusUnauthorized, httpResp.StatusCode)
+	}
+	assertLoginThrottled(t, doReset("wrong"), utils.LoginLimitBackoff)
+}
+
+func TestVerifyEmailNotThrottled(t *testing.T) {
+	t.Parallel()
+	user := createUserForTest(t, "email_verify", 0)
+	// Wrong verification codes from a logged in user are not login failures.
+	for i := 0; i < 5; i++ {
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("auth.email.verify"), request.VerifyEmailRequest{
+			Token: fmt.Sprintf("wron%d", i),
+		}, applyUser(user), headerOption{
+			"X-Forwarded-For": {"198.51.100.5"},
+		}))
+		assert.Equal(t, http.StatusUnauthorized, httpResp.StatusCode)
+	}
+	httpResp := loginFromIPForTest(t, user.Username, "test_email_verify_user_0_pwd", "198.51.100.5")
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+}
+
+func TestLoginLockout(t *testing.T) {
+	viper.Set("auth.login_throttle.backoff_base", 0)
+	defer viper.Set("auth.login_throttle.backoff_base", 1)
+	viper.Set("auth.login_throttle.ip.lockout_attempts", 3)
+	defer viper.Set("auth.login_throttle.ip.lockout_attempts", 100)
+
+	t.Run("Account", func(t *testing.T) {
+		user := createUserForTest(t, "login_lockout", 0)
+		for i := 0; i < 10; i++ {
+			// Each failure comes from a different ip, so that the ip is not locked out.
+			httpResp := loginFromIPForTest(t, user.Username, "wrong_password", fmt.Sprintf("198.51.101.%d", i))
+			assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+		}
+		assertLoginThrottled(t, loginFromIPForTest(t, user.Username, "test_login_lockout_user_0_pwd", "198.51.101.100"),
+			utils.LoginLimitLockout)
+
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("admin.user.getLoginLockouts"), nil,
+			applyAdminUser, queryOption{
+				"user_id": {fmt.Sprint(user.ID)},
+			}))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.AdminGetLoginLockoutsResponse{}
+		mustJsonDecode(httpResp, &resp)
+		if assert.Len(t, resp.Data.LoginLockouts, 1) {
+			lockout := resp.Data.LoginLockouts[0]
+			assert.Equal(t, models.LoginLockoutAccount, lockout.Type)
+			assert.Equal(t, user.Username, lockout.User.Username)
+			assert.Equal(t, "198.51.101.9", lockout.IP)
+			assert.Equal(t, int64(10), lockout.Failures)
+			assert.Nil(t, lockout.UnlockedAt)
+		}
+
+		httpResp = makeResp(makeReq(t, "DELETE", base.Echo.Reverse("admin.user.unlockUser", user.ID),
+			request.AdminUnlockUserRequest{}, applyNormalUser))
+		assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+		httpResp = makeResp(makeReq(t, "DELETE", base.Echo.Reverse("admin.user.unlockUser", user.ID),
+			request.AdminUnlockUserRequest{}, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		httpResp = loginFromIPForTest(t, user.Username, "test_login_lockout_user_0_pwd", "198.51.101.100")
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+
+		lockout := models.LoginLockout{}
+		assert.NoError(t, base.DB.First(&lockout, "user_id = ?", user.ID).Error)
+		assert.NotNil(t, lockout.UnlockedAt)
+		if assert.NotNil(t, lockout.UnlockedByID) {
+			assert.NotZero(t, *lockout.UnlockedByID)
+		}
+	})
+	t.Run("SpoofedIP", func(t *testing.T) {
+		// X-Forwarded-For is ignored for requests not from a trusted proxy, so the ip could not be changed with it.
+		for i := 0; i < 3; i++ {
+			httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("auth.login"), request.LoginRequest{
+				UsernameOrEmail: fmt.Sprintf("test_login_lockout_spoofed_%d", i),
+				Password:        "wrong_password",
+			}, headerOption{
+				"X-Forwarded-For": {fmt.Sprintf("198.51.104.%d", i)},
+			}, remoteAddrOption("203.0.113.2:1234")))
+			assert.Equal(t, http.StatusNotFound, httpResp.StatusCode)
+		}
+		user := createUserForTest(t, "login_lockout", 2)
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("auth.login"), request.LoginRequest{
+			UsernameOrEmail: user.Username,
+			Password:        "test_login_lockout_user_2_pwd",
+		}, headerOption{
+			"X-Forwarded-For": {"198.51.104.100"},
+		}, remoteAddrOption("203.0.113.2:1234")))
+		assertLoginThrottled(t, httpResp, utils.LoginLimitLockout)
+	})
+	t.Run("IP", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			httpResp := loginFromIPForTest(t, fmt.Sprintf("test_login_lockout_ip_%d", i), "wrong_password", "198.51.102.1")
+			assert.Equal(t, http.StatusNotFound, httpResp.StatusCode)
+		}
+		user := createUserForTest(t, "login_lockout", 1)
+		assertLoginThrottled(t, loginFromIPForTest(t, user.Username, "test_login_lockout_user_1_pwd", "198.51.102.1"),
+			utils.LoginLimitLockout)
+		// Other ips are not affected.
+		httpResp := loginFromIPForTest(t, user.Username, "test_login_lockout_user_1_pwd", "198.51.102.2")
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+
+		lockout := models.LoginLockout{}
+		assert.NoError(t, base.DB.First(&lockout, "type = ? and ip = ?", models.LoginLockoutIP, "198.51.102.1").Error)
+		assert.Zero(t, lockout.UserID)
+		assert.Equal(t, int64(3), lockout.Failures)
+
+		httpResp = makeResp(makeReq(t, "DELETE", base.Echo.Reverse("admin.user.unlockLoginLockout", lockout.ID),
+			request.AdminUnlockLoginLockoutRequest{}, applyNormalUser))
+		assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+		httpResp = makeResp(makeReq(t, "DELETE", base.Echo.Reverse("admin.user.unlockLoginLockout", -1),
+			request.AdminUnlockLoginLockoutRequest{}, applyAdminUser))
+		assert.Equal(t, http.StatusNotFound, httpResp.StatusCode)
+		httpResp = makeResp(makeReq(t, "DELETE", base.Echo.Reverse("admin.user.unlockLoginLockout", lockout.ID),
+			request.AdminUnlockLoginLockoutRequest{}, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		httpResp = loginFromIPForTest(t, user.Username, "test_login_lockout_user_1_pwd", "198.51.102.1")
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		assert.NoError(t, base.DB.First(&lockout, lockout.ID).Error)
+		assert.NotNil(t, lockout.UnlockedAt)
+	})
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/labstack/echo/v4"
+)
+
+func GetNotifications(c echo.Context) error {
+	req := request.GetNotificationsRequest{}
+	err, ok := utils.BindAndValidate(&req, c)
+	if !ok {
+		return err
+	}
+	user := c.Get("user").(models.User)
+	query := base.DB.Model(&models.Notification{}).Where("user_id = ?", user.ID).Order("id desc")
+	if req.Unread {
+		query = query.Where("has_read = ?", false)
+	}
+	var notifications []*models.Notification
+	total, prevUrl, nextUrl, err := utils.Paginator(query, req.Limit, req.Offset, c.Request().URL, &notifications)
+	if err != nil {
+		if herr, ok := err.(utils.HttpError); ok {
+			return herr.Response(c)
+		}
+		panic(err)
+	}
+	return c.JSON(http.StatusOK, response.GetNotificationsResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			Notifications []resource.Notification `json:"notifications"`
+			Unread        int64                   `json:"unread"`
+			Total         int                     `json:"total"`
+			Count         int                     `json:"count"`
+			Offset        int                     `json:"offset"`
+			Prev          *string                 `json:"prev"`
+			Next          *string                 `json:"next"`
+		}{
+			resource.GetNotificationSlice(notifications),
+			countUnreadNotifications(user.ID),
+			total,
+			len(notifications),
+			req.Offset,
+			prevUrl,
+			nextUrl,
+		},
+	})
+}
+
+func ReadNotifications(c echo.Context) error {
+	req := request.ReadNotificationsRequest{}
+	err, ok := utils.BindAndValidate(&req, c)
+	if !ok {
+		return err
+	}
+	user := c.Get("user").(models.User)
+	query := base.DB.Model(&models.Notification{}).Where("user_id = ?", user.ID)
+	if !req.All {
+		query = query.Where("id in (?)", req.IDs)
+	}
+	if req.All || len(req.IDs) > 0 {
+		utils.PanicIfDBError(query.Update("has_read", true), "could not mark notifications as read")
+	}
+	return c.JSON(http.StatusOK, response.ReadNotificationsResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			Unread int64 `json:"unread"`
+		}{
+			countUnreadNotifications(user.ID),
+		},
+	})
+}
+
+func UpdateNotificationSettings(c echo.Context) error {
+	req := request.UpdateNotificationSettingsRequest{}
+	err, ok := utils.BindAndValidate(&req, c)
+	if !ok {
+		return err
+	}
+	user := c.Get("user").(models.User)
+	utils.PanicIfDBError(base.DB.Model(&user).Update("email_notification", req.EmailNotification),
+		"could not update notification settings")
+	return c.JSON(http.StatusOK, response.UpdateNotificationSettingsResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			EmailNotification bool `json:"email_notification"`
+		}{
+			req.EmailNotification,
+		},
+	})
+}
+
+func countUnreadNotifications(userID uint) (count int64) {
+	utils.PanicIfDBError(base.DB.Model(&models.Notification{}).Where("user_id = ? and has_read = ?", userID, false).
+		Count(&count), "could not count unread notifications")
+	return
+}
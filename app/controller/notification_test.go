@@ -0,0 +1,122 @@
+package controller_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func createNotificationForTest(t *testing.T, name string, id int, user *models.User, read bool) models.Notification {
+	notification := models.Notification{
+		UserID:  user.ID,
+		Type:    models.NotificationTypeAnnouncement,
+		Title:   fmt.Sprintf("test_%s_%d_title", name, id),
+		Content: fmt.Sprintf("test_%s_%d_content", name, id),
+		Read:    read,
+	}
+	assert.NoError(t, base.DB.Create(&notification).Error)
+	return notification
+}
+
+func TestGetNotifications(t *testing.T) {
+	t.Parallel()
+	user := createUserForTest(t, "get_notifications", 0)
+	otherUser := createUserForTest(t, "get_notifications", 1)
+	notification1 := createNotificationForTest(t, "get_notifications", 1, &user, true)
+	notification2 := createNotificationForTest(t, "get_notifications", 2, &user, false)
+	notification3 := createNotificationForTest(t, "get_notifications", 3, &user, false)
+	createNotificationForTest(t, "get_notifications", 4, &otherUser, false)
+
+	t.Run("All", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("user.getNotifications"),
+			request.GetNotificationsRequest{}, applyUser(user)))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.GetNotificationsResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, int64(2), resp.Data.Unread)
+		assert.Equal(t, 3, resp.Data.Total)
+		expected := resource.GetNotificationSlice([]*models.Notification{&notification3, &notification2, &notification1})
+		assert.Len(t, resp.Data.Notifications, 3)
+		for i := range expected {
+			assert.Equal(t, expected[i].ID, resp.Data.Notifications[i].ID)
+			assert.Equal(t, expected[i].Title, resp.Data.Notifications[i].Title)
+			assert.Equal(t, expected[i].Read, resp.Data.Notifications[i].Read)
+		}
+	})
+	t.Run("Unread", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("user.getNotifications"),
+			request.GetNotificationsRequest{
+				Unread: true,
+			}, applyUser(user)))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.GetNotificationsResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, 2, resp.Data.Total)
+		assert.Len(t, resp.Data.Notifications, 2)
+		assert.Equal(t, notification3.ID, resp.Data.Notifications[0].ID)
+		assert.Equal(t, notification2.ID, resp.Data.Notifications[1].ID)
+	})
+}
+
+func TestReadNotifications(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ByIDs", func(t *testing.T) {
+		t.Parallel()
+		user := createUserForTest(t, "read_notifications", 0)
+		otherUser := createUserForTest(t, "read_notifications", 1)
+		notification1 := createNotificationForTest(t, "read_notifications", 1, &user, false)
+		notification2 := createNotificationForTest(t, "read_notifications", 2, &user, false)
+		otherNotification := createNotificationForTest(t, "read_notifications", 3, &otherUser, false)
+		httpResp := makeResp(makeReq(t, "PUT", base.Echo.Reverse("user.readNotifications"), request.ReadNotificationsRequest{
+			IDs: []uint{notification1.ID, otherNotification.ID},
+		}, applyUser(user)))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.ReadNotificationsResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, int64(1), resp.Data.Unread)
+
+		assert.NoError(t, base.DB.First(&notification1, notification1.ID).Error)
+		assert.NoError(t, base.DB.First(&notification2, notification2.ID).Error)
+		assert.NoError(t, base.DB.First(&otherNotification, otherNotification.ID).Error)
+		assert.True(t, notification1.Read)
+		assert.False(t, notification2.Read)
+		assert.False(t, otherNotification.Read)
+	})
+	t.Run("All", func(t *testing.T) {
+		t.Parallel()
+		user := createUserForTest(t, "read_notifications", 2)
+		createNotificationForTest(t, "read_notifications", 4, &user, false)
+		createNotificationForTest(t, "read_notifications", 5, &user, false)
+		httpResp := makeResp(makeReq(t, "PUT", base.Echo.Reverse("user.readNotifications"), request.ReadNotificationsRequest{
+			All: true,
+		}, applyUser(user)))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.ReadNotificationsResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, int64(0), resp.Data.Unread)
+	})
+}
+
+func TestUpdateNotificationSettings(t *testing.T) {
+	t.Parallel()
+	user := createUserForTest(t, "update_notification_settings", 0)
+	httpResp := makeResp(makeReq(t, "PUT", base.Echo.Reverse("user.updateNotificationSettings"), request.UpdateNotificationSettingsRequest{
+		EmailNotification: true,
+	}, applyUser(user)))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	resp := response.UpdateNotificationSettingsResponse{}
+	mustJsonDecode(httpResp, &resp)
+	assert.True(t, resp.Data.EmailNotification)
+	assert.NoError(t, base.DB.First(&user, user.ID).Error)
+	assert.True(t, user.EmailNotification)
+}
@@ -0,0 +1,275 @@
+package controller
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/log"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/labstack/echo/v4"
+	"github.com/patrickmn/go-cache"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// oidcBindingCookie keeps the binding of the authorization code flow started by the client.
+const oidcBindingCookie = "oidc_binding"
+
+// oidcSession is kept between the start and the callback of an authorization code flow.
+type oidcSession struct {
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	// Binding is also set as a cookie of the client starting the flow,
+	// so that the authorization url could not be finished by others.
+	Binding string
+	// LinkUserID is the user to link the identity to, or 0 when logging in.
+	LinkUserID uint
+}
+
+func GetOIDCProviders(c echo.Context) error {
+	providers, err := utils.GetOIDCProviders()
+	if err != nil {
+		panic(err)
+	}
+	ret := make([]response.OIDCProvider, len(providers))
+	for i, p := range providers {
+		ret[i] = response.OIDCProvider{
+			Name:        p.Name,
+			DisplayName: p.DisplayName,
+		}
+	}
+	return c.JSON(http.StatusOK, response.GetOIDCProvidersResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			Providers []response.OIDCProvider `json:"providers"`
+		}{
+			ret,
+		},
+	})
+}
+
+// beginOIDC starts an authorization code flow, and responds the url to redirect the user to.
+func beginOIDC(c echo.Context, linkUserID uint) error {
+	provider, err := utils.GetOIDCProvider(c.Param("provider"))
+	if err != nil {
+		panic(err)
+	}
+	if provider == nil {
+		return c.JSON(http.StatusNotFound, response.ErrorResp("PROVIDER_NOT_FOUND", nil))
+	}
+	session := oidcSession{
+		Provider:     provider.Name,
+		Nonce:        utils.RandStr(32),
+		CodeVerifier: utils.NewPKCEVerifier(),
+		Binding:      utils.RandStr(32),
+		LinkUserID:   linkUserID,
+	}
+	state := utils.RandStr(32)
+	authorizationURL, err := provider.AuthCodeURL(state, session.Nonce, session.CodeVerifier)
+	if err != nil {
+		log.Errorf("could not start oidc login with %s: %v", provider.Name, err)
+		return c.JSON(http.StatusBadGateway, response.ErrorResp("PROVIDER_UNAVAILABLE", nil))
+	}
+	cac.Set("oidc"+state, &session, cache.DefaultExpiration)
+	c.SetCookie(&http.Cookie{
+		Name:     oidcBindingCookie,
+		Value:    session.Binding,
+		Path:     "/api",
+		MaxAge:   int((5 * time.Minute).Seconds()),
+		Secure:   c.Scheme() == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return c.JSON(http.StatusOK, response.BeginOIDCLoginResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			AuthorizationURL string `json:"authorization_url"`
+			State            string `json:"state"`
+		}{
+			authorizationURL,
+			state,
+		},
+	})
+}
+
+func BeginOIDCLogin(c echo.Context) error {
+	return beginOIDC(c, 0)
+}
+
+func BeginLinkOIDCIdentity(c echo.Context) error {
+	user := c.Get("user").(models.User)
+	return beginOIDC(c, user.ID)
+}
+
+// finishOIDC checks the state of a callback and exchanges the code for the claims of the identity.
+// The flow should be started by the same client for the same user to link, which is 0 when logging in.
+func finishOIDC(c echo.Context, state, code string, linkUserID uint) (provider *utils.OIDCProvider,
+	claims *utils.OIDCClaims, err error, ok bool) {
+	s, found := cac.Get("oidc" + state)
+	if !found {
+		return nil, nil, c.JSON(http.StatusBadRequest, response.ErrorResp("INVALID_STATE", nil)), false
+	}
+	session := s.(*oidcSession)
+	cookie, cookieErr := c.Cookie(oidcBindingCookie)
+	if session.Provider != c.Param("provider") || session.LinkUserID != linkUserID || cookieErr != nil ||
+		subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(session.Binding)) != 1 {
+		return nil, nil, c.JSON(http.StatusBadRequest, response.ErrorResp("INVALID_STATE", nil)), false
+	}
+	cac.Delete("oidc" + state)
+	provider, err = utils.GetOIDCProvider(session.Provider)
+	if err != nil {
+		panic(err)
+	}
+	if provider == nil {
+		return nil, nil, c.JSON(http.StatusNotFound, response.ErrorResp("PROVIDER_NOT_FOUND", nil)), false
+	}
+	claims, err = provider.Exchange(code, session.CodeVerifier, session.Nonce)
+	if err != nil {
+		log.Errorf("could not finish oidc flow with %s: %v", provider.Name, err)
+		return nil, nil, c.JSON(http.StatusUnauthorized, response.ErrorResp("OIDC_FAILED", nil)), false
+	}
+	return provider, claims, nil, true
+}
+
+// findUserIdentity finds the identity of the claims from the provider, returning nil if it does not exist.
+func findUserIdentity(provider *utils.OIDCProvider, claims *utils.OIDCClaims) *models.UserIdentity {
+	identity := models.UserIdentity{}
+	err := base.DB.Preload("User").First(&identity, "provider = ? and subject = ?", provider.Name, claims.Subject).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		panic(errors.Wrap(err, "could not find user identity"))
+	}
+	return &identity
+}
+
+func createUserIdentity(user models.User, provider *utils.OIDCProvider, claims *utils.OIDCClaims) models.UserIdentity {
+	identity := models.UserIdentity{
+		UserID:   user.ID,
+		Provider: provider.Name,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+	utils.PanicIfDBError(base.DB.Omit("User").Create(&identity), "could not create user identity")
+	return identity
+}
+
+// FinishOIDCLogin finishes an authorization code flow started by BeginOIDCLogin.
+// The identity is linked to the user with the same verified email, or a new user if the provider
+// auto-provisions users. A token of the user is returned, or a two-factor challenge if the user enabled it.
+func FinishOIDCLogin(c echo.Context) error {
+	req := request.FinishOIDCLoginRequest{}
+	if err, ok := utils.BindAndValidate(&req, c); !ok {
+		return err
+	}
+	provider, claims, err, ok := finishOIDC(c, req.State, req.Code, 0)
+	if !ok {
+		return err
+	}
+
+	user := models.User{}
+	if identity := findUserIdentity(provider, claims); identity != nil {
+		user = *identity.User
+	} else {
+		if claims.Email == "" || !claims.EmailVerified {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("USER_NOT_FOUND", nil))
+		}
+		err := base.DB.First(&user, "email = ?", claims.Email).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if !provider.AutoProvision {
+				return c.JSON(http.StatusNotFound, response.ErrorResp("USER_NOT_FOUND", nil))
+			}
+			user = provisionOIDCUser(claims)
+		case err != nil:
+			panic(errors.Wrap(err, "could not find user by email for oidc login"))
+		case !user.EmailVerified:
+			// The email may be registered by someone else, so the identity should be linked by the user explicitly.
+			return c.JSON(http.StatusConflict, response.ErrorResp("CONFLICT_EMAIL", nil))
+		}
+		createUserIdentity(user, provider, claims)
+	}
+
+	return loginOrChallenge(c, user, req.RememberMe)
+}
+
+// FinishLinkOIDCIdentity finishes an authorization code flow started by BeginLinkOIDCIdentity,
+// and links the identity to the current user.
+func FinishLinkOIDCIdentity(c echo.Context) error {
+	user := c.Get("user").(models.User)
+	req := request.FinishLinkOIDCIdentityRequest{}
+	if err, ok := utils.BindAndValidate(&req, c); !ok {
+		return err
+	}
+	provider, claims, err, ok := finishOIDC(c, req.State, req.Code, user.ID)
+	if !ok {
+		return err
+	}
+
+	identity := findUserIdentity(provider, claims)
+	if identity != nil && identity.UserID != user.ID {
+		return c.JSON(http.StatusConflict, response.ErrorResp("IDENTITY_LINKED", nil))
+	}
+	if identity == nil {
+		created := createUserIdentity(user, provider, claims)
+		identity = &created
+	}
+	return c.JSON(http.StatusOK, response.FinishLinkOIDCIdentityResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.UserIdentity `json:"identity"`
+		}{
+			resource.GetUserIdentity(identity),
+		},
+	})
+}
+
+// provisionOIDCUser creates a user for an identity, named after the username claim or the email.
+func provisionOIDCUser(claims *utils.OIDCClaims) models.User {
+	username := claims.Username
+	if username == "" {
+		username = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+	return provisionUser(username, claims.Name, claims.Email)
+}
+
+func GetMyIdentities(c echo.Context) error {
+	user := c.Get("user").(models.User)
+	var identities []models.UserIdentity
+	utils.PanicIfDBError(base.DB.Order("id").Find(&identities, "user_id = ?", user.ID), "could not get user identities")
+	return c.JSON(http.StatusOK, response.GetMyIdentitiesResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			Identities []resource.UserIdentity `json:"identities"`
+		}{
+			resource.GetUserIdentitySlice(identities),
+		},
+	})
+}
+
+func DeleteMyIdentity(c echo.Context) error {
+	user := c.Get("user").(models.User)
+	result := base.DB.Delete(&models.UserIdentity{}, "id = ? and user_id = ?", c.Param("id"), user.ID)
+	utils.PanicIfDBError(result, "could not delete user identity")
+	if result.RowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+	}
+	return c.JSON(http.StatusOK, response.DeleteMyIdentityResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data:    nil,
+	})
+}
@@ -0,0 +1,471 @@
+package controller_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockIdPCode struct {
+	challenge string
+	claims    map[string]interface{}
+}
+
+// mockIdP is an OpenID Connect provider issuing authorization codes for the given claims directly.
+type mockIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	codes  sync.Map
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	idp := &mockIdP{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{
+				{
+					"kty": "RSA",
+					"kid": "test_key",
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				},
+			},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		v, ok := idp.codes.LoadAndDelete(r.PostFormValue("code"))
+		if !ok || r.PostFormValue("grant_type") != "authorization_code" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		code := v.(mockIdPCode)
+		if utils.PKCEChallenge(r.PostFormValue("code_verifier")) != code.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "test_access_token",
+			"token_type":   "Bearer",
+			"id_token":     idp.sign(t, code.claims),
+		})
+	})
+	idp.server = httptest.NewServer(mux)
+	return idp
+}
+
+func (idp *mockIdP) sign(t *testing.T, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": "test_key", "typ": "JWT"})
+	assert.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	assert.NoError(t, err)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hashed := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, hashed[:])
+	assert.NoError(t, err)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// authorize simulates the user authorizing at the authorization url, and returns the code and the state.
+func (idp *mockIdP) authorize(t *testing.T, authorizationURL string, claims map[string]interface{}) (string, string) {
+	u, err := url.Parse(authorizationURL)
+	assert.NoError(t, err)
+	q := u.Query()
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+	assert.Equal(t, "test_client", q.Get("client_id"))
+	c := map[string]interface{}{
+		"iss":   idp.server.URL,
+		"aud":   "test_client",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": q.Get("nonce"),
+	}
+	for k, v := range claims {
+		c[k] = v
+	}
+	code := utils.RandStr(16)
+	idp.codes.Store(code, mockIdPCode{
+		challenge: q.Get("code_challenge"),
+		claims:    c,
+	})
+	return code, q.Get("state")
+}
+
+// oidcCookie returns the binding cookie set by the response starting an authorization code flow.
+func oidcCookie(t *testing.T, httpResp *http.Response) headerOption {
+	for _, cookie := range httpResp.Cookies() {
+		if cookie.Name == "oidc_binding" {
+			return headerOption{
+				"Cookie": {cookie.Name + "=" + cookie.Value},
+			}
+		}
+	}
+	t.Error("oidc binding cookie not set")
+	return headerOption{}
+}
+
+func beginOIDCLoginForTest(t *testing.T, provider string) (string, headerOption) {
+	httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("auth.oidc.beginLogin", provider), nil))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	resp := response.BeginOIDCLoginResponse{}
+	mustJsonDecode(httpResp, &resp)
+	return resp.Data.AuthorizationURL, oidcCookie(t, httpResp)
+}
+
+func beginLinkOIDCIdentityForTest(t *testing.T, provider string, user models.User) (string, headerOption) {
+	httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("user.linkIdentity", provider), nil, applyUser(user)))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	resp := response.BeginOIDCLoginResponse{}
+	mustJsonDecode(httpResp, &resp)
+	return resp.Data.AuthorizationURL, oidcCookie(t, httpResp)
+}
+
+func verifyEmailForTest(t *testing.T, user *models.User) {
+	user.EmailVerified = true
+	assert.NoError(t, base.DB.Model(user).Update("email_verified", true).Error)
+}
+
+func TestOIDC(t *testing.T) {
+	idp := newMockIdP(t)
+	t.Cleanup(idp.server.Close)
+	viper.Set("auth.oidc.providers", []map[string]interface{}{
+		{
+			"name":         "test_oidc",
+			"display_name": "Test OIDC",
+			"issuer":       idp.server.URL,
+			"client_id":    "test_client",
+			"redirect_url": "http://localhost/oidc/test_oidc/callback",
+		},
+		{
+			"name":           "test_oidc_auto",
+			"issuer":         idp.server.URL,
+			"client_id":      "test_client",
+			"client_secret":  "test_secret",
+			"redirect_url":   "http://localhost/oidc/test_oidc_auto/callback",
+			"auto_provision": true,
+		},
+	})
+	t.Parallel()
+
+	finish := func(t *testing.T, provider string, claims map[string]interface{}) *http.Response {
+		authorizationURL, cookie := beginOIDCLoginForTest(t, provider)
+		code, state := idp.authorize(t, authorizationURL, claims)
+		return makeResp(makeReq(t, "POST", base.Echo.Reverse("auth.oidc.finishLogin", provider), request.FinishOIDCLoginRequest{
+			Code:  code,
+			State: state,
+		}, cookie))
+	}
+	checkLogin := func(t *testing.T, httpResp *http.Response, user models.User) {
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.LoginResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, user.ID, resp.Data.User.ID)
+		token := models.Token{}
+		assert.NoError(t, base.DB.First(&token, "token = ?", resp.Data.Token).Error)
+		assert.Equal(t, user.ID, token.UserID)
+	}
+
+	t.Run("GetProviders", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("auth.oidc.getProviders"), nil))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.GetOIDCProvidersResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, []response.OIDCProvider{
+			{Name: "test_oidc", DisplayName: "Test OIDC"},
+			{Name: "test_oidc_auto", DisplayName: "test_oidc_auto"},
+		}, resp.Data.Providers)
+	})
+	t.Run("ProviderNotFound", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("auth.oidc.beginLogin", "non_existing"), nil))
+		assert.Equal(t, http.StatusNotFound, httpResp.StatusCode)
+		resp := response.Response{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, response.ErrorResp("PROVIDER_NOT_FOUND", nil), resp)
+	})
+	t.Run("InvalidState", func(t *testing.T) {
+		t.Parallel()
+		user := createUserForTest(t, "oidc_invalid_state", 0)
+		authorizationURL, cookie := beginOIDCLoginForTest(t, "test_oidc")
+		code, state := idp.authorize(t, authorizationURL, map[string]interface{}{
+			"sub": "test_oidc_invalid_state",
+		})
+		_, otherCookie := beginOIDCLoginForTest(t, "test_oidc")
+		linkURL, linkCookie := beginLinkOIDCIdentityForTest(t, "test_oidc", user)
+		linkCode, linkState := idp.authorize(t, linkURL, map[string]interface{}{
+			"sub": "test_oidc_invalid_state_link",
+		})
+		for _, c := range []struct {
+			name     string
+			provider string
+			code     string
+			state    string
+			cookie   headerOption
+		}{
+			{"NonExistingState", "test_oidc", code, "non_existing_state", cookie},
+			{"OtherProvider", "test_oidc_auto", code, state, cookie},
+			{"NoCookie", "test_oidc", code, state, headerOption{}},
+			// The authorization url started by another client could not be finished.
+			{"OtherClient", "test_oidc", code, state, otherCookie},
+			// Links should be finished by the user starting them.
+			{"LinkState", "test_oidc", linkCode, linkState, linkCookie},
+		} {
+			httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("auth.oidc.finishLogin", c.provider), request.FinishOIDCLoginRequest{
+				Code:  c.code,
+				State: c.state,
+			}, c.cookie))
+			assert.Equal(t, http.StatusBadRequest, httpResp.StatusCode, c.name)
+			resp := response.Response{}
+			mustJsonDecode(httpResp, &resp)
+			assert.Equal(t, response.ErrorResp("INVALID_STATE", nil), resp, c.name)
+		}
+	})
+	t.Run("InvalidCode", func(t *testing.T) {
+		t.Parallel()
+		authorizationURL, cookie := beginOIDCLoginForTest(t, "test_oidc")
+		_, state := idp.authorize(t, authorizationURL, map[string]interface{}{
+			"sub": "test_oidc_invalid_code",
+		})
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("auth.oidc.finishLogin", "test_oidc"), request.FinishOIDCLoginRequest{
+			Code:  "invalid_code",
+			State: state,
+		}, cookie))
+		assert.Equal(t, http.StatusUnauthorized, httpResp.StatusCode)
+		resp := response.Response{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, response.ErrorResp("OIDC_FAILED", nil), resp)
+	})
+	t.Run("StateUsedOnce", func(t *testing.T) {
+		t.Parallel()
+		user := createUserForTest(t, "oidc_state_used_once", 0)
+		verifyEmailForTest(t, &user)
+		authorizationURL, cookie := beginOIDCLoginForTest(t, "test_oidc")
+		code, state := idp.authorize(t, authorizationURL, map[string]interface{}{
+			"sub":            "test_oidc_state_used_once",
+			"email":          user.Email,
+			"email_verified": true,
+		})
+		req := request.FinishOIDCLoginRequest{
+			Code:  code,
+			State: state,
+		}
+		checkLogin(t, makeResp(makeReq(t, "POST", base.Echo.Reverse("auth.oidc.finishLogin", "test_oidc"), req, cookie)), user)
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("auth.oidc.finishLogin", "test_oidc"), req, cookie))
+		assert.Equal(t, http.StatusBadRequest, httpResp.StatusCode)
+	})
+	t.Run("LinkByVerifiedEmail", func(t *testing.T) {
+		t.Parallel()
+		user := createUserForTest(t, "oidc_link_by_email", 0)
+		verifyEmailForTest(t, &user)
+		claims := map[string]interface{}{
+			"sub":            "test_oidc_link_by_email",
+			"email":          user.Email,
+			"email_verified": true,
+		}
+		checkLogin(t, finish(t, "test_oidc", claims), user)
+		identity := models.UserIdentity{}
+		assert.NoError(t, base.DB.First(&identity, "provider = ? and subject = ?", "test_oidc", "test_oidc_link_by_email").Error)
+		assert.Equal(t, user.ID, identity.UserID)
+		assert.Equal(t, user.Email, identity.Email)
+
+		// The linked identity logs in even if the email changes.
+		claims["email"] = "test_oidc_link_by_email_changed@e.e"
+		checkLogin(t, finish(t, "test_oidc", claims), user)
+	})
+	t.Run("TwoFactor", func(t *testing.T) {
+		t.Parallel()
+		user := createUserForTest(t, "oidc_two_factor", 0)
+		verifyEmailForTest(t, &user)
+		secret, _ := enableTOTPForTest(t, user)
+		httpResp := finish(t, "test_oidc", map[string]interface{}{
+			"sub":            "test_oidc_two_factor",
+			"email":          user.Email,
+			"email_verified": true,
+		})
+		assert.Equal(t, http.StatusAccepted, httpResp.StatusCode)
+		resp := response.TwoFactorChallengeResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, "TWO_FACTOR_REQUIRED", resp.Message)
+		code, err := utils.TOTPCode(secret, utils.TOTPStep(time.Now())+1)
+		assert.NoError(t, err)
+		checkLogin(t, makeResp(makeReq(t, "POST", base.Echo.Reverse("auth.twoFactor.finishLogin"), request.FinishTwoFactorLoginRequest{
+			Challenge: resp.Data.Challenge,
+			Code:      code,
+		})), user)
+	})
+	t.Run("UnverifiedEmail", func(t *testing.T) {
+		t.Parallel()
+		user := createUserForTest(t, "oidc_unverified_email", 0)
+		httpResp := finish(t, "test_oidc_auto", map[string]interface{}{
+			"sub":            "test_oidc_unverified_email",
+			"email":          user.Email,
+			"email_verified": false,
+		})
+		assert.Equal(t, http.StatusNotFound, httpResp.StatusCode)
+		resp := response.Response{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, response.ErrorResp("USER_NOT_FOUND", nil), resp)
+	})
+	t.Run("UnverifiedLocalEmail", func(t *testing.T) {
+		t.Parallel()
+		// The email of the local user may be registered by someone else.
+		user := createUserForTest(t, "oidc_unverified_local_email", 0)
+		httpResp := finish(t, "test_oidc_auto", map[string]interface{}{
+			"sub":            "test_oidc_unverified_local_email",
+			"email":          user.Email,
+			"email_verified": true,
+		})
+		assert.Equal(t, http.StatusConflict, httpResp.StatusCode)
+		resp := response.Response{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, response.ErrorResp("CONFLICT_EMAIL", nil), resp)
+		var count int64
+		assert.NoError(t, base.DB.Model(&models.UserIdentity{}).Where("user_id = ?", user.ID).Count(&count).Error)
+		assert.Equal(t, int64(0), count)
+	})
+	t.Run("NoAutoProvision", func(t *testing.T) {
+		t.Parallel()
+		httpResp := finish(t, "test_oidc", map[string]interface{}{
+			"sub":            "test_oidc_no_auto_provision",
+			"email":          "test_oidc_no_auto_provision@e.e",
+			"email_verified": true,
+		})
+		assert.Equal(t, http.StatusNotFound, httpResp.StatusCode)
+		resp := response.Response{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, response.ErrorResp("USER_NOT_FOUND", nil), resp)
+		var count int64
+		assert.NoError(t, base.DB.Model(&models.User{}).Where("email = ?", "test_oidc_no_auto_provision@e.e").Count(&count).Error)
+		assert.Equal(t, int64(0), count)
+	})
+	t.Run("AutoProvision", func(t *testing.T) {
+		t.Parallel()
+		existing := createUserForTest(t, "oidc_auto", 0)
+		httpResp := finish(t, "test_oidc_auto", map[string]interface{}{
+			"sub":                "test_oidc_auto_provision",
+			"email":              "test_oidc_auto_provision@e.e",
+			"email_verified":     true,
+			"name":               "Test OIDC Auto Provision",
+			"preferred_username": existing.Username,
+		})
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.LoginResponse{}
+		mustJsonDecode(httpResp, &resp)
+		user := models.User{}
+		assert.NoError(t, base.DB.First(&user, resp.Data.User.ID).Error)
+		assert.Equal(t, existing.Username+"_1", user.Username)
+		assert.Equal(t, "Test OIDC Auto Provision", user.Nickname)
+		assert.Equal(t, "test_oidc_auto_provision@e.e", user.Email)
+		assert.True(t, user.EmailVerified)
+	})
+	t.Run("AutoProvisionUsernameFromEmail", func(t *testing.T) {
+		t.Parallel()
+		httpResp := finish(t, "test_oidc_auto", map[string]interface{}{
+			"sub":            "test_oidc_auto_provision_email",
+			"email":          "a.b@test-oidc-auto-provision.e",
+			"email_verified": true,
+		})
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.LoginResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, "a_b__", resp.Data.User.Username)
+		assert.Equal(t, "a_b__", resp.Data.User.Nickname)
+	})
+	t.Run("Link", func(t *testing.T) {
+		t.Parallel()
+		user := createUserForTest(t, "oidc_link", 0)
+		other := createUserForTest(t, "oidc_link", 1)
+		link := func(t *testing.T, user models.User, subject string) *http.Response {
+			authorizationURL, cookie := beginLinkOIDCIdentityForTest(t, "test_oidc", user)
+			code, state := idp.authorize(t, authorizationURL, map[string]interface{}{
+				"sub":   subject,
+				"email": "test_oidc_link_idp@e.e",
+			})
+			return makeResp(makeReq(t, "POST", base.Echo.Reverse("user.finishLinkIdentity", "test_oidc"), request.FinishLinkOIDCIdentityRequest{
+				Code:  code,
+				State: state,
+			}, applyUser(user), cookie))
+		}
+		httpResp := link(t, user, "test_oidc_link")
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		linkResp := response.FinishLinkOIDCIdentityResponse{}
+		mustJsonDecode(httpResp, &linkResp)
+		assert.Equal(t, "test_oidc", linkResp.Data.Provider)
+		assert.Equal(t, "test_oidc_link_idp@e.e", linkResp.Data.Email)
+
+		httpResp = link(t, other, "test_oidc_link")
+		assert.Equal(t, http.StatusConflict, httpResp.StatusCode)
+		errResp := response.Response{}
+		mustJsonDecode(httpResp, &errResp)
+		assert.Equal(t, response.ErrorResp("IDENTITY_LINKED", nil), errResp)
+
+		// A link started by a user could not be finished by another user.
+		authorizationURL, cookie := beginLinkOIDCIdentityForTest(t, "test_oidc", other)
+		code, state := idp.authorize(t, authorizationURL, map[string]interface{}{
+			"sub": "test_oidc_link_other",
+		})
+		httpResp = makeResp(makeReq(t, "POST", base.Echo.Reverse("user.finishLinkIdentity", "test_oidc"), request.FinishLinkOIDCIdentityRequest{
+			Code:  code,
+			State: state,
+		}, applyUser(user), cookie))
+		assert.Equal(t, http.StatusBadRequest, httpResp.StatusCode)
+		mustJsonDecode(httpResp, &errResp)
+		assert.Equal(t, response.ErrorResp("INVALID_STATE", nil), errResp)
+
+		// The unverified email is linked to the user explicitly.
+		checkLogin(t, finish(t, "test_oidc", map[string]interface{}{
+			"sub": "test_oidc_link",
+		}), user)
+
+		var identities []models.UserIdentity
+		assert.NoError(t, base.DB.Find(&identities, "user_id = ?", user.ID).Error)
+		assert.Len(t, identities, 1)
+		httpResp = makeResp(makeReq(t, "GET", base.Echo.Reverse("user.getMyIdentities"), nil, applyUser(user)))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.GetMyIdentitiesResponse{}
+		mustJsonDecode(httpResp, &resp)
+		jsonEQ(t, resource.GetUserIdentitySlice(identities), resp.Data.Identities)
+
+		httpResp = makeResp(makeReq(t, "DELETE", base.Echo.Reverse("user.deleteMyIdentity", identities[0].ID), nil, applyUser(other)))
+		assert.Equal(t, http.StatusNotFound, httpResp.StatusCode)
+		httpResp = makeResp(makeReq(t, "DELETE", base.Echo.Reverse("user.deleteMyIdentity", identities[0].ID), nil, applyUser(user)))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		assert.NoError(t, base.DB.Find(&identities, "user_id = ?", user.ID).Error)
+		assert.Len(t, identities, 0)
+
+		httpResp = finish(t, "test_oidc", map[string]interface{}{
+			"sub": "test_oidc_link",
+		})
+		assert.Equal(t, http.StatusNotFound, httpResp.StatusCode)
+	})
+}
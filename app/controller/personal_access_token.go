@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/labstack/echo/v4"
+)
+
+// @summary      Get the personal access tokens of the current user.
+// @description  Get the personal access tokens of the current user, without the token strings.
+// @router       /user/me/personal_access_tokens [GET]
+// @produce      json
+// @tags         User
+// @success      200  {object}  response.GetPersonalAccessTokensResponse
+// @failure      500  {object}  response.Response
+// @security     ApiKeyAuth
+func GetPersonalAccessTokens(c echo.Context) error {
+	user := c.Get("user").(models.User)
+	var tokens []models.PersonalAccessToken
+	utils.PanicIfDBError(base.DB.Order("id").Find(&tokens, "user_id = ?", user.ID), "could not get personal access tokens")
+	return c.JSON(http.StatusOK, response.GetPersonalAccessTokensResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			Tokens []resource.PersonalAccessToken `json:"tokens"`
+		}{
+			resource.GetPersonalAccessTokenSlice(tokens),
+		},
+	})
+}
+
+// @summary      Create a personal access token.
+// @description  Create a personal access token for scripts. The token could only access the APIs covered by its
+// @description  scopes, which are problem:read, problem:write, grades:read, grades:write, submission:read and
+// @description  submission:write. The token is only shown in this response.
+// @router       /user/me/personal_access_tokens [POST]
+// @produce      json
+// @tags         User
+// @param        request  body      request.CreatePersonalAccessTokenRequest  true  "The name, scopes and expiry of the token."
+// @success      201      {object}  response.CreatePersonalAccessTokenResponse
+// @failure      400      {object}  response.Response{data=[]response.ValidationError}  "Validation error"
+// @failure      400      {object}  response.Response                                   "Unknown scope, with message `INVALID_SCOPE`"
+// @failure      400      {object}  response.Response                                   "Expiry in the past, with message `INVALID_EXPIRY`"
+// @failure      500      {object}  response.Response
+// @security     ApiKeyAuth
+func CreatePersonalAccessToken(c echo.Context) error {
+	user := c.Get("user").(models.User)
+	req := request.CreatePersonalAccessTokenRequest{}
+	if err, ok := utils.BindAndValidate(&req, c); !ok {
+		return err
+	}
+	for _, scope := range req.Scopes {
+		if !utils.IsValidTokenScope(scope) {
+			return c.JSON(http.StatusBadRequest, response.ErrorResp("INVALID_SCOPE", nil))
+		}
+	}
+	if !req.ExpiresAt.After(time.Now()) {
+		return c.JSON(http.StatusBadRequest, response.ErrorResp("INVALID_EXPIRY", nil))
+	}
+	tokenString, token, err := utils.NewPersonalAccessToken(user.ID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		panic(err)
+	}
+	return c.JSON(http.StatusCreated, response.CreatePersonalAccessTokenResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			PersonalAccessToken *resource.PersonalAccessToken `json:"personal_access_token"`
+			// Token is only shown once here.
+			Token string `json:"token"`
+		}{
+			resource.GetPersonalAccessToken(token),
+			tokenString,
+		},
+	})
+}
+
+// @summary      Revoke a personal access token.
+// @description  Revoke a personal access token of the current user.
+// @router       /user/me/personal_access_tokens/{id} [DELETE]
+// @produce      json
+// @tags         User
+// @param        id   path      int  true  "The id of the token."
+// @success      200  {object}  response.DeletePersonalAccessTokenResponse
+// @failure      404  {object}  response.Response  "Token not found, with message `NOT_FOUND`"
+// @failure      500  {object}  response.Response
+// @security     ApiKeyAuth
+func DeletePersonalAccessToken(c echo.Context) error {
+	user := c.Get("user").(models.User)
+	result := base.DB.Delete(&models.PersonalAccessToken{}, "id = ? and user_id = ?", c.Param("id"), user.ID)
+	utils.PanicIfDBError(result, "could not delete personal access token")
+	if result.RowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+	}
+	return c.JSON(http.StatusOK, response.DeletePersonalAccessTokenResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data:    nil,
+	})
+}
@@ -0,0 +1,192 @@
+package controller_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func createPersonalAccessTokenForTest(t *testing.T, user models.User, name string, scopes ...string) response.CreatePersonalAccessTokenResponse {
+	httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("user.createPersonalAccessToken"), request.CreatePersonalAccessTokenRequest{
+		Name:      name,
+		Scopes:    scopes,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}, applyUser(user)))
+	assert.Equal(t, http.StatusCreated, httpResp.StatusCode)
+	resp := response.CreatePersonalAccessTokenResponse{}
+	mustJsonDecode(httpResp, &resp)
+	return resp
+}
+
+func applyPersonalAccessToken(token string) headerOption {
+	return headerOption{
+		"Authorization": {token},
+	}
+}
+
+func TestCreatePersonalAccessToken(t *testing.T) {
+	t.Parallel()
+	user := createUserForTest(t, "create_pat", 0)
+
+	failTests := []failTest{
+		{
+			name:   "InvalidScope",
+			method: "POST",
+			path:   base.Echo.Reverse("user.createPersonalAccessToken"),
+			req: request.CreatePersonalAccessTokenRequest{
+				Name:      "test_create_pat_invalid_scope",
+				Scopes:    []string{"problem:read", "user:write"},
+				ExpiresAt: time.Now().Add(time.Hour),
+			},
+			reqOptions: []reqOption{
+				applyUser(user),
+			},
+			statusCode: http.StatusBadRequest,
+			resp:       response.ErrorResp("INVALID_SCOPE", nil),
+		},
+		{
+			name:   "Expired",
+			method: "POST",
+			path:   base.Echo.Reverse("user.createPersonalAccessToken"),
+			req: request.CreatePersonalAccessTokenRequest{
+				Name:      "test_create_pat_expired",
+				Scopes:    []string{"problem:read"},
+				ExpiresAt: time.Now().Add(-time.Hour),
+			},
+			reqOptions: []reqOption{
+				applyUser(user),
+			},
+			statusCode: http.StatusBadRequest,
+			resp:       response.ErrorResp("INVALID_EXPIRY", nil),
+		},
+	}
+	runFailTests(t, failTests, "CreatePersonalAccessToken")
+
+	resp := createPersonalAccessTokenForTest(t, user, "test_create_pat", "problem:write", "grades:read")
+	assert.Equal(t, "test_create_pat", resp.Data.PersonalAccessToken.Name)
+	assert.Equal(t, []string{"problem:write", "grades:read"}, resp.Data.PersonalAccessToken.Scopes)
+	assert.Nil(t, resp.Data.PersonalAccessToken.LastUsedAt)
+	assert.NotEmpty(t, resp.Data.Token)
+
+	httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("user.getPersonalAccessTokens"), nil, applyUser(user)))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	getResp := response.GetPersonalAccessTokensResponse{}
+	mustJsonDecode(httpResp, &getResp)
+	if assert.Len(t, getResp.Data.Tokens, 1) {
+		assert.Equal(t, resp.Data.PersonalAccessToken.ID, getResp.Data.Tokens[0].ID)
+		assert.Equal(t, "test_create_pat", getResp.Data.Tokens[0].Name)
+	}
+	// The token string is never stored.
+	assert.NotContains(t, mustJsonEncode(t, getResp), resp.Data.Token)
+}
+
+func TestPersonalAccessTokenAuthentication(t *testing.T) {
+	t.Parallel()
+	user := createUserForTest(t, "pat_auth", 0)
+	problem := createProblemForTest(t, "pat_auth", 0, nil, user)
+	token := createPersonalAccessTokenForTest(t, user, "test_pat_auth", "problem:read").Data
+
+	t.Run("InScope", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("problem.getProblem", problem.ID), nil,
+			applyPersonalAccessToken(token.Token)))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		stored := models.PersonalAccessToken{}
+		assert.NoError(t, base.DB.First(&stored, token.PersonalAccessToken.ID).Error)
+		assert.NotNil(t, stored.LastUsedAt)
+	})
+	t.Run("OutOfScope", func(t *testing.T) {
+		t.Parallel()
+		for _, req := range []*http.Request{
+			makeReq(t, "GET", base.Echo.Reverse("user.getMe"), nil, applyPersonalAccessToken(token.Token)),
+			makeReq(t, "DELETE", base.Echo.Reverse("problem.deleteProblem", problem.ID), nil, applyPersonalAccessToken(token.Token)),
+			makeReq(t, "POST", base.Echo.Reverse("user.createPersonalAccessToken"), request.CreatePersonalAccessTokenRequest{
+				Name:      "test_pat_auth_created_by_token",
+				Scopes:    []string{"problem:write"},
+				ExpiresAt: time.Now().Add(time.Hour),
+			}, applyPersonalAccessToken(token.Token)),
+		} {
+			httpResp := makeResp(req)
+			assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+			resp := response.Response{}
+			mustJsonDecode(httpResp, &resp)
+			assert.Equal(t, response.ErrorResp("INSUFFICIENT_SCOPE", nil), resp)
+		}
+	})
+	t.Run("Expired", func(t *testing.T) {
+		t.Parallel()
+		expired := createPersonalAccessTokenForTest(t, user, "test_pat_auth_expired", "problem:read").Data
+		assert.NoError(t, base.DB.Model(&models.PersonalAccessToken{}).Where("id = ?", expired.PersonalAccessToken.ID).
+			Update("expires_at", time.Now().Add(-time.Minute)).Error)
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("problem.getProblem", problem.ID), nil,
+			applyPersonalAccessToken(expired.Token)))
+		assert.Equal(t, http.StatusRequestTimeout, httpResp.StatusCode)
+		resp := response.Response{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, response.ErrorResp("AUTH_TOKEN_EXPIRED", nil), resp)
+	})
+	t.Run("DeletedUser", func(t *testing.T) {
+		t.Parallel()
+		deleted := createUserForTest(t, "pat_auth", 1)
+		deletedToken := createPersonalAccessTokenForTest(t, deleted, "test_pat_auth_deleted_user", "submission:read").Data
+		// The token is left by deleting the user without deleting its tokens.
+		assert.NoError(t, base.DB.Delete(&deleted).Error)
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("submission.getSubmissions"), nil,
+			applyPersonalAccessToken(deletedToken.Token)))
+		assert.Equal(t, http.StatusUnauthorized, httpResp.StatusCode)
+		resp := response.Response{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, response.ErrorResp("AUTH_NEED_TOKEN", nil), resp)
+
+		deletedByAdmin := createUserForTest(t, "pat_auth", 2)
+		createPersonalAccessTokenForTest(t, deletedByAdmin, "test_pat_auth_deleted_by_admin", "submission:read")
+		httpResp = makeResp(makeReq(t, "DELETE", base.Echo.Reverse("admin.user.deleteUser", deletedByAdmin.ID), nil,
+			applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		var count int64
+		assert.NoError(t, base.DB.Model(&models.PersonalAccessToken{}).Where("user_id = ?", deletedByAdmin.ID).
+			Count(&count).Error)
+		assert.Equal(t, int64(0), count)
+	})
+}
+
+func TestDeletePersonalAccessToken(t *testing.T) {
+	t.Parallel()
+	user := createUserForTest(t, "delete_pat", 0)
+	other := createUserForTest(t, "delete_pat", 1)
+	token := createPersonalAccessTokenForTest(t, user, "test_delete_pat", "submission:read").Data
+
+	httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("submission.getSubmissions"), nil,
+		applyPersonalAccessToken(token.Token)))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+
+	for _, req := range []*http.Request{
+		makeReq(t, "DELETE", base.Echo.Reverse("user.deletePersonalAccessToken", -1),
+			request.DeletePersonalAccessTokenRequest{}, applyUser(user)),
+		makeReq(t, "DELETE", base.Echo.Reverse("user.deletePersonalAccessToken", token.PersonalAccessToken.ID),
+			request.DeletePersonalAccessTokenRequest{}, applyUser(other)),
+	} {
+		httpResp := makeResp(req)
+		assert.Equal(t, http.StatusNotFound, httpResp.StatusCode)
+		resp := response.Response{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, response.ErrorResp("NOT_FOUND", nil), resp)
+	}
+
+	httpResp = makeResp(makeReq(t, "DELETE", base.Echo.Reverse("user.deletePersonalAccessToken", token.PersonalAccessToken.ID),
+		request.DeletePersonalAccessTokenRequest{}, applyUser(user)))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+
+	httpResp = makeResp(makeReq(t, "GET", base.Echo.Reverse("submission.getSubmissions"), nil,
+		applyPersonalAccessToken(token.Token)))
+	assert.Equal(t, http.StatusUnauthorized, httpResp.StatusCode)
+	resp := response.Response{}
+	mustJsonDecode(httpResp, &resp)
+	assert.Equal(t, response.ErrorResp("AUTH_NEED_TOKEN", nil), resp)
+}
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/exit"
+	"github.com/EduOJ/backend/base/log"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func CreatePlagiarismReport(c echo.Context) error {
+	problemSet := models.ProblemSet{}
+	if err := base.DB.First(&problemSet, "id = ? and class_id = ?", c.Param("id"), c.Param("class_id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not get problem set for creating plagiarism report"))
+	}
+	user := c.Get("user").(models.User)
+	report := models.PlagiarismReport{
+		ProblemSetID: problemSet.ID,
+		OperatorID:   user.ID,
+		Operator:     &user,
+		Status:       models.PlagiarismReportStatusPending,
+		Instance:     utils.InstanceID(),
+		HeartbeatAt:  time.Now(),
+	}
+	utils.PanicIfDBError(base.DB.Omit(clause.Associations).Create(&report), "could not create plagiarism report")
+	action := func(report models.PlagiarismReport) {
+		report.Operator = nil
+		if err := utils.RunPlagiarismAnalysis(&report); err != nil {
+			log.Errorf("%+v\n", err)
+		}
+	}
+	// The analysis downloads and compares all the submissions, which takes a while.
+	if inTest {
+		action(report)
+	} else {
+		// Shutting down waits for the analysis, so that the report is not left running.
+		exit.QuitWG.Add(1)
+		go func() {
+			defer exit.QuitWG.Done()
+			action(report)
+		}()
+	}
+	return c.JSON(http.StatusAccepted, response.CreatePlagiarismReportResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.PlagiarismReport `json:"report"`
+		}{
+			resource.GetPlagiarismReport(&report),
+		},
+	})
+}
+
+func GetPlagiarismReports(c echo.Context) error {
+	problemSet := models.ProblemSet{}
+	if err := base.DB.First(&problemSet, "id = ? and class_id = ?", c.Param("id"), c.Param("class_id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not get problem set for getting plagiarism reports"))
+	}
+	var reports []*models.PlagiarismReport
+	utils.PanicIfDBError(base.DB.Preload("Operator").Order("id desc").
+		Find(&reports, "problem_set_id = ?", problemSet.ID), "could not get plagiarism reports")
+	return c.JSON(http.StatusOK, response.GetPlagiarismReportsResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			Reports []resource.PlagiarismReport `json:"reports"`
+		}{
+			resource.GetPlagiarismReportSlice(reports),
+		},
+	})
+}
+
+func GetPlagiarismReport(c echo.Context) error {
+	report := models.PlagiarismReport{}
+	if err := base.DB.Preload("Operator").
+		Preload("Pairs", func(db *gorm.DB) *gorm.DB {
+			return db.Order("similarity desc, id")
+		}).
+		Preload("Pairs.FirstUser").Preload("Pairs.SecondUser").
+		First(&report, "id = ? and problem_set_id in (?)", c.Param("report_id"),
+			base.DB.Model(&models.ProblemSet{}).Select("id").Where("id = ? and class_id = ?", c.Param("id"), c.Param("class_id"))).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not get plagiarism report"))
+	}
+	return c.JSON(http.StatusOK, response.GetPlagiarismReportResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.PlagiarismReportDetail `json:"report"`
+		}{
+			resource.GetPlagiarismReportDetail(&report),
+		},
+	})
+}
@@ -0,0 +1,91 @@
+package controller_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlagiarismReport(t *testing.T) {
+	t.Parallel()
+	student1 := createUserForTest(t, "plagiarism_report", 0)
+	student2 := createUserForTest(t, "plagiarism_report", 1)
+	problem := createProblemForTest(t, "plagiarism_report", 0, nil, student1)
+	class := createClassForTest(t, "plagiarism_report", 0, nil, []*models.User{&student1, &student2})
+	problemSet := createProblemSetForTest(t, "plagiarism_report", 0, &class, []models.Problem{problem}, inProgress)
+	code := "int main() {\n  int n;\n  scanf(\"%d\", &n);\n  for (int i = 0; i < n; i++) {\n    printf(\"%d\\n\", i * i);\n  }\n  return 0;\n}\n"
+	for i, student := range []*models.User{&student1, &student2} {
+		submission := createSubmissionForTest(t, "plagiarism_report", i, &problem, student,
+			newFileContent("code", "code_file_name", b64Encode(code)), 0)
+		submission.ProblemSetID = problemSet.ID
+		assert.NoError(t, base.DB.Save(&submission).Error)
+	}
+
+	failTests := []failTest{
+		{
+			name:       "NonExistingProblemSet",
+			method:     "POST",
+			path:       base.Echo.Reverse("problemSet.createPlagiarismReport", class.ID, -1),
+			req:        nil,
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:       "PermissionDenied",
+			method:     "POST",
+			path:       base.Echo.Reverse("problemSet.createPlagiarismReport", class.ID, problemSet.ID),
+			req:        nil,
+			reqOptions: []reqOption{applyUser(student1)},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+		{
+			name:       "NonExistingReport",
+			method:     "GET",
+			path:       base.Echo.Reverse("problemSet.getPlagiarismReport", class.ID, problemSet.ID, -1),
+			req:        nil,
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "PlagiarismReport")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("problemSet.createPlagiarismReport", class.ID, problemSet.ID), nil, applyAdminUser))
+		assert.Equal(t, http.StatusAccepted, httpResp.StatusCode)
+		createResp := response.CreatePlagiarismReportResponse{}
+		mustJsonDecode(httpResp, &createResp)
+		assert.Equal(t, problemSet.ID, createResp.Data.ProblemSetID)
+
+		httpResp = makeResp(makeReq(t, "GET", base.Echo.Reverse("problemSet.getPlagiarismReports", class.ID, problemSet.ID), nil, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		listResp := response.GetPlagiarismReportsResponse{}
+		mustJsonDecode(httpResp, &listResp)
+		if assert.Len(t, listResp.Data.Reports, 1) {
+			assert.Equal(t, models.PlagiarismReportStatusFinished, listResp.Data.Reports[0].Status)
+		}
+
+		httpResp = makeResp(makeReq(t, "GET", base.Echo.Reverse("problemSet.getPlagiarismReport", class.ID, problemSet.ID,
+			createResp.Data.PlagiarismReport.ID), nil, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.GetPlagiarismReportResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, models.PlagiarismReportStatusFinished, resp.Data.Status)
+		assert.Equal(t, uint(2), resp.Data.SubmissionCount)
+		if assert.Len(t, resp.Data.Pairs, 1) {
+			assert.Equal(t, 1.0, resp.Data.Pairs[0].Similarity)
+			assert.Equal(t, student1.ID, resp.Data.Pairs[0].FirstUserID)
+			assert.Equal(t, student2.ID, resp.Data.Pairs[0].SecondUserID)
+			assert.Equal(t, student2.Username, resp.Data.Pairs[0].SecondUser.Username)
+			assert.NotEmpty(t, resp.Data.Pairs[0].Regions)
+		}
+	})
+}
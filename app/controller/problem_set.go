@@ -3,6 +3,7 @@ package controller
 import (
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/EduOJ/backend/app/request"
@@ -10,12 +11,31 @@ import (
 	"github.com/EduOJ/backend/app/response/resource"
 	"github.com/EduOJ/backend/base"
 	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database"
 	"github.com/EduOJ/backend/database/models"
 	"github.com/labstack/echo/v4"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
 
+// parseLanguages splits a comma separated list of language names,
+// and tells if all of them are existing languages.
+func parseLanguages(list string) (database.StringArray, bool) {
+	languages := database.StringArray{}
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" && !utils.Contain(name, languages) {
+			languages = append(languages, name)
+		}
+	}
+	if len(languages) == 0 {
+		return languages, true
+	}
+	var count int64
+	utils.PanicIfDBError(base.DB.Model(&models.Language{}).Where("name in (?)", []string(languages)).Count(&count),
+		"could not count languages")
+	return languages, count == int64(len(languages))
+}
+
 func CreateProblemSet(c echo.Context) error {
 	req := request.CreateProblemSetRequest{}
 	err, ok := utils.BindAndValidate(&req, c)
@@ -29,6 +49,10 @@ func CreateProblemSet(c echo.Context) error {
 		}
 		panic(errors.Wrap(err, "could not get class while creating problem set"))
 	}
+	languages, ok := parseLanguages(req.LanguageAllowed)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, response.ErrorResp("INVALID_LANGUAGE", nil))
+	}
 	problemSet := models.ProblemSet{
 		ClassID:     class.ID,
 		Name:        req.Name,
@@ -37,6 +61,9 @@ func CreateProblemSet(c echo.Context) error {
 		Grades:      nil,
 		StartTime:   req.StartTime,
 		EndTime:     req.EndTime,
+
+		AllowUpsolve:    req.AllowUpsolve,
+		LanguageAllowed: languages,
 	}
 	utils.PanicIfDBError(base.DB.Create(&problemSet), "could not create problem set for creating problem set")
 	return c.JSON(http.StatusCreated, response.CreateProblemSetResponse{
@@ -64,7 +91,7 @@ func CloneProblemSet(c echo.Context) error {
 		panic(errors.Wrap(err, "could not get class while cloning problem set"))
 	}
 	sourceProblemSet := models.ProblemSet{}
-	if err := base.DB.Preload("Problems").
+	if err := base.DB.Preload("Problems").Preload("ProblemEntries").
 		First(&sourceProblemSet, "id = ? and class_id = ?", req.SourceProblemSetID, req.SourceClassID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return c.JSON(http.StatusNotFound, response.ErrorResp("SOURCE_NOT_FOUND", nil))
@@ -80,8 +107,17 @@ func CloneProblemSet(c echo.Context) error {
 		Grades:      nil,
 		StartTime:   sourceProblemSet.StartTime,
 		EndTime:     sourceProblemSet.EndTime,
+
+		ExamMode:     sourceProblemSet.ExamMode,
+		ExamIPRanges: sourceProblemSet.ExamIPRanges,
+		AllowUpsolve: sourceProblemSet.AllowUpsolve,
+
+		LanguageAllowed: sourceProblemSet.LanguageAllowed,
 	}
 	utils.PanicIfDBError(base.DB.Create(&problemSet), "could not add problem set for class when cloning problem set")
+	if err := problemSet.SetProblemEntries(base.DB, sourceProblemSet.ProblemEntries); err != nil {
+		panic(errors.Wrap(err, "could not copy problems when cloning problem set"))
+	}
 	return c.JSON(http.StatusCreated, response.CloneProblemSetResponse{
 		Message: "SUCCESS",
 		Error:   nil,
@@ -104,7 +140,7 @@ func GetProblemSet(c echo.Context) error {
 
 	user := c.Get("user").(models.User)
 	problemSet := models.ProblemSet{}
-	if err := base.DB.Preload("Problems").Preload("Problems.Tags").
+	if err := base.DB.Preload("Problems").Preload("Problems.Tags").Preload("ProblemEntries").
 		First(&problemSet, "id = ? and class_id = ?", c.Param("problem_set_id"), c.Param("class_id")).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
@@ -152,17 +188,23 @@ func UpdateProblemSet(c echo.Context) error {
 		return err
 	}
 	problemSet := models.ProblemSet{}
-	if err := base.DB.Preload("Problems").Preload("Problems.Tags").
+	if err := base.DB.Preload("Problems").Preload("Problems.Tags").Preload("ProblemEntries").
 		First(&problemSet, "id = ? and class_id = ?", c.Param("problem_set_id"), c.Param("class_id")).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
 		}
 		panic(errors.Wrap(err, "could not get problem set for updating problem set"))
 	}
+	languages, ok := parseLanguages(req.LanguageAllowed)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, response.ErrorResp("INVALID_LANGUAGE", nil))
+	}
 	problemSet.Name = req.Name
 	problemSet.Description = req.Description
 	problemSet.StartTime = req.StartTime
 	problemSet.EndTime = req.EndTime
+	problemSet.AllowUpsolve = req.AllowUpsolve
+	problemSet.LanguageAllowed = languages
 	utils.PanicIfDBError(base.DB.Save(&problemSet), "could not update problem set for updating problem set")
 	return c.JSON(http.StatusOK, response.UpdateProblemSetResponse{
 		Message: "SUCCESS",
@@ -193,6 +235,12 @@ func AddProblemsToSet(c echo.Context) error {
 	if err := problemSet.AddProblems(req.ProblemIDs); err != nil {
 		panic(errors.Wrap(err, "could not add problems to problem set"))
 	}
+	if err := utils.RecalculateGrades(&problemSet, nil, req.ProblemIDs); err != nil {
+		panic(errors.Wrap(err, "could not recalculate grades for adding problems to problem set"))
+	}
+	if err := problemSet.LoadProblemEntries(); err != nil {
+		panic(errors.Wrap(err, "could not get problems for adding problems to problem set"))
+	}
 	return c.JSON(http.StatusOK, response.AddProblemsToSetResponse{
 		Message: "SUCCESS",
 		Error:   nil,
@@ -222,6 +270,13 @@ func DeleteProblemsFromSet(c echo.Context) error {
 	if err := problemSet.DeleteProblems(req.ProblemIDs); err != nil {
 		panic(errors.Wrap(err, "could not delete problems from problem set"))
 	}
+	// The scores of the deleted problems are removed from the grades.
+	if err := utils.RecalculateGrades(&problemSet, nil, []uint{}); err != nil {
+		panic(errors.Wrap(err, "could not recalculate grades for deleting problems from problem set"))
+	}
+	if err := problemSet.LoadProblemEntries(); err != nil {
+		panic(errors.Wrap(err, "could not get problems for deleting problems from problem set"))
+	}
 	return c.JSON(http.StatusOK, response.DeleteProblemsFromSetResponse{
 		Message: "SUCCESS",
 		Error:   nil,
@@ -233,6 +288,69 @@ func DeleteProblemsFromSet(c echo.Context) error {
 	})
 }
 
+func ReorderProblemsInSet(c echo.Context) error {
+	req := request.ReorderProblemsInSetRequest{}
+	err, ok := utils.BindAndValidate(&req, c)
+	if !ok {
+		return err
+	}
+
+	problemSet := models.ProblemSet{}
+	if err := base.DB.Preload("Problems").Preload("Problems.Tags").Preload("ProblemEntries").Preload("Class.Students").
+		First(&problemSet, "id = ? and class_id = ?", c.Param("id"), c.Param("class_id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not get problem set for reordering problems in problem set"))
+	}
+	fullMarks := make(map[uint]uint)
+	for _, entry := range problemSet.ProblemEntries {
+		fullMarks[entry.ProblemID] = entry.FullMark
+	}
+	if len(req.Problems) != len(fullMarks) {
+		return c.JSON(http.StatusBadRequest, response.ErrorResp("PROBLEMS_MISMATCH", nil))
+	}
+	entries := make([]*models.ProblemInProblemSet, len(req.Problems))
+	fullMarkChanged := false
+	for i, p := range req.Problems {
+		fullMark, ok := fullMarks[p.ProblemID]
+		if !ok {
+			return c.JSON(http.StatusBadRequest, response.ErrorResp("PROBLEMS_MISMATCH", nil))
+		}
+		// Mark the problem as listed, so that duplicated problems are rejected.
+		delete(fullMarks, p.ProblemID)
+		if fullMark != p.FullMark {
+			fullMarkChanged = true
+		}
+		entries[i] = &models.ProblemInProblemSet{
+			ProblemID: p.ProblemID,
+			Order:     uint(i),
+			Label:     p.Label,
+			FullMark:  p.FullMark,
+		}
+		if entries[i].Label == "" {
+			entries[i].Label = models.ProblemLabel(i)
+		}
+	}
+	if err := problemSet.SetProblemEntries(base.DB, entries); err != nil {
+		panic(errors.Wrap(err, "could not reorder problems in problem set"))
+	}
+	if fullMarkChanged {
+		if err := utils.RecalculateGrades(&problemSet, nil, nil); err != nil {
+			panic(errors.Wrap(err, "could not recalculate grades for reordering problems in problem set"))
+		}
+	}
+	return c.JSON(http.StatusOK, response.ReorderProblemsInSetResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.ProblemSetDetail `json:"problem_set"`
+		}{
+			resource.GetProblemSetDetail(&problemSet),
+		},
+	})
+}
+
 func DeleteProblemSet(c echo.Context) error {
 	problemSet := models.ProblemSet{}
 	if err := base.DB.First(&problemSet, "id = ? and class_id = ?", c.Param("problem_set_id"), c.Param("class_id")).Error; err != nil {
@@ -296,6 +414,8 @@ func GetProblemSetProblem(c echo.Context) error {
 		panic(errors.Wrap(err, "could not find problem for getting problem set problem"))
 	}
 
+	problem.LanguageAllowed = problemSet.AllowedLanguages(problem)
+
 	user := c.Get("user").(models.User)
 	if isAdmin {
 		return c.JSON(http.StatusOK, response.GetProblemSetProblemResponseForAdmin{
@@ -464,7 +584,7 @@ func GetProblemSetProblemOutputFile(c echo.Context) error {
 
 func RefreshGrades(c echo.Context) error {
 	problemSet := models.ProblemSet{}
-	if err := base.DB.Preload("Problems").Preload("Class.Students").Preload("Grades").
+	if err := base.DB.Preload("Problems").Preload("ProblemEntries").Preload("Class.Students").Preload("Grades").
 		First(&problemSet, "id = ? and class_id = ?", c.Param("id"), c.Param("class_id")).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
@@ -487,7 +607,7 @@ func RefreshGrades(c echo.Context) error {
 
 func GetProblemSetGrades(c echo.Context) error {
 	problemSet := models.ProblemSet{}
-	if err := base.DB.Preload("Problems").Preload("Class.Students").Preload("Grades").Preload("Grades.User").
+	if err := base.DB.Preload("Problems").Preload("ProblemEntries").Preload("Class.Students").Preload("Grades").Preload("Grades.User").
 		First(&problemSet, "id = ? and class_id = ?", c.Param("id"), c.Param("class_id")).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
@@ -500,6 +620,9 @@ func GetProblemSetGrades(c echo.Context) error {
 	if err := base.DB.Preload("Grades.User").First(&problemSet).Error; err != nil {
 		panic(errors.Wrap(err, "could not load Grades.User"))
 	}
+	if err := utils.LoadUpsolveDetails(&problemSet); err != nil {
+		panic(errors.Wrap(err, "could not load upsolve details to get problem set grades"))
+	}
 	return c.JSON(http.StatusOK, response.GetProblemSetGradesResponse{
 		Message: "SUCCESS",
 		Error:   nil,
@@ -513,7 +636,7 @@ func GetProblemSetGrades(c echo.Context) error {
 
 func GetClassGrades(c echo.Context) error {
 	class := models.Class{}
-	if err := base.DB.Preload("Students").Preload("ProblemSets.Grades.User").Preload("ProblemSets.Problems").
+	if err := base.DB.Preload("Students").Preload("ProblemSets.Grades.User").Preload("ProblemSets.Problems").Preload("ProblemSets.ProblemEntries").
 		First(&class, "id = ?", c.Param("id")).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
@@ -529,6 +652,9 @@ func GetClassGrades(c echo.Context) error {
 		if err := base.DB.Preload("Grades.User").First(problemSet).Error; err != nil {
 			panic(errors.Wrap(err, "could not load Grades.User"))
 		}
+		if err := utils.LoadUpsolveDetails(problemSet); err != nil {
+			panic(errors.Wrap(err, "could not load upsolve details to get class grades"))
+		}
 		ret = append(ret, resource.GetProblemSetWithGrades(problemSet))
 	}
 	return c.JSON(http.StatusOK, response.GetClassGradesResponse{
@@ -44,6 +44,23 @@ func ProblemSetCreateSubmission(c echo.Context) error {
 	if len(students) == 0 {
 		return c.JSON(http.StatusForbidden, response.ErrorResp("PERMISSION_DENIED", nil))
 	}
+	if problemSet.ExamRunning(time.Now()) {
+		token, _ := c.Get("token").(models.Token)
+		violation, err := utils.CheckExamAccess(problemSet, user.ID, token.ID, c.RealIP(), c.Request().URL.Path)
+		if err != nil {
+			panic(errors.Wrap(err, "could not check exam access for problem set creating submissions"))
+		}
+		switch violation {
+		case models.ExamViolationIPNotAllowed:
+			return c.JSON(http.StatusForbidden, response.ErrorResp("EXAM_IP_NOT_ALLOWED", nil))
+		case models.ExamViolationSessionConflict:
+			return c.JSON(http.StatusForbidden, response.ErrorResp("EXAM_SESSION_CONFLICT", nil))
+		}
+	}
+	upsolve := time.Now().After(problemSet.EndTime)
+	if upsolve && !problemSet.AllowUpsolve {
+		return c.JSON(http.StatusForbidden, response.ErrorResp("PROBLEM_SET_ENDED", nil))
+	}
 
 	var problems []models.Problem
 	if err := base.DB.Model(&problemSet).Association("Problems").Find(&problems, "id = ?", c.Param("problem_id")); err != nil {
@@ -54,7 +71,7 @@ func ProblemSetCreateSubmission(c echo.Context) error {
 	}
 	problems[0].LoadTestCases()
 
-	if !utils.Contain(req.Language, problems[0].LanguageAllowed) {
+	if !utils.Contain(req.Language, problemSet.AllowedLanguages(&problems[0])) {
 		return c.JSON(http.StatusBadRequest, response.ErrorResp("INVALID_LANGUAGE", nil))
 	}
 
@@ -71,6 +88,9 @@ func ProblemSetCreateSubmission(c echo.Context) error {
 	if file == nil {
 		return c.JSON(http.StatusBadRequest, response.ErrorResp("INVALID_FILE", nil))
 	}
+	if err, ok := checkSubmissionRateLimit(c, &user, &class, problemSet.ID, problems[0].ID); !ok {
+		return err
+	}
 	priority := models.PriorityDefault + 8
 
 	submission := models.Submission{
@@ -86,6 +106,7 @@ func ProblemSetCreateSubmission(c echo.Context) error {
 		Judged:       false,
 		Score:        0,
 		Status:       "PENDING",
+		Upsolve:      upsolve,
 		Runs:         make([]models.Run, len(problems[0].TestCases)),
 	}
 	for i, testCase := range problems[0].TestCases {
@@ -122,6 +143,74 @@ func ProblemSetCreateSubmission(c echo.Context) error {
 	})
 }
 
+func ProblemSetRejudgeSubmission(c echo.Context) error {
+	problemSet := models.ProblemSet{}
+	if err := base.DB.First(&problemSet, "class_id = ? and id = ?", c.Param("class_id"), c.Param("problem_set_id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("PROBLEM_SET_NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not find problem set for rejudging submission"))
+	}
+	submission := models.Submission{}
+	if err := base.DB.Preload("Problem").Preload("User").
+		First(&submission, "problem_set_id = ? and id = ?", problemSet.ID, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not find submission for rejudging"))
+	}
+	if err := utils.RejudgeSubmission(&submission); err != nil {
+		panic(errors.Wrap(err, "could not rejudge submission"))
+	}
+
+	if !inTest {
+		base.Redis.Publish(context.Background(), "runs", nil)
+	}
+
+	return c.JSON(http.StatusOK, response.ProblemSetRejudgeSubmissionResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.SubmissionDetail `json:"submission"`
+		}{
+			resource.GetSubmissionDetail(&submission),
+		},
+	})
+}
+
+func ProblemSetUpdateSubmissionFeedback(c echo.Context) error {
+	req := request.ProblemSetUpdateSubmissionFeedbackRequest{}
+	if err, ok := utils.BindAndValidate(&req, c); !ok {
+		return err
+	}
+	problemSet := models.ProblemSet{}
+	if err := base.DB.First(&problemSet, "class_id = ? and id = ?", c.Param("class_id"), c.Param("problem_set_id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("PROBLEM_SET_NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not find problem set for updating submission feedback"))
+	}
+	submission := models.Submission{}
+	if err := base.DB.Preload("Problem").Preload("User").
+		First(&submission, "problem_set_id = ? and id = ?", problemSet.ID, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		}
+		panic(errors.Wrap(err, "could not find submission for updating feedback"))
+	}
+	utils.PanicIfDBError(base.DB.Model(&submission).Update("feedback", req.Feedback), "could not update submission feedback")
+	submission.LoadRuns()
+	return c.JSON(http.StatusOK, response.ProblemSetUpdateSubmissionFeedbackResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.SubmissionDetail `json:"submission"`
+		}{
+			resource.GetSubmissionDetail(&submission),
+		},
+	})
+}
+
 func ProblemSetGetSubmission(c echo.Context) error {
 	startedAt := time.Now()
 	poll := false
@@ -307,9 +396,69 @@ func ProblemSetGetSubmissionCode(c echo.Context) error {
 	if err != nil {
 		panic(errors.Wrap(err, "could not get presigned url"))
 	}
+	if c.QueryParam("comments") == "1" {
+		return c.JSON(http.StatusOK, response.ProblemSetGetSubmissionCodeResponse{
+			Message: "SUCCESS",
+			Error:   nil,
+			Data: struct {
+				URL      string                       `json:"url"`
+				Comments []resource.SubmissionComment `json:"comments"`
+			}{
+				presignedUrl,
+				resource.GetSubmissionCommentThreads(getSubmissionComments(submission.ID)),
+			},
+		})
+	}
 	return c.Redirect(http.StatusFound, presignedUrl)
 }
 
+func ProblemSetCreateSubmissionComment(c echo.Context) error {
+	req := request.ProblemSetCreateSubmissionCommentRequest{}
+	if err, ok := utils.BindAndValidate(&req, c); !ok {
+		return err
+	}
+	problemSet := c.Get("problem_set")
+	if problemSet != nil {
+		err := c.Get("find_problem_set_error")
+		if err != nil {
+			if errors.Is(err.(error), gorm.ErrRecordNotFound) {
+				return c.JSON(http.StatusNotFound, response.ErrorResp("PROBLEM_SET_NOT_FOUND", nil))
+			}
+			panic(errors.Wrap(err.(error), "could not find problem set for creating submission comment"))
+		}
+	}
+
+	user := c.Get("user").(models.User)
+	submission := models.Submission{}
+	if err := base.DB.First(&submission, "problem_set_id = ? and id = ?",
+		c.Param("problem_set_id"), c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+		} else {
+			panic(errors.Wrap(err, "could not find submission for creating submission comment"))
+		}
+	}
+
+	// If problem set is empty here, the user is considered to have permission read_answers(because of
+	// the short-circuit in middleware HasPermission), and is a reviewer of the submission.
+	if user.ID != submission.UserID && problemSet != nil {
+		return c.JSON(http.StatusForbidden, response.ErrorResp("PERMISSION_DENIED", nil))
+	}
+	comment, err, ok := createSubmissionComment(c, &submission, problemSet == nil, request.CreateSubmissionCommentRequest(req))
+	if !ok {
+		return err
+	}
+	return c.JSON(http.StatusCreated, response.ProblemSetCreateSubmissionCommentResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.SubmissionComment `json:"comment"`
+		}{
+			resource.GetSubmissionComment(comment),
+		},
+	})
+}
+
 func ProblemSetGetRunCompilerOutput(c echo.Context) error {
 	problemSet := c.Get("problem_set")
 	if problemSet != nil {
@@ -28,6 +28,10 @@ func TestProblemSetCreateSubmission(t *testing.T) {
 	class := createClassForTest(t, "test_problem_set_create_submission_fail", 0, nil, []*models.User{&user})
 	problemSetInProgress := createProblemSetForTest(t, "test_problem_set_create_submission_fail", 0, &class, []models.Problem{problem}, inProgress)
 	problemSetNotStartYet := createProblemSetForTest(t, "test_problem_set_create_submission_not_in_open_time", 0, &class, []models.Problem{problem}, notStartYet)
+	problemSetEnded := createProblemSetForTest(t, "test_problem_set_create_submission_ended", 0, &class, []models.Problem{problem}, ended)
+	problemSetLanguageRestricted := createProblemSetForTest(t, "test_problem_set_create_submission_language_restricted", 0, &class, []models.Problem{problem}, inProgress)
+	problemSetLanguageRestricted.LanguageAllowed = []string{"golang"}
+	assert.NoError(t, base.DB.Save(problemSetLanguageRestricted).Error)
 
 	failTests := []failTest{
 		{
@@ -112,6 +116,19 @@ func TestProblemSetCreateSubmission(t *testing.T) {
 			statusCode: http.StatusBadRequest,
 			resp:       response.ErrorResp("INVALID_LANGUAGE", nil),
 		},
+		{
+			name:   "LanguageNotAllowedInProblemSet",
+			method: "POST",
+			path:   base.Echo.Reverse("problemSet.createSubmission", class.ID, problemSetLanguageRestricted.ID, problem.ID),
+			req: addFieldContentSlice([]reqContent{
+				newFileContent("code", "code_file_name", b64Encode("test code content")),
+			}, map[string]string{"language": "test_language"}),
+			reqOptions: []reqOption{
+				applyUser(user),
+			},
+			statusCode: http.StatusBadRequest,
+			resp:       response.ErrorResp("INVALID_LANGUAGE", nil),
+		},
 		{
 			name:   "NotInOpenTime",
 			method: "POST",
@@ -125,6 +142,19 @@ func TestProblemSetCreateSubmission(t *testing.T) {
 			statusCode: http.StatusForbidden,
 			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
 		},
+		{
+			name:   "Ended",
+			method: "POST",
+			path:   base.Echo.Reverse("problemSet.createSubmission", class.ID, problemSetEnded.ID, problem.ID),
+			req: addFieldContentSlice([]reqContent{
+				newFileContent("code", "code_file_name", b64Encode("test code content")),
+			}, map[string]string{"language": "test_language"}),
+			reqOptions: []reqOption{
+				applyUser(user),
+			},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PROBLEM_SET_ENDED", nil),
+		},
 		{
 			name:   "PermissionDenied",
 			method: "POST",
@@ -261,6 +291,34 @@ func TestProblemSetCreateSubmission(t *testing.T) {
 		}, resp)
 		assert.Equal(t, "problem_set_create_submission_code_success",
 			string(getObjectContent(t, "submissions", fmt.Sprintf("%d/code", databaseSubmission.ID))))
+		assert.False(t, resp.Data.Upsolve)
+	})
+
+	t.Run("Upsolve", func(t *testing.T) {
+		t.Parallel()
+
+		student := createUserForTest(t, "test_problem_set_create_submission_upsolve", 0)
+		problem := createProblemForTest(t, "test_problem_set_create_submission_upsolve", 0, nil, student)
+		class := createClassForTest(t, "test_problem_set_create_submission_upsolve", 0, nil, []*models.User{&student})
+		problemSet := createProblemSetForTest(t, "test_problem_set_create_submission_upsolve", 0, &class, []models.Problem{problem}, ended)
+		problemSet.AllowUpsolve = true
+		assert.NoError(t, base.DB.Save(problemSet).Error)
+
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("problemSet.createSubmission", class.ID, problemSet.ID, problem.ID),
+			addFieldContentSlice([]reqContent{
+				newFileContent("code", "code_file_name.test_language", b64Encode("problem_set_create_submission_code_upsolve")),
+			}, map[string]string{
+				"language": "test_language",
+			}), applyUser(student)))
+		assert.Equal(t, http.StatusCreated, httpResp.StatusCode)
+		resp := response.ProblemSetCreateSubmissionResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.True(t, resp.Data.Upsolve)
+
+		databaseSubmission := models.Submission{}
+		assert.NoError(t, base.DB.First(&databaseSubmission, resp.Data.ID).Error)
+		assert.True(t, databaseSubmission.Upsolve)
+		assert.Equal(t, problemSet.ID, databaseSubmission.ProblemSetID)
 	})
 }
 
@@ -1310,3 +1368,142 @@ func TestProblemSetGetRunComparerOutput(t *testing.T) {
 		assert.Equal(t, "problem_set_get_submission_run_comparer_output_0", getPresignedURLContent(t, httpResp.Header.Get("Location")))
 	})
 }
+
+func TestProblemSetRejudgeSubmission(t *testing.T) {
+	t.Parallel()
+
+	user := createUserForTest(t, "problem_set_rejudge_submission", 0)
+	assistant := createUserForTest(t, "problem_set_rejudge_submission", 1)
+	problem := createProblemForTest(t, "problem_set_rejudge_submission", 0, nil, user)
+	class := createClassForTest(t, "problem_set_rejudge_submission", 0, nil, []*models.User{&user})
+	otherClass := createClassForTest(t, "problem_set_rejudge_submission", 1, nil, nil)
+	assert.NoError(t, class.AddAssistants([]uint{assistant.ID}))
+	problemSet := createProblemSetForTest(t, "problem_set_rejudge_submission", 0, &class, []models.Problem{problem}, inProgress)
+	submission := createSubmissionForTest(t, "problem_set_rejudge_submission", 0, &problem, &user, nil, 2, "WRONG_ANSWER")
+	submission.ProblemSetID = problemSet.ID
+	submission.Judged = true
+	submission.Score = 50
+	assert.NoError(t, base.DB.Save(&submission).Error)
+
+	failTests := []failTest{
+		{
+			name:       "NonExistingProblemSet",
+			method:     "POST",
+			path:       base.Echo.Reverse("problemSet.rejudgeSubmission", class.ID, -1, submission.ID),
+			req:        request.ProblemSetRejudgeSubmissionRequest{},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("PROBLEM_SET_NOT_FOUND", nil),
+		},
+		{
+			name:       "ProblemSetInOtherClass",
+			method:     "POST",
+			path:       base.Echo.Reverse("problemSet.rejudgeSubmission", otherClass.ID, problemSet.ID, submission.ID),
+			req:        request.ProblemSetRejudgeSubmissionRequest{},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("PROBLEM_SET_NOT_FOUND", nil),
+		},
+		{
+			name:       "NonExistingSubmission",
+			method:     "POST",
+			path:       base.Echo.Reverse("problemSet.rejudgeSubmission", class.ID, problemSet.ID, -1),
+			req:        request.ProblemSetRejudgeSubmissionRequest{},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:       "PermissionDenied",
+			method:     "POST",
+			path:       base.Echo.Reverse("problemSet.rejudgeSubmission", class.ID, problemSet.ID, submission.ID),
+			req:        request.ProblemSetRejudgeSubmissionRequest{},
+			reqOptions: []reqOption{applyUser(user)},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+	}
+	runFailTests(t, failTests, "ProblemSetRejudgeSubmission")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("problemSet.rejudgeSubmission", class.ID, problemSet.ID, submission.ID),
+			request.ProblemSetRejudgeSubmissionRequest{}, applyUser(assistant)))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+
+		databaseSubmission := models.Submission{}
+		assert.NoError(t, base.DB.Preload("Runs").First(&databaseSubmission, submission.ID).Error)
+		assert.False(t, databaseSubmission.Judged)
+		assert.Equal(t, uint(0), databaseSubmission.Score)
+		assert.Equal(t, "PENDING", databaseSubmission.Status)
+		assert.Len(t, databaseSubmission.Runs, 2)
+		for i, run := range databaseSubmission.Runs {
+			assert.Equal(t, "PENDING", run.Status)
+			assert.False(t, run.Judged)
+			assert.Equal(t, problem.TestCases[i].ID, run.TestCaseID)
+			assert.NotEqual(t, submission.Runs[i].ID, run.ID)
+		}
+		var count int64
+		assert.NoError(t, base.DB.Model(&models.Run{}).Where("id in (?)", []uint{submission.Runs[0].ID, submission.Runs[1].ID}).Count(&count).Error)
+		assert.Equal(t, int64(0), count)
+
+		resp := response.ProblemSetRejudgeSubmissionResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, databaseSubmission.ID, resp.Data.ID)
+		assert.Equal(t, "PENDING", resp.Data.Status)
+		assert.Len(t, resp.Data.Runs, 2)
+	})
+}
+
+func TestProblemSetUpdateSubmissionFeedback(t *testing.T) {
+	t.Parallel()
+
+	user := createUserForTest(t, "problem_set_update_submission_feedback", 0)
+	problem := createProblemForTest(t, "problem_set_update_submission_feedback", 0, nil, user)
+	class := createClassForTest(t, "problem_set_update_submission_feedback", 0, nil, []*models.User{&user})
+	problemSet := createProblemSetForTest(t, "problem_set_update_submission_feedback", 0, &class, []models.Problem{problem}, inProgress)
+	submission := createSubmissionForTest(t, "problem_set_update_submission_feedback", 0, &problem, &user, nil, 0)
+	submission.ProblemSetID = problemSet.ID
+	assert.NoError(t, base.DB.Save(&submission).Error)
+
+	failTests := []failTest{
+		{
+			name:       "NonExistingSubmission",
+			method:     "PUT",
+			path:       base.Echo.Reverse("problemSet.updateSubmissionFeedback", class.ID, problemSet.ID, -1),
+			req:        request.ProblemSetUpdateSubmissionFeedbackRequest{Feedback: "test_feedback"},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:       "PermissionDenied",
+			method:     "PUT",
+			path:       base.Echo.Reverse("problemSet.updateSubmissionFeedback", class.ID, problemSet.ID, submission.ID),
+			req:        request.ProblemSetUpdateSubmissionFeedbackRequest{Feedback: "test_feedback"},
+			reqOptions: []reqOption{applyUser(user)},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "ProblemSetUpdateSubmissionFeedback")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "PUT", base.Echo.Reverse("problemSet.updateSubmissionFeedback", class.ID, problemSet.ID, submission.ID),
+			request.ProblemSetUpdateSubmissionFeedbackRequest{Feedback: "test_feedback"}, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.ProblemSetUpdateSubmissionFeedbackResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, "test_feedback", resp.Data.Feedback)
+
+		// The submitter sees the feedback in the submission detail.
+		httpResp = makeResp(makeReq(t, "GET", base.Echo.Reverse("problemSet.getSubmission", class.ID, problemSet.ID, submission.ID),
+			request.ProblemSetGetSubmissionRequest{}, applyUser(user)))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		getResp := response.ProblemSetGetSubmissionResponse{}
+		mustJsonDecode(httpResp, &getResp)
+		assert.Equal(t, "test_feedback", getResp.Data.Feedback)
+	})
+}
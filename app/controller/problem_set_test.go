@@ -12,6 +12,7 @@ import (
 	"github.com/EduOJ/backend/app/response/resource"
 	"github.com/EduOJ/backend/base"
 	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database"
 	"github.com/EduOJ/backend/database/models"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
@@ -81,10 +82,39 @@ func TestCreateProblemSet(t *testing.T) {
 			statusCode: http.StatusForbidden,
 			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
 		},
+		{
+			name:   "InvalidLanguage",
+			method: "POST",
+			path:   base.Echo.Reverse("problemSet.createProblemSet", class.ID),
+			req: request.CreateProblemSetRequest{
+				Name:            "test_create_problem_set_invalid_language_name",
+				Description:     "test_create_problem_set_invalid_language_description",
+				StartTime:       hashStringToTime("test_create_problem_set_invalid_language_time"),
+				EndTime:         hashStringToTime("test_create_problem_set_invalid_language_time").Add(time.Hour),
+				LanguageAllowed: "golang,non_existing_language",
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusBadRequest,
+			resp:       response.ErrorResp("INVALID_LANGUAGE", nil),
+		},
 	}
 
 	runFailTests(t, failTests, "")
 
+	t.Run("Languages", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("problemSet.createProblemSet", class.ID), request.CreateProblemSetRequest{
+			Name:            "test_create_problem_set_languages_name",
+			Description:     "test_create_problem_set_languages_description",
+			StartTime:       hashStringToTime("test_create_problem_set_languages_time"),
+			EndTime:         hashStringToTime("test_create_problem_set_languages_time").Add(time.Hour),
+			LanguageAllowed: "golang, test_language ,golang,",
+		}, applyAdminUser))
+		assert.Equal(t, http.StatusCreated, httpResp.StatusCode)
+		databaseProblemSet := models.ProblemSet{}
+		assert.NoError(t, base.DB.First(&databaseProblemSet, "name = ?", "test_create_problem_set_languages_name").Error)
+		assert.Equal(t, database.StringArray{"golang", "test_language"}, databaseProblemSet.LanguageAllowed)
+	})
 	t.Run("Success", func(t *testing.T) {
 		user := createUserForTest(t, "create_problem_set_success", 0)
 		class := createClassForTest(t, "create_problem_set_success", 0, nil, nil)
@@ -101,17 +131,18 @@ func TestCreateProblemSet(t *testing.T) {
 		databaseProblemSet := models.ProblemSet{}
 		assert.NoError(t, base.DB.Preload("Problems").Preload("Grades").First(&databaseProblemSet, "name = ?", "test_create_problem_set_success_name").Error)
 		expectedProblemSet := models.ProblemSet{
-			ID:          databaseProblemSet.ID,
-			ClassID:     class.ID,
-			Name:        "test_create_problem_set_success_name",
-			Description: "test_create_problem_set_success_description",
-			Problems:    []*models.Problem{},
-			Grades:      []*models.Grade{},
-			StartTime:   hashStringToTime("test_create_problem_set_success_time"),
-			EndTime:     hashStringToTime("test_create_problem_set_success_time").Add(time.Hour),
-			CreatedAt:   databaseProblemSet.CreatedAt,
-			UpdatedAt:   databaseProblemSet.UpdatedAt,
-			DeletedAt:   gorm.DeletedAt{},
+			ID:              databaseProblemSet.ID,
+			ClassID:         class.ID,
+			Name:            "test_create_problem_set_success_name",
+			Description:     "test_create_problem_set_success_description",
+			Problems:        []*models.Problem{},
+			Grades:          []*models.Grade{},
+			StartTime:       hashStringToTime("test_create_problem_set_success_time"),
+			EndTime:         hashStringToTime("test_create_problem_set_success_time").Add(time.Hour),
+			LanguageAllowed: database.StringArray{},
+			CreatedAt:       databaseProblemSet.CreatedAt,
+			UpdatedAt:       databaseProblemSet.UpdatedAt,
+			DeletedAt:       gorm.DeletedAt{},
 		}
 		assert.Equal(t, expectedProblemSet, databaseProblemSet)
 
@@ -255,6 +286,10 @@ func TestCloneProblemSet(t *testing.T) {
 		problem1 := createProblemForTest(t, "clone_problem_set_success_source", 1, nil, user)
 		problem2 := createProblemForTest(t, "clone_problem_set_success_source", 2, nil, user)
 		sourceProblemSet := createProblemSetForTest(t, "clone_problem_set_success_source", 0, &sourceClass, []models.Problem{problem1, problem2})
+		assert.NoError(t, sourceProblemSet.SetProblemEntries(base.DB, []*models.ProblemInProblemSet{
+			{ProblemID: problem1.ID, Order: 1, Label: "B", FullMark: 50},
+			{ProblemID: problem2.ID, Order: 0, Label: "A", FullMark: 30},
+		}))
 		assert.NoError(t, utils.UpdateGrade(&models.Submission{
 			ProblemSetID: sourceProblemSet.ID,
 			UserID:       user.ID,
@@ -276,7 +311,7 @@ func TestCloneProblemSet(t *testing.T) {
 		assert.Equal(t, http.StatusCreated, httpResp.StatusCode)
 
 		databaseProblemSet := models.ProblemSet{}
-		assert.NoError(t, base.DB.Preload("Problems").Preload("Grades").
+		assert.NoError(t, base.DB.Preload("Problems").Preload("Grades").Preload("ProblemEntries").
 			First(&databaseProblemSet, "name = ? and class_id = ?", "test_clone_problem_set_success_source_0_name", class.ID).Error)
 		expectedProblemSet := models.ProblemSet{
 			ID:          databaseProblemSet.ID,
@@ -284,15 +319,20 @@ func TestCloneProblemSet(t *testing.T) {
 			Name:        "test_clone_problem_set_success_source_0_name",
 			Description: "test_clone_problem_set_success_source_0_description",
 			Problems: []*models.Problem{
-				&problem1,
 				&problem2,
+				&problem1,
 			},
-			Grades:    []*models.Grade{},
-			StartTime: hashStringToTime("test_clone_problem_set_success_source_0_time"),
-			EndTime:   hashStringToTime("test_clone_problem_set_success_source_0_time").Add(time.Hour),
-			CreatedAt: databaseProblemSet.CreatedAt,
-			UpdatedAt: databaseProblemSet.UpdatedAt,
-			DeletedAt: gorm.DeletedAt{},
+			Grades: []*models.Grade{},
+			ProblemEntries: []*models.ProblemInProblemSet{
+				{ProblemSetID: databaseProblemSet.ID, ProblemID: problem2.ID, Order: 0, Label: "A", FullMark: 30},
+				{ProblemSetID: databaseProblemSet.ID, ProblemID: problem1.ID, Order: 1, Label: "B", FullMark: 50},
+			},
+			StartTime:       hashStringToTime("test_clone_problem_set_success_source_0_time"),
+			EndTime:         hashStringToTime("test_clone_problem_set_success_source_0_time").Add(time.Hour),
+			LanguageAllowed: database.StringArray{},
+			CreatedAt:       databaseProblemSet.CreatedAt,
+			UpdatedAt:       databaseProblemSet.UpdatedAt,
+			DeletedAt:       gorm.DeletedAt{},
 		}
 		assert.Equal(t, expectedProblemSet, databaseProblemSet)
 
@@ -379,7 +419,7 @@ func TestGetProblemSet(t *testing.T) {
 		ProblemID:    problem2.ID,
 		Score:        20,
 	}))
-	assert.NoError(t, base.DB.Preload("Grades").First(&problemSetInProgress, problemSetInProgress.ID).Error)
+	assert.NoError(t, base.DB.Preload("Grades").Preload("ProblemEntries").First(&problemSetInProgress, problemSetInProgress.ID).Error)
 	problemSetInProgress.StartTime = time.Now().Add(-1 * time.Hour)
 	problemSetInProgress.EndTime = time.Now().Add(time.Hour)
 	assert.NoError(t, base.DB.Save(&problemSetInProgress).Error)
@@ -536,19 +576,22 @@ func TestUpdateProblemSet(t *testing.T) {
 		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
 
 		databaseProblemSet := models.ProblemSet{}
-		assert.NoError(t, base.DB.Preload("Problems").Preload("Grades").First(&databaseProblemSet, problemSet.ID).Error)
+		assert.NoError(t, base.DB.Preload("Problems").Preload("Grades").Preload("ProblemEntries").First(&databaseProblemSet, problemSet.ID).Error)
+		assert.NoError(t, problemSet.LoadProblemEntries())
 		expectedProblemSet := models.ProblemSet{
-			ID:          databaseProblemSet.ID,
-			ClassID:     class.ID,
-			Name:        "test_update_problem_set_success_00_name",
-			Description: "test_update_problem_set_success_00_description",
-			Problems:    problemSet.Problems,
-			Grades:      problemSet.Grades,
-			StartTime:   hashStringToTime("test_update_problem_set_success_00_time"),
-			EndTime:     hashStringToTime("test_update_problem_set_success_00_time").Add(time.Hour),
-			CreatedAt:   databaseProblemSet.CreatedAt,
-			UpdatedAt:   databaseProblemSet.UpdatedAt,
-			DeletedAt:   gorm.DeletedAt{},
+			ID:              databaseProblemSet.ID,
+			ClassID:         class.ID,
+			Name:            "test_update_problem_set_success_00_name",
+			Description:     "test_update_problem_set_success_00_description",
+			Problems:        problemSet.Problems,
+			Grades:          problemSet.Grades,
+			ProblemEntries:  problemSet.ProblemEntries,
+			StartTime:       hashStringToTime("test_update_problem_set_success_00_time"),
+			EndTime:         hashStringToTime("test_update_problem_set_success_00_time").Add(time.Hour),
+			LanguageAllowed: database.StringArray{},
+			CreatedAt:       databaseProblemSet.CreatedAt,
+			UpdatedAt:       databaseProblemSet.UpdatedAt,
+			DeletedAt:       gorm.DeletedAt{},
 		}
 		assert.Equal(t, expectedProblemSet, databaseProblemSet)
 		resp := response.UpdateProblemSetResponse{}
@@ -657,7 +700,7 @@ func TestAddProblemsToSetProblemSet(t *testing.T) {
 		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
 
 		databaseProblemSet := models.ProblemSet{}
-		assert.NoError(t, base.DB.Preload("Problems").Preload("Grades").First(&databaseProblemSet, problemSet.ID).Error)
+		assert.NoError(t, base.DB.Preload("Problems").Preload("Grades").Preload("ProblemEntries").First(&databaseProblemSet, problemSet.ID).Error)
 		expectedProblemSet := models.ProblemSet{
 			ID:          databaseProblemSet.ID,
 			ClassID:     class.ID,
@@ -668,12 +711,19 @@ func TestAddProblemsToSetProblemSet(t *testing.T) {
 				&problem2,
 				&problem3,
 			},
-			Grades:    problemSet.Grades,
-			StartTime: hashStringToTime("test_add_problems_to_set_success_0_time"),
-			EndTime:   hashStringToTime("test_add_problems_to_set_success_0_time").Add(time.Hour),
-			CreatedAt: databaseProblemSet.CreatedAt,
-			UpdatedAt: databaseProblemSet.UpdatedAt,
-			DeletedAt: gorm.DeletedAt{},
+			// The grade of the user is dropped as the user is not a student of the class.
+			Grades: []*models.Grade{},
+			ProblemEntries: []*models.ProblemInProblemSet{
+				{ProblemSetID: problemSet.ID, ProblemID: problem1.ID, Order: 0, Label: "", FullMark: 100},
+				{ProblemSetID: problemSet.ID, ProblemID: problem2.ID, Order: 1, Label: "B", FullMark: 100},
+				{ProblemSetID: problemSet.ID, ProblemID: problem3.ID, Order: 2, Label: "C", FullMark: 100},
+			},
+			StartTime:       hashStringToTime("test_add_problems_to_set_success_0_time"),
+			EndTime:         hashStringToTime("test_add_problems_to_set_success_0_time").Add(time.Hour),
+			LanguageAllowed: database.StringArray{},
+			CreatedAt:       databaseProblemSet.CreatedAt,
+			UpdatedAt:       databaseProblemSet.UpdatedAt,
+			DeletedAt:       gorm.DeletedAt{},
 		}
 		assert.Equal(t, expectedProblemSet, databaseProblemSet)
 		resp := response.AddProblemsToSetResponse{}
@@ -781,7 +831,7 @@ func TestDeleteProblemsFromSetProblemSet(t *testing.T) {
 		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
 
 		databaseProblemSet := models.ProblemSet{}
-		assert.NoError(t, base.DB.Preload("Problems").Preload("Grades").First(&databaseProblemSet, problemSet.ID).Error)
+		assert.NoError(t, base.DB.Preload("Problems").Preload("Grades").Preload("ProblemEntries").First(&databaseProblemSet, problemSet.ID).Error)
 		expectedProblemSet := models.ProblemSet{
 			ID:          databaseProblemSet.ID,
 			ClassID:     class.ID,
@@ -790,12 +840,17 @@ func TestDeleteProblemsFromSetProblemSet(t *testing.T) {
 			Problems: []*models.Problem{
 				&problem1,
 			},
-			Grades:    problemSet.Grades,
-			StartTime: hashStringToTime("test_delete_problems_from_set_success_0_time"),
-			EndTime:   hashStringToTime("test_delete_problems_from_set_success_0_time").Add(time.Hour),
-			CreatedAt: databaseProblemSet.CreatedAt,
-			UpdatedAt: databaseProblemSet.UpdatedAt,
-			DeletedAt: gorm.DeletedAt{},
+			// The grade of the user is dropped as the user is not a student of the class.
+			Grades: []*models.Grade{},
+			ProblemEntries: []*models.ProblemInProblemSet{
+				{ProblemSetID: problemSet.ID, ProblemID: problem1.ID, Order: 0, Label: "", FullMark: 100},
+			},
+			StartTime:       hashStringToTime("test_delete_problems_from_set_success_0_time"),
+			EndTime:         hashStringToTime("test_delete_problems_from_set_success_0_time").Add(time.Hour),
+			LanguageAllowed: database.StringArray{},
+			CreatedAt:       databaseProblemSet.CreatedAt,
+			UpdatedAt:       databaseProblemSet.UpdatedAt,
+			DeletedAt:       gorm.DeletedAt{},
 		}
 		assert.Equal(t, expectedProblemSet, databaseProblemSet)
 		resp := response.AddProblemsToSetResponse{}
@@ -812,6 +867,118 @@ func TestDeleteProblemsFromSetProblemSet(t *testing.T) {
 	})
 }
 
+func TestReorderProblemsInSet(t *testing.T) {
+	t.Parallel()
+
+	user := createUserForTest(t, "reorder_problems_in_set", 0)
+	problem1 := createProblemForTest(t, "reorder_problems_in_set", 1, nil, user)
+	problem2 := createProblemForTest(t, "reorder_problems_in_set", 2, nil, user)
+	problem3 := createProblemForTest(t, "reorder_problems_in_set", 3, nil, user)
+	class := createClassForTest(t, "reorder_problems_in_set", 0, nil, []*models.User{&user})
+	problemSet := createProblemSetForTest(t, "reorder_problems_in_set", 0, &class, []models.Problem{problem1, problem2}, inProgress)
+	submission := createSubmissionForTest(t, "reorder_problems_in_set", 0, &problem1, &user, nil, 0, "WRONG_ANSWER")
+	submission.ProblemSetID = problemSet.ID
+	submission.Score = 50
+	assert.NoError(t, base.DB.Save(&submission).Error)
+
+	failTests := []failTest{
+		{
+			name:   "NonExistingProblemSet",
+			method: "PUT",
+			path:   base.Echo.Reverse("problemSet.reorderProblemsInSet", class.ID, -1),
+			req: request.ReorderProblemsInSetRequest{
+				Problems: []request.ProblemInSet{
+					{ProblemID: problem1.ID, FullMark: 100},
+				},
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:   "PermissionDenied",
+			method: "PUT",
+			path:   base.Echo.Reverse("problemSet.reorderProblemsInSet", class.ID, problemSet.ID),
+			req: request.ReorderProblemsInSetRequest{
+				Problems: []request.ProblemInSet{
+					{ProblemID: problem1.ID, FullMark: 100},
+				},
+			},
+			reqOptions: []reqOption{applyNormalUser},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+		{
+			name:   "MissingProblem",
+			method: "PUT",
+			path:   base.Echo.Reverse("problemSet.reorderProblemsInSet", class.ID, problemSet.ID),
+			req: request.ReorderProblemsInSetRequest{
+				Problems: []request.ProblemInSet{
+					{ProblemID: problem1.ID, FullMark: 100},
+				},
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusBadRequest,
+			resp:       response.ErrorResp("PROBLEMS_MISMATCH", nil),
+		},
+		{
+			name:   "ProblemNotInSet",
+			method: "PUT",
+			path:   base.Echo.Reverse("problemSet.reorderProblemsInSet", class.ID, problemSet.ID),
+			req: request.ReorderProblemsInSetRequest{
+				Problems: []request.ProblemInSet{
+					{ProblemID: problem1.ID, FullMark: 100},
+					{ProblemID: problem3.ID, FullMark: 100},
+				},
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusBadRequest,
+			resp:       response.ErrorResp("PROBLEMS_MISMATCH", nil),
+		},
+		{
+			name:   "DuplicatedProblem",
+			method: "PUT",
+			path:   base.Echo.Reverse("problemSet.reorderProblemsInSet", class.ID, problemSet.ID),
+			req: request.ReorderProblemsInSetRequest{
+				Problems: []request.ProblemInSet{
+					{ProblemID: problem1.ID, FullMark: 100},
+					{ProblemID: problem1.ID, FullMark: 100},
+				},
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusBadRequest,
+			resp:       response.ErrorResp("PROBLEMS_MISMATCH", nil),
+		},
+	}
+	runFailTests(t, failTests, "")
+
+	t.Run("Success", func(t *testing.T) {
+		httpResp := makeResp(makeReq(t, "PUT", base.Echo.Reverse("problemSet.reorderProblemsInSet", class.ID, problemSet.ID),
+			request.ReorderProblemsInSetRequest{
+				Problems: []request.ProblemInSet{
+					{ProblemID: problem2.ID, Label: "P1", FullMark: 100},
+					{ProblemID: problem1.ID, FullMark: 30},
+				},
+			}, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.ReorderProblemsInSetResponse{}
+		mustJsonDecode(httpResp, &resp)
+		if assert.Len(t, resp.Data.Problems, 2) {
+			assert.Equal(t, problem2.ID, resp.Data.Problems[0].ID)
+			assert.Equal(t, problem1.ID, resp.Data.Problems[1].ID)
+		}
+		assert.Equal(t, []resource.ProblemInProblemSet{
+			{ProblemID: problem2.ID, Order: 0, Label: "P1", FullMark: 100},
+			{ProblemID: problem1.ID, Order: 1, Label: "B", FullMark: 30},
+		}, resp.Data.ProblemEntries)
+		// Changing the full mark rescales the grades.
+		assert.Equal(t, map[uint]uint{
+			problem1.ID: 15,
+			problem2.ID: 0,
+		}, getGradeDetailForTest(t, problemSet, &user))
+	})
+}
+
 func TestDeleteProblemSet(t *testing.T) {
 	t.Parallel()
 
@@ -980,6 +1147,21 @@ func TestGetProblemSetProblem(t *testing.T) {
 			},
 		}, resp)
 	})
+	t.Run("LanguageRestricted", func(t *testing.T) {
+		t.Parallel()
+		problem := createProblemForTest(t, "get_problem_set_problem_language_restricted", 0, nil, user)
+		problem.LanguageAllowed = []string{"c", "cpp", "python"}
+		assert.NoError(t, base.DB.Save(&problem).Error)
+		problemSet := createProblemSetForTest(t, "get_problem_set_problem_language_restricted", 0, &class, []models.Problem{problem}, inProgress)
+		problemSet.LanguageAllowed = []string{"c", "python"}
+		assert.NoError(t, base.DB.Save(problemSet).Error)
+		httpResp := makeResp(makeReq(t, "GET",
+			base.Echo.Reverse("problemSet.getProblemSetProblem", class.ID, problemSet.ID, problem.ID), nil, applyUser(user)))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.GetProblemSetProblemResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, []string{"c", "python"}, resp.Data.LanguageAllowed)
+	})
 }
 
 func TestGetProblemSetProblemInputFile(t *testing.T) {
@@ -1253,7 +1435,8 @@ func TestRefreshGrades(t *testing.T) {
 		httpResp := makeResp(makeReq(t, "POST",
 			base.Echo.Reverse("problemSet.RefreshGrades", class.ID, ps.ID), nil, applyAdminUser))
 		databaseProblemSet := models.ProblemSet{}
-		assert.NoError(t, base.DB.Preload("Grades").Preload("Problems").First(&databaseProblemSet, ps.ID).Error)
+		assert.NoError(t, base.DB.Preload("Grades").Preload("Problems").Preload("ProblemEntries").First(&databaseProblemSet, ps.ID).Error)
+		assert.NoError(t, ps.LoadProblemEntries())
 		j, err := json.Marshal(map[uint]uint{
 			problem1.ID: 0,
 			problem2.ID: 0,
@@ -1263,12 +1446,13 @@ func TestRefreshGrades(t *testing.T) {
 			ps.Problems[i].TestCases = nil
 		}
 		expectedProblemSet := models.ProblemSet{
-			ID:          ps.ID,
-			ClassID:     class.ID,
-			Class:       nil,
-			Name:        ps.Name,
-			Description: ps.Description,
-			Problems:    ps.Problems,
+			ID:             ps.ID,
+			ClassID:        class.ID,
+			Class:          nil,
+			Name:           ps.Name,
+			Description:    ps.Description,
+			Problems:       ps.Problems,
+			ProblemEntries: ps.ProblemEntries,
 			Grades: []*models.Grade{
 				{
 					ID:           databaseProblemSet.Grades[0].ID,
@@ -1297,11 +1481,12 @@ func TestRefreshGrades(t *testing.T) {
 					UpdatedAt:    databaseProblemSet.Grades[1].UpdatedAt,
 				},
 			},
-			StartTime: ps.StartTime,
-			EndTime:   ps.EndTime,
-			CreatedAt: ps.CreatedAt,
-			UpdatedAt: databaseProblemSet.UpdatedAt,
-			DeletedAt: gorm.DeletedAt{},
+			StartTime:       ps.StartTime,
+			EndTime:         ps.EndTime,
+			LanguageAllowed: database.StringArray{},
+			CreatedAt:       ps.CreatedAt,
+			UpdatedAt:       databaseProblemSet.UpdatedAt,
+			DeletedAt:       gorm.DeletedAt{},
 		}
 		assert.Equal(t, expectedProblemSet, databaseProblemSet)
 		resp := response.RefreshGradesResponse{}
@@ -1352,7 +1537,8 @@ func TestRefreshGrades(t *testing.T) {
 		httpResp := makeResp(makeReq(t, "POST",
 			base.Echo.Reverse("problemSet.RefreshGrades", class.ID, ps.ID), nil, applyAdminUser))
 		databaseProblemSet := models.ProblemSet{}
-		assert.NoError(t, base.DB.Preload("Grades").Preload("Problems").First(&databaseProblemSet, ps.ID).Error)
+		assert.NoError(t, base.DB.Preload("Grades").Preload("Problems").Preload("ProblemEntries").First(&databaseProblemSet, ps.ID).Error)
+		assert.NoError(t, ps.LoadProblemEntries())
 		j1, err := json.Marshal(map[uint]uint{
 			problem1.ID: 40,
 			problem2.ID: 0,
@@ -1364,12 +1550,13 @@ func TestRefreshGrades(t *testing.T) {
 		})
 		assert.NoError(t, err)
 		expectedProblemSet := models.ProblemSet{
-			ID:          ps.ID,
-			ClassID:     class.ID,
-			Class:       nil,
-			Name:        ps.Name,
-			Description: ps.Description,
-			Problems:    ps.Problems,
+			ID:             ps.ID,
+			ClassID:        class.ID,
+			Class:          nil,
+			Name:           ps.Name,
+			Description:    ps.Description,
+			Problems:       ps.Problems,
+			ProblemEntries: ps.ProblemEntries,
 			Grades: []*models.Grade{
 				{
 					ID:           databaseProblemSet.Grades[0].ID,
@@ -1398,11 +1585,12 @@ func TestRefreshGrades(t *testing.T) {
 					UpdatedAt:    databaseProblemSet.Grades[1].UpdatedAt,
 				},
 			},
-			StartTime: ps.StartTime,
-			EndTime:   ps.EndTime,
-			CreatedAt: ps.CreatedAt,
-			UpdatedAt: databaseProblemSet.UpdatedAt,
-			DeletedAt: gorm.DeletedAt{},
+			StartTime:       ps.StartTime,
+			EndTime:         ps.EndTime,
+			LanguageAllowed: database.StringArray{},
+			CreatedAt:       ps.CreatedAt,
+			UpdatedAt:       databaseProblemSet.UpdatedAt,
+			DeletedAt:       gorm.DeletedAt{},
 		}
 		assert.Equal(t, expectedProblemSet, databaseProblemSet)
 		resp := response.RefreshGradesResponse{}
@@ -1418,6 +1606,39 @@ func TestRefreshGrades(t *testing.T) {
 			},
 		}, resp)
 	})
+	t.Run("ScaledToFullMark", func(t *testing.T) {
+		t.Parallel()
+
+		user := createUserForTest(t, "refresh_grades", 5)
+		class := createClassForTest(t, "refresh_grades", 2, nil, []*models.User{&user})
+		problem1 := createProblemForTest(t, "refresh_grades", 5, nil, user)
+		problem2 := createProblemForTest(t, "refresh_grades", 6, nil, user)
+		ps := createProblemSetForTest(t, "refresh_grades_scaled_to_full_mark", 0, &class, []models.Problem{problem1, problem2}, inProgress)
+		assert.NoError(t, ps.SetProblemEntries(base.DB, []*models.ProblemInProblemSet{
+			{ProblemID: problem1.ID, Order: 0, Label: "A", FullMark: 50},
+			{ProblemID: problem2.ID, Order: 1, Label: "B", FullMark: 10},
+		}))
+		for i, score := range []uint{40, 100} {
+			problem := []*models.Problem{&problem1, &problem2}[i]
+			submission := createSubmissionForTest(t, "refresh_grades_scaled_to_full_mark", i, problem, &user, nil, 0, "ACCEPTED")
+			submission.ProblemSetID = ps.ID
+			submission.Score = score
+			assert.NoError(t, base.DB.Save(&submission).Error)
+		}
+
+		httpResp := makeResp(makeReq(t, "POST",
+			base.Echo.Reverse("problemSet.RefreshGrades", class.ID, ps.ID), nil, applyAdminUser))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		grade := models.Grade{}
+		assert.NoError(t, base.DB.First(&grade, "problem_set_id = ? and user_id = ?", ps.ID, user.ID).Error)
+		j, err := json.Marshal(map[uint]uint{
+			problem1.ID: 20,
+			problem2.ID: 10,
+		})
+		assert.NoError(t, err)
+		assert.JSONEq(t, string(j), string(grade.Detail))
+		assert.Equal(t, uint(30), grade.Total)
+	})
 }
 
 // Let's deal with this later
@@ -1625,193 +1846,10 @@ func TestGetClassGrades(t *testing.T) {
 			base.Echo.Reverse("class.getClassGrades", class.ID), nil, applyAdminUser))
 		databaseProblemSet1 := models.ProblemSet{}
 		databaseProblemSet2 := models.ProblemSet{}
-		assert.NoError(t, base.DB.Preload("Grades").Preload("Problems").First(&databaseProblemSet1, problemSet1.ID).Error)
-		assert.NoError(t, base.DB.Preload("Grades").Preload("Problems").First(&databaseProblemSet2, problemSet2.ID).Error)
-
-		jsonEmpty1, err := json.Marshal(map[uint]uint{
-			problem1.ID: 0,
-			problem2.ID: 0,
-		})
-		jsonEmpty2, err := json.Marshal(map[uint]uint{
-			problem1.ID: 0,
-		})
-		assert.NoError(t, err)
-		expectedProblemSet1 := models.ProblemSet{
-			ID:          problemSet1.ID,
-			ClassID:     class.ID,
-			Class:       nil,
-			Name:        problemSet1.Name,
-			Description: problemSet1.Description,
-			Problems:    problemSet1.Problems,
-			Grades: []*models.Grade{
-				{
-					ID:           databaseProblemSet1.Grades[0].ID,
-					UserID:       user1.ID,
-					User:         nil,
-					ProblemSetID: problemSet1.ID,
-					ProblemSet:   nil,
-					ClassID:      class.ID,
-					Class:        nil,
-					Detail:       jsonExisting1,
-					Total:        60,
-					CreatedAt:    databaseProblemSet1.Grades[0].CreatedAt,
-					UpdatedAt:    databaseProblemSet1.Grades[0].UpdatedAt,
-				},
-				{
-					ID:           databaseProblemSet1.Grades[1].ID,
-					UserID:       user2.ID,
-					User:         nil,
-					ProblemSetID: problemSet1.ID,
-					ProblemSet:   nil,
-					ClassID:      class.ID,
-					Class:        nil,
-					Detail:       jsonEmpty1,
-					Total:        0,
-					CreatedAt:    databaseProblemSet1.Grades[1].CreatedAt,
-					UpdatedAt:    databaseProblemSet1.Grades[1].UpdatedAt,
-				},
-			},
-			StartTime: problemSet1.StartTime,
-			EndTime:   problemSet1.EndTime,
-			CreatedAt: problemSet1.CreatedAt,
-			UpdatedAt: databaseProblemSet1.UpdatedAt,
-			DeletedAt: gorm.DeletedAt{},
-		}
-		expectedProblemSet2 := models.ProblemSet{
-			ID:          problemSet2.ID,
-			ClassID:     class.ID,
-			Class:       nil,
-			Name:        problemSet2.Name,
-			Description: problemSet2.Description,
-			Problems:    problemSet2.Problems,
-			Grades: []*models.Grade{
-				{
-					ID:           databaseProblemSet2.Grades[0].ID,
-					UserID:       user2.ID,
-					User:         nil,
-					ProblemSetID: problemSet2.ID,
-					ProblemSet:   nil,
-					ClassID:      class.ID,
-					Class:        nil,
-					Detail:       jsonExisting2,
-					Total:        70,
-					CreatedAt:    databaseProblemSet2.Grades[0].CreatedAt,
-					UpdatedAt:    databaseProblemSet2.Grades[0].UpdatedAt,
-				},
-				{
-					ID:           databaseProblemSet2.Grades[1].ID,
-					UserID:       user1.ID,
-					User:         nil,
-					ProblemSetID: problemSet2.ID,
-					ProblemSet:   nil,
-					ClassID:      class.ID,
-					Class:        nil,
-					Detail:       jsonEmpty2,
-					Total:        0,
-					CreatedAt:    databaseProblemSet2.Grades[1].CreatedAt,
-					UpdatedAt:    databaseProblemSet2.Grades[1].UpdatedAt,
-				},
-			},
-			StartTime: problemSet2.StartTime,
-			EndTime:   problemSet2.EndTime,
-			CreatedAt: problemSet2.CreatedAt,
-			UpdatedAt: databaseProblemSet2.UpdatedAt,
-			DeletedAt: gorm.DeletedAt{},
-		}
-		assert.Equal(t, expectedProblemSet1, databaseProblemSet1)
-		assert.Equal(t, expectedProblemSet2.Grades, databaseProblemSet2.Grades)
-		assert.Equal(t, expectedProblemSet2, databaseProblemSet2)
-		resp := response.GetClassGradesResponse{}
-		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
-		mustJsonDecode(httpResp, &resp)
-		assert.Equal(t, response.GetClassGradesResponse{
-			Message: "SUCCESS",
-			Error:   nil,
-			Data: struct {
-				ProblemSets []*resource.ProblemSetWithGrades `json:"problem_sets"`
-			}{
-				ProblemSets: []*resource.ProblemSetWithGrades{
-					resource.GetProblemSetWithGrades(&expectedProblemSet1),
-					resource.GetProblemSetWithGrades(&expectedProblemSet2),
-				},
-			},
-		}, resp)
-	})
-}
-
-func TestGetClassGrades(t *testing.T) {
-	t.Parallel()
-	user1 := createUserForTest(t, "get_class_grades", 1)
-	user2 := createUserForTest(t, "get_class_grades", 2)
-	class := createClassForTest(t, "get_class_grades", 0, nil, []*models.User{&user1, &user2})
-	problem1 := createProblemForTest(t, "get_class_grades", 1, nil, user1)
-	problem2 := createProblemForTest(t, "get_class_grades", 2, nil, user1)
-	problemSet1 := createProblemSetForTest(t, "get_class_grades", 1, &class, []models.Problem{problem1, problem2}, inProgress)
-	problemSet2 := createProblemSetForTest(t, "get_class_grades", 2, &class, []models.Problem{problem1}, inProgress)
-	jsonExisting1, err := json.Marshal(map[uint]uint{
-		problem1.ID: 0,
-		problem2.ID: 60,
-	})
-	assert.NoError(t, err)
-	jsonExisting2, err := json.Marshal(map[uint]uint{
-		problem1.ID: 70,
-	})
-	assert.NoError(t, err)
-	gradeExisting1 := models.Grade{
-		UserID:       user1.ID,
-		ProblemSetID: problemSet1.ID,
-		ClassID:      class.ID,
-		Detail:       jsonExisting1,
-		Total:        60,
-	}
-	assert.NoError(t, err)
-	gradeExisting2 := models.Grade{
-		UserID:       user2.ID,
-		ProblemSetID: problemSet2.ID,
-		ClassID:      class.ID,
-		Detail:       jsonExisting2,
-		Total:        70,
-	}
-	assert.NoError(t, err)
-	assert.NoError(t, base.DB.Create(&gradeExisting1).Error)
-	assert.NoError(t, base.DB.Create(&gradeExisting2).Error)
-
-	failTests := []failTest{
-		{
-			name:   "NonExistingClass",
-			method: "GET",
-			path:   base.Echo.Reverse("class.getClassGrades", -1),
-			req:    request.GetClassGradesRequest{},
-			reqOptions: []reqOption{
-				applyAdminUser,
-			},
-			statusCode: http.StatusNotFound,
-			resp:       response.ErrorResp("NOT_FOUND", nil),
-		},
-		{
-			name:   "PermissionDenied",
-			method: "GET",
-			path:   base.Echo.Reverse("class.getClassGrades", class.ID),
-			req:    request.GetClassGradesRequest{},
-			reqOptions: []reqOption{
-				applyNormalUser,
-			},
-			statusCode: http.StatusForbidden,
-			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
-		},
-	}
-
-	runFailTests(t, failTests, "GetClassGrades")
-
-	t.Run("Success", func(t *testing.T) {
-		t.Parallel()
-
-		httpResp := makeResp(makeReq(t, "GET",
-			base.Echo.Reverse("class.getClassGrades", class.ID), nil, applyAdminUser))
-		databaseProblemSet1 := models.ProblemSet{}
-		databaseProblemSet2 := models.ProblemSet{}
-		assert.NoError(t, base.DB.Preload("Grades").Preload("Problems").First(&databaseProblemSet1, problemSet1.ID).Error)
-		assert.NoError(t, base.DB.Preload("Grades").Preload("Problems").First(&databaseProblemSet2, problemSet2.ID).Error)
+		assert.NoError(t, base.DB.Preload("Grades").Preload("Problems").Preload("ProblemEntries").First(&databaseProblemSet1, problemSet1.ID).Error)
+		assert.NoError(t, base.DB.Preload("Grades").Preload("Problems").Preload("ProblemEntries").First(&databaseProblemSet2, problemSet2.ID).Error)
+		assert.NoError(t, problemSet1.LoadProblemEntries())
+		assert.NoError(t, problemSet2.LoadProblemEntries())
 
 		jsonEmpty1, err := json.Marshal(map[uint]uint{
 			problem1.ID: 0,
@@ -1822,12 +1860,13 @@ func TestGetClassGrades(t *testing.T) {
 		})
 		assert.NoError(t, err)
 		expectedProblemSet1 := models.ProblemSet{
-			ID:          problemSet1.ID,
-			ClassID:     class.ID,
-			Class:       nil,
-			Name:        problemSet1.Name,
-			Description: problemSet1.Description,
-			Problems:    problemSet1.Problems,
+			ID:             problemSet1.ID,
+			ClassID:        class.ID,
+			Class:          nil,
+			Name:           problemSet1.Name,
+			Description:    problemSet1.Description,
+			Problems:       problemSet1.Problems,
+			ProblemEntries: problemSet1.ProblemEntries,
 			Grades: []*models.Grade{
 				{
 					ID:           databaseProblemSet1.Grades[0].ID,
@@ -1856,19 +1895,21 @@ func TestGetClassGrades(t *testing.T) {
 					UpdatedAt:    databaseProblemSet1.Grades[1].UpdatedAt,
 				},
 			},
-			StartTime: problemSet1.StartTime,
-			EndTime:   problemSet1.EndTime,
-			CreatedAt: problemSet1.CreatedAt,
-			UpdatedAt: databaseProblemSet1.UpdatedAt,
-			DeletedAt: gorm.DeletedAt{},
+			StartTime:       problemSet1.StartTime,
+			EndTime:         problemSet1.EndTime,
+			LanguageAllowed: database.StringArray{},
+			CreatedAt:       problemSet1.CreatedAt,
+			UpdatedAt:       databaseProblemSet1.UpdatedAt,
+			DeletedAt:       gorm.DeletedAt{},
 		}
 		expectedProblemSet2 := models.ProblemSet{
-			ID:          problemSet2.ID,
-			ClassID:     class.ID,
-			Class:       nil,
-			Name:        problemSet2.Name,
-			Description: problemSet2.Description,
-			Problems:    problemSet2.Problems,
+			ID:             problemSet2.ID,
+			ClassID:        class.ID,
+			Class:          nil,
+			Name:           problemSet2.Name,
+			Description:    problemSet2.Description,
+			Problems:       problemSet2.Problems,
+			ProblemEntries: problemSet2.ProblemEntries,
 			Grades: []*models.Grade{
 				{
 					ID:           databaseProblemSet2.Grades[0].ID,
@@ -1897,15 +1938,21 @@ func TestGetClassGrades(t *testing.T) {
 					UpdatedAt:    databaseProblemSet2.Grades[1].UpdatedAt,
 				},
 			},
-			StartTime: problemSet2.StartTime,
-			EndTime:   problemSet2.EndTime,
-			CreatedAt: problemSet2.CreatedAt,
-			UpdatedAt: databaseProblemSet2.UpdatedAt,
-			DeletedAt: gorm.DeletedAt{},
+			StartTime:       problemSet2.StartTime,
+			EndTime:         problemSet2.EndTime,
+			LanguageAllowed: database.StringArray{},
+			CreatedAt:       problemSet2.CreatedAt,
+			UpdatedAt:       databaseProblemSet2.UpdatedAt,
+			DeletedAt:       gorm.DeletedAt{},
 		}
 		assert.Equal(t, expectedProblemSet1, databaseProblemSet1)
 		assert.Equal(t, expectedProblemSet2.Grades, databaseProblemSet2.Grades)
 		assert.Equal(t, expectedProblemSet2, databaseProblemSet2)
+		// The grades in the response come with their users.
+		expectedProblemSet1.Grades[0].User = &user1
+		expectedProblemSet1.Grades[1].User = &user2
+		expectedProblemSet2.Grades[0].User = &user2
+		expectedProblemSet2.Grades[1].User = &user1
 		resp := response.GetClassGradesResponse{}
 		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
 		mustJsonDecode(httpResp, &resp)
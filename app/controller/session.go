@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// @summary      Get the sessions of the current user.
+// @description  Get the unexpired sessions of the current user, with the user agents and ips, ordered by the last
+// @description  used time.
+// @router       /user/me/sessions [GET]
+// @produce      json
+// @tags         User
+// @success      200  {object}  response.GetMySessionsResponse
+// @failure      500  {object}  response.Response
+// @security     ApiKeyAuth
+func GetMySessions(c echo.Context) error {
+	user := c.Get("user").(models.User)
+	current, _ := c.Get("token").(models.Token)
+	var tokens []models.Token
+	utils.PanicIfDBError(base.DB.Order("updated_at desc, id desc").Find(&tokens, "user_id = ?", user.ID),
+		"could not get tokens")
+	sessions := make([]models.Token, 0, len(tokens))
+	for _, token := range tokens {
+		if !utils.IsTokenExpired(token) {
+			sessions = append(sessions, token)
+		}
+	}
+	return c.JSON(http.StatusOK, response.GetMySessionsResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			Sessions []resource.Session `json:"sessions"`
+		}{
+			resource.GetSessionSlice(sessions, current.ID),
+		},
+	})
+}
+
+// @summary      Revoke a session of the current user.
+// @description  Revoke a session of the current user, which could be the current session.
+// @router       /user/me/sessions/{id} [DELETE]
+// @produce      json
+// @tags         User
+// @param        id   path      int  true  "The id of the session."
+// @success      200  {object}  response.DeleteMySessionResponse
+// @failure      404  {object}  response.Response  "Session not found, with message `NOT_FOUND`"
+// @failure      500  {object}  response.Response
+// @security     ApiKeyAuth
+func DeleteMySession(c echo.Context) error {
+	user := c.Get("user").(models.User)
+	result := base.DB.Delete(&models.Token{}, "id = ? and user_id = ?", c.Param("id"), user.ID)
+	utils.PanicIfDBError(result, "could not delete token")
+	if result.RowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+	}
+	return c.JSON(http.StatusOK, response.DeleteMySessionResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data:    nil,
+	})
+}
+
+// @summary      Revoke the other sessions of the current user.
+// @description  Revoke all sessions of the current user except the current one.
+// @router       /user/me/sessions [DELETE]
+// @produce      json
+// @tags         User
+// @success      200  {object}  response.DeleteMyOtherSessionsResponse
+// @failure      500  {object}  response.Response
+// @security     ApiKeyAuth
+func DeleteMyOtherSessions(c echo.Context) error {
+	user := c.Get("user").(models.User)
+	current, _ := c.Get("token").(models.Token)
+	utils.PanicIfDBError(base.DB.Delete(&models.Token{}, "user_id = ? and id != ?", user.ID, current.ID),
+		"could not delete tokens")
+	return c.JSON(http.StatusOK, response.DeleteMyOtherSessionsResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data:    nil,
+	})
+}
+
+// @summary      Force a user to logout.
+// @description  Revoke all sessions of a user. Personal access tokens of the user are not revoked.
+// @router       /admin/user/{id}/sessions [DELETE]
+// @produce      json
+// @tags         Admin
+// @param        id   path      string  true  "The id or username of the user."
+// @success      200  {object}  response.AdminDeleteUserSessionsResponse
+// @failure      404  {object}  response.Response  "User not found, with message `NOT_FOUND`"
+// @failure      403  {object}  response.Response  "Permission denied, with message `PERMISSION_DENIED`"
+// @failure      500  {object}  response.Response
+// @security     ApiKeyAuth
+func AdminDeleteUserSessions(c echo.Context) error {
+	user, err := utils.FindUser(c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+	} else if err != nil {
+		panic(err)
+	}
+	utils.PanicIfDBError(base.DB.Delete(&models.Token{}, "user_id = ?", user.ID), "could not delete tokens")
+	return c.JSON(http.StatusOK, response.AdminDeleteUserSessionsResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data:    nil,
+	})
+}
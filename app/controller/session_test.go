@@ -0,0 +1,148 @@
+package controller_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func loginForTest(t *testing.T, user models.User, password, userAgent string) string {
+	httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("auth.login"), request.LoginRequest{
+		UsernameOrEmail: user.Username,
+		Password:        password,
+	}, headerOption{
+		"User-Agent": {userAgent},
+	}))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	resp := response.LoginResponse{}
+	mustJsonDecode(httpResp, &resp)
+	return resp.Data.Token
+}
+
+func applyToken(token string) headerOption {
+	return headerOption{
+		"Authorization": {token},
+	}
+}
+
+func getMySessionsForTest(t *testing.T, token string) response.GetMySessionsResponse {
+	httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("user.getMySessions"), nil, applyToken(token)))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	resp := response.GetMySessionsResponse{}
+	mustJsonDecode(httpResp, &resp)
+	return resp
+}
+
+func TestMySessions(t *testing.T) {
+	t.Parallel()
+	user := createUserForTest(t, "sess", 0)
+	other := createUserForTest(t, "sess", 1)
+	first := loginForTest(t, user, "test_sess_user_0_pwd", "test_sessions_agent_0")
+	second := loginForTest(t, user, "test_sess_user_0_pwd", "test_sessions_agent_1")
+	third := loginForTest(t, user, "test_sess_user_0_pwd", "test_sessions_agent_2")
+	otherToken, err := utils.GetToken(loginForTest(t, other, "test_sess_user_1_pwd", "test_sessions_agent_other"))
+	assert.NoError(t, err)
+
+	sessions := getMySessionsForTest(t, first).Data.Sessions
+	if assert.Len(t, sessions, 3) {
+		// The current session is the most recently used one.
+		assert.True(t, sessions[0].Current)
+		assert.Equal(t, "test_sessions_agent_0", sessions[0].UserAgent)
+		assert.Equal(t, "192.0.2.1", sessions[0].IP)
+		assert.False(t, sessions[1].Current)
+		assert.Equal(t, "test_sessions_agent_2", sessions[1].UserAgent)
+		assert.Equal(t, "test_sessions_agent_1", sessions[2].UserAgent)
+	}
+
+	thirdToken, err := utils.GetToken(third)
+	assert.NoError(t, err)
+	for _, id := range []uint{0, otherToken.ID} {
+		httpResp := makeResp(makeReq(t, "DELETE", base.Echo.Reverse("user.deleteMySession", id),
+			request.DeleteMySessionRequest{}, applyToken(first)))
+		assert.Equal(t, http.StatusNotFound, httpResp.StatusCode)
+		resp := response.Response{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, response.ErrorResp("NOT_FOUND", nil), resp)
+	}
+	httpResp := makeResp(makeReq(t, "DELETE", base.Echo.Reverse("user.deleteMySession", thirdToken.ID),
+		request.DeleteMySessionRequest{}, applyToken(first)))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	httpResp = makeResp(makeReq(t, "GET", base.Echo.Reverse("user.getMe"), nil, applyToken(third)))
+	assert.Equal(t, http.StatusUnauthorized, httpResp.StatusCode)
+	assert.Len(t, getMySessionsForTest(t, second).Data.Sessions, 2)
+
+	httpResp = makeResp(makeReq(t, "DELETE", base.Echo.Reverse("user.deleteMyOtherSessions"),
+		request.DeleteMyOtherSessionsRequest{}, applyToken(second)))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	sessions = getMySessionsForTest(t, second).Data.Sessions
+	if assert.Len(t, sessions, 1) {
+		assert.True(t, sessions[0].Current)
+		assert.Equal(t, "test_sessions_agent_1", sessions[0].UserAgent)
+	}
+	_, err = utils.GetToken(otherToken.Token)
+	assert.NoError(t, err)
+}
+
+func TestSessionCountLimitedAtLogin(t *testing.T) {
+	t.Parallel()
+	user := createUserForTest(t, "sess_limit", 0)
+	tokens := make([]string, 11)
+	for i := range tokens {
+		tokens[i] = loginForTest(t, user, "test_sess_limit_user_0_pwd", fmt.Sprintf("test_session_limit_agent_%d", i))
+	}
+	count := int64(0)
+	assert.NoError(t, base.DB.Model(&models.Token{}).Where("user_id = ?", user.ID).Count(&count).Error)
+	assert.Equal(t, int64(10), count)
+	// The least recently used session is logged out.
+	_, err := utils.GetToken(tokens[0])
+	assert.Error(t, err)
+	_, err = utils.GetToken(tokens[1])
+	assert.NoError(t, err)
+}
+
+func TestAdminDeleteUserSessions(t *testing.T) {
+	t.Parallel()
+	user := createUserForTest(t, "admin_sess", 0)
+	token := loginForTest(t, user, "test_admin_sess_user_0_pwd", "test_admin_sessions_agent")
+
+	failTests := []failTest{
+		{
+			name:   "NonExistingUser",
+			method: "DELETE",
+			path:   base.Echo.Reverse("admin.user.deleteUserSessions", "test_admin_sessions_non_existing"),
+			req:    request.AdminDeleteUserSessionsRequest{},
+			reqOptions: []reqOption{
+				applyAdminUser,
+			},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:   "PermissionDenied",
+			method: "DELETE",
+			path:   base.Echo.Reverse("admin.user.deleteUserSessions", user.ID),
+			req:    request.AdminDeleteUserSessionsRequest{},
+			reqOptions: []reqOption{
+				applyNormalUser,
+			},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+	}
+	runFailTests(t, failTests, "AdminDeleteUserSessions")
+
+	_, err := utils.GetToken(token)
+	assert.NoError(t, err)
+	httpResp := makeResp(makeReq(t, "DELETE", base.Echo.Reverse("admin.user.deleteUserSessions", user.ID),
+		request.AdminDeleteUserSessionsRequest{}, applyAdminUser))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	_, err = utils.GetToken(token)
+	assert.Error(t, err)
+}
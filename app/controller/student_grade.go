@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// GetMyGrades returns the standing of the user in the started problem sets of the classes the user takes.
+func GetMyGrades(c echo.Context) error {
+	req := request.GetMyGradesRequest{}
+	if err, ok := utils.BindAndValidate(&req, c); !ok {
+		return err
+	}
+	user := c.Get("user").(models.User)
+	var classes []models.Class
+	query := base.DB.Model(&user).Order("id")
+	if req.ClassID != 0 {
+		query = query.Where("id = ?", req.ClassID)
+	}
+	if err := query.Preload("ProblemSets", func(db *gorm.DB) *gorm.DB {
+		return db.Where("start_time <= ?", time.Now()).Order("start_time").Order("id")
+	}).Preload("ProblemSets.ProblemEntries").Association("ClassesTaking").Find(&classes); err != nil {
+		panic(errors.Wrap(err, "could not get classes for getting my grades"))
+	}
+	ret := make([]*resource.StudentClassGrade, len(classes))
+	for i := range classes {
+		for _, problemSet := range classes[i].ProblemSets {
+			problemSet.SortProblems()
+		}
+		grades, err := utils.GetStudentGrades(user.ID, classes[i].ProblemSets)
+		if err != nil {
+			panic(errors.Wrap(err, "could not get my grades"))
+		}
+		ret[i] = resource.GetStudentClassGrade(&classes[i], grades)
+	}
+	return c.JSON(http.StatusOK, response.GetMyGradesResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			Classes []*resource.StudentClassGrade `json:"classes"`
+		}{
+			ret,
+		},
+	})
+}
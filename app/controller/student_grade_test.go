@@ -0,0 +1,124 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/app/response/resource"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMyGrades(t *testing.T) {
+	t.Parallel()
+	student := createUserForTest(t, "get_my_grades", 0)
+	problem1 := createProblemForTest(t, "get_my_grades", 0, nil, student)
+	problem2 := createProblemForTest(t, "get_my_grades", 1, nil, student)
+	class1 := createClassForTest(t, "get_my_grades", 0, nil, []*models.User{&student})
+	class2 := createClassForTest(t, "get_my_grades", 1, nil, []*models.User{&student})
+	createClassForTest(t, "get_my_grades", 2, nil, nil)
+	problemSet := createProblemSetForTest(t, "get_my_grades", 0, &class1, []models.Problem{problem1, problem2}, inProgress)
+	problemSet.AllowUpsolve = true
+	assert.NoError(t, base.DB.Save(problemSet).Error)
+	assert.NoError(t, problemSet.SetProblemEntries(base.DB, []*models.ProblemInProblemSet{
+		{ProblemID: problem1.ID, Order: 0, Label: "A", FullMark: 100},
+		{ProblemID: problem2.ID, Order: 1, Label: "B", FullMark: 50},
+	}))
+	createProblemSetForTest(t, "get_my_grades", 1, &class1, []models.Problem{problem1}, notStartYet)
+
+	firstAcceptedAt := time.Now().Add(-2 * time.Minute).Truncate(time.Second)
+	for _, submission := range []models.Submission{
+		{ProblemID: problem1.ID, Score: 40, Status: "WRONG_ANSWER", CreatedAt: firstAcceptedAt.Add(-time.Minute)},
+		{ProblemID: problem1.ID, Score: 100, Status: "ACCEPTED", CreatedAt: firstAcceptedAt},
+		{ProblemID: problem1.ID, Score: 100, Status: "ACCEPTED", CreatedAt: firstAcceptedAt.Add(time.Minute)},
+		{ProblemID: problem2.ID, Score: 60, Status: "WRONG_ANSWER", CreatedAt: firstAcceptedAt},
+		{ProblemID: problem2.ID, Score: 90, Status: "WRONG_ANSWER", CreatedAt: firstAcceptedAt, Upsolve: true},
+	} {
+		submission.UserID = student.ID
+		submission.ProblemSetID = problemSet.ID
+		submission.Judged = true
+		assert.NoError(t, base.DB.Create(&submission).Error)
+	}
+	assert.NoError(t, utils.RefreshGrades(problemSet))
+	admin := models.User{}
+	assert.NoError(t, base.DB.First(&admin, "username = ?", "test_admin_user").Error)
+	override, err := utils.OverrideGrade(problemSet, student.ID, problem2.ID, 45, "test_get_my_grades_reason", &admin)
+	assert.NoError(t, err)
+	assert.NoError(t, base.DB.Preload("User").Preload("Operator").First(override, override.ID).Error)
+
+	upsolveScore := uint(45)
+	expectedClass1 := resource.StudentClassGrade{
+		ClassID:    class1.ID,
+		Name:       class1.Name,
+		CourseName: class1.CourseName,
+		ProblemSets: []resource.StudentProblemSetGrade{
+			{
+				ProblemSetID: problemSet.ID,
+				Name:         problemSet.Name,
+				StartTime:    problemSet.StartTime,
+				EndTime:      problemSet.EndTime,
+				Total:        145,
+				FullMark:     150,
+				Problems: []resource.StudentProblemGrade{
+					{
+						ProblemID:       problem1.ID,
+						Label:           "A",
+						FullMark:        100,
+						Score:           100,
+						BestScore:       100,
+						Attempts:        3,
+						FirstAcceptedAt: &firstAcceptedAt,
+					},
+					{
+						ProblemID:    problem2.ID,
+						Label:        "B",
+						FullMark:     50,
+						Score:        45,
+						BestScore:    30,
+						Attempts:     1,
+						Override:     resource.GetGradeOverride(override),
+						UpsolveScore: &upsolveScore,
+					},
+				},
+			},
+		},
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("user.getMyGrades"), nil, applyUser(student)))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.GetMyGradesResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Len(t, resp.Data.Classes, 2)
+		// the times are compared after encoding, which drops their locations.
+		expected, err := json.Marshal(expectedClass1)
+		assert.NoError(t, err)
+		actual, err := json.Marshal(resp.Data.Classes[0])
+		assert.NoError(t, err)
+		assert.JSONEq(t, string(expected), string(actual))
+		assert.Equal(t, &resource.StudentClassGrade{
+			ClassID:     class2.ID,
+			Name:        class2.Name,
+			CourseName:  class2.CourseName,
+			ProblemSets: []resource.StudentProblemSetGrade{},
+		}, resp.Data.Classes[1])
+	})
+	t.Run("FilterByClass", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("user.getMyGrades"), nil, applyUser(student), queryOption{
+			"class_id": {fmt.Sprint(class2.ID)},
+		}))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.GetMyGradesResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Len(t, resp.Data.Classes, 1)
+		assert.Equal(t, class2.ID, resp.Data.Classes[0].ClassID)
+	})
+}
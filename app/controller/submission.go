@@ -3,6 +3,7 @@ package controller
 import (
 	"context"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
@@ -23,6 +24,27 @@ import (
 
 var inTest bool
 
+// checkSubmissionRateLimit rejects the submission if the user exceeds the submission rate limits.
+// Admins and the managers of the class are exempt.
+func checkSubmissionRateLimit(c echo.Context, user *models.User, class *models.Class, problemSetID, problemID uint) (err error, ok bool) {
+	if user.Can("manage_class") || (class != nil && user.Can("manage_class", class)) {
+		return nil, true
+	}
+	limit, retryAfter, err := utils.CheckSubmissionRateLimit(user.ID, problemSetID, problemID)
+	if err != nil {
+		panic(errors.Wrap(err, "could not check submission rate limit"))
+	}
+	if limit == "" {
+		return nil, true
+	}
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	c.Response().Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	return c.JSON(http.StatusTooManyRequests, response.ErrorResp("SUBMISSION_RATE_LIMITED", response.RateLimitError{
+		Limit:      limit,
+		RetryAfter: seconds,
+	})), false
+}
+
 func CreateSubmission(c echo.Context) error {
 	req := request.CreateSubmissionRequest{}
 	if err, ok := utils.BindAndValidate(&req, c); !ok {
@@ -62,6 +84,9 @@ func CreateSubmission(c echo.Context) error {
 	if file == nil {
 		return c.JSON(http.StatusBadRequest, response.ErrorResp("INVALID_FILE", nil))
 	}
+	if err, ok := checkSubmissionRateLimit(c, &user, nil, 0, problem.ID); !ok {
+		return err
+	}
 
 	priority := models.PriorityDefault
 
@@ -263,9 +288,59 @@ func GetSubmissionCode(c echo.Context) error {
 	if err != nil {
 		panic(errors.Wrap(err, "could not get presigned url"))
 	}
+	if c.QueryParam("comments") == "1" {
+		return c.JSON(http.StatusOK, response.GetSubmissionCodeResponse{
+			Message: "SUCCESS",
+			Error:   nil,
+			Data: struct {
+				URL      string                       `json:"url"`
+				Comments []resource.SubmissionComment `json:"comments"`
+			}{
+				presignedUrl,
+				resource.GetSubmissionCommentThreads(getSubmissionComments(submission.ID)),
+			},
+		})
+	}
 	return c.Redirect(http.StatusFound, presignedUrl)
 }
 
+func CreateSubmissionComment(c echo.Context) error {
+	req := request.CreateSubmissionCommentRequest{}
+	if err, ok := utils.BindAndValidate(&req, c); !ok {
+		return err
+	}
+	user := c.Get("user").(models.User)
+	submission := models.Submission{}
+	if err := base.DB.Preload("Problem").First(&submission, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if user.Can("read_submission") {
+				return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+			} else {
+				return c.JSON(http.StatusForbidden, response.ErrorResp("PERMISSION_DENIED", nil))
+			}
+		} else {
+			panic(errors.Wrap(err, "could not find submission for creating comment"))
+		}
+	}
+	isReviewer := user.Can("read_submission", submission.Problem) || user.Can("read_submission")
+	if !(user.ID == submission.UserID && submission.ProblemSetID == 0) && !isReviewer {
+		return c.JSON(http.StatusForbidden, response.ErrorResp("PERMISSION_DENIED", nil))
+	}
+	comment, err, ok := createSubmissionComment(c, &submission, isReviewer, req)
+	if !ok {
+		return err
+	}
+	return c.JSON(http.StatusCreated, response.CreateSubmissionCommentResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			*resource.SubmissionComment `json:"comment"`
+		}{
+			resource.GetSubmissionComment(comment),
+		},
+	})
+}
+
 func GetRunCompilerOutput(c echo.Context) error {
 	user := c.Get("user").(models.User)
 	submission := models.Submission{}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func getSubmissionComments(submissionID uint) (comments []*models.SubmissionComment) {
+	utils.PanicIfDBError(base.DB.Preload("User").Order("id").Find(&comments, "submission_id = ?", submissionID),
+		"could not get submission comments")
+	return
+}
+
+// createSubmissionComment creates a code review comment on the submission.
+// Only reviewers can start a thread, while the submitter can reply to the threads.
+func createSubmissionComment(c echo.Context, submission *models.Submission, isReviewer bool,
+	req request.CreateSubmissionCommentRequest) (comment *models.SubmissionComment, err error, ok bool) {
+	user := c.Get("user").(models.User)
+	if !isReviewer && (user.ID != submission.UserID || req.ParentID == 0) {
+		return nil, c.JSON(http.StatusForbidden, response.ErrorResp("PERMISSION_DENIED", nil)), false
+	}
+	comment = &models.SubmissionComment{
+		SubmissionID: submission.ID,
+		UserID:       user.ID,
+		User:         &user,
+		ParentID:     req.ParentID,
+		StartLine:    req.StartLine,
+		EndLine:      req.EndLine,
+		Content:      req.Content,
+	}
+	notified := submission.UserID
+	if req.ParentID != 0 {
+		parent := models.SubmissionComment{}
+		if err := base.DB.First(&parent, "id = ? and submission_id = ? and parent_id = 0", req.ParentID, submission.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, c.JSON(http.StatusNotFound, response.ErrorResp("COMMENT_NOT_FOUND", nil)), false
+			}
+			panic(errors.Wrap(err, "could not find comment for replying"))
+		}
+		comment.StartLine = parent.StartLine
+		comment.EndLine = parent.EndLine
+		if user.ID == submission.UserID {
+			notified = parent.UserID
+		}
+	} else if req.StartLine == 0 || req.EndLine < req.StartLine {
+		return nil, c.JSON(http.StatusBadRequest, response.ErrorResp("INVALID_LINE_RANGE", nil)), false
+	}
+	utils.PanicIfDBError(base.DB.Omit("User").Create(comment), "could not create submission comment")
+	if notified != user.ID {
+		if err := utils.CreateNotifications([]uint{notified}, models.Notification{
+			Type:     models.NotificationTypeSubmissionComment,
+			Title:    fmt.Sprintf("New comment on submission #%d", submission.ID),
+			Content:  fmt.Sprintf("Line %d-%d: %s", comment.StartLine, comment.EndLine, comment.Content),
+			TargetID: submission.ID,
+		}); err != nil {
+			panic(errors.Wrap(err, "could not notify submission comment"))
+		}
+	}
+	return comment, nil, true
+}
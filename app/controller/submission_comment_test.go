@@ -0,0 +1,219 @@
+package controller_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateSubmissionComment(t *testing.T) {
+	t.Parallel()
+
+	user := createUserForTest(t, "create_submission_comment", 0)
+	otherUser := createUserForTest(t, "create_submission_comment", 1)
+	creator := createUserForTest(t, "create_submission_comment", 2)
+	problem := createProblemForTest(t, "create_submission_comment", 0, nil, creator)
+	submission := createSubmissionForTest(t, "create_submission_comment", 0, &problem, &user,
+		newFileContent("code", "code_file_name", b64Encode("test_create_submission_comment_0")), 0)
+	otherSubmission := createSubmissionForTest(t, "create_submission_comment", 1, &problem, &user,
+		newFileContent("code", "code_file_name", b64Encode("test_create_submission_comment_1")), 0)
+	otherComment := models.SubmissionComment{
+		SubmissionID: otherSubmission.ID,
+		UserID:       otherUser.ID,
+		StartLine:    1,
+		EndLine:      1,
+		Content:      "test_create_submission_comment_other",
+	}
+	assert.NoError(t, base.DB.Create(&otherComment).Error)
+
+	failTests := []failTest{
+		{
+			name:   "NonExisting",
+			method: "POST",
+			path:   base.Echo.Reverse("submission.createSubmissionComment", -1),
+			req: request.CreateSubmissionCommentRequest{
+				StartLine: 1,
+				EndLine:   1,
+				Content:   "test_create_submission_comment_content",
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("NOT_FOUND", nil),
+		},
+		{
+			name:   "SubmittedByOthers",
+			method: "POST",
+			path:   base.Echo.Reverse("submission.createSubmissionComment", submission.ID),
+			req: request.CreateSubmissionCommentRequest{
+				StartLine: 1,
+				EndLine:   1,
+				Content:   "test_create_submission_comment_content",
+			},
+			reqOptions: []reqOption{applyUser(otherUser)},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+		{
+			name:   "SubmitterStartThread",
+			method: "POST",
+			path:   base.Echo.Reverse("submission.createSubmissionComment", submission.ID),
+			req: request.CreateSubmissionCommentRequest{
+				StartLine: 1,
+				EndLine:   1,
+				Content:   "test_create_submission_comment_content",
+			},
+			reqOptions: []reqOption{applyUser(user)},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+		{
+			name:   "InvalidLineRange",
+			method: "POST",
+			path:   base.Echo.Reverse("submission.createSubmissionComment", submission.ID),
+			req: request.CreateSubmissionCommentRequest{
+				StartLine: 3,
+				EndLine:   2,
+				Content:   "test_create_submission_comment_content",
+			},
+			reqOptions: []reqOption{applyAdminUser},
+			statusCode: http.StatusBadRequest,
+			resp:       response.ErrorResp("INVALID_LINE_RANGE", nil),
+		},
+		{
+			name:   "ParentInOtherSubmission",
+			method: "POST",
+			path:   base.Echo.Reverse("submission.createSubmissionComment", submission.ID),
+			req: request.CreateSubmissionCommentRequest{
+				ParentID: otherComment.ID,
+				Content:  "test_create_submission_comment_content",
+			},
+			reqOptions: []reqOption{applyUser(user)},
+			statusCode: http.StatusNotFound,
+			resp:       response.ErrorResp("COMMENT_NOT_FOUND", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "CreateSubmissionComment")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("submission.createSubmissionComment", submission.ID), request.CreateSubmissionCommentRequest{
+			StartLine: 2,
+			EndLine:   3,
+			Content:   "test_create_submission_comment_review",
+		}, applyAdminUser))
+		assert.Equal(t, http.StatusCreated, httpResp.StatusCode)
+		resp := response.CreateSubmissionCommentResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, uint(2), resp.Data.StartLine)
+		assert.Equal(t, uint(3), resp.Data.EndLine)
+		threadID := resp.Data.SubmissionComment.ID
+
+		notification := models.Notification{}
+		assert.NoError(t, base.DB.First(&notification, "user_id = ? and type = ?", user.ID, models.NotificationTypeSubmissionComment).Error)
+		assert.Equal(t, submission.ID, notification.TargetID)
+
+		httpResp = makeResp(makeReq(t, "POST", base.Echo.Reverse("submission.createSubmissionComment", submission.ID), request.CreateSubmissionCommentRequest{
+			ParentID: threadID,
+			Content:  "test_create_submission_comment_reply",
+		}, applyUser(user)))
+		assert.Equal(t, http.StatusCreated, httpResp.StatusCode)
+		resp = response.CreateSubmissionCommentResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, threadID, resp.Data.ParentID)
+		assert.Equal(t, uint(2), resp.Data.StartLine)
+		assert.Equal(t, uint(3), resp.Data.EndLine)
+
+		httpResp = makeResp(makeReq(t, "GET", base.Echo.Reverse("submission.getSubmissionCode", submission.ID)+"?comments=1",
+			nil, applyUser(user)))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		codeResp := response.GetSubmissionCodeResponse{}
+		mustJsonDecode(httpResp, &codeResp)
+		assert.NotEmpty(t, codeResp.Data.URL)
+		assert.Len(t, codeResp.Data.Comments, 1)
+		assert.Equal(t, "test_create_submission_comment_review", codeResp.Data.Comments[0].Content)
+		assert.Len(t, codeResp.Data.Comments[0].Replies, 1)
+		assert.Equal(t, "test_create_submission_comment_reply", codeResp.Data.Comments[0].Replies[0].Content)
+	})
+}
+
+func TestProblemSetCreateSubmissionComment(t *testing.T) {
+	t.Parallel()
+
+	user := createUserForTest(t, "problem_set_create_submission_comment", 0)
+	otherUser := createUserForTest(t, "problem_set_create_submission_comment", 1)
+	creator := createUserForTest(t, "problem_set_create_submission_comment", 2)
+	problem := createProblemForTest(t, "problem_set_create_submission_comment", 0, nil, creator)
+	class := createClassForTest(t, "problem_set_create_submission_comment", 0, nil, []*models.User{&user, &otherUser})
+	problemSet := createProblemSetForTest(t, "problem_set_create_submission_comment", 0, &class, []models.Problem{problem}, inProgress)
+	submission := createSubmissionForTest(t, "problem_set_create_submission_comment", 0, &problem, &user,
+		newFileContent("code", "code_file_name", b64Encode("test_problem_set_create_submission_comment_0")), 0)
+	submission.ProblemSetID = problemSet.ID
+	assert.NoError(t, base.DB.Save(&submission).Error)
+
+	failTests := []failTest{
+		{
+			name:   "SubmittedByOthers",
+			method: "POST",
+			path:   base.Echo.Reverse("problemSet.createSubmissionComment", class.ID, problemSet.ID, submission.ID),
+			req: request.ProblemSetCreateSubmissionCommentRequest{
+				StartLine: 1,
+				EndLine:   1,
+				Content:   "test_problem_set_create_submission_comment_content",
+			},
+			reqOptions: []reqOption{applyUser(otherUser)},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+		{
+			name:   "SubmitterStartThread",
+			method: "POST",
+			path:   base.Echo.Reverse("problemSet.createSubmissionComment", class.ID, problemSet.ID, submission.ID),
+			req: request.ProblemSetCreateSubmissionCommentRequest{
+				StartLine: 1,
+				EndLine:   1,
+				Content:   "test_problem_set_create_submission_comment_content",
+			},
+			reqOptions: []reqOption{applyUser(user)},
+			statusCode: http.StatusForbidden,
+			resp:       response.ErrorResp("PERMISSION_DENIED", nil),
+		},
+	}
+
+	runFailTests(t, failTests, "ProblemSetCreateSubmissionComment")
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("problemSet.createSubmissionComment", class.ID, problemSet.ID, submission.ID),
+			request.ProblemSetCreateSubmissionCommentRequest{
+				StartLine: 1,
+				EndLine:   4,
+				Content:   "test_problem_set_create_submission_comment_review",
+			}, applyAdminUser))
+		assert.Equal(t, http.StatusCreated, httpResp.StatusCode)
+		resp := response.ProblemSetCreateSubmissionCommentResponse{}
+		mustJsonDecode(httpResp, &resp)
+
+		httpResp = makeResp(makeReq(t, "POST", base.Echo.Reverse("problemSet.createSubmissionComment", class.ID, problemSet.ID, submission.ID),
+			request.ProblemSetCreateSubmissionCommentRequest{
+				ParentID: resp.Data.SubmissionComment.ID,
+				Content:  "test_problem_set_create_submission_comment_reply",
+			}, applyUser(user)))
+		assert.Equal(t, http.StatusCreated, httpResp.StatusCode)
+
+		httpResp = makeResp(makeReq(t, "GET", base.Echo.Reverse("problemSet.getSubmissionCode", class.ID, problemSet.ID, submission.ID)+"?comments=1",
+			nil, applyUser(user)))
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		codeResp := response.ProblemSetGetSubmissionCodeResponse{}
+		mustJsonDecode(httpResp, &codeResp)
+		assert.NotEmpty(t, codeResp.Data.URL)
+		assert.Len(t, codeResp.Data.Comments, 1)
+		assert.Equal(t, uint(4), codeResp.Data.Comments[0].EndLine)
+		assert.Len(t, codeResp.Data.Comments[0].Replies, 1)
+	})
+}
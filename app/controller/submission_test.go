@@ -13,6 +13,7 @@ import (
 	"github.com/EduOJ/backend/app/response"
 	"github.com/EduOJ/backend/app/response/resource"
 	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/utils"
 	"github.com/EduOJ/backend/database"
 	"github.com/EduOJ/backend/database/models"
 	"github.com/minio/minio-go/v7"
@@ -1420,3 +1421,46 @@ func TestGetRunInput(t *testing.T) {
 		}
 	})
 }
+
+func TestCreateSubmissionRateLimited(t *testing.T) {
+	t.Parallel()
+	user := createUserForTest(t, "test_create_submission_rate_limited", 0)
+	problem := createProblemForTest(t, "test_create_submission_rate_limited", 0, nil, user)
+	problem.LanguageAllowed = []string{"test_language"}
+	assert.NoError(t, base.DB.Save(&problem).Error)
+	createSubmission := func(option reqOption) *http.Response {
+		return makeResp(makeReq(t, "POST", base.Echo.Reverse("submission.createSubmission", problem.ID),
+			addFieldContentSlice([]reqContent{
+				newFileContent("code", "code_file_name", b64Encode("test code content")),
+			}, map[string]string{"language": "test_language"}), option))
+	}
+	t.Run("User", func(t *testing.T) {
+		t.Parallel()
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, http.StatusCreated, createSubmission(applyUser(user)).StatusCode)
+		}
+		httpResp := createSubmission(applyUser(user))
+		assert.Equal(t, http.StatusTooManyRequests, httpResp.StatusCode)
+		retryAfter, err := strconv.Atoi(httpResp.Header.Get("Retry-After"))
+		assert.NoError(t, err)
+		assert.True(t, retryAfter > 0 && retryAfter <= 60)
+		resp := struct {
+			Message string                  `json:"message"`
+			Error   response.RateLimitError `json:"error"`
+		}{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, "SUBMISSION_RATE_LIMITED", resp.Message)
+		assert.Equal(t, response.RateLimitError{
+			Limit:      utils.SubmissionLimitUser,
+			RetryAfter: int64(retryAfter),
+		}, resp.Error)
+	})
+	t.Run("AdminExempted", func(t *testing.T) {
+		t.Parallel()
+		admin := createUserForTest(t, "test_create_submission_rate_limited", 1)
+		admin.GrantRole("admin")
+		for i := 0; i < 11; i++ {
+			assert.Equal(t, http.StatusCreated, createSubmission(applyUser(admin)).StatusCode)
+		}
+	})
+}
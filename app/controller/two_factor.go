@@ -0,0 +1,333 @@
+package controller
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/labstack/echo/v4"
+	"github.com/patrickmn/go-cache"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// twoFactorMaxAttempts is the count of wrong codes after which a two-factor login challenge is dropped.
+const twoFactorMaxAttempts = 5
+
+// twoFactorChallenge is kept between a password login and the second step of the login.
+type twoFactorChallenge struct {
+	UserID     uint
+	RememberMe bool
+	// Attempts is shared by parallel requests, and should be accessed atomically.
+	Attempts int32
+}
+
+func beginTwoFactorLogin(c echo.Context, user models.User, rememberMe bool) error {
+	challenge := utils.RandStr(32)
+	cac.Set("2fa"+challenge, &twoFactorChallenge{
+		UserID:     user.ID,
+		RememberMe: rememberMe,
+	}, cache.DefaultExpiration)
+	return c.JSON(http.StatusAccepted, response.TwoFactorChallengeResponse{
+		Message: "TWO_FACTOR_REQUIRED",
+		Error:   nil,
+		Data: struct {
+			Challenge string `json:"challenge"`
+		}{
+			challenge,
+		},
+	})
+}
+
+// @summary      Finish a login with two-factor authentication.
+// @description  Finish a login with two-factor authentication, using a TOTP code or an unused recovery code.
+// @description  The challenge expires in 5 minutes, or after 5 wrong codes. Wrong codes are counted as login failures
+// @description  of the account.
+// @router       /auth/login/two_factor [POST]
+// @produce      json
+// @tags         Auth
+// @param        request  body      request.FinishTwoFactorLoginRequest  true  "The challenge and the code."
+// @success      200      {object}  response.LoginResponse
+// @failure      500      {object}  response.Response
+// @failure      400      {object}  response.Response{data=[]response.ValidationError}  "Validation error"
+// @failure      400      {object}  response.Response                                   "Invalid or expired challenge, with message `INVALID_CHALLENGE`"
+// @failure      403      {object}  response.Response                                   "Wrong code, with message `WRONG_CODE`"
+// @failure      429      {object}  response.Response{error=response.RateLimitError}    "Too many failures of the account or the ip, with message `LOGIN_THROTTLED`"
+func FinishTwoFactorLogin(c echo.Context) error {
+	req := request.FinishTwoFactorLoginRequest{}
+	if err, ok := utils.BindAndValidate(&req, c); !ok {
+		return err
+	}
+	v, ok := cac.Get("2fa" + req.Challenge)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, response.ErrorResp("INVALID_CHALLENGE", nil))
+	}
+	challenge := v.(*twoFactorChallenge)
+	user := models.User{}
+	utils.PanicIfDBError(base.DB.First(&user, challenge.UserID), "could not find user for two-factor login")
+	if err, ok := checkLoginThrottle(c, user.ID, user.Username); !ok {
+		return err
+	}
+	credential, err := utils.GetTOTPCredential(user.ID)
+	if err != nil {
+		panic(err)
+	}
+	if credential == nil {
+		// Two-factor authentication is disabled after the challenge is created.
+		cac.Delete("2fa" + req.Challenge)
+		return c.JSON(http.StatusBadRequest, response.ErrorResp("INVALID_CHALLENGE", nil))
+	}
+	// The attempt is counted before the code is verified, so that parallel requests could not exceed the limit.
+	attempts := atomic.AddInt32(&challenge.Attempts, 1)
+	if attempts > twoFactorMaxAttempts {
+		cac.Delete("2fa" + req.Challenge)
+		return c.JSON(http.StatusBadRequest, response.ErrorResp("INVALID_CHALLENGE", nil))
+	}
+	ok, err = utils.VerifyTwoFactorCode(credential, req.Code)
+	if err != nil {
+		panic(err)
+	}
+	if !ok {
+		if attempts >= twoFactorMaxAttempts {
+			cac.Delete("2fa" + req.Challenge)
+		}
+		// Wrong codes are counted against the account, so that codes could not be guessed with new challenges.
+		recordLoginFailure(c, user.ID, user.Username)
+		return c.JSON(http.StatusForbidden, response.ErrorResp("WRONG_CODE", nil))
+	}
+	cac.Delete("2fa" + req.Challenge)
+	if err := utils.ResetLoginFailures(user.ID); err != nil {
+		panic(err)
+	}
+	return loginResponse(c, user, challenge.RememberMe)
+}
+
+// @summary      Get the two-factor authentication status of the current user.
+// @description  Get whether two-factor authentication is enabled and required, and the count of unused recovery codes.
+// @router       /user/me/two_factor [GET]
+// @produce      json
+// @tags         User
+// @success      200  {object}  response.GetTwoFactorResponse
+// @failure      500  {object}  response.Response
+// @security     ApiKeyAuth
+func GetTwoFactor(c echo.Context) error {
+	user := c.Get("user").(models.User)
+	credential, err := utils.GetTOTPCredential(user.ID)
+	if err != nil {
+		panic(err)
+	}
+	var remaining int64
+	utils.PanicIfDBError(base.DB.Model(&models.RecoveryCode{}).Where("user_id = ? and used = ?", user.ID, false).
+		Count(&remaining), "could not count recovery codes")
+	return c.JSON(http.StatusOK, response.GetTwoFactorResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			Enabled               bool  `json:"enabled"`
+			Required              bool  `json:"required"`
+			RecoveryCodeRemaining int64 `json:"recovery_code_remaining"`
+		}{
+			Enabled:               credential != nil,
+			Required:              utils.TwoFactorRequired(&user),
+			RecoveryCodeRemaining: remaining,
+		},
+	})
+}
+
+// @summary      Begin enrolling a TOTP authenticator.
+// @description  Generate a new TOTP secret for the current user. Two-factor authentication is enabled after the
+// @description  enrolment is finished with a code generated with the secret.
+// @router       /user/me/two_factor/totp [POST]
+// @produce      json
+// @tags         User
+// @success      200  {object}  response.BeginTOTPEnrolmentResponse
+// @failure      409  {object}  response.Response  "Two-factor authentication is already enabled, with message `TWO_FACTOR_ENABLED`"
+// @failure      500  {object}  response.Response
+// @security     ApiKeyAuth
+func BeginTOTPEnrolment(c echo.Context) error {
+	user := c.Get("user").(models.User)
+	credential := models.TOTPCredential{}
+	err := base.DB.First(&credential, "user_id = ?", user.ID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		panic(errors.Wrap(err, "could not get totp credential"))
+	}
+	if credential.Enabled {
+		return c.JSON(http.StatusConflict, response.ErrorResp("TWO_FACTOR_ENABLED", nil))
+	}
+	secret, err := utils.NewTOTPSecret()
+	if err != nil {
+		panic(err)
+	}
+	credential.UserID = user.ID
+	credential.Secret = secret
+	credential.LastUsedStep = 0
+	utils.PanicIfDBError(base.DB.Save(&credential), "could not save totp credential")
+	return c.JSON(http.StatusOK, response.BeginTOTPEnrolmentResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			Secret          string `json:"secret"`
+			ProvisioningURI string `json:"provisioning_uri"`
+		}{
+			Secret:          secret,
+			ProvisioningURI: utils.TOTPProvisioningURI(secret, user.Username),
+		},
+	})
+}
+
+// @summary      Finish enrolling a TOTP authenticator.
+// @description  Enable two-factor authentication with a code generated with the new secret. Recovery codes are
+// @description  returned, and could not be read again.
+// @router       /user/me/two_factor/totp [PUT]
+// @produce      json
+// @tags         User
+// @param        request  body      request.FinishTOTPEnrolmentRequest  true  "The code."
+// @success      200      {object}  response.FinishTOTPEnrolmentResponse
+// @failure      400      {object}  response.Response{data=[]response.ValidationError}  "Validation error"
+// @failure      403      {object}  response.Response                                   "Wrong code, with message `WRONG_CODE`"
+// @failure      404      {object}  response.Response                                   "Enrolment not begun, with message `NOT_FOUND`"
+// @failure      409      {object}  response.Response                                   "Two-factor authentication is already enabled, with message `TWO_FACTOR_ENABLED`"
+// @failure      500      {object}  response.Response
+// @security     ApiKeyAuth
+func FinishTOTPEnrolment(c echo.Context) error {
+	user := c.Get("user").(models.User)
+	req := request.FinishTOTPEnrolmentRequest{}
+	if err, ok := utils.BindAndValidate(&req, c); !ok {
+		return err
+	}
+	credential := models.TOTPCredential{}
+	err := base.DB.First(&credential, "user_id = ?", user.ID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+	}
+	if err != nil {
+		panic(errors.Wrap(err, "could not get totp credential"))
+	}
+	if credential.Enabled {
+		return c.JSON(http.StatusConflict, response.ErrorResp("TWO_FACTOR_ENABLED", nil))
+	}
+	step, ok := utils.VerifyTOTP(credential.Secret, req.Code, time.Now(), credential.LastUsedStep)
+	if !ok {
+		return c.JSON(http.StatusForbidden, response.ErrorResp("WRONG_CODE", nil))
+	}
+	credential.Enabled = true
+	credential.LastUsedStep = step
+	utils.PanicIfDBError(base.DB.Save(&credential), "could not enable totp credential")
+	codes, err := utils.NewRecoveryCodes(user.ID)
+	if err != nil {
+		panic(err)
+	}
+	return c.JSON(http.StatusOK, response.FinishTOTPEnrolmentResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			RecoveryCodes []string `json:"recovery_codes"`
+		}{
+			codes,
+		},
+	})
+}
+
+// verifyMyTwoFactorCode checks a code of the current user for managing two-factor authentication.
+// A response is written and false is returned if two-factor authentication is disabled or the code is wrong.
+func verifyMyTwoFactorCode(c echo.Context, user models.User, code string) (bool, error) {
+	credential, err := utils.GetTOTPCredential(user.ID)
+	if err != nil {
+		panic(err)
+	}
+	if credential == nil {
+		return false, c.JSON(http.StatusNotFound, response.ErrorResp("NOT_FOUND", nil))
+	}
+	ok, err := utils.VerifyTwoFactorCode(credential, code)
+	if err != nil {
+		panic(err)
+	}
+	if !ok {
+		return false, c.JSON(http.StatusForbidden, response.ErrorResp("WRONG_CODE", nil))
+	}
+	return true, nil
+}
+
+// @summary      Disable two-factor authentication.
+// @description  Disable two-factor authentication of the current user with a TOTP code or an unused recovery code.
+// @description  Users required to use two-factor authentication could not disable it.
+// @router       /user/me/two_factor/totp [DELETE]
+// @produce      json
+// @tags         User
+// @param        request  body      request.DisableTwoFactorRequest  true  "The code."
+// @success      200      {object}  response.DisableTwoFactorResponse
+// @failure      400      {object}  response.Response{data=[]response.ValidationError}  "Validation error"
+// @failure      403      {object}  response.Response                                   "Wrong code, with message `WRONG_CODE`"
+// @failure      403      {object}  response.Response                                   "Two-factor authentication is required, with message `TWO_FACTOR_REQUIRED`"
+// @failure      404      {object}  response.Response                                   "Two-factor authentication is disabled, with message `NOT_FOUND`"
+// @failure      500      {object}  response.Response
+// @security     ApiKeyAuth
+func DisableTwoFactor(c echo.Context) error {
+	user := c.Get("user").(models.User)
+	req := request.DisableTwoFactorRequest{}
+	if err, ok := utils.BindAndValidate(&req, c); !ok {
+		return err
+	}
+	if utils.TwoFactorRequired(&user) {
+		return c.JSON(http.StatusForbidden, response.ErrorResp("TWO_FACTOR_REQUIRED", nil))
+	}
+	if ok, err := verifyMyTwoFactorCode(c, user, req.Code); !ok {
+		return err
+	}
+	err := base.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.TOTPCredential{}, "user_id = ?", user.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.RecoveryCode{}, "user_id = ?", user.ID).Error
+	})
+	if err != nil {
+		panic(errors.Wrap(err, "could not disable two-factor authentication"))
+	}
+	return c.JSON(http.StatusOK, response.DisableTwoFactorResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data:    nil,
+	})
+}
+
+// @summary      Regenerate recovery codes.
+// @description  Replace the recovery codes of the current user with new ones, using a TOTP code or an unused
+// @description  recovery code.
+// @router       /user/me/two_factor/recovery_codes [POST]
+// @produce      json
+// @tags         User
+// @param        request  body      request.RegenerateRecoveryCodesRequest  true  "The code."
+// @success      200      {object}  response.RegenerateRecoveryCodesResponse
+// @failure      400      {object}  response.Response{data=[]response.ValidationError}  "Validation error"
+// @failure      403      {object}  response.Response                                   "Wrong code, with message `WRONG_CODE`"
+// @failure      404      {object}  response.Response                                   "Two-factor authentication is disabled, with message `NOT_FOUND`"
+// @failure      500      {object}  response.Response
+// @security     ApiKeyAuth
+func RegenerateRecoveryCodes(c echo.Context) error {
+	user := c.Get("user").(models.User)
+	req := request.RegenerateRecoveryCodesRequest{}
+	if err, ok := utils.BindAndValidate(&req, c); !ok {
+		return err
+	}
+	if ok, err := verifyMyTwoFactorCode(c, user, req.Code); !ok {
+		return err
+	}
+	codes, err := utils.NewRecoveryCodes(user.ID)
+	if err != nil {
+		panic(err)
+	}
+	return c.JSON(http.StatusOK, response.RegenerateRecoveryCodesResponse{
+		Message: "SUCCESS",
+		Error:   nil,
+		Data: struct {
+			RecoveryCodes []string `json:"recovery_codes"`
+		}{
+			codes,
+		},
+	})
+}
@@ -0,0 +1,241 @@
+package controller_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/EduOJ/backend/app/request"
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// enableTOTPForTest enrolls a TOTP authenticator for the user, and returns the secret and the recovery codes.
+// The code of the next time step should be used next, as the code of the current step is used in the enrolment.
+func enableTOTPForTest(t *testing.T, user models.User) (string, []string) {
+	httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("user.beginTOTPEnrolment"), nil, applyUser(user)))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	beginResp := response.BeginTOTPEnrolmentResponse{}
+	mustJsonDecode(httpResp, &beginResp)
+	code, err := utils.TOTPCode(beginResp.Data.Secret, utils.TOTPStep(time.Now()))
+	assert.NoError(t, err)
+	httpResp = makeResp(makeReq(t, "PUT", base.Echo.Reverse("user.finishTOTPEnrolment"), request.FinishTOTPEnrolmentRequest{
+		Code: code,
+	}, applyUser(user)))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	finishResp := response.FinishTOTPEnrolmentResponse{}
+	mustJsonDecode(httpResp, &finishResp)
+	return beginResp.Data.Secret, finishResp.Data.RecoveryCodes
+}
+
+func TestTOTPEnrolment(t *testing.T) {
+	t.Parallel()
+	user := createUserForTest(t, "totp_enrolment", 0)
+
+	httpResp := makeResp(makeReq(t, "PUT", base.Echo.Reverse("user.finishTOTPEnrolment"), request.FinishTOTPEnrolmentRequest{
+		Code: "123456",
+	}, applyUser(user)))
+	assert.Equal(t, http.StatusNotFound, httpResp.StatusCode)
+
+	httpResp = makeResp(makeReq(t, "POST", base.Echo.Reverse("user.beginTOTPEnrolment"), nil, applyUser(user)))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	beginResp := response.BeginTOTPEnrolmentResponse{}
+	mustJsonDecode(httpResp, &beginResp)
+	u, err := url.Parse(beginResp.Data.ProvisioningURI)
+	assert.NoError(t, err)
+	assert.Equal(t, beginResp.Data.Secret, u.Query().Get("secret"))
+	assert.Equal(t, "/EduOJ:"+user.Username, u.Path)
+
+	// Two-factor authentication is not enabled before the enrolment is finished.
+	httpResp = makeResp(makeReq(t, "POST", base.Echo.Reverse("auth.login"), request.LoginRequest{
+		UsernameOrEmail: user.Username,
+		Password:        "test_totp_enrolment_user_0_pwd",
+	}))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+
+	wrongCode, err := utils.TOTPCode(beginResp.Data.Secret, utils.TOTPStep(time.Now())+5)
+	assert.NoError(t, err)
+	httpResp = makeResp(makeReq(t, "PUT", base.Echo.Reverse("user.finishTOTPEnrolment"), request.FinishTOTPEnrolmentRequest{
+		Code: wrongCode,
+	}, applyUser(user)))
+	assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+	resp := response.Response{}
+	mustJsonDecode(httpResp, &resp)
+	assert.Equal(t, response.ErrorResp("WRONG_CODE", nil), resp)
+
+	code, err := utils.TOTPCode(beginResp.Data.Secret, utils.TOTPStep(time.Now()))
+	assert.NoError(t, err)
+	httpResp = makeResp(makeReq(t, "PUT", base.Echo.Reverse("user.finishTOTPEnrolment"), request.FinishTOTPEnrolmentRequest{
+		Code: code,
+	}, applyUser(user)))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	finishResp := response.FinishTOTPEnrolmentResponse{}
+	mustJsonDecode(httpResp, &finishResp)
+	assert.Len(t, finishResp.Data.RecoveryCodes, 10)
+
+	httpResp = makeResp(makeReq(t, "POST", base.Echo.Reverse("user.beginTOTPEnrolment"), nil, applyUser(user)))
+	assert.Equal(t, http.StatusConflict, httpResp.StatusCode)
+
+	httpResp = makeResp(makeReq(t, "GET", base.Echo.Reverse("user.getTwoFactor"), nil, applyUser(user)))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	getResp := response.GetTwoFactorResponse{}
+	mustJsonDecode(httpResp, &getResp)
+	assert.True(t, getResp.Data.Enabled)
+	assert.False(t, getResp.Data.Required)
+	assert.Equal(t, int64(10), getResp.Data.RecoveryCodeRemaining)
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	t.Parallel()
+	user := createUserForTest(t, "tfa_login", 0)
+	secret, recoveryCodes := enableTOTPForTest(t, user)
+
+	login := func(t *testing.T) string {
+		httpResp := makeResp(makeReq(t, "POST", base.Echo.Reverse("auth.login"), request.LoginRequest{
+			UsernameOrEmail: user.Username,
+			Password:        "test_tfa_login_user_0_pwd",
+			RememberMe:      true,
+		}))
+		assert.Equal(t, http.StatusAccepted, httpResp.StatusCode)
+		resp := response.TwoFactorChallengeResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, "TWO_FACTOR_REQUIRED", resp.Message)
+		assert.NotEmpty(t, resp.Data.Challenge)
+		return resp.Data.Challenge
+	}
+	finish := func(t *testing.T, challenge, code string) *http.Response {
+		return makeResp(makeReq(t, "POST", base.Echo.Reverse("auth.twoFactor.finishLogin"), request.FinishTwoFactorLoginRequest{
+			Challenge: challenge,
+			Code:      code,
+		}))
+	}
+	checkToken := func(t *testing.T, httpResp *http.Response) {
+		assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+		resp := response.LoginResponse{}
+		mustJsonDecode(httpResp, &resp)
+		assert.Equal(t, user.ID, resp.Data.User.ID)
+		token := models.Token{}
+		assert.NoError(t, base.DB.First(&token, "token = ?", resp.Data.Token).Error)
+		assert.True(t, token.RememberMe)
+	}
+
+	httpResp := finish(t, "non_existing_challenge", "123456")
+	assert.Equal(t, http.StatusBadRequest, httpResp.StatusCode)
+	resp := response.Response{}
+	mustJsonDecode(httpResp, &resp)
+	assert.Equal(t, response.ErrorResp("INVALID_CHALLENGE", nil), resp)
+
+	challenge := login(t)
+	httpResp = finish(t, challenge, "wrong_code")
+	assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+	mustJsonDecode(httpResp, &resp)
+	assert.Equal(t, response.ErrorResp("WRONG_CODE", nil), resp)
+	code, err := utils.TOTPCode(secret, utils.TOTPStep(time.Now())+1)
+	assert.NoError(t, err)
+	checkToken(t, finish(t, challenge, code))
+	// Challenges could be used once.
+	assert.Equal(t, http.StatusBadRequest, finish(t, challenge, recoveryCodes[0]).StatusCode)
+
+	challenge = login(t)
+	checkToken(t, finish(t, challenge, recoveryCodes[0]))
+	challenge = login(t)
+	assert.Equal(t, http.StatusForbidden, finish(t, challenge, recoveryCodes[0]).StatusCode)
+
+	// Challenges are dropped after too many wrong codes. The failures are cleared on the way,
+	// so that the attempts are not delayed by the login throttle, which is tested in TestTwoFactorLoginBackoff.
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, utils.ResetLoginFailures(user.ID))
+		assert.Equal(t, http.StatusForbidden, finish(t, challenge, "wrong_code").StatusCode)
+	}
+	assert.NoError(t, utils.ResetLoginFailures(user.ID))
+	assert.Equal(t, http.StatusBadRequest, finish(t, challenge, recoveryCodes[1]).StatusCode)
+}
+
+func TestDisableTwoFactor(t *testing.T) {
+	t.Parallel()
+	user := createUserForTest(t, "tfa_disable", 0)
+
+	httpResp := makeResp(makeReq(t, "DELETE", base.Echo.Reverse("user.disableTwoFactor"), request.DisableTwoFactorRequest{
+		Code: "123456",
+	}, applyUser(user)))
+	assert.Equal(t, http.StatusNotFound, httpResp.StatusCode)
+
+	_, recoveryCodes := enableTOTPForTest(t, user)
+	httpResp = makeResp(makeReq(t, "POST", base.Echo.Reverse("user.regenerateRecoveryCodes"), request.RegenerateRecoveryCodesRequest{
+		Code: recoveryCodes[0],
+	}, applyUser(user)))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	regenerateResp := response.RegenerateRecoveryCodesResponse{}
+	mustJsonDecode(httpResp, &regenerateResp)
+	assert.Len(t, regenerateResp.Data.RecoveryCodes, 10)
+
+	httpResp = makeResp(makeReq(t, "DELETE", base.Echo.Reverse("user.disableTwoFactor"), request.DisableTwoFactorRequest{
+		Code: recoveryCodes[1],
+	}, applyUser(user)))
+	assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+	httpResp = makeResp(makeReq(t, "DELETE", base.Echo.Reverse("user.disableTwoFactor"), request.DisableTwoFactorRequest{
+		Code: regenerateResp.Data.RecoveryCodes[0],
+	}, applyUser(user)))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+
+	var count int64
+	assert.NoError(t, base.DB.Model(&models.RecoveryCode{}).Where("user_id = ?", user.ID).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+	httpResp = makeResp(makeReq(t, "POST", base.Echo.Reverse("auth.login"), request.LoginRequest{
+		UsernameOrEmail: user.Username,
+		Password:        "test_tfa_disable_user_0_pwd",
+	}))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+}
+
+// TestTwoFactorRequiredForManagers is not parallel, as it changes a setting affecting other tests.
+func TestTwoFactorRequiredForManagers(t *testing.T) {
+	viper.Set("auth.two_factor.required_for_managers", true)
+	defer viper.Set("auth.two_factor.required_for_managers", false)
+	admin := createUserForTest(t, "two_factor_required", 0)
+	admin.GrantRole("admin")
+	student := createUserForTest(t, "two_factor_required", 1)
+	manager := createUserForTest(t, "two_factor_required", 2)
+	class := createClassForTest(t, "two_factor_required", 0, []*models.User{&manager}, nil)
+	manager.GrantRole("class_creator", class)
+
+	httpResp := makeResp(makeReq(t, "GET", base.Echo.Reverse("user.getTwoFactor"), nil, applyUser(admin)))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+	getResp := response.GetTwoFactorResponse{}
+	mustJsonDecode(httpResp, &getResp)
+	assert.True(t, getResp.Data.Required)
+	assert.False(t, getResp.Data.Enabled)
+
+	httpResp = makeResp(makeReq(t, "GET", base.Echo.Reverse("admin.user.getUser", student.ID), nil, applyUser(admin)))
+	assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+	resp := response.Response{}
+	mustJsonDecode(httpResp, &resp)
+	assert.Equal(t, response.ErrorResp("TWO_FACTOR_REQUIRED", nil), resp)
+	httpResp = makeResp(makeReq(t, "GET", base.Echo.Reverse("admin.user.getUser", student.ID), nil, applyUser(student)))
+	assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+	mustJsonDecode(httpResp, &resp)
+	assert.Equal(t, response.ErrorResp("PERMISSION_DENIED", nil), resp)
+
+	// The permissions checked by the controllers directly are not usable either.
+	httpResp = makeResp(makeReq(t, "GET", base.Echo.Reverse("class.getClass", class.ID), nil, applyUser(manager)))
+	assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+	enableTOTPForTest(t, manager)
+	httpResp = makeResp(makeReq(t, "GET", base.Echo.Reverse("class.getClass", class.ID), nil, applyUser(manager)))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+
+	_, recoveryCodes := enableTOTPForTest(t, admin)
+	httpResp = makeResp(makeReq(t, "GET", base.Echo.Reverse("admin.user.getUser", student.ID), nil, applyUser(admin)))
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+
+	httpResp = makeResp(makeReq(t, "DELETE", base.Echo.Reverse("user.disableTwoFactor"), request.DisableTwoFactorRequest{
+		Code: recoveryCodes[0],
+	}, applyUser(admin)))
+	assert.Equal(t, http.StatusForbidden, httpResp.StatusCode)
+	mustJsonDecode(httpResp, &resp)
+	assert.Equal(t, response.ErrorResp("TWO_FACTOR_REQUIRED", nil), resp)
+}
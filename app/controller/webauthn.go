@@ -130,12 +130,10 @@ func FinishLogin(c echo.Context) error {
 	if err != nil {
 		panic(errors.Wrap(err, "could not validate login webauthn"))
 	}
-	token := models.Token{
-		Token:      utils.RandStr(32),
-		User:       user,
-		RememberMe: false,
+	token, err := utils.NewToken(user, false, c.Request().UserAgent(), c.RealIP())
+	if err != nil {
+		panic(errors.Wrap(err, "could not create token for users"))
 	}
-	utils.PanicIfDBError(base.DB.Create(&token), "could not create token for users")
 	if !user.RoleLoaded {
 		user.LoadRoles()
 	}
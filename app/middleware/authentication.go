@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/EduOJ/backend/app/response"
@@ -17,11 +18,15 @@ import (
 )
 
 func Authentication(next echo.HandlerFunc) echo.HandlerFunc {
+	next = restrictTwoFactorPendingUser(next)
 	return func(c echo.Context) error {
 		tokenString := c.Request().Header.Get("Authorization")
 		if tokenString == "" {
 			return next(c)
 		}
+		if strings.HasPrefix(tokenString, utils.PersonalAccessTokenPrefix) {
+			return personalAccessTokenAuthentication(c, next, tokenString)
+		}
 		token, err := utils.GetToken(tokenString)
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return next(c)
@@ -35,12 +40,76 @@ func Authentication(next echo.HandlerFunc) echo.HandlerFunc {
 			return c.JSON(http.StatusRequestTimeout, response.ErrorResp("AUTH_SESSION_EXPIRED", nil))
 		}
 		token.UpdatedAt = time.Now()
+		token.IP = c.RealIP()
 		utils.PanicIfDBError(base.DB.Omit(clause.Associations).Save(&token), "could not update token")
 		c.Set("user", token.User)
+		c.Set("token", token)
 		return next(c)
 	}
 }
 
+// personalAccessTokenAuthentication authenticates a request with a personal access token. The request is only allowed
+// if the route is covered by the scopes of the token, and the user could only use the permissions of the scopes.
+func personalAccessTokenAuthentication(c echo.Context, next echo.HandlerFunc, tokenString string) error {
+	token, err := utils.GetPersonalAccessToken(tokenString)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return next(c)
+	}
+	if err != nil {
+		log.Error(errors.Wrap(err, "fail to get user from personal access token"), c)
+		return response.InternalErrorResp(c)
+	}
+	// The token of a deleted user is invalid.
+	if token.User == nil {
+		return next(c)
+	}
+	if utils.IsPersonalAccessTokenExpired(token) {
+		return c.JSON(http.StatusRequestTimeout, response.ErrorResp("AUTH_TOKEN_EXPIRED", nil))
+	}
+	if !utils.TokenScopesAllowRoute(token.Scopes, routeName(c)) {
+		return c.JSON(http.StatusForbidden, response.ErrorResp("INSUFFICIENT_SCOPE", nil))
+	}
+	utils.PanicIfDBError(base.DB.Model(&token).UpdateColumn("last_used_at", time.Now()),
+		"could not update personal access token")
+	user := *token.User
+	user.AllowedPermissions = utils.TokenScopePermissions(token.Scopes)
+	c.Set("user", user)
+	c.Set("personal_access_token", token)
+	return next(c)
+}
+
+// restrictTwoFactorPendingUser takes away the permissions of the user who is required to enable two-factor
+// authentication but has not enabled it, so the controllers checking the permissions directly are covered as well.
+// HasPermission responds TWO_FACTOR_REQUIRED for such users.
+func restrictTwoFactorPendingUser(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := c.Get("user").(models.User)
+		if !ok || !utils.TwoFactorRequired(&user) {
+			return next(c)
+		}
+		credential, err := utils.GetTOTPCredential(user.ID)
+		if err != nil {
+			panic(err)
+		}
+		if credential == nil {
+			user.AllowedPermissions = []string{}
+			c.Set("user", user)
+			c.Set("two_factor_pending", true)
+		}
+		return next(c)
+	}
+}
+
+// routeName returns the name of the route matched by the request.
+func routeName(c echo.Context) string {
+	for _, route := range c.Echo().Routes() {
+		if route.Method == c.Request().Method && route.Path == c.Path() {
+			return route.Name
+		}
+	}
+	return ""
+}
+
 func Logged(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		_, ok := c.Get("user").(models.User)
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/EduOJ/backend/app/response"
+	"github.com/EduOJ/backend/base/utils"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+// ExamRestriction restricts students taking a running exam to the problem set of the exam.
+// Requests to the exam are checked against its IP ranges and the token bound to the student.
+// Rejected requests are logged as exam violations for the teachers to review.
+func ExamRestriction(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := c.Get("user").(models.User)
+		if !ok || user.ID == 0 {
+			return next(c)
+		}
+		exam, err := utils.GetRunningExam(user.ID)
+		if err != nil {
+			panic(errors.Wrap(err, "could not check running exam"))
+		}
+		if exam == nil {
+			return next(c)
+		}
+		if c.Param("class_id") != fmt.Sprint(exam.ClassID) || c.Param("problem_set_id") != fmt.Sprint(exam.ID) {
+			if err := utils.LogExamViolation(exam.ID, user.ID, models.ExamViolationOutOfExamAccess, c.RealIP(), c.Request().URL.Path); err != nil {
+				panic(err)
+			}
+			return c.JSON(http.StatusForbidden, response.ErrorResp("EXAM_IN_PROGRESS", nil))
+		}
+		if err, ok := checkExamAccess(c, exam); !ok {
+			return err
+		}
+		return next(c)
+	}
+}
+
+// checkExamAccess checks the request of the user to the running exam.
+func checkExamAccess(c echo.Context, exam *models.ProblemSet) (err error, ok bool) {
+	user := c.Get("user").(models.User)
+	token, _ := c.Get("token").(models.Token)
+	violation, err := utils.CheckExamAccess(exam, user.ID, token.ID, c.RealIP(), c.Request().URL.Path)
+	if err != nil {
+		panic(errors.Wrap(err, "could not check exam access"))
+	}
+	switch violation {
+	case models.ExamViolationIPNotAllowed:
+		return c.JSON(http.StatusForbidden, response.ErrorResp("EXAM_IP_NOT_ALLOWED", nil)), false
+	case models.ExamViolationSessionConflict:
+		return c.JSON(http.StatusForbidden, response.ErrorResp("EXAM_SESSION_CONFLICT", nil)), false
+	}
+	return nil, true
+}
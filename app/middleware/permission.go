@@ -89,10 +89,15 @@ func (p CustomPermission) Check(c echo.Context) bool {
 func HasPermission(p PermissionOption) func(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			if p.Check(c) {
-				return next(c)
+			if !p.Check(c) {
+				// Admins and class managers could not use their permissions before enabling two-factor authentication
+				// if it is required. They could still login and enable it.
+				if c.Get("two_factor_pending") != nil {
+					return c.JSON(http.StatusForbidden, response.ErrorResp("TWO_FACTOR_REQUIRED", nil))
+				}
+				return c.JSON(http.StatusForbidden, response.ErrorResp("PERMISSION_DENIED", nil))
 			}
-			return c.JSON(http.StatusForbidden, response.ErrorResp("PERMISSION_DENIED", nil))
+			return next(c)
 		}
 	}
 }
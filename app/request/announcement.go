@@ -0,0 +1,19 @@
+package request
+
+type GetAnnouncementsRequest struct {
+}
+
+type CreateAnnouncementRequest struct {
+	Title   string `json:"title" form:"title" query:"title" validate:"required,max=255"`
+	Content string `json:"content" form:"content" query:"content" validate:"required"`
+	Pinned  bool   `json:"pinned" form:"pinned" query:"pinned"`
+}
+
+type UpdateAnnouncementRequest struct {
+	Title   string `json:"title" form:"title" query:"title" validate:"required,max=255"`
+	Content string `json:"content" form:"content" query:"content" validate:"required"`
+	Pinned  bool   `json:"pinned" form:"pinned" query:"pinned"`
+}
+
+type DeleteAnnouncementRequest struct {
+}
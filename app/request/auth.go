@@ -33,3 +33,26 @@ type DoResetPasswordRequest struct {
 	Token           string `json:"token" form:"token" query:"token" validate:"required,max=5,min=5"`
 	Password        string `json:"password" form:"password" query:"password" validate:"required,max=30,min=5"`
 }
+
+type FinishOIDCLoginRequest struct {
+	// The authorization code returned by the provider.
+	Code string `json:"code" form:"code" query:"code" validate:"required,max=2048"`
+	// The state returned by the provider, which is responded by the request starting the login.
+	State string `json:"state" form:"state" query:"state" validate:"required,max=255"`
+	// If true, the created token will last longer.
+	RememberMe bool `json:"remember_me" example:"false"`
+}
+
+type FinishLinkOIDCIdentityRequest struct {
+	// The authorization code returned by the provider.
+	Code string `json:"code" form:"code" query:"code" validate:"required,max=2048"`
+	// The state returned by the provider, which is responded by the request starting the link.
+	State string `json:"state" form:"state" query:"state" validate:"required,max=255"`
+}
+
+type FinishTwoFactorLoginRequest struct {
+	// The challenge returned by the login request.
+	Challenge string `json:"challenge" form:"challenge" query:"challenge" validate:"required,max=255"`
+	// A TOTP code, or an unused recovery code.
+	Code string `json:"code" form:"code" query:"code" validate:"required,max=32"`
+}
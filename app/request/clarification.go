@@ -0,0 +1,16 @@
+package request
+
+type GetClarificationsRequest struct {
+}
+
+type CreateClarificationRequest struct {
+	// ProblemID is 0 if the clarification is not about a specific problem.
+	ProblemID uint   `json:"problem_id" form:"problem_id" query:"problem_id"`
+	Content   string `json:"content" form:"content" query:"content" validate:"required"`
+}
+
+type ReplyClarificationRequest struct {
+	Content string `json:"content" form:"content" query:"content" validate:"required"`
+	// Public broadcasts the clarification to the whole class. Only managers of the class can set it.
+	Public bool `json:"public" form:"public" query:"public"`
+}
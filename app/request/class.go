@@ -1,11 +1,21 @@
 package request
 
+import "time"
+
 type CreateClassRequest struct {
 	Name        string `json:"name" form:"name" query:"name" validate:"required,max=255"`
 	CourseName  string `json:"course_name" form:"course_name" query:"course_name" validate:"required,max=255"`
 	Description string `json:"description" form:"description" query:"description" validate:"required"`
 }
 
+// CloneClassRequest clones the class in the path. The problem sets are shifted by TimeOffset seconds,
+// or so that the earliest one starts at StartTime if it is given.
+type CloneClassRequest struct {
+	Name       string     `json:"name" form:"name" query:"name" validate:"required,max=255"`
+	TimeOffset int64      `json:"time_offset" form:"time_offset" query:"time_offset"`
+	StartTime  *time.Time `json:"start_time" form:"start_time" query:"start_time"`
+}
+
 type GetClassRequest struct {
 }
 
@@ -23,12 +33,48 @@ type DeleteStudentsRequest struct {
 	UserIds []uint `json:"user_ids" form:"user_ids" query:"user_ids" validate:"required,min=1"`
 }
 
+// ImportStudentsRequest carries the roster as a CSV file in the multipart form field "file".
+// The first line of the file is a header naming the username, nickname and email columns.
+type ImportStudentsRequest struct {
+}
+
+// ImportStudentsRow is a row of the roster used to create a missing account.
+type ImportStudentsRow struct {
+	Username string `json:"username" validate:"required,max=30,min=5,username"`
+	Nickname string `json:"nickname" validate:"required,max=30,min=1"`
+	Email    string `json:"email" validate:"required,email,max=320,min=5"`
+}
+
+type AddAssistantsRequest struct {
+	UserIds []uint `json:"user_ids" form:"user_ids" query:"user_ids" validate:"required,min=1"`
+}
+
+type DeleteAssistantsRequest struct {
+	UserIds []uint `json:"user_ids" form:"user_ids" query:"user_ids" validate:"required,min=1"`
+}
+
 type RefreshInviteCodeRequest struct {
 }
 
+type UpdateInviteCodeSettingsRequest struct {
+	ExpireAt        *time.Time `json:"expire_at" form:"expire_at" query:"expire_at"`
+	MaxUses         uint       `json:"max_uses" form:"max_uses" query:"max_uses"`
+	RequireApproval bool       `json:"require_approval" form:"require_approval" query:"require_approval"`
+}
+
 type JoinClassRequest struct {
 	InviteCode string `json:"invite_code" form:"invite_code" query:"invite_code" validate:"required,max=255"`
 }
 
+type GetClassJoinRequestsRequest struct {
+	Status string `json:"status" form:"status" query:"status" validate:"omitempty,oneof=PENDING APPROVED REJECTED"`
+}
+
+type ApproveClassJoinRequestRequest struct {
+}
+
+type RejectClassJoinRequestRequest struct {
+}
+
 type DeleteClassRequest struct {
 }
@@ -0,0 +1,20 @@
+package request
+
+type ProblemGradeWeight struct {
+	ProblemID uint    `json:"problem_id" form:"problem_id" query:"problem_id" validate:"required"`
+	Weight    float64 `json:"weight" form:"weight" query:"weight" validate:"min=0"`
+}
+
+type ProblemSetGradeWeight struct {
+	ProblemSetID uint                 `json:"problem_set_id" form:"problem_set_id" query:"problem_set_id" validate:"required"`
+	Weight       float64              `json:"weight" form:"weight" query:"weight" validate:"min=0"`
+	Problems     []ProblemGradeWeight `json:"problems" form:"problems" query:"problems" validate:"dive"`
+}
+
+type UpdateGradeWeightsRequest struct {
+	DropLowest  uint                    `json:"drop_lowest" form:"drop_lowest" query:"drop_lowest"`
+	ProblemSets []ProblemSetGradeWeight `json:"problem_sets" form:"problem_sets" query:"problem_sets" validate:"dive"`
+}
+
+type GetCourseGradesRequest struct {
+}
@@ -0,0 +1,18 @@
+package request
+
+// UpdateExamSettingsRequest sets the exam mode of a problem set.
+// An empty list of IP ranges accepts requests from any address.
+type UpdateExamSettingsRequest struct {
+	ExamMode        bool     `json:"exam_mode" form:"exam_mode" query:"exam_mode"`
+	AllowedIPRanges []string `json:"allowed_ip_ranges" form:"allowed_ip_ranges" query:"allowed_ip_ranges" validate:"dive,cidr"`
+}
+
+type GetExamViolationsRequest struct {
+	UserID uint `json:"user_id" form:"user_id" query:"user_id"`
+}
+
+// ResetExamSessionRequest unbinds a student from the token used in the exam,
+// so that the student could continue the exam on another device.
+type ResetExamSessionRequest struct {
+	UserID uint `json:"user_id" form:"user_id" query:"user_id" validate:"required"`
+}
@@ -0,0 +1,11 @@
+package request
+
+type GetGradeChangesRequest struct {
+	UserID    uint `json:"user_id" form:"user_id" query:"user_id"`
+	ProblemID uint `json:"problem_id" form:"problem_id" query:"problem_id"`
+}
+
+type GetMyGradeChangesRequest struct {
+	ClassID      uint `json:"class_id" form:"class_id" query:"class_id"`
+	ProblemSetID uint `json:"problem_set_id" form:"problem_set_id" query:"problem_set_id"`
+}
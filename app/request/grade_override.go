@@ -0,0 +1,17 @@
+package request
+
+type GetGradeOverridesRequest struct {
+}
+
+type OverrideGradeRequest struct {
+	UserID    uint   `json:"user_id" form:"user_id" query:"user_id" validate:"required"`
+	ProblemID uint   `json:"problem_id" form:"problem_id" query:"problem_id" validate:"required"`
+	Score     uint   `json:"score" form:"score" query:"score"`
+	Reason    string `json:"reason" form:"reason" query:"reason" validate:"required,max=255"`
+}
+
+type RemoveGradeOverrideRequest struct {
+	UserID    uint   `json:"user_id" form:"user_id" query:"user_id" validate:"required"`
+	ProblemID uint   `json:"problem_id" form:"problem_id" query:"problem_id" validate:"required"`
+	Reason    string `json:"reason" form:"reason" query:"reason" validate:"required,max=255"`
+}
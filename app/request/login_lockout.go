@@ -0,0 +1,14 @@
+package request
+
+type AdminGetLoginLockoutsRequest struct {
+	UserID uint `json:"user_id" form:"user_id" query:"user_id"`
+
+	Limit  int `json:"limit" form:"limit" query:"limit" validate:"max=100,min=0"`
+	Offset int `json:"offset" form:"offset" query:"offset" validate:"min=0"`
+}
+
+type AdminUnlockUserRequest struct {
+}
+
+type AdminUnlockLoginLockoutRequest struct {
+}
@@ -0,0 +1,20 @@
+package request
+
+type GetNotificationsRequest struct {
+	// Unread filters out the notifications which have been read.
+	Unread bool `json:"unread" form:"unread" query:"unread"`
+
+	Limit  int `json:"limit" form:"limit" query:"limit" validate:"max=100,min=0"`
+	Offset int `json:"offset" form:"offset" query:"offset" validate:"min=0"`
+}
+
+// ReadNotificationsRequest marks the notifications with the given ids as read,
+// or all notifications of the user if All is true.
+type ReadNotificationsRequest struct {
+	IDs []uint `json:"ids" form:"ids" query:"ids"`
+	All bool   `json:"all" form:"all" query:"all"`
+}
+
+type UpdateNotificationSettingsRequest struct {
+	EmailNotification bool `json:"email_notification" form:"email_notification" query:"email_notification"`
+}
@@ -0,0 +1,16 @@
+package request
+
+import "time"
+
+type GetPersonalAccessTokensRequest struct {
+}
+
+type CreatePersonalAccessTokenRequest struct {
+	Name string `json:"name" form:"name" query:"name" validate:"required,max=255"`
+	// Scopes of the token, e.g. problem:write, grades:read and submission:read.
+	Scopes    []string  `json:"scopes" form:"scopes" query:"scopes" validate:"required,min=1"`
+	ExpiresAt time.Time `json:"expires_at" form:"expires_at" query:"expires_at" validate:"required"`
+}
+
+type DeletePersonalAccessTokenRequest struct {
+}
@@ -8,6 +8,9 @@ type CreateProblemSetRequest struct {
 
 	StartTime time.Time `json:"start_time" form:"start_time" query:"start_time" validate:"required"`
 	EndTime   time.Time `json:"end_time" form:"end_time" query:"end_time" validate:"required,gtefield=StartTime"`
+
+	AllowUpsolve    bool   `json:"allow_upsolve" form:"allow_upsolve" query:"allow_upsolve"`
+	LanguageAllowed string `json:"language_allowed" form:"language_allowed" query:"language_allowed" validate:"max=255"` // E.g.    c,python
 }
 
 type CloneProblemSetRequest struct {
@@ -24,6 +27,9 @@ type UpdateProblemSetRequest struct {
 
 	StartTime time.Time `json:"start_time" form:"start_time" query:"start_time" validate:"required"`
 	EndTime   time.Time `json:"end_time" form:"end_time" query:"end_time" validate:"required,gtefield=StartTime"`
+
+	AllowUpsolve    bool   `json:"allow_upsolve" form:"allow_upsolve" query:"allow_upsolve"`
+	LanguageAllowed string `json:"language_allowed" form:"language_allowed" query:"language_allowed" validate:"max=255"` // E.g.    c,python
 }
 
 type AddProblemsToSetRequest struct {
@@ -34,6 +40,18 @@ type DeleteProblemsFromSetRequest struct {
 	ProblemIDs []uint `json:"problem_ids" form:"problem_ids" query:"problem_ids" validate:"required,min=1"`
 }
 
+type ProblemInSet struct {
+	ProblemID uint   `json:"problem_id" form:"problem_id" query:"problem_id" validate:"required"`
+	Label     string `json:"label" form:"label" query:"label" validate:"max=255"`
+	FullMark  uint   `json:"full_mark" form:"full_mark" query:"full_mark" validate:"required"`
+}
+
+// ReorderProblemsInSetRequest lists all the problems in a problem set in the display order.
+// Problems with an empty label are labelled by their position.
+type ReorderProblemsInSetRequest struct {
+	Problems []ProblemInSet `json:"problems" form:"problems" query:"problems" validate:"required,min=1,dive"`
+}
+
 type DeleteProblemSetRequest struct {
 }
 
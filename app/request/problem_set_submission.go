@@ -5,6 +5,9 @@ type ProblemSetCreateSubmissionRequest struct {
 	// code(required)
 }
 
+type ProblemSetRejudgeSubmissionRequest struct {
+}
+
 type ProblemSetGetSubmissionRequest struct {
 }
 
@@ -15,3 +18,18 @@ type ProblemSetGetSubmissionsRequest struct {
 	Limit  int `json:"limit" form:"limit" query:"limit" validate:"max=100,min=0"`
 	Offset int `json:"offset" form:"offset" query:"offset" validate:"min=0"`
 }
+
+type ProblemSetUpdateSubmissionFeedbackRequest struct {
+	Feedback string `json:"feedback" form:"feedback" query:"feedback"`
+}
+
+type ProblemSetGetSubmissionCodeRequest struct {
+	Comments bool `json:"comments" form:"comments" query:"comments"`
+}
+
+type ProblemSetCreateSubmissionCommentRequest struct {
+	ParentID  uint   `json:"parent_id" form:"parent_id" query:"parent_id"`
+	StartLine uint   `json:"start_line" form:"start_line" query:"start_line"`
+	EndLine   uint   `json:"end_line" form:"end_line" query:"end_line"`
+	Content   string `json:"content" form:"content" query:"content" validate:"required"`
+}
@@ -0,0 +1,13 @@
+package request
+
+type GetMySessionsRequest struct {
+}
+
+type DeleteMySessionRequest struct {
+}
+
+type DeleteMyOtherSessionsRequest struct {
+}
+
+type AdminDeleteUserSessionsRequest struct {
+}
@@ -0,0 +1,5 @@
+package request
+
+type GetMyGradesRequest struct {
+	ClassID uint `json:"class_id" form:"class_id" query:"class_id"`
+}
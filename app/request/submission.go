@@ -15,3 +15,18 @@ type GetSubmissionsRequest struct {
 	Limit  int `json:"limit" form:"limit" query:"limit" validate:"max=100,min=0"`
 	Offset int `json:"offset" form:"offset" query:"offset" validate:"min=0"`
 }
+
+// GetSubmissionCodeRequest redirects to the code by default.
+// With comments=1, the url of the code is returned together with the code review comments.
+type GetSubmissionCodeRequest struct {
+	Comments bool `json:"comments" form:"comments" query:"comments"`
+}
+
+type CreateSubmissionCommentRequest struct {
+	// ParentID is the id of the comment to reply to, or 0 to start a new thread.
+	// Replies share the lines of the comment replied to.
+	ParentID  uint   `json:"parent_id" form:"parent_id" query:"parent_id"`
+	StartLine uint   `json:"start_line" form:"start_line" query:"start_line"`
+	EndLine   uint   `json:"end_line" form:"end_line" query:"end_line"`
+	Content   string `json:"content" form:"content" query:"content" validate:"required"`
+}
@@ -0,0 +1,22 @@
+package request
+
+type GetTwoFactorRequest struct {
+}
+
+type BeginTOTPEnrolmentRequest struct {
+}
+
+type FinishTOTPEnrolmentRequest struct {
+	// A TOTP code generated with the new secret.
+	Code string `json:"code" form:"code" query:"code" validate:"required,max=32"`
+}
+
+type DisableTwoFactorRequest struct {
+	// A TOTP code, or an unused recovery code.
+	Code string `json:"code" form:"code" query:"code" validate:"required,max=32"`
+}
+
+type RegenerateRecoveryCodesRequest struct {
+	// A TOTP code, or an unused recovery code.
+	Code string `json:"code" form:"code" query:"code" validate:"required,max=32"`
+}
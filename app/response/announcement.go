@@ -0,0 +1,27 @@
+package response
+
+import "github.com/EduOJ/backend/app/response/resource"
+
+type GetAnnouncementsResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		Announcements []resource.Announcement `json:"announcements"`
+	} `json:"data"`
+}
+
+type CreateAnnouncementResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.Announcement `json:"announcement"`
+	} `json:"data"`
+}
+
+type UpdateAnnouncementResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.Announcement `json:"announcement"`
+	} `json:"data"`
+}
@@ -47,3 +47,55 @@ type EmailVerificationResponse struct {
 	Error   interface{} `json:"error"`
 	Data    interface{} `json:"data"`
 }
+
+type OIDCProvider struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+type GetOIDCProvidersResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		Providers []OIDCProvider `json:"providers"`
+	} `json:"data"`
+}
+
+type BeginOIDCLoginResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		AuthorizationURL string `json:"authorization_url"`
+		State            string `json:"state"`
+	} `json:"data"`
+}
+
+type GetMyIdentitiesResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		Identities []resource.UserIdentity `json:"identities"`
+	} `json:"data"`
+}
+
+type FinishLinkOIDCIdentityResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.UserIdentity `json:"identity"`
+	} `json:"data"`
+}
+
+type DeleteMyIdentityResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    interface{} `json:"data"`
+}
+
+type TwoFactorChallengeResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		Challenge string `json:"challenge"`
+	} `json:"data"`
+}
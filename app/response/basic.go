@@ -12,6 +12,12 @@ type ValidationError struct {
 	Translation string `json:"translation"`
 }
 
+// RateLimitError tells which limit is exceeded, and the seconds to wait before retrying.
+type RateLimitError struct {
+	Limit      string `json:"limit"`
+	RetryAfter int64  `json:"retry_after"`
+}
+
 type Response struct {
 	Message string      `json:"message"`
 	Error   interface{} `json:"error"`
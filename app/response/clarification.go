@@ -0,0 +1,27 @@
+package response
+
+import "github.com/EduOJ/backend/app/response/resource"
+
+type GetClarificationsResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		Clarifications []resource.Clarification `json:"clarifications"`
+	} `json:"data"`
+}
+
+type CreateClarificationResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.Clarification `json:"clarification"`
+	} `json:"data"`
+}
+
+type ReplyClarificationResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.Clarification `json:"clarification"`
+	} `json:"data"`
+}
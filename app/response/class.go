@@ -10,6 +10,14 @@ type CreateClassResponse struct {
 	} `json:"data"`
 }
 
+type CloneClassResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.ClassDetail `json:"class"`
+	} `json:"data"`
+}
+
 type GetClassResponse struct {
 	Message string      `json:"message"`
 	Error   interface{} `json:"error"`
@@ -42,6 +50,44 @@ type AddStudentsResponse struct {
 	} `json:"data"`
 }
 
+// ImportStudentsRow is the import result of a row in the roster.
+// Status is one of CREATED, ADDED, ALREADY_IN_CLASS, CONFLICT and INVALID.
+type ImportStudentsRow struct {
+	Line     int    `json:"line"`
+	Username string `json:"username"`
+	Nickname string `json:"nickname"`
+	Email    string `json:"email"`
+
+	Status string            `json:"status"`
+	UserID uint              `json:"user_id"`
+	Errors []ValidationError `json:"errors"`
+}
+
+type ImportStudentsResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.ClassDetail `json:"class"`
+		Rows                  []ImportStudentsRow `json:"rows"`
+	} `json:"data"`
+}
+
+type AddAssistantsResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.ClassDetail `json:"class"`
+	} `json:"data"`
+}
+
+type DeleteAssistantsResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.ClassDetail `json:"class"`
+	} `json:"data"`
+}
+
 type DeleteStudentsResponse struct {
 	Message string      `json:"message"`
 	Error   interface{} `json:"error"`
@@ -58,6 +104,14 @@ type RefreshInviteCodeResponse struct {
 	} `json:"data"`
 }
 
+type UpdateInviteCodeSettingsResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.ClassDetail `json:"class"`
+	} `json:"data"`
+}
+
 type JoinClassResponse struct {
 	Message string      `json:"message"`
 	Error   interface{} `json:"error"`
@@ -65,3 +119,43 @@ type JoinClassResponse struct {
 		*resource.Class `json:"class"`
 	} `json:"data"`
 }
+
+type RequestJoinClassResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.ClassJoinRequest `json:"join_request"`
+	} `json:"data"`
+}
+
+type GetClassJoinRequestsResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		JoinRequests []resource.ClassJoinRequest `json:"join_requests"`
+	} `json:"data"`
+}
+
+type ApproveClassJoinRequestResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.ClassJoinRequest `json:"join_request"`
+	} `json:"data"`
+}
+
+type RejectClassJoinRequestResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.ClassJoinRequest `json:"join_request"`
+	} `json:"data"`
+}
+
+type GetClassStatisticsResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.ClassStatistics `json:"statistics"`
+	} `json:"data"`
+}
@@ -0,0 +1,23 @@
+package response
+
+import "github.com/EduOJ/backend/app/response/resource"
+
+type UpdateGradeWeightsResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		DropLowest   uint                   `json:"drop_lowest"`
+		GradeWeights []resource.GradeWeight `json:"grade_weights"`
+		CourseGrades []resource.CourseGrade `json:"course_grades"`
+	} `json:"data"`
+}
+
+type GetCourseGradesResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		DropLowest   uint                   `json:"drop_lowest"`
+		GradeWeights []resource.GradeWeight `json:"grade_weights"`
+		CourseGrades []resource.CourseGrade `json:"course_grades"`
+	} `json:"data"`
+}
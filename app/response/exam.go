@@ -0,0 +1,25 @@
+package response
+
+import "github.com/EduOJ/backend/app/response/resource"
+
+type UpdateExamSettingsResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.ProblemSetDetail `json:"problem_set"`
+	} `json:"data"`
+}
+
+type GetExamViolationsResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		ExamViolations []resource.ExamViolation `json:"exam_violations"`
+	} `json:"data"`
+}
+
+type ResetExamSessionResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    interface{} `json:"data"`
+}
@@ -0,0 +1,19 @@
+package response
+
+import "github.com/EduOJ/backend/app/response/resource"
+
+type GetGradeChangesResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		GradeChanges []resource.GradeChange `json:"grade_changes"`
+	} `json:"data"`
+}
+
+type GetMyGradeChangesResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		GradeChanges []resource.GradeChange `json:"grade_changes"`
+	} `json:"data"`
+}
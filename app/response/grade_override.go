@@ -0,0 +1,27 @@
+package response
+
+import "github.com/EduOJ/backend/app/response/resource"
+
+type GetGradeOverridesResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		GradeOverrides []resource.GradeOverride `json:"grade_overrides"`
+	} `json:"data"`
+}
+
+type OverrideGradeResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.GradeOverride `json:"grade_override"`
+	} `json:"data"`
+}
+
+type RemoveGradeOverrideResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.GradeOverride `json:"grade_override"`
+	} `json:"data"`
+}
@@ -0,0 +1,28 @@
+package response
+
+import "github.com/EduOJ/backend/app/response/resource"
+
+type AdminGetLoginLockoutsResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		LoginLockouts []resource.LoginLockout `json:"login_lockouts"`
+		Total         int                     `json:"total"`
+		Count         int                     `json:"count"`
+		Offset        int                     `json:"offset"`
+		Prev          *string                 `json:"prev"`
+		Next          *string                 `json:"next"`
+	} `json:"data"`
+}
+
+type AdminUnlockUserResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    interface{} `json:"data"`
+}
+
+type AdminUnlockLoginLockoutResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    interface{} `json:"data"`
+}
@@ -0,0 +1,33 @@
+package response
+
+import "github.com/EduOJ/backend/app/response/resource"
+
+type GetNotificationsResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		Notifications []resource.Notification `json:"notifications"`
+		Unread        int64                   `json:"unread"`
+		Total         int                     `json:"total"`
+		Count         int                     `json:"count"`
+		Offset        int                     `json:"offset"`
+		Prev          *string                 `json:"prev"`
+		Next          *string                 `json:"next"`
+	} `json:"data"`
+}
+
+type ReadNotificationsResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		Unread int64 `json:"unread"`
+	} `json:"data"`
+}
+
+type UpdateNotificationSettingsResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		EmailNotification bool `json:"email_notification"`
+	} `json:"data"`
+}
@@ -0,0 +1,27 @@
+package response
+
+import "github.com/EduOJ/backend/app/response/resource"
+
+type GetPersonalAccessTokensResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		Tokens []resource.PersonalAccessToken `json:"tokens"`
+	} `json:"data"`
+}
+
+type CreatePersonalAccessTokenResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		PersonalAccessToken *resource.PersonalAccessToken `json:"personal_access_token"`
+		// Token is only shown once here.
+		Token string `json:"token"`
+	} `json:"data"`
+}
+
+type DeletePersonalAccessTokenResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    interface{} `json:"data"`
+}
@@ -0,0 +1,27 @@
+package response
+
+import "github.com/EduOJ/backend/app/response/resource"
+
+type CreatePlagiarismReportResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.PlagiarismReport `json:"report"`
+	} `json:"data"`
+}
+
+type GetPlagiarismReportsResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		Reports []resource.PlagiarismReport `json:"reports"`
+	} `json:"data"`
+}
+
+type GetPlagiarismReportResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.PlagiarismReportDetail `json:"report"`
+	} `json:"data"`
+}
@@ -66,6 +66,14 @@ type DeleteProblemsFromSetResponse struct {
 	} `json:"data"`
 }
 
+type ReorderProblemsInSetResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.ProblemSetDetail `json:"problem_set"`
+	} `json:"data"`
+}
+
 type GetProblemSetProblemResponseForAdmin struct {
 	Message string      `json:"message"`
 	Error   interface{} `json:"error"`
@@ -12,6 +12,22 @@ type ProblemSetCreateSubmissionResponse struct {
 	} `json:"data"`
 }
 
+type ProblemSetRejudgeSubmissionResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.SubmissionDetail `json:"submission"`
+	} `json:"data"`
+}
+
+type ProblemSetUpdateSubmissionFeedbackResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.SubmissionDetail `json:"submission"`
+	} `json:"data"`
+}
+
 type ProblemSetGetSubmissionResponse struct {
 	Message string      `json:"message"`
 	Error   interface{} `json:"error"`
@@ -32,3 +48,20 @@ type ProblemSetGetSubmissionsResponse struct {
 		Next        *string               `json:"next"`
 	} `json:"data"`
 }
+
+type ProblemSetGetSubmissionCodeResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		URL      string                       `json:"url"`
+		Comments []resource.SubmissionComment `json:"comments"`
+	} `json:"data"`
+}
+
+type ProblemSetCreateSubmissionCommentResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.SubmissionComment `json:"comment"`
+	} `json:"data"`
+}
@@ -0,0 +1,80 @@
+package resource
+
+import (
+	"time"
+
+	"github.com/EduOJ/backend/database/models"
+)
+
+type Announcement struct {
+	ID uint `json:"id"`
+
+	ClassID uint  `json:"class_id"`
+	UserID  uint  `json:"user_id"`
+	User    *User `json:"user"`
+
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	Pinned  bool   `json:"pinned"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Notification struct {
+	ID uint `json:"id"`
+
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Content  string `json:"content"`
+	ClassID  uint   `json:"class_id"`
+	TargetID uint   `json:"target_id"`
+	Read     bool   `json:"read"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (a *Announcement) convert(announcement *models.Announcement) {
+	a.ID = announcement.ID
+	a.ClassID = announcement.ClassID
+	a.UserID = announcement.UserID
+	a.User = GetUser(announcement.User)
+	a.Title = announcement.Title
+	a.Content = announcement.Content
+	a.Pinned = announcement.Pinned
+	a.CreatedAt = announcement.CreatedAt
+	a.UpdatedAt = announcement.UpdatedAt
+}
+
+func (n *Notification) convert(notification *models.Notification) {
+	n.ID = notification.ID
+	n.Type = notification.Type
+	n.Title = notification.Title
+	n.Content = notification.Content
+	n.ClassID = notification.ClassID
+	n.TargetID = notification.TargetID
+	n.Read = notification.Read
+	n.CreatedAt = notification.CreatedAt
+}
+
+func GetAnnouncement(announcement *models.Announcement) *Announcement {
+	a := Announcement{}
+	a.convert(announcement)
+	return &a
+}
+
+func GetAnnouncementSlice(announcements []*models.Announcement) (a []Announcement) {
+	a = make([]Announcement, len(announcements))
+	for i, announcement := range announcements {
+		a[i].convert(announcement)
+	}
+	return
+}
+
+func GetNotificationSlice(notifications []*models.Notification) (n []Notification) {
+	n = make([]Notification, len(notifications))
+	for i, notification := range notifications {
+		n[i].convert(notification)
+	}
+	return
+}
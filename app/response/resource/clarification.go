@@ -0,0 +1,73 @@
+package resource
+
+import (
+	"time"
+
+	"github.com/EduOJ/backend/database/models"
+)
+
+type ClarificationReply struct {
+	ID uint `json:"id"`
+
+	UserID uint  `json:"user_id"`
+	User   *User `json:"user"`
+
+	Content string `json:"content"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Clarification struct {
+	ID uint `json:"id"`
+
+	ProblemSetID uint  `json:"problem_set_id"`
+	ProblemID    uint  `json:"problem_id"`
+	UserID       uint  `json:"user_id"`
+	User         *User `json:"user"`
+
+	Content string `json:"content"`
+	Public  bool   `json:"public"`
+
+	Replies []ClarificationReply `json:"replies"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (r *ClarificationReply) convert(reply *models.ClarificationReply) {
+	r.ID = reply.ID
+	r.UserID = reply.UserID
+	r.User = GetUser(reply.User)
+	r.Content = reply.Content
+	r.CreatedAt = reply.CreatedAt
+}
+
+func (c *Clarification) convert(clarification *models.Clarification) {
+	c.ID = clarification.ID
+	c.ProblemSetID = clarification.ProblemSetID
+	c.ProblemID = clarification.ProblemID
+	c.UserID = clarification.UserID
+	c.User = GetUser(clarification.User)
+	c.Content = clarification.Content
+	c.Public = clarification.Public
+	c.Replies = make([]ClarificationReply, len(clarification.Replies))
+	for i, reply := range clarification.Replies {
+		c.Replies[i].convert(&reply)
+	}
+	c.CreatedAt = clarification.CreatedAt
+	c.UpdatedAt = clarification.UpdatedAt
+}
+
+func GetClarification(clarification *models.Clarification) *Clarification {
+	c := Clarification{}
+	c.convert(clarification)
+	return &c
+}
+
+func GetClarificationSlice(clarifications []*models.Clarification) (c []Clarification) {
+	c = make([]Clarification, len(clarifications))
+	for i, clarification := range clarifications {
+		c[i].convert(clarification)
+	}
+	return
+}
@@ -1,6 +1,10 @@
 package resource
 
-import "github.com/EduOJ/backend/database/models"
+import (
+	"time"
+
+	"github.com/EduOJ/backend/database/models"
+)
 
 type Class struct {
 	ID uint `json:"id"`
@@ -22,11 +26,32 @@ type ClassDetail struct {
 	Description string `json:"description"`
 	InviteCode  string `json:"invite_code"`
 
+	InviteCodeExpireAt *time.Time `json:"invite_code_expire_at"`
+	InviteCodeMaxUses  uint       `json:"invite_code_max_uses"`
+	InviteCodeUses     uint       `json:"invite_code_uses"`
+	RequireApproval    bool       `json:"require_approval"`
+
 	Managers    []User              `json:"managers"`
 	Students    []User              `json:"students"`
+	Assistants  []User              `json:"assistants"`
 	ProblemSets []ProblemSetSummary `json:"problem_sets"`
 }
 
+type ClassJoinRequest struct {
+	ID uint `json:"id"`
+
+	ClassID uint  `json:"class_id"`
+	UserID  uint  `json:"user_id"`
+	User    *User `json:"user"`
+
+	Status     string `json:"status"`
+	OperatorID uint   `json:"operator_id"`
+	Operator   *User  `json:"operator"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
 func (c *Class) convert(class *models.Class) {
 	c.ID = class.ID
 	c.Name = class.Name
@@ -43,8 +68,13 @@ func (c *ClassDetail) convert(class *models.Class) {
 	c.CourseName = class.CourseName
 	c.Description = class.Description
 	c.InviteCode = class.InviteCode
+	c.InviteCodeExpireAt = class.InviteCodeExpireAt
+	c.InviteCodeMaxUses = class.InviteCodeMaxUses
+	c.InviteCodeUses = class.InviteCodeUses
+	c.RequireApproval = class.RequireApproval
 	c.Managers = GetUserSlice(class.Managers)
 	c.Students = GetUserSlice(class.Students)
+	c.Assistants = GetUserSlice(class.Assistants)
 	c.ProblemSets = GetProblemSetSummarySlice(class.ProblemSets)
 }
 
@@ -67,3 +97,29 @@ func GetClassSlice(classes []models.Class) (c []Class) {
 	}
 	return
 }
+
+func (r *ClassJoinRequest) convert(joinRequest *models.ClassJoinRequest) {
+	r.ID = joinRequest.ID
+	r.ClassID = joinRequest.ClassID
+	r.UserID = joinRequest.UserID
+	r.User = GetUser(joinRequest.User)
+	r.Status = joinRequest.Status
+	r.OperatorID = joinRequest.OperatorID
+	r.Operator = GetUser(joinRequest.Operator)
+	r.CreatedAt = joinRequest.CreatedAt
+	r.UpdatedAt = joinRequest.UpdatedAt
+}
+
+func GetClassJoinRequest(joinRequest *models.ClassJoinRequest) *ClassJoinRequest {
+	r := ClassJoinRequest{}
+	r.convert(joinRequest)
+	return &r
+}
+
+func GetClassJoinRequestSlice(joinRequests []*models.ClassJoinRequest) (r []ClassJoinRequest) {
+	r = make([]ClassJoinRequest, len(joinRequests))
+	for i, joinRequest := range joinRequests {
+		r[i].convert(joinRequest)
+	}
+	return
+}
@@ -0,0 +1,44 @@
+package resource
+
+type DailySubmissionCount struct {
+	Date  string `json:"date"`
+	Count uint   `json:"count"`
+}
+
+type StatusCount struct {
+	Status string `json:"status"`
+	Count  uint   `json:"count"`
+}
+
+type ProblemStatistics struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+
+	SubmissionCount uint `json:"submission_count"`
+	// AcceptedCount is the number of students who solved the problem.
+	AcceptedCount uint `json:"accepted_count"`
+	// AverageAttempts is the average number of submissions before and including the first accepted one.
+	AverageAttempts float64       `json:"average_attempts"`
+	FailureStatuses []StatusCount `json:"failure_statuses"`
+}
+
+type ProblemSetStatistics struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+
+	SubmissionCount  uint                   `json:"submission_count"`
+	DailySubmissions []DailySubmissionCount `json:"daily_submissions"`
+	// ScoreDistribution counts the students by their score percentage,
+	// in 10 buckets of [0, 10), [10, 20), ..., [90, 100].
+	ScoreDistribution []uint  `json:"score_distribution"`
+	CompletedCount    uint    `json:"completed_count"`
+	CompletionRate    float64 `json:"completion_rate"`
+
+	Problems []ProblemStatistics `json:"problems"`
+}
+
+type ClassStatistics struct {
+	StudentCount     uint                   `json:"student_count"`
+	ProblemSets      []ProblemSetStatistics `json:"problem_sets"`
+	InactiveStudents []User                 `json:"inactive_students"`
+}
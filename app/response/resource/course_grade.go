@@ -0,0 +1,76 @@
+package resource
+
+import (
+	"encoding/json"
+
+	"github.com/EduOJ/backend/database/models"
+	"github.com/pkg/errors"
+)
+
+type GradeWeight struct {
+	ProblemSetID uint    `json:"problem_set_id"`
+	ProblemID    uint    `json:"problem_id"`
+	Weight       float64 `json:"weight"`
+}
+
+type CourseGradeItem struct {
+	ProblemSetID uint    `json:"problem_set_id"`
+	Score        float64 `json:"score"`
+	Weight       float64 `json:"weight"`
+	Dropped      bool    `json:"dropped"`
+}
+
+type CourseGrade struct {
+	ID uint `json:"id"`
+
+	UserID  uint  `json:"user_id"`
+	User    *User `json:"user"`
+	ClassID uint  `json:"class_id"`
+
+	Detail []CourseGradeItem `json:"detail"`
+	Total  float64           `json:"total"`
+}
+
+func (w *GradeWeight) convert(weight *models.GradeWeight) {
+	w.ProblemSetID = weight.ProblemSetID
+	w.ProblemID = weight.ProblemID
+	w.Weight = weight.Weight
+}
+
+func (g *CourseGrade) convert(courseGrade *models.CourseGrade) {
+	g.ID = courseGrade.ID
+	g.UserID = courseGrade.UserID
+	g.User = GetUser(courseGrade.User)
+	g.ClassID = courseGrade.ClassID
+	var items []models.CourseGradeItem
+	if err := json.Unmarshal(courseGrade.Detail, &items); err != nil {
+		panic(errors.Wrap(err, "could not unmarshal json for converting course grade"))
+	}
+	g.Detail = make([]CourseGradeItem, len(items))
+	for i, item := range items {
+		g.Detail[i] = CourseGradeItem(item)
+	}
+	g.Total = courseGrade.Total
+}
+
+func GetGradeWeightSlice(weights []models.GradeWeight) (w []GradeWeight) {
+	w = make([]GradeWeight, len(weights))
+	for i, weight := range weights {
+		w[i].convert(&weight)
+	}
+	return
+}
+
+func GetCourseGrade(courseGrade *models.CourseGrade) *CourseGrade {
+	g := CourseGrade{}
+	g.convert(courseGrade)
+	return &g
+}
+
+func GetCourseGradeSlice(courseGrades []*models.CourseGrade) (g []CourseGrade) {
+	g = make([]CourseGrade, len(courseGrades))
+	for i, courseGrade := range courseGrades {
+		g[i].convert(courseGrade)
+	}
+	return
+}
@@ -0,0 +1,40 @@
+package resource
+
+import (
+	"time"
+
+	"github.com/EduOJ/backend/database/models"
+)
+
+type ExamViolation struct {
+	ID uint `json:"id"`
+
+	ProblemSetID uint  `json:"problem_set_id"`
+	UserID       uint  `json:"user_id"`
+	User         *User `json:"user"`
+
+	Type string `json:"type"`
+	IP   string `json:"ip"`
+	Path string `json:"path"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (v *ExamViolation) convert(violation *models.ExamViolation) {
+	v.ID = violation.ID
+	v.ProblemSetID = violation.ProblemSetID
+	v.UserID = violation.UserID
+	v.User = GetUser(violation.User)
+	v.Type = violation.Type
+	v.IP = violation.IP
+	v.Path = violation.Path
+	v.CreatedAt = violation.CreatedAt
+}
+
+func GetExamViolationSlice(violations []*models.ExamViolation) (v []ExamViolation) {
+	v = make([]ExamViolation, len(violations))
+	for i, violation := range violations {
+		v[i].convert(violation)
+	}
+	return
+}
@@ -0,0 +1,52 @@
+package resource
+
+import (
+	"time"
+
+	"github.com/EduOJ/backend/database/models"
+)
+
+type GradeChange struct {
+	ID uint `json:"id"`
+
+	ClassID      uint  `json:"class_id"`
+	ProblemSetID uint  `json:"problem_set_id"`
+	ProblemID    uint  `json:"problem_id"`
+	UserID       uint  `json:"user_id"`
+	User         *User `json:"user"`
+
+	OldScore     uint   `json:"old_score"`
+	NewScore     uint   `json:"new_score"`
+	Cause        string `json:"cause"`
+	SubmissionID uint   `json:"submission_id"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (g *GradeChange) convert(change *models.GradeChange) {
+	g.ID = change.ID
+	g.ClassID = change.ClassID
+	g.ProblemSetID = change.ProblemSetID
+	g.ProblemID = change.ProblemID
+	g.UserID = change.UserID
+	g.User = GetUser(change.User)
+	g.OldScore = change.OldScore
+	g.NewScore = change.NewScore
+	g.Cause = change.Cause
+	g.SubmissionID = change.SubmissionID
+	g.CreatedAt = change.CreatedAt
+}
+
+func GetGradeChange(change *models.GradeChange) *GradeChange {
+	g := GradeChange{}
+	g.convert(change)
+	return &g
+}
+
+func GetGradeChangeSlice(changes []*models.GradeChange) (g []GradeChange) {
+	g = make([]GradeChange, len(changes))
+	for i, change := range changes {
+		g[i].convert(change)
+	}
+	return
+}
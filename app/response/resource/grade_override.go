@@ -0,0 +1,53 @@
+package resource
+
+import (
+	"time"
+
+	"github.com/EduOJ/backend/database/models"
+)
+
+type GradeOverride struct {
+	ID uint `json:"id"`
+
+	ProblemSetID uint  `json:"problem_set_id"`
+	ProblemID    uint  `json:"problem_id"`
+	UserID       uint  `json:"user_id"`
+	User         *User `json:"user"`
+
+	Score   uint   `json:"score"`
+	Removed bool   `json:"removed"`
+	Reason  string `json:"reason"`
+
+	OperatorID uint  `json:"operator_id"`
+	Operator   *User `json:"operator"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (o *GradeOverride) convert(override *models.GradeOverride) {
+	o.ID = override.ID
+	o.ProblemSetID = override.ProblemSetID
+	o.ProblemID = override.ProblemID
+	o.UserID = override.UserID
+	o.User = GetUser(override.User)
+	o.Score = override.Score
+	o.Removed = override.Removed
+	o.Reason = override.Reason
+	o.OperatorID = override.OperatorID
+	o.Operator = GetUser(override.Operator)
+	o.CreatedAt = override.CreatedAt
+}
+
+func GetGradeOverride(override *models.GradeOverride) *GradeOverride {
+	o := GradeOverride{}
+	o.convert(override)
+	return &o
+}
+
+func GetGradeOverrideSlice(overrides []*models.GradeOverride) (o []GradeOverride) {
+	o = make([]GradeOverride, len(overrides))
+	for i, override := range overrides {
+		o[i].convert(override)
+	}
+	return
+}
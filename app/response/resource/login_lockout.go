@@ -0,0 +1,49 @@
+package resource
+
+import (
+	"time"
+
+	"github.com/EduOJ/backend/database/models"
+)
+
+type LoginLockout struct {
+	ID uint `json:"id"`
+
+	Type   string `json:"type"`
+	UserID uint   `json:"user_id"`
+	// User is nil for ip lockouts and lockouts of users not existing locally.
+	User     *User  `json:"user"`
+	Username string `json:"username"`
+	IP       string `json:"ip"`
+	Failures int64  `json:"failures"`
+
+	ExpiresAt    time.Time  `json:"expires_at"`
+	UnlockedByID *uint      `json:"unlocked_by_id"`
+	UnlockedAt   *time.Time `json:"unlocked_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (l *LoginLockout) convert(lockout *models.LoginLockout) {
+	l.ID = lockout.ID
+	l.Type = lockout.Type
+	l.UserID = lockout.UserID
+	if lockout.User != nil {
+		l.User = GetUser(lockout.User)
+	}
+	l.Username = lockout.Username
+	l.IP = lockout.IP
+	l.Failures = lockout.Failures
+	l.ExpiresAt = lockout.ExpiresAt
+	l.UnlockedByID = lockout.UnlockedByID
+	l.UnlockedAt = lockout.UnlockedAt
+	l.CreatedAt = lockout.CreatedAt
+}
+
+func GetLoginLockoutSlice(lockouts []models.LoginLockout) (l []LoginLockout) {
+	l = make([]LoginLockout, len(lockouts))
+	for i := range lockouts {
+		l[i].convert(&lockouts[i])
+	}
+	return
+}
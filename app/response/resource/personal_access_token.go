@@ -0,0 +1,40 @@
+package resource
+
+import (
+	"time"
+
+	"github.com/EduOJ/backend/database/models"
+)
+
+type PersonalAccessToken struct {
+	ID     uint     `json:"id"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (t *PersonalAccessToken) convert(token *models.PersonalAccessToken) {
+	t.ID = token.ID
+	t.Name = token.Name
+	t.Scopes = token.Scopes
+	t.ExpiresAt = token.ExpiresAt
+	t.LastUsedAt = token.LastUsedAt
+	t.CreatedAt = token.CreatedAt
+}
+
+func GetPersonalAccessToken(token *models.PersonalAccessToken) *PersonalAccessToken {
+	t := PersonalAccessToken{}
+	t.convert(token)
+	return &t
+}
+
+func GetPersonalAccessTokenSlice(tokens []models.PersonalAccessToken) (t []PersonalAccessToken) {
+	t = make([]PersonalAccessToken, len(tokens))
+	for i := range tokens {
+		t[i].convert(&tokens[i])
+	}
+	return
+}
@@ -0,0 +1,112 @@
+package resource
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/EduOJ/backend/database/models"
+	"github.com/pkg/errors"
+)
+
+type PlagiarismReport struct {
+	ID uint `json:"id"`
+
+	ProblemSetID uint  `json:"problem_set_id"`
+	OperatorID   uint  `json:"operator_id"`
+	Operator     *User `json:"operator"`
+
+	Status          string `json:"status"`
+	Message         string `json:"message"`
+	SubmissionCount uint   `json:"submission_count"`
+
+	FinishedAt *time.Time `json:"finished_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type PlagiarismReportDetail struct {
+	PlagiarismReport
+	Pairs []PlagiarismPair `json:"pairs"`
+}
+
+type PlagiarismRegion struct {
+	FirstStartLine  uint `json:"first_start_line"`
+	FirstEndLine    uint `json:"first_end_line"`
+	SecondStartLine uint `json:"second_start_line"`
+	SecondEndLine   uint `json:"second_end_line"`
+}
+
+type PlagiarismPair struct {
+	ID uint `json:"id"`
+
+	ProblemID uint `json:"problem_id"`
+
+	FirstSubmissionID  uint  `json:"first_submission_id"`
+	FirstUserID        uint  `json:"first_user_id"`
+	FirstUser          *User `json:"first_user"`
+	SecondSubmissionID uint  `json:"second_submission_id"`
+	SecondUserID       uint  `json:"second_user_id"`
+	SecondUser         *User `json:"second_user"`
+
+	Similarity float64            `json:"similarity"`
+	Regions    []PlagiarismRegion `json:"regions"`
+}
+
+func (r *PlagiarismReport) convert(report *models.PlagiarismReport) {
+	r.ID = report.ID
+	r.ProblemSetID = report.ProblemSetID
+	r.OperatorID = report.OperatorID
+	r.Operator = GetUser(report.Operator)
+	r.Status = report.Status
+	r.Message = report.Message
+	r.SubmissionCount = report.SubmissionCount
+	r.FinishedAt = report.FinishedAt
+	r.CreatedAt = report.CreatedAt
+}
+
+func (r *PlagiarismReportDetail) convert(report *models.PlagiarismReport) {
+	r.PlagiarismReport.convert(report)
+	r.Pairs = make([]PlagiarismPair, len(report.Pairs))
+	for i := range report.Pairs {
+		r.Pairs[i].convert(&report.Pairs[i])
+	}
+}
+
+func (p *PlagiarismPair) convert(pair *models.PlagiarismPair) {
+	p.ID = pair.ID
+	p.ProblemID = pair.ProblemID
+	p.FirstSubmissionID = pair.FirstSubmissionID
+	p.FirstUserID = pair.FirstUserID
+	p.FirstUser = GetUser(pair.FirstUser)
+	p.SecondSubmissionID = pair.SecondSubmissionID
+	p.SecondUserID = pair.SecondUserID
+	p.SecondUser = GetUser(pair.SecondUser)
+	p.Similarity = pair.Similarity
+	var regions []models.PlagiarismRegion
+	if err := json.Unmarshal(pair.Regions, &regions); err != nil {
+		panic(errors.Wrap(err, "could not unmarshal json for converting plagiarism pair"))
+	}
+	p.Regions = make([]PlagiarismRegion, len(regions))
+	for i, region := range regions {
+		p.Regions[i] = PlagiarismRegion(region)
+	}
+}
+
+func GetPlagiarismReport(report *models.PlagiarismReport) *PlagiarismReport {
+	r := PlagiarismReport{}
+	r.convert(report)
+	return &r
+}
+
+func GetPlagiarismReportDetail(report *models.PlagiarismReport) *PlagiarismReportDetail {
+	r := PlagiarismReportDetail{}
+	r.convert(report)
+	return &r
+}
+
+func GetPlagiarismReportSlice(reports []*models.PlagiarismReport) (r []PlagiarismReport) {
+	r = make([]PlagiarismReport, len(reports))
+	for i, report := range reports {
+		r[i].convert(report)
+	}
+	return
+}
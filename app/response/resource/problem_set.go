@@ -15,8 +15,9 @@ type ProblemSetWithGrades struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 
-	Problems []ProblemSummary `json:"problems"`
-	Grades   []Grade          `json:"grades"`
+	Problems       []ProblemSummary      `json:"problems"`
+	ProblemEntries []ProblemInProblemSet `json:"problem_entries"`
+	Grades         []Grade               `json:"grades"`
 
 	StartTime time.Time `json:"start_time"`
 	EndTime   time.Time `json:"end_time"`
@@ -29,10 +30,16 @@ type ProblemSetDetail struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 
-	Problems []ProblemSummary `json:"problems"`
+	Problems       []ProblemSummary      `json:"problems"`
+	ProblemEntries []ProblemInProblemSet `json:"problem_entries"`
 
 	StartTime time.Time `json:"start_time"`
 	EndTime   time.Time `json:"end_time"`
+
+	ExamMode        bool     `json:"exam_mode"`
+	AllowedIPRanges []string `json:"allowed_ip_ranges"`
+	AllowUpsolve    bool     `json:"allow_upsolve"`
+	LanguageAllowed []string `json:"language_allowed"`
 }
 
 type ProblemSet struct {
@@ -42,10 +49,15 @@ type ProblemSet struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 
-	Problems []ProblemSummary `json:"problems"`
+	Problems       []ProblemSummary      `json:"problems"`
+	ProblemEntries []ProblemInProblemSet `json:"problem_entries"`
 
 	StartTime time.Time `json:"start_time"`
 	EndTime   time.Time `json:"end_time"`
+
+	ExamMode        bool     `json:"exam_mode"`
+	AllowUpsolve    bool     `json:"allow_upsolve"`
+	LanguageAllowed []string `json:"language_allowed"`
 }
 
 type ProblemSetSummary struct {
@@ -59,6 +71,13 @@ type ProblemSetSummary struct {
 	EndTime   time.Time `json:"end_time"`
 }
 
+type ProblemInProblemSet struct {
+	ProblemID uint   `json:"problem_id"`
+	Order     uint   `json:"order"`
+	Label     string `json:"label"`
+	FullMark  uint   `json:"full_mark"`
+}
+
 type Grade struct {
 	ID uint `json:"id"`
 
@@ -68,6 +87,8 @@ type Grade struct {
 
 	Detail string `json:"detail"`
 	Total  uint   `json:"total"`
+
+	UpsolveDetail string `json:"upsolve_detail"`
 }
 
 func (p *ProblemSetWithGrades) convert(problemSet *models.ProblemSet) {
@@ -76,6 +97,7 @@ func (p *ProblemSetWithGrades) convert(problemSet *models.ProblemSet) {
 	p.Name = problemSet.Name
 	p.Description = problemSet.Description
 	p.Problems = GetProblemSummarySlice(problemSet.Problems, make([]sql.NullBool, len(problemSet.Problems)))
+	p.ProblemEntries = GetProblemInProblemSetSlice(problemSet.ProblemEntries)
 	p.Grades = GetGradeSlice(problemSet.Grades)
 	p.StartTime = problemSet.StartTime
 	p.EndTime = problemSet.EndTime
@@ -87,8 +109,13 @@ func (p *ProblemSetDetail) convert(problemSet *models.ProblemSet) {
 	p.Name = problemSet.Name
 	p.Description = problemSet.Description
 	p.Problems = GetProblemSummarySlice(problemSet.Problems, make([]sql.NullBool, len(problemSet.Problems)))
+	p.ProblemEntries = GetProblemInProblemSetSlice(problemSet.ProblemEntries)
 	p.StartTime = problemSet.StartTime
 	p.EndTime = problemSet.EndTime
+	p.ExamMode = problemSet.ExamMode
+	p.AllowedIPRanges = problemSet.AllowedIPRanges()
+	p.AllowUpsolve = problemSet.AllowUpsolve
+	p.LanguageAllowed = problemSet.Languages()
 }
 
 func (p *ProblemSet) convert(problemSet *models.ProblemSet) {
@@ -97,8 +124,12 @@ func (p *ProblemSet) convert(problemSet *models.ProblemSet) {
 	p.Name = problemSet.Name
 	p.Description = problemSet.Description
 	p.Problems = GetProblemSummarySlice(problemSet.Problems, make([]sql.NullBool, len(problemSet.Problems)))
+	p.ProblemEntries = GetProblemInProblemSetSlice(problemSet.ProblemEntries)
 	p.StartTime = problemSet.StartTime
 	p.EndTime = problemSet.EndTime
+	p.ExamMode = problemSet.ExamMode
+	p.AllowUpsolve = problemSet.AllowUpsolve
+	p.LanguageAllowed = problemSet.Languages()
 }
 
 func (p *ProblemSetSummary) convert(problemSet *models.ProblemSet) {
@@ -150,6 +181,21 @@ func GetProblemSetSlice(problemSetSlice []*models.ProblemSet) (ps []ProblemSet)
 	return
 }
 
+func (p *ProblemInProblemSet) convert(entry *models.ProblemInProblemSet) {
+	p.ProblemID = entry.ProblemID
+	p.Order = entry.Order
+	p.Label = entry.Label
+	p.FullMark = entry.FullMark
+}
+
+func GetProblemInProblemSetSlice(entries []*models.ProblemInProblemSet) (p []ProblemInProblemSet) {
+	p = make([]ProblemInProblemSet, len(entries))
+	for i, entry := range entries {
+		p[i].convert(entry)
+	}
+	return
+}
+
 func (g *Grade) convert(grade *models.Grade) {
 	g.ID = grade.ID
 	g.UserID = grade.UserID
@@ -161,6 +207,10 @@ func (g *Grade) convert(grade *models.Grade) {
 	}
 	g.Detail = string(b)
 	g.Total = grade.Total
+	g.UpsolveDetail = "{}"
+	if grade.UpsolveDetail != nil {
+		g.UpsolveDetail = string(grade.UpsolveDetail)
+	}
 }
 
 func GetGrade(grade *models.Grade) *Grade {
@@ -0,0 +1,37 @@
+package resource
+
+import (
+	"time"
+
+	"github.com/EduOJ/backend/database/models"
+)
+
+type Session struct {
+	ID         uint   `json:"id"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`
+	RememberMe bool   `json:"remember_me"`
+	// Current is whether the session is the one making the request.
+	Current bool `json:"current"`
+
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+func (s *Session) convert(token *models.Token, currentID uint) {
+	s.ID = token.ID
+	s.UserAgent = token.UserAgent
+	s.IP = token.IP
+	s.RememberMe = token.RememberMe
+	s.Current = token.ID == currentID
+	s.CreatedAt = token.CreatedAt
+	s.LastUsedAt = token.UpdatedAt
+}
+
+func GetSessionSlice(tokens []models.Token, currentID uint) (s []Session) {
+	s = make([]Session, len(tokens))
+	for i := range tokens {
+		s[i].convert(&tokens[i], currentID)
+	}
+	return
+}
@@ -0,0 +1,79 @@
+package resource
+
+import (
+	"time"
+
+	"github.com/EduOJ/backend/database/models"
+)
+
+type StudentProblemGrade struct {
+	ProblemID uint   `json:"problem_id"`
+	Label     string `json:"label"`
+	FullMark  uint   `json:"full_mark"`
+
+	Score           uint           `json:"score"`
+	BestScore       uint           `json:"best_score"`
+	Attempts        int64          `json:"attempts"`
+	FirstAcceptedAt *time.Time     `json:"first_accepted_at"`
+	Override        *GradeOverride `json:"override"`
+	UpsolveScore    *uint          `json:"upsolve_score"`
+}
+
+type StudentProblemSetGrade struct {
+	ProblemSetID uint      `json:"problem_set_id"`
+	Name         string    `json:"name"`
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+
+	Total    uint                  `json:"total"`
+	FullMark uint                  `json:"full_mark"`
+	Problems []StudentProblemGrade `json:"problems"`
+}
+
+type StudentClassGrade struct {
+	ClassID     uint                     `json:"class_id"`
+	Name        string                   `json:"name"`
+	CourseName  string                   `json:"course_name"`
+	ProblemSets []StudentProblemSetGrade `json:"problem_sets"`
+}
+
+func (g *StudentProblemGrade) convert(grade *models.StudentProblemGrade) {
+	g.ProblemID = grade.ProblemID
+	g.Label = grade.Label
+	g.FullMark = grade.FullMark
+	g.Score = grade.Score
+	g.BestScore = grade.BestScore
+	g.Attempts = grade.Attempts
+	g.FirstAcceptedAt = grade.FirstAcceptedAt
+	if grade.Override != nil {
+		g.Override = GetGradeOverride(grade.Override)
+	}
+	g.UpsolveScore = grade.UpsolveScore
+}
+
+func (g *StudentProblemSetGrade) convert(grade *models.StudentProblemSetGrade) {
+	g.ProblemSetID = grade.ProblemSet.ID
+	g.Name = grade.ProblemSet.Name
+	g.StartTime = grade.ProblemSet.StartTime
+	g.EndTime = grade.ProblemSet.EndTime
+	g.Total = grade.Total
+	g.FullMark = grade.FullMark
+	g.Problems = make([]StudentProblemGrade, len(grade.Problems))
+	for i, problem := range grade.Problems {
+		g.Problems[i].convert(problem)
+	}
+}
+
+// GetStudentClassGrade converts the standing of a student in the problem sets of a class.
+func GetStudentClassGrade(class *models.Class, grades []*models.StudentProblemSetGrade) *StudentClassGrade {
+	g := StudentClassGrade{
+		ClassID:     class.ID,
+		Name:        class.Name,
+		CourseName:  class.CourseName,
+		ProblemSets: make([]StudentProblemSetGrade, len(grades)),
+	}
+	for i, grade := range grades {
+		g.ProblemSets[i].convert(grade)
+	}
+	return &g
+}
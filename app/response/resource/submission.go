@@ -20,6 +20,8 @@ type Submission struct {
 	Score  uint   `json:"score"`
 	Status string `json:"status"`
 
+	Upsolve bool `json:"upsolve"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -35,6 +37,7 @@ func (s *Submission) convert(submission *models.Submission) {
 	s.Judged = submission.Judged
 	s.Score = submission.Score
 	s.Status = submission.Status
+	s.Upsolve = submission.Upsolve
 	s.CreatedAt = submission.CreatedAt
 	s.UpdatedAt = submission.UpdatedAt
 }
@@ -69,6 +72,10 @@ type SubmissionDetail struct {
 	Score  uint   `json:"score"`
 	Status string `json:"status"`
 
+	Upsolve bool `json:"upsolve"`
+
+	Feedback string `json:"feedback"`
+
 	Runs []Run `json:"runs"`
 
 	CreatedAt time.Time `json:"created_at"`
@@ -88,6 +95,8 @@ func (s *SubmissionDetail) convert(submission *models.Submission) {
 	s.Judged = submission.Judged
 	s.Score = submission.Score
 	s.Status = submission.Status
+	s.Upsolve = submission.Upsolve
+	s.Feedback = submission.Feedback
 	s.Runs = GetRunSlice(submission.Runs)
 	s.CreatedAt = submission.CreatedAt
 	s.UpdatedAt = submission.UpdatedAt
@@ -0,0 +1,67 @@
+package resource
+
+import (
+	"time"
+
+	"github.com/EduOJ/backend/database/models"
+)
+
+type SubmissionComment struct {
+	ID uint `json:"id"`
+
+	SubmissionID uint  `json:"submission_id"`
+	UserID       uint  `json:"user_id"`
+	User         *User `json:"user"`
+	ParentID     uint  `json:"parent_id"`
+
+	StartLine uint   `json:"start_line"`
+	EndLine   uint   `json:"end_line"`
+	Content   string `json:"content"`
+
+	// Replies is only filled in for the top-level comments of a thread.
+	Replies []SubmissionComment `json:"replies,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (s *SubmissionComment) convert(comment *models.SubmissionComment) {
+	s.ID = comment.ID
+	s.SubmissionID = comment.SubmissionID
+	s.UserID = comment.UserID
+	s.User = GetUser(comment.User)
+	s.ParentID = comment.ParentID
+	s.StartLine = comment.StartLine
+	s.EndLine = comment.EndLine
+	s.Content = comment.Content
+	s.CreatedAt = comment.CreatedAt
+}
+
+func GetSubmissionComment(comment *models.SubmissionComment) *SubmissionComment {
+	s := SubmissionComment{}
+	s.convert(comment)
+	return &s
+}
+
+// GetSubmissionCommentThreads groups the comments into threads, with the replies of each
+// top-level comment in the order of the given comments.
+func GetSubmissionCommentThreads(comments []*models.SubmissionComment) []SubmissionComment {
+	threads := make([]SubmissionComment, 0)
+	index := make(map[uint]int)
+	for _, comment := range comments {
+		if comment.ParentID == 0 {
+			index[comment.ID] = len(threads)
+			thread := SubmissionComment{}
+			thread.convert(comment)
+			thread.Replies = make([]SubmissionComment, 0)
+			threads = append(threads, thread)
+		}
+	}
+	for _, comment := range comments {
+		if i, ok := index[comment.ParentID]; ok {
+			reply := SubmissionComment{}
+			reply.convert(comment)
+			threads[i].Replies = append(threads[i].Replies, reply)
+		}
+	}
+	return threads
+}
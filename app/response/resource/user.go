@@ -20,6 +20,8 @@ type UserForAdmin struct {
 	Nickname string `json:"nickname"`
 	// Email is the user's email.
 	Email string `json:"email"`
+	// EmailNotification is whether notifications are also sent to the user's email.
+	EmailNotification bool `json:"email_notification"`
 
 	// Role is the user's role, and is used to obtain the permissions of a user.
 	Roles []Role `json:"roles"`
@@ -43,6 +45,7 @@ func (p *UserForAdmin) convert(user *models.User) {
 	p.Username = user.Username
 	p.Nickname = user.Nickname
 	p.Email = user.Email
+	p.EmailNotification = user.EmailNotification
 	p.Roles = GetRoleSlice(user.Roles)
 }
 
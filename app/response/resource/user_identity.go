@@ -0,0 +1,38 @@
+package resource
+
+import (
+	"time"
+
+	"github.com/EduOJ/backend/database/models"
+)
+
+type UserIdentity struct {
+	ID       uint   `json:"id"`
+	Provider string `json:"provider"`
+	Subject  string `json:"subject"`
+	Email    string `json:"email"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (i *UserIdentity) convert(identity *models.UserIdentity) {
+	i.ID = identity.ID
+	i.Provider = identity.Provider
+	i.Subject = identity.Subject
+	i.Email = identity.Email
+	i.CreatedAt = identity.CreatedAt
+}
+
+func GetUserIdentity(identity *models.UserIdentity) *UserIdentity {
+	i := UserIdentity{}
+	i.convert(identity)
+	return &i
+}
+
+func GetUserIdentitySlice(identities []models.UserIdentity) (i []UserIdentity) {
+	i = make([]UserIdentity, len(identities))
+	for j := range identities {
+		i[j].convert(&identities[j])
+	}
+	return
+}
@@ -0,0 +1,29 @@
+package response
+
+import "github.com/EduOJ/backend/app/response/resource"
+
+type GetMySessionsResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		Sessions []resource.Session `json:"sessions"`
+	} `json:"data"`
+}
+
+type DeleteMySessionResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    interface{} `json:"data"`
+}
+
+type DeleteMyOtherSessionsResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    interface{} `json:"data"`
+}
+
+type AdminDeleteUserSessionsResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    interface{} `json:"data"`
+}
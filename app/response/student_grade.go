@@ -0,0 +1,11 @@
+package response
+
+import "github.com/EduOJ/backend/app/response/resource"
+
+type GetMyGradesResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		Classes []*resource.StudentClassGrade `json:"classes"`
+	} `json:"data"`
+}
@@ -32,3 +32,20 @@ type GetSubmissionsResponse struct {
 		Next        *string               `json:"next"`
 	} `json:"data"`
 }
+
+type GetSubmissionCodeResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		URL      string                       `json:"url"`
+		Comments []resource.SubmissionComment `json:"comments"`
+	} `json:"data"`
+}
+
+type CreateSubmissionCommentResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		*resource.SubmissionComment `json:"comment"`
+	} `json:"data"`
+}
@@ -0,0 +1,44 @@
+package response
+
+type GetTwoFactorResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		Enabled bool `json:"enabled"`
+		// Required is whether the user is required to enable two-factor authentication to use manager permissions.
+		Required              bool  `json:"required"`
+		RecoveryCodeRemaining int64 `json:"recovery_code_remaining"`
+	} `json:"data"`
+}
+
+type BeginTOTPEnrolmentResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		Secret string `json:"secret"`
+		// ProvisioningURI is the otpauth uri to be shown as a QR code.
+		ProvisioningURI string `json:"provisioning_uri"`
+	} `json:"data"`
+}
+
+type FinishTOTPEnrolmentResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	} `json:"data"`
+}
+
+type DisableTwoFactorResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    interface{} `json:"data"`
+}
+
+type RegenerateRecoveryCodesResponse struct {
+	Message string      `json:"message"`
+	Error   interface{} `json:"error"`
+	Data    struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	} `json:"data"`
+}
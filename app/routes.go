@@ -16,6 +16,7 @@ import (
 
 func Register(e *echo.Echo) {
 	utils.InitOrigin()
+	e.IPExtractor = utils.GetIPExtractor()
 
 	e.GET("/doc/*", echoSwagger.WrapHandler)
 
@@ -42,6 +43,10 @@ func Register(e *echo.Echo) {
 	auth.POST("/auth/login", controller.Login).Name = "auth.login"
 	auth.GET("/auth/login/webauthn", controller.BeginLogin).Name = "auth.webauthn.beginLogin"
 	auth.POST("/auth/login/webauthn", controller.FinishLogin).Name = "auth.webauthn.finishLogin"
+	auth.POST("/auth/login/two_factor", controller.FinishTwoFactorLogin).Name = "auth.twoFactor.finishLogin"
+	auth.GET("/auth/oidc/providers", controller.GetOIDCProviders).Name = "auth.oidc.getProviders"
+	auth.GET("/auth/oidc/:provider/login", controller.BeginOIDCLogin).Name = "auth.oidc.beginLogin"
+	auth.POST("/auth/oidc/:provider/callback", controller.FinishOIDCLogin).Name = "auth.oidc.finishLogin"
 	auth.POST("/auth/register", controller.Register).Name = "auth.register"
 	auth.GET("/auth/email_registered", controller.EmailRegistered).Name = "auth.emailRegistered"
 	auth.POST("/auth/password_reset", controller.RequestResetPassword).Name = "auth.resetPassword"
@@ -70,11 +75,39 @@ func Register(e *echo.Echo) {
 	user.GET("/user/:id/problem_info", controller.GetUserProblemInfo).Name = "user.getUserProblemInfo"
 	user.GET("/users", controller.GetUsers).Name = "user.getUsers"
 	user.POST("/user/change_password", controller.ChangePassword).Name = "user.changePassword"
+	user.GET("/user/me/notifications", controller.GetNotifications).Name = "user.getNotifications"
+	user.PUT("/user/me/notifications/read", controller.ReadNotifications).Name = "user.readNotifications"
+	user.PUT("/user/me/notification_settings", controller.UpdateNotificationSettings).Name = "user.updateNotificationSettings"
+	user.GET("/user/me/grades", controller.GetMyGrades).Name = "user.getMyGrades"
+	user.GET("/user/me/grade_changes", controller.GetMyGradeChanges).Name = "user.getMyGradeChanges"
+	user.GET("/user/me/identities", controller.GetMyIdentities).Name = "user.getMyIdentities"
+	user.POST("/user/me/identities/:provider", controller.BeginLinkOIDCIdentity).Name = "user.linkIdentity"
+	user.POST("/user/me/identities/:provider/callback", controller.FinishLinkOIDCIdentity).Name = "user.finishLinkIdentity"
+	user.DELETE("/user/me/identities/:id", controller.DeleteMyIdentity).Name = "user.deleteMyIdentity"
+	user.GET("/user/me/two_factor", controller.GetTwoFactor).Name = "user.getTwoFactor"
+	user.POST("/user/me/two_factor/totp", controller.BeginTOTPEnrolment).Name = "user.beginTOTPEnrolment"
+	user.PUT("/user/me/two_factor/totp", controller.FinishTOTPEnrolment).Name = "user.finishTOTPEnrolment"
+	user.DELETE("/user/me/two_factor/totp", controller.DisableTwoFactor).Name = "user.disableTwoFactor"
+	user.POST("/user/me/two_factor/recovery_codes", controller.RegenerateRecoveryCodes).Name = "user.regenerateRecoveryCodes"
+	user.GET("/user/me/personal_access_tokens", controller.GetPersonalAccessTokens).Name = "user.getPersonalAccessTokens"
+	user.POST("/user/me/personal_access_tokens", controller.CreatePersonalAccessToken).Name = "user.createPersonalAccessToken"
+	user.DELETE("/user/me/personal_access_tokens/:id", controller.DeletePersonalAccessToken).Name = "user.deletePersonalAccessToken"
+	user.GET("/user/me/sessions", controller.GetMySessions).Name = "user.getMySessions"
+	user.DELETE("/user/me/sessions", controller.DeleteMyOtherSessions).Name = "user.deleteMyOtherSessions"
+	user.DELETE("/user/me/sessions/:id", controller.DeleteMySession).Name = "user.deleteMySession"
 	readUser.GET("/admin/user/:id", controller.AdminGetUser).Name = "admin.user.getUser"
 	readUser.GET("/admin/users", controller.AdminGetUsers).Name = "admin.user.getUsers"
 	manageUsers.POST("/admin/user", controller.AdminCreateUser).Name = "admin.user.createUser"
 	manageUsers.PUT("/admin/user/:id", controller.AdminUpdateUser).Name = "admin.user.updateUser"
 	manageUsers.DELETE("/admin/user/:id", controller.AdminDeleteUser).Name = "admin.user.deleteUser"
+	manageUsers.DELETE("/admin/user/:id/sessions", controller.AdminDeleteUserSessions).Name = "admin.user.deleteUserSessions"
+	manageUsers.DELETE("/admin/user/:id/login_lockout", controller.AdminUnlockUser).Name = "admin.user.unlockUser"
+	readUser.GET("/admin/login_lockouts", controller.AdminGetLoginLockouts).Name = "admin.user.getLoginLockouts"
+	manageUsers.DELETE("/admin/login_lockout/:id", controller.AdminUnlockLoginLockout,
+		middleware.ValidateParams(map[string]string{
+			"id": "NOT_FOUND",
+		}),
+	).Name = "admin.user.unlockLoginLockout"
 
 	// webauthn APIs
 	webauthn := api.Group("",
@@ -93,7 +126,7 @@ func Register(e *echo.Echo) {
 		middleware.ValidateParams(map[string]string{
 			"id": "NOT_FOUND",
 		}),
-		middleware.EmailVerified, middleware.AllowGuest)
+		middleware.EmailVerified, middleware.ExamRestriction, middleware.AllowGuest)
 	readProblemSecret := api.Group("",
 		middleware.ValidateParams(map[string]string{
 			"id":           "NOT_FOUND",
@@ -155,12 +188,13 @@ func Register(e *echo.Echo) {
 			"submission_id": "SUBMISSION_NOT_FOUND",
 			"problem_id":    "PROBLEM_NOT_FOUND",
 		}),
-		middleware.Logged, middleware.EmailVerified,
+		middleware.Logged, middleware.EmailVerified, middleware.ExamRestriction,
 	)
 	submission.POST("/problem/:problem_id/submission", controller.CreateSubmission).Name = "submission.createSubmission"
 	submission.GET("/submission/:id", controller.GetSubmission).Name = "submission.getSubmission"
 	submission.GET("/submissions", controller.GetSubmissions, middleware.Logged).Name = "submission.getSubmissions"
 	submission.GET("/submission/:id/code", controller.GetSubmissionCode, middleware.Logged).Name = "submission.getSubmissionCode"
+	submission.POST("/submission/:id/comments", controller.CreateSubmissionComment).Name = "submission.createSubmissionComment"
 	submission.GET("/submission/:submission_id/run/:id/output", controller.GetRunOutput, middleware.Logged).Name = "submission.getRunOutput"
 	submission.GET("/submission/:submission_id/run/:id/input", controller.GetRunInput, middleware.Logged).Name = "submission.getRunInput"
 	submission.GET("/submission/:submission_id/run/:id/compiler_output", controller.GetRunCompilerOutput, middleware.Logged).Name = "submission.getRunCompilerOutput"
@@ -199,18 +233,71 @@ func Register(e *echo.Echo) {
 			B: middleware.UnscopedPermission{P: "manage_grades"},
 		}),
 	)
+	readClassGrades := api.Group("",
+		middleware.ValidateParams(map[string]string{
+			"id": "NOT_FOUND",
+		}),
+		middleware.Logged, middleware.EmailVerified,
+		middleware.HasPermission(middleware.OrPermission{
+			A: middleware.OrPermission{
+				A: middleware.ScopedPermission{P: "manage_grades", T: "class"},
+				B: middleware.ScopedPermission{P: "read_grades", T: "class"},
+			},
+			B: middleware.OrPermission{
+				A: middleware.UnscopedPermission{P: "manage_grades"},
+				B: middleware.UnscopedPermission{P: "read_grades"},
+			},
+		}),
+	)
 	api.POST("/class", controller.CreateClass,
 		middleware.Logged, middleware.EmailVerified,
 		middleware.HasPermission(middleware.UnscopedPermission{P: "manage_class"}),
 	).Name = "class.createClass"
+	api.POST("/class/:id/clone", controller.CloneClass,
+		middleware.ValidateParams(map[string]string{
+			"id": "NOT_FOUND",
+		}),
+		middleware.Logged, middleware.EmailVerified,
+		middleware.HasPermission(middleware.UnscopedPermission{P: "manage_class"}),
+	).Name = "class.cloneClass"
 	class.GET("/class/:id", controller.GetClass).Name = "class.getClass"
 	class.POST("/class/:id/join", controller.JoinClass).Name = "class.joinClass"
 	manageClass.PUT("/class/:id", controller.UpdateClass).Name = "class.updateClass"
 	manageClass.PUT("/class/:id/invite_code", controller.RefreshInviteCode).Name = "class.refreshInviteCode"
+	manageClass.PUT("/class/:id/invite_code/settings", controller.UpdateInviteCodeSettings).Name = "class.updateInviteCodeSettings"
+	manageClass.GET("/class/:id/join_requests", controller.GetClassJoinRequests).Name = "class.getJoinRequests"
+	manageClass.POST("/class/:id/join_requests/:request_id/approve", controller.ApproveClassJoinRequest,
+		middleware.ValidateParams(map[string]string{
+			"request_id": "NOT_FOUND",
+		}),
+	).Name = "class.approveJoinRequest"
+	manageClass.POST("/class/:id/join_requests/:request_id/reject", controller.RejectClassJoinRequest,
+		middleware.ValidateParams(map[string]string{
+			"request_id": "NOT_FOUND",
+		}),
+	).Name = "class.rejectJoinRequest"
 	manageClass.POST("/class/:id/students", controller.AddStudents).Name = "class.addStudents"
+	manageClass.POST("/class/:id/students/import", controller.ImportStudents).Name = "class.importStudents"
 	manageClass.DELETE("/class/:id/students", controller.DeleteStudents).Name = "class.deleteStudents"
+	manageClass.POST("/class/:id/assistants", controller.AddAssistants).Name = "class.addAssistants"
+	manageClass.DELETE("/class/:id/assistants", controller.DeleteAssistants).Name = "class.deleteAssistants"
 	manageClass.DELETE("/class/:id", controller.DeleteClass).Name = "class.deleteClass"
-	manageClassGrades.GET("/class/:id/grades", controller.GetClassGrades).Name = "class.getClassGrades"
+	readClassGrades.GET("/class/:id/grades", controller.GetClassGrades).Name = "class.getClassGrades"
+	readClassGrades.GET("/class/:id/course_grades", controller.GetCourseGrades).Name = "class.getCourseGrades"
+	readClassGrades.GET("/class/:id/statistics", controller.GetClassStatistics).Name = "class.getClassStatistics"
+	manageClassGrades.PUT("/class/:id/grade_weights", controller.UpdateGradeWeights).Name = "class.updateGradeWeights"
+	class.GET("/class/:id/announcements", controller.GetAnnouncements).Name = "class.getAnnouncements"
+	manageClass.POST("/class/:id/announcements", controller.CreateAnnouncement).Name = "class.createAnnouncement"
+	manageClass.PUT("/class/:id/announcements/:announcement_id", controller.UpdateAnnouncement,
+		middleware.ValidateParams(map[string]string{
+			"announcement_id": "NOT_FOUND",
+		}),
+	).Name = "class.updateAnnouncement"
+	manageClass.DELETE("/class/:id/announcements/:announcement_id", controller.DeleteAnnouncement,
+		middleware.ValidateParams(map[string]string{
+			"announcement_id": "NOT_FOUND",
+		}),
+	).Name = "class.deleteAnnouncement"
 
 	// problem set APIs
 	createProblemSet := api.Group("",
@@ -240,7 +327,7 @@ func Register(e *echo.Echo) {
 			"class_id":       "CLASS_NOT_FOUND",
 			"problem_set_id": "PROBLEM_SET_NOT_FOUND",
 		}),
-		middleware.Logged, middleware.EmailVerified,
+		middleware.Logged, middleware.EmailVerified, middleware.ExamRestriction,
 		middleware.HasPermission(middleware.OrPermission{
 			A: middleware.OrPermission{
 				A: middleware.ScopedPermission{P: "manage_problem_sets", T: "class", IdFieldName: "class_id"},
@@ -260,12 +347,29 @@ func Register(e *echo.Echo) {
 			B: middleware.UnscopedPermission{P: "manage_grades"},
 		}),
 	)
-	api.GET("/class/:class_id/problem_set/:problem_set_id", controller.GetProblemSet,
+	readProblemSetGrades := api.Group("",
 		middleware.ValidateParams(map[string]string{
 			"id":       "NOT_FOUND",
 			"class_id": "CLASS_NOT_FOUND",
 		}),
 		middleware.Logged, middleware.EmailVerified,
+		middleware.HasPermission(middleware.OrPermission{
+			A: middleware.OrPermission{
+				A: middleware.ScopedPermission{P: "manage_grades", T: "class", IdFieldName: "class_id"},
+				B: middleware.ScopedPermission{P: "read_grades", T: "class", IdFieldName: "class_id"},
+			},
+			B: middleware.OrPermission{
+				A: middleware.UnscopedPermission{P: "manage_grades"},
+				B: middleware.UnscopedPermission{P: "read_grades"},
+			},
+		}),
+	)
+	api.GET("/class/:class_id/problem_set/:problem_set_id", controller.GetProblemSet,
+		middleware.ValidateParams(map[string]string{
+			"id":       "NOT_FOUND",
+			"class_id": "CLASS_NOT_FOUND",
+		}),
+		middleware.Logged, middleware.EmailVerified, middleware.ExamRestriction,
 		middleware.HasPermission(middleware.OrPermission{
 			A: middleware.OrPermission{
 				A: middleware.ScopedPermission{P: "manage_problem_sets", T: "class", IdFieldName: "class_id"},
@@ -279,7 +383,11 @@ func Register(e *echo.Echo) {
 	manageProblemSet.PUT("/class/:class_id/problem_set/:problem_set_id", controller.UpdateProblemSet).Name = "problemSet.updateProblemSet"
 	manageProblemSet.POST("/class/:class_id/problem_set/:id/problems", controller.AddProblemsToSet).Name = "problemSet.addProblemsToSet"
 	manageProblemSet.DELETE("/class/:class_id/problem_set/:id/problems", controller.DeleteProblemsFromSet).Name = "problemSet.deleteProblemsFromSet"
+	manageProblemSet.PUT("/class/:class_id/problem_set/:id/problems", controller.ReorderProblemsInSet).Name = "problemSet.reorderProblemsInSet"
 	manageProblemSet.DELETE("/class/:class_id/problem_set/:problem_set_id", controller.DeleteProblemSet).Name = "problemSet.deleteProblemSet"
+	manageProblemSet.PUT("/class/:class_id/problem_set/:id/exam", controller.UpdateExamSettings).Name = "problemSet.updateExamSettings"
+	manageProblemSet.GET("/class/:class_id/problem_set/:id/exam/violations", controller.GetExamViolations).Name = "problemSet.getExamViolations"
+	manageProblemSet.DELETE("/class/:class_id/problem_set/:id/exam/session", controller.ResetExamSession).Name = "problemSet.resetExamSession"
 	problemSetProblem.GET("/class/:class_id/problem_set/:problem_set_id/problem/:id", controller.GetProblemSetProblem).Name = "problemSet.getProblemSetProblem"
 	problemSetProblem.GET("/class/:class_id/problem_set/:problem_set_id/problem/:id/test_case/:test_case_id/input_file", controller.GetProblemSetProblemInputFile,
 		middleware.HasPermission(middleware.OrPermission{
@@ -301,8 +409,18 @@ func Register(e *echo.Echo) {
 				B: middleware.UnscopedPermission{P: "read_problem_secrets"},
 			},
 		})).Name = "problemSet.getProblemSetProblemOutputFile"
-	manageProblemSetGrades.GET("/class/:class_id/problem_set/:id/grades", controller.GetProblemSetGrades).Name = "problemSet.GetProblemSetGrades"
+	readProblemSetGrades.GET("/class/:class_id/problem_set/:id/grades", controller.GetProblemSetGrades).Name = "problemSet.GetProblemSetGrades"
 	manageProblemSetGrades.POST("/class/:class_id/problem_set/:id/grades/refresh", controller.RefreshGrades).Name = "problemSet.RefreshGrades"
+	readProblemSetGrades.GET("/class/:class_id/problem_set/:id/grades/overrides", controller.GetGradeOverrides).Name = "problemSet.getGradeOverrides"
+	readProblemSetGrades.GET("/class/:class_id/problem_set/:id/grades/changes", controller.GetGradeChanges).Name = "problemSet.getGradeChanges"
+	manageProblemSetGrades.POST("/class/:class_id/problem_set/:id/grades/overrides", controller.OverrideGrade).Name = "problemSet.overrideGrade"
+	manageProblemSetGrades.DELETE("/class/:class_id/problem_set/:id/grades/overrides", controller.RemoveGradeOverride).Name = "problemSet.removeGradeOverride"
+	manageProblemSetGrades.POST("/class/:class_id/problem_set/:id/plagiarism", controller.CreatePlagiarismReport).Name = "problemSet.createPlagiarismReport"
+	manageProblemSetGrades.GET("/class/:class_id/problem_set/:id/plagiarism", controller.GetPlagiarismReports).Name = "problemSet.getPlagiarismReports"
+	manageProblemSetGrades.GET("/class/:class_id/problem_set/:id/plagiarism/:report_id", controller.GetPlagiarismReport,
+		middleware.ValidateParams(map[string]string{
+			"report_id": "NOT_FOUND",
+		})).Name = "problemSet.getPlagiarismReport"
 
 	// problem set submission APIs
 	problemSetSubmission := api.Group("",
@@ -312,7 +430,7 @@ func Register(e *echo.Echo) {
 			"problem_set_id": "PROBLEM_SET_NOT_FOUND",
 			"submission_id":  "SUBMISSION_NOT_FOUND",
 		}),
-		middleware.Logged, middleware.EmailVerified,
+		middleware.Logged, middleware.EmailVerified, middleware.ExamRestriction,
 		middleware.HasPermission(middleware.OrPermission{
 			A: middleware.OrPermission{
 				A: middleware.ScopedPermission{P: "read_answers", T: "class", IdFieldName: "class_id"},
@@ -327,17 +445,62 @@ func Register(e *echo.Echo) {
 			"problem_set_id": "PROBLEM_SET_NOT_FOUND",
 			"problem_id":     "PROBLEM_NOT_FOUND",
 		}),
-		middleware.Logged, middleware.EmailVerified,
+		middleware.Logged, middleware.EmailVerified, middleware.ExamRestriction,
 		middleware.HasPermission(middleware.CustomPermission{F: middleware.ProblemSetStarted}),
 	).Name = "problemSet.createSubmission"
+	api.POST("/class/:class_id/problem_set/:problem_set_id/submission/:id/rejudge", controller.ProblemSetRejudgeSubmission,
+		middleware.ValidateParams(map[string]string{
+			"id":             "NOT_FOUND",
+			"class_id":       "CLASS_NOT_FOUND",
+			"problem_set_id": "PROBLEM_SET_NOT_FOUND",
+		}),
+		middleware.Logged, middleware.EmailVerified,
+		middleware.HasPermission(middleware.OrPermission{
+			A: middleware.ScopedPermission{P: "rejudge", T: "class", IdFieldName: "class_id"},
+			B: middleware.UnscopedPermission{P: "rejudge"},
+		}),
+	).Name = "problemSet.rejudgeSubmission"
+	api.PUT("/class/:class_id/problem_set/:problem_set_id/submission/:id/feedback", controller.ProblemSetUpdateSubmissionFeedback,
+		middleware.ValidateParams(map[string]string{
+			"id":             "NOT_FOUND",
+			"class_id":       "CLASS_NOT_FOUND",
+			"problem_set_id": "PROBLEM_SET_NOT_FOUND",
+		}),
+		middleware.Logged, middleware.EmailVerified,
+		middleware.HasPermission(middleware.OrPermission{
+			A: middleware.ScopedPermission{P: "manage_grades", T: "class", IdFieldName: "class_id"},
+			B: middleware.UnscopedPermission{P: "manage_grades"},
+		}),
+	).Name = "problemSet.updateSubmissionFeedback"
 	problemSetSubmission.GET("/class/:class_id/problem_set/:problem_set_id/submission/:id", controller.ProblemSetGetSubmission).Name = "problemSet.getSubmission"
 	problemSetSubmission.GET("/class/:class_id/problem_set/:problem_set_id/submissions", controller.ProblemSetGetSubmissions).Name = "problemSet.getSubmissions"
 	problemSetSubmission.GET("/class/:class_id/problem_set/:problem_set_id/submission/:id/code", controller.ProblemSetGetSubmissionCode).Name = "problemSet.getSubmissionCode"
+	problemSetSubmission.POST("/class/:class_id/problem_set/:problem_set_id/submission/:id/comments", controller.ProblemSetCreateSubmissionComment).Name = "problemSet.createSubmissionComment"
 	problemSetSubmission.GET("/class/:class_id/problem_set/:problem_set_id/submission/:submission_id/run/:id/output", controller.ProblemSetGetRunOutput).Name = "problemSet.getRunOutput"
 	problemSetSubmission.GET("/class/:class_id/problem_set/:problem_set_id/submission/:submission_id/run/:id/input", controller.ProblemSetGetRunInput).Name = "problemSet.getRunInput"
 	problemSetSubmission.GET("/class/:class_id/problem_set/:problem_set_id/submission/:submission_id/run/:id/compiler_output", controller.ProblemSetGetRunCompilerOutput).Name = "problemSet.getRunCompilerOutput"
 	problemSetSubmission.GET("/class/:class_id/problem_set/:problem_set_id/submission/:submission_id/run/:id/comparer_output", controller.ProblemSetGetRunComparerOutput).Name = "problemSet.getRunComparerOutput"
 
+	// problem set clarification APIs
+	problemSetClarification := api.Group("",
+		middleware.ValidateParams(map[string]string{
+			"id":             "NOT_FOUND",
+			"class_id":       "CLASS_NOT_FOUND",
+			"problem_set_id": "PROBLEM_SET_NOT_FOUND",
+		}),
+		middleware.Logged, middleware.EmailVerified, middleware.ExamRestriction,
+		middleware.HasPermission(middleware.OrPermission{
+			A: middleware.OrPermission{
+				A: middleware.ScopedPermission{P: "manage_clarifications", T: "class", IdFieldName: "class_id"},
+				B: middleware.UnscopedPermission{P: "manage_clarifications"},
+			},
+			B: middleware.CustomPermission{F: middleware.ProblemSetStarted},
+		}),
+	)
+	problemSetClarification.GET("/class/:class_id/problem_set/:problem_set_id/clarifications", controller.GetClarifications).Name = "problemSet.getClarifications"
+	problemSetClarification.POST("/class/:class_id/problem_set/:problem_set_id/clarifications", controller.CreateClarification).Name = "problemSet.createClarification"
+	problemSetClarification.POST("/class/:class_id/problem_set/:problem_set_id/clarification/:id/replies", controller.ReplyClarification).Name = "problemSet.replyClarification"
+
 	// pprof APIs
 	if viper.GetBool("debug") {
 		log.Debugf("Adding pprof handlers. SHOULD NOT BE USED IN PRODUCTION")
@@ -24,7 +24,7 @@ func init() {
 func initAuthConfig() {
 	SessionTimeout = time.Second * viper.GetDuration("auth.session_timeout")
 	RememberMeTimeout = time.Second * viper.GetDuration("auth.remember_me_timeout")
-	SessionCount = viper.GetInt("auth.session_timeout")
+	SessionCount = viper.GetInt("auth.session_count")
 }
 
 func IsTokenExpired(token models.Token) bool {
@@ -36,6 +36,50 @@ func IsTokenExpired(token models.Token) bool {
 	}
 }
 
+// userAgentMaxLength is the max length of user agents stored in tokens.
+const userAgentMaxLength = 255
+
+// NewToken creates a session token for the user, and removes the least recently used sessions of the user
+// beyond auth.session_count.
+func NewToken(user models.User, rememberMe bool, userAgent, ip string) (models.Token, error) {
+	if len(userAgent) > userAgentMaxLength {
+		userAgent = userAgent[:userAgentMaxLength]
+	}
+	token := models.Token{
+		Token:      RandStr(32),
+		User:       user,
+		RememberMe: rememberMe,
+		UserAgent:  userAgent,
+		IP:         ip,
+	}
+	if err := base.DB.Create(&token).Error; err != nil {
+		return token, errors.Wrap(err, "could not create token")
+	}
+	if err := LimitSessions(user.ID); err != nil {
+		return token, err
+	}
+	return token, nil
+}
+
+// LimitSessions removes the least recently used tokens of the user beyond auth.session_count.
+func LimitSessions(userID uint) error {
+	initAuth.Do(initAuthConfig)
+	var tokenIds []uint
+	err := base.DB.Model(&models.Token{}).Where("user_id = ?", userID).
+		Order("updated_at desc, id desc").Pluck("id", &tokenIds).Error
+	if err != nil {
+		return errors.Wrap(err, "could not find tokens")
+	}
+	if len(tokenIds) <= SessionCount {
+		return nil
+	}
+	err = base.DB.Delete(&models.Token{}, "id in (?)", tokenIds[SessionCount:]).Error
+	if err != nil {
+		return errors.Wrap(err, "could not delete tokens")
+	}
+	return nil
+}
+
 // TODO: Use this function in timed tasks
 func CleanUpExpiredTokens() error {
 	initAuth.Do(initAuthConfig)
@@ -2,9 +2,12 @@ package utils
 
 import (
 	"sync"
+	"time"
 
 	"github.com/EduOJ/backend/base"
 	"github.com/EduOJ/backend/database/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
 )
 
 var inviteCodeLock sync.Mutex
@@ -21,3 +24,92 @@ func GenerateInviteCode() (code string) {
 	}
 	return
 }
+
+// CloneClass copies the metadata, managers, grade weights and problem sets of a class into a new class,
+// shifting the start and end time of the problem sets by offset. Students and grades are not copied.
+// The operator is added to the managers of the new class.
+func CloneClass(source *models.Class, name string, offset time.Duration, operator *models.User) (*models.Class, error) {
+	if err := base.DB.Preload("Managers").Preload("ProblemSets", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Preload("ProblemSets.Problems").Preload("ProblemSets.ProblemEntries").First(source, source.ID).Error; err != nil {
+		return nil, errors.Wrap(err, "could not get source class for cloning class")
+	}
+	class := models.Class{
+		Name:        name,
+		CourseName:  source.CourseName,
+		Description: source.Description,
+		InviteCode:  GenerateInviteCode(),
+		Managers:    source.Managers,
+		Students:    []*models.User{},
+		DropLowest:  source.DropLowest,
+
+		RequireApproval: source.RequireApproval,
+	}
+	isManager := false
+	for _, manager := range source.Managers {
+		if manager.ID == operator.ID {
+			isManager = true
+		}
+	}
+	if !isManager {
+		class.Managers = append(class.Managers, operator)
+	}
+	err := base.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&class).Error; err != nil {
+			return errors.Wrap(err, "could not create class for cloning class")
+		}
+		problemSetIDs := make(map[uint]uint)
+		for _, sourceProblemSet := range source.ProblemSets {
+			problemSet := models.ProblemSet{
+				ClassID:     class.ID,
+				Name:        sourceProblemSet.Name,
+				Description: sourceProblemSet.Description,
+				Problems:    sourceProblemSet.Problems,
+				StartTime:   sourceProblemSet.StartTime.Add(offset),
+				EndTime:     sourceProblemSet.EndTime.Add(offset),
+
+				ExamMode:     sourceProblemSet.ExamMode,
+				ExamIPRanges: sourceProblemSet.ExamIPRanges,
+				AllowUpsolve: sourceProblemSet.AllowUpsolve,
+
+				LanguageAllowed: sourceProblemSet.LanguageAllowed,
+			}
+			if err := tx.Create(&problemSet).Error; err != nil {
+				return errors.Wrap(err, "could not create problem set for cloning class")
+			}
+			if err := problemSet.SetProblemEntries(tx, sourceProblemSet.ProblemEntries); err != nil {
+				return errors.Wrap(err, "could not copy problems for cloning class")
+			}
+			problemSetIDs[sourceProblemSet.ID] = problemSet.ID
+			class.ProblemSets = append(class.ProblemSets, &problemSet)
+		}
+		var sourceWeights []models.GradeWeight
+		if err := tx.Find(&sourceWeights, "class_id = ?", source.ID).Error; err != nil {
+			return errors.Wrap(err, "could not get grade weights for cloning class")
+		}
+		var weights []models.GradeWeight
+		for _, weight := range sourceWeights {
+			problemSetID, ok := problemSetIDs[weight.ProblemSetID]
+			if !ok {
+				continue
+			}
+			weights = append(weights, models.GradeWeight{
+				ClassID:      class.ID,
+				ProblemSetID: problemSetID,
+				ProblemID:    weight.ProblemID,
+				Weight:       weight.Weight,
+			})
+		}
+		if len(weights) == 0 {
+			return nil
+		}
+		return errors.Wrap(tx.Create(&weights).Error, "could not create grade weights for cloning class")
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, manager := range class.Managers {
+		manager.GrantRole("class_creator", class)
+	}
+	return &class, nil
+}
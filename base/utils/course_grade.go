@@ -0,0 +1,242 @@
+package utils
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gradeWeights struct {
+	problemSets map[uint]float64
+	problems    map[uint]map[uint]float64
+}
+
+func loadGradeWeights(classID uint) (*gradeWeights, error) {
+	var weights []models.GradeWeight
+	if err := base.DB.Find(&weights, "class_id = ?", classID).Error; err != nil {
+		return nil, errors.Wrap(err, "could not get grade weights")
+	}
+	w := gradeWeights{
+		problemSets: make(map[uint]float64),
+		problems:    make(map[uint]map[uint]float64),
+	}
+	for _, weight := range weights {
+		if weight.ProblemID == 0 {
+			w.problemSets[weight.ProblemSetID] = weight.Weight
+			continue
+		}
+		if w.problems[weight.ProblemSetID] == nil {
+			w.problems[weight.ProblemSetID] = make(map[uint]float64)
+		}
+		w.problems[weight.ProblemSetID][weight.ProblemID] = weight.Weight
+	}
+	return &w, nil
+}
+
+func (w *gradeWeights) problemSet(problemSetID uint) float64 {
+	if weight, ok := w.problemSets[problemSetID]; ok {
+		return weight
+	}
+	return 1
+}
+
+func (w *gradeWeights) problem(problemSetID, problemID uint) float64 {
+	if weight, ok := w.problems[problemSetID][problemID]; ok {
+		return weight
+	}
+	return 1
+}
+
+// gradePercentage calculates the weighted score of a grade as a percentage.
+// A nil grade scores 0.
+func gradePercentage(problemSet *models.ProblemSet, grade *models.Grade, weights *gradeWeights) (float64, error) {
+	detail := make(map[uint]uint)
+	if grade != nil {
+		if err := json.Unmarshal(grade.Detail, &detail); err != nil {
+			return 0, errors.Wrap(err, "could not unmarshal grade detail")
+		}
+	}
+	var score, full float64
+	for _, p := range problemSet.Problems {
+		weight := weights.problem(problemSet.ID, p.ID)
+		score += weight * float64(detail[p.ID])
+		full += weight * float64(problemSet.FullMark(p.ID))
+	}
+	if full == 0 {
+		return 0, nil
+	}
+	return score * 100 / full, nil
+}
+
+// calculateCourseGrade calculates the course grade of a student from the grades of the student,
+// keyed by problem set id. Problem sets with a weight of 0 are ignored, and the lowest
+// dropLowest problem set scores are dropped, keeping at least one.
+func calculateCourseGrade(problemSets []*models.ProblemSet, grades map[uint]*models.Grade,
+	weights *gradeWeights, dropLowest uint) (items []models.CourseGradeItem, total float64, err error) {
+	items = make([]models.CourseGradeItem, 0, len(problemSets))
+	var counted []int
+	for _, problemSet := range problemSets {
+		item := models.CourseGradeItem{
+			ProblemSetID: problemSet.ID,
+			Weight:       weights.problemSet(problemSet.ID),
+		}
+		if item.Score, err = gradePercentage(problemSet, grades[problemSet.ID], weights); err != nil {
+			return
+		}
+		if item.Weight > 0 {
+			counted = append(counted, len(items))
+		}
+		items = append(items, item)
+	}
+	sort.SliceStable(counted, func(i, j int) bool {
+		return items[counted[i]].Score < items[counted[j]].Score
+	})
+	drop := int(dropLowest)
+	if drop >= len(counted) {
+		drop = len(counted) - 1
+	}
+	for i := 0; i < drop; i++ {
+		items[counted[i]].Dropped = true
+	}
+	var weightSum float64
+	for _, i := range counted {
+		if items[i].Dropped {
+			continue
+		}
+		total += items[i].Score * items[i].Weight
+		weightSum += items[i].Weight
+	}
+	if weightSum > 0 {
+		total /= weightSum
+	}
+	return
+}
+
+// saveCourseGrades recalculates and saves the course grades of the given students in a class, whose problem sets
+// should be preloaded with their problems and problem entries.
+func saveCourseGrades(class *models.Class, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	weights, err := loadGradeWeights(class.ID)
+	if err != nil {
+		return err
+	}
+	var grades []*models.Grade
+	if err := base.DB.Find(&grades, "class_id = ? and user_id in ?", class.ID, userIDs).Error; err != nil {
+		return errors.Wrap(err, "could not get grades for updating course grades")
+	}
+	gradeMap := make(map[uint]map[uint]*models.Grade)
+	for _, g := range grades {
+		if gradeMap[g.UserID] == nil {
+			gradeMap[g.UserID] = make(map[uint]*models.Grade)
+		}
+		gradeMap[g.UserID][g.ProblemSetID] = g
+	}
+	courseGrades := make([]models.CourseGrade, 0, len(userIDs))
+	for _, userID := range userIDs {
+		items, total, err := calculateCourseGrade(class.ProblemSets, gradeMap[userID], weights, class.DropLowest)
+		if err != nil {
+			return err
+		}
+		detail, err := json.Marshal(items)
+		if err != nil {
+			return errors.Wrap(err, "could not marshal course grade detail")
+		}
+		courseGrades = append(courseGrades, models.CourseGrade{
+			ClassID: class.ID,
+			UserID:  userID,
+			Detail:  detail,
+			Total:   total,
+		})
+	}
+	err = base.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "class_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"detail", "total", "updated_at"}),
+	}).CreateInBatches(&courseGrades, 100).Error
+	return errors.Wrap(err, "could not save course grades")
+}
+
+// updateCourseGrades recalculates the course grades of the given students in a class.
+// The caller should hold the grade lock of the class.
+func updateCourseGrades(classID uint, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	class := models.Class{}
+	if err := base.DB.Preload("ProblemSets.Problems").Preload("ProblemSets.ProblemEntries").First(&class, classID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return errors.Wrap(err, "could not get class for updating course grades")
+	}
+	return saveCourseGrades(&class, userIDs)
+}
+
+// updateCourseGrade recalculates the course grade of a student.
+// The caller should hold the grade lock of the class.
+func updateCourseGrade(classID, userID uint) error {
+	return updateCourseGrades(classID, []uint{userID})
+}
+
+// deleteRemovedCourseGrades deletes the course grades of the users in a class who are not in the given students.
+// The caller should hold the grade lock of the class.
+func deleteRemovedCourseGrades(classID uint, studentIDs []uint) error {
+	query := base.DB.Where("class_id = ?", classID)
+	if len(studentIDs) > 0 {
+		query = query.Where("user_id not in (?)", studentIDs)
+	}
+	return errors.Wrap(query.Delete(&models.CourseGrade{}).Error, "could not delete course grades of removed students")
+}
+
+// refreshCourseGrades recalculates the course grades of all students in a class.
+// The caller should hold the grade lock of the class.
+func refreshCourseGrades(classID uint) error {
+	class := models.Class{}
+	if err := base.DB.Preload("Students").Preload("ProblemSets.Problems").Preload("ProblemSets.ProblemEntries").First(&class, classID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return errors.Wrap(err, "could not get class for refreshing course grades")
+	}
+	userIDs := make([]uint, len(class.Students))
+	for i, u := range class.Students {
+		userIDs[i] = u.ID
+	}
+	if err := deleteRemovedCourseGrades(classID, userIDs); err != nil {
+		return err
+	}
+	return saveCourseGrades(&class, userIDs)
+}
+
+// RefreshCourseGrades recalculates the course grades of all students in a class.
+func RefreshCourseGrades(classID uint) error {
+	defer lockGrades(classID)()
+	return refreshCourseGrades(classID)
+}
+
+// SetGradeWeights replaces the grade weights of a class and recalculates its course grades.
+func SetGradeWeights(class *models.Class, dropLowest uint, weights []models.GradeWeight) error {
+	defer lockGrades(class.ID)()
+	if err := base.DB.Delete(&models.GradeWeight{}, "class_id = ?", class.ID).Error; err != nil {
+		return errors.Wrap(err, "could not delete grade weights")
+	}
+	for i := range weights {
+		weights[i].ClassID = class.ID
+	}
+	if len(weights) > 0 {
+		if err := base.DB.Create(&weights).Error; err != nil {
+			return errors.Wrap(err, "could not create grade weights")
+		}
+	}
+	class.DropLowest = dropLowest
+	if err := base.DB.Model(class).Update("drop_lowest", dropLowest).Error; err != nil {
+		return errors.Wrap(err, "could not update drop lowest")
+	}
+	return refreshCourseGrades(class.ID)
+}
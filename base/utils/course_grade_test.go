@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateCourseGrade(t *testing.T) {
+	t.Parallel()
+
+	problemSets := []*models.ProblemSet{
+		{ID: 1, Problems: []*models.Problem{{ID: 1}, {ID: 2}}},
+		{ID: 2, Problems: []*models.Problem{{ID: 1}}},
+		{ID: 3, Problems: []*models.Problem{{ID: 3}}},
+	}
+	grades := map[uint]*models.Grade{
+		1: {ProblemSetID: 1, Detail: createJSONForTest(t, map[uint]uint{1: 100, 2: 40})},
+		2: {ProblemSetID: 2, Detail: createJSONForTest(t, map[uint]uint{1: 50})},
+	}
+	t.Run("DefaultWeights", func(t *testing.T) {
+		t.Parallel()
+		weights := &gradeWeights{
+			problemSets: map[uint]float64{},
+			problems:    map[uint]map[uint]float64{},
+		}
+		items, total, err := calculateCourseGrade(problemSets, grades, weights, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.CourseGradeItem{
+			{ProblemSetID: 1, Score: 70, Weight: 1},
+			{ProblemSetID: 2, Score: 50, Weight: 1},
+			{ProblemSetID: 3, Score: 0, Weight: 1},
+		}, items)
+		assert.Equal(t, float64(40), total)
+	})
+	t.Run("WeightsAndDropLowest", func(t *testing.T) {
+		t.Parallel()
+		weights := &gradeWeights{
+			problemSets: map[uint]float64{1: 3, 2: 1},
+			problems: map[uint]map[uint]float64{
+				1: {1: 1, 2: 3},
+			},
+		}
+		items, total, err := calculateCourseGrade(problemSets, grades, weights, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.CourseGradeItem{
+			{ProblemSetID: 1, Score: 55, Weight: 3},
+			{ProblemSetID: 2, Score: 50, Weight: 1},
+			{ProblemSetID: 3, Score: 0, Weight: 1, Dropped: true},
+		}, items)
+		assert.Equal(t, float64(53.75), total)
+	})
+	t.Run("KeepAtLeastOne", func(t *testing.T) {
+		t.Parallel()
+		weights := &gradeWeights{
+			problemSets: map[uint]float64{2: 0},
+			problems:    map[uint]map[uint]float64{},
+		}
+		items, total, err := calculateCourseGrade(problemSets, grades, weights, 5)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.CourseGradeItem{
+			{ProblemSetID: 1, Score: 70, Weight: 1},
+			{ProblemSetID: 2, Score: 50, Weight: 0},
+			{ProblemSetID: 3, Score: 0, Weight: 1, Dropped: true},
+		}, items)
+		assert.Equal(t, float64(70), total)
+	})
+	t.Run("FullMarks", func(t *testing.T) {
+		t.Parallel()
+		problemSets := []*models.ProblemSet{
+			{
+				ID:       4,
+				Problems: []*models.Problem{{ID: 1}, {ID: 2}},
+				ProblemEntries: []*models.ProblemInProblemSet{
+					{ProblemSetID: 4, ProblemID: 1, FullMark: 20},
+					{ProblemSetID: 4, ProblemID: 2, FullMark: 60},
+				},
+			},
+		}
+		grades := map[uint]*models.Grade{
+			4: {ProblemSetID: 4, Detail: createJSONForTest(t, map[uint]uint{1: 20, 2: 40})},
+		}
+		weights := &gradeWeights{
+			problemSets: map[uint]float64{},
+			problems:    map[uint]map[uint]float64{},
+		}
+		items, total, err := calculateCourseGrade(problemSets, grades, weights, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []models.CourseGradeItem{
+			{ProblemSetID: 4, Score: 75, Weight: 1},
+		}, items)
+		assert.Equal(t, float64(75), total)
+	})
+}
+
+func TestUpdateGradeUpdatesCourseGrade(t *testing.T) {
+	t.Parallel()
+
+	user := models.User{
+		Username: "test_update_grade_course_grade_username",
+		Nickname: "test_update_grade_course_grade_nickname",
+		Email:    "test_update_grade_course_grade@mail.com",
+		Password: "test_update_grade_course_grade_password",
+	}
+	assert.NoError(t, base.DB.Create(&user).Error)
+	problem := models.Problem{
+		Name: "test_update_grade_course_grade_name",
+	}
+	assert.NoError(t, base.DB.Create(&problem).Error)
+	class := models.Class{
+		Name:       "test_update_grade_course_grade_name",
+		InviteCode: GenerateInviteCode(),
+		Students:   []*models.User{&user},
+	}
+	assert.NoError(t, base.DB.Create(&class).Error)
+	problemSet := models.ProblemSet{
+		ClassID:   class.ID,
+		Name:      "test_update_grade_course_grade_name",
+		Problems:  []*models.Problem{&problem},
+		StartTime: time.Now().Add(-1 * time.Hour),
+		EndTime:   time.Now().Add(time.Hour),
+	}
+	assert.NoError(t, base.DB.Create(&problemSet).Error)
+	assert.NoError(t, SetGradeWeights(&class, 0, []models.GradeWeight{
+		{ProblemSetID: problemSet.ID, Weight: 2},
+	}))
+
+	assert.NoError(t, UpdateGrade(&models.Submission{
+		UserID:       user.ID,
+		ProblemID:    problem.ID,
+		ProblemSetID: problemSet.ID,
+		Score:        80,
+	}))
+	courseGrade := models.CourseGrade{}
+	assert.NoError(t, base.DB.First(&courseGrade, "class_id = ? and user_id = ?", class.ID, user.ID).Error)
+	assert.Equal(t, float64(80), courseGrade.Total)
+	assert.Equal(t, createJSONForTest(t, []models.CourseGradeItem{
+		{ProblemSetID: problemSet.ID, Score: 80, Weight: 2},
+	}), courseGrade.Detail)
+}
+
+func TestRecalculateGradesUpdatesCourseGrades(t *testing.T) {
+	t.Parallel()
+
+	users := make([]*models.User, 3)
+	for i := range users {
+		users[i] = &models.User{
+			Username: fmt.Sprintf("test_recalculate_grades_course_grades_username_%d", i),
+			Nickname: fmt.Sprintf("test_recalculate_grades_course_grades_nickname_%d", i),
+			Email:    fmt.Sprintf("test_recalculate_grades_course_grades_%d@mail.com", i),
+			Password: "test_recalculate_grades_course_grades_password",
+		}
+		assert.NoError(t, base.DB.Create(users[i]).Error)
+	}
+	problem := models.Problem{
+		Name: "test_recalculate_grades_course_grades_name",
+	}
+	assert.NoError(t, base.DB.Create(&problem).Error)
+	class := models.Class{
+		Name:       "test_recalculate_grades_course_grades_name",
+		InviteCode: GenerateInviteCode(),
+		Students:   users,
+	}
+	assert.NoError(t, base.DB.Create(&class).Error)
+	problemSet := models.ProblemSet{
+		ClassID:   class.ID,
+		Name:      "test_recalculate_grades_course_grades_name",
+		Problems:  []*models.Problem{&problem},
+		StartTime: time.Now().Add(-1 * time.Hour),
+		EndTime:   time.Now().Add(time.Hour),
+	}
+	assert.NoError(t, base.DB.Create(&problemSet).Error)
+	// The course grade of the first student exists before, and is updated in place.
+	assert.NoError(t, RefreshCourseGrades(class.ID))
+	existing := models.CourseGrade{}
+	assert.NoError(t, base.DB.First(&existing, "class_id = ? and user_id = ?", class.ID, users[0].ID).Error)
+	for i, score := range []uint{60, 90} {
+		assert.NoError(t, base.DB.Create(&models.Submission{
+			UserID:       users[i].ID,
+			ProblemID:    problem.ID,
+			ProblemSetID: problemSet.ID,
+			Score:        score,
+			Status:       "WRONG_ANSWER",
+		}).Error)
+	}
+
+	assert.NoError(t, RecalculateGrades(&problemSet, nil, nil))
+	var courseGrades []models.CourseGrade
+	assert.NoError(t, base.DB.Order("user_id").Find(&courseGrades, "class_id = ?", class.ID).Error)
+	if assert.Len(t, courseGrades, 3) {
+		assert.Equal(t, existing.ID, courseGrades[0].ID)
+		for i, total := range []float64{60, 90, 0} {
+			assert.Equal(t, users[i].ID, courseGrades[i].UserID)
+			assert.Equal(t, total, courseGrades[i].Total)
+		}
+	}
+}
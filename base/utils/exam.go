@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"net"
+	"time"
+
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// GetRunningExam returns the exam taken by the student at the moment, or nil if there is none.
+func GetRunningExam(userID uint) (*models.ProblemSet, error) {
+	now := time.Now()
+	problemSet := models.ProblemSet{}
+	err := base.DB.
+		Where("exam_mode = ? and start_time <= ? and end_time > ?", true, now, now).
+		Where("class_id in (?)", base.DB.Table("user_in_classes").Select("class_id").Where("user_id = ?", userID)).
+		Order("id").First(&problemSet).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get running exam")
+	}
+	return &problemSet, nil
+}
+
+// IPAllowedInExam tells if the exam accepts requests from the given address.
+func IPAllowedInExam(problemSet *models.ProblemSet, ip string) bool {
+	ranges := problemSet.AllowedIPRanges()
+	if len(ranges) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, r := range ranges {
+		_, network, err := net.ParseCIDR(r)
+		if err == nil && network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckExamAccess checks a request of a student to a running exam against its IP ranges and
+// the token bound to the student. The first token used in the exam is bound to the student.
+// Requests without a session token, e.g. with personal access tokens, have a token id of 0 and are rejected
+// as session conflicts. The type of the violation is returned and logged if the request should be rejected.
+func CheckExamAccess(problemSet *models.ProblemSet, userID, tokenID uint, ip, path string) (violation string, err error) {
+	if !IPAllowedInExam(problemSet, ip) {
+		return models.ExamViolationIPNotAllowed, LogExamViolation(problemSet.ID, userID, models.ExamViolationIPNotAllowed, ip, path)
+	}
+	if tokenID == 0 {
+		return models.ExamViolationSessionConflict, LogExamViolation(problemSet.ID, userID, models.ExamViolationSessionConflict, ip, path)
+	}
+	session := models.ExamSession{}
+	if err := base.DB.Where("problem_set_id = ? and user_id = ?", problemSet.ID, userID).
+		Attrs(models.ExamSession{
+			ProblemSetID: problemSet.ID,
+			UserID:       userID,
+			TokenID:      tokenID,
+			IP:           ip,
+		}).FirstOrCreate(&session).Error; err != nil {
+		return "", errors.Wrap(err, "could not get exam session")
+	}
+	if session.TokenID != tokenID {
+		return models.ExamViolationSessionConflict, LogExamViolation(problemSet.ID, userID, models.ExamViolationSessionConflict, ip, path)
+	}
+	return "", nil
+}
+
+func LogExamViolation(problemSetID, userID uint, violationType, ip, path string) error {
+	violation := models.ExamViolation{
+		ProblemSetID: problemSetID,
+		UserID:       userID,
+		Type:         violationType,
+		IP:           ip,
+		Path:         path,
+	}
+	if err := base.DB.Create(&violation).Error; err != nil {
+		return errors.Wrap(err, "could not log exam violation")
+	}
+	return nil
+}
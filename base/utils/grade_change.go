@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"sort"
+
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/pkg/errors"
+)
+
+// gradeChanges returns the changes of the scores of a grade, sorted by problem id.
+// Problems missing in the old detail are taken as scored 0, and problems missing in the new detail are ignored.
+func gradeChanges(grade *models.Grade, oldDetail, newDetail map[uint]uint, cause string, submissionID uint) []*models.GradeChange {
+	var changes []*models.GradeChange
+	for problemID, newScore := range newDetail {
+		if oldDetail[problemID] == newScore {
+			continue
+		}
+		changes = append(changes, &models.GradeChange{
+			ClassID:      grade.ClassID,
+			ProblemSetID: grade.ProblemSetID,
+			ProblemID:    problemID,
+			UserID:       grade.UserID,
+			OldScore:     oldDetail[problemID],
+			NewScore:     newScore,
+			Cause:        cause,
+			SubmissionID: submissionID,
+		})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].ProblemID < changes[j].ProblemID
+	})
+	return changes
+}
+
+func logGradeChanges(changes []*models.GradeChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	return errors.Wrap(base.DB.Omit("User").Create(&changes).Error, "could not log grade changes")
+}
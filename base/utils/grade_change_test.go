@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func getGradeChangesForTest(t *testing.T, problemSet *models.ProblemSet, userID uint) []models.GradeChange {
+	var changes []models.GradeChange
+	assert.NoError(t, base.DB.Order("id").Find(&changes, "problem_set_id = ? and user_id = ?", problemSet.ID, userID).Error)
+	for i := range changes {
+		changes[i].ID = 0
+		changes[i].CreatedAt = time.Time{}
+	}
+	return changes
+}
+
+func TestGradeChanges(t *testing.T) {
+	t.Parallel()
+	user := models.User{
+		Username: "test_grade_changes_username",
+		Nickname: "test_grade_changes_nickname",
+		Email:    "test_grade_changes@mail.com",
+		Password: "test_grade_changes_password",
+	}
+	assert.NoError(t, base.DB.Create(&user).Error)
+	problem := models.Problem{
+		Name:        "test_grade_changes_name",
+		Description: "test_grade_changes_description",
+	}
+	assert.NoError(t, base.DB.Create(&problem).Error)
+	class := models.Class{
+		Name:       "test_grade_changes_name",
+		InviteCode: GenerateInviteCode(),
+		Students:   []*models.User{&user},
+	}
+	assert.NoError(t, base.DB.Create(&class).Error)
+	problemSet := models.ProblemSet{
+		ClassID:   class.ID,
+		Name:      "test_grade_changes_name",
+		Problems:  []*models.Problem{&problem},
+		StartTime: time.Now().Add(-1 * time.Hour),
+		EndTime:   time.Now().Add(time.Hour),
+	}
+	assert.NoError(t, base.DB.Create(&problemSet).Error)
+
+	submission := createSubmissionForTest(t, &problemSet, user.ID, problem.ID, 80, "WRONG_ANSWER", 0)
+	assert.NoError(t, UpdateGrade(submission))
+	// a lower score changes nothing.
+	lowerSubmission := createSubmissionForTest(t, &problemSet, user.ID, problem.ID, 30, "WRONG_ANSWER", 0)
+	assert.NoError(t, UpdateGrade(lowerSubmission))
+	assert.NoError(t, RefreshGrades(&problemSet))
+
+	submission.Score = 50
+	submission.Rejudged = true
+	assert.NoError(t, base.DB.Save(submission).Error)
+	assert.NoError(t, UpdateGrade(submission))
+
+	operator := models.User{ID: user.ID}
+	_, err := OverrideGrade(&problemSet, user.ID, problem.ID, 90, "test_grade_changes_reason", &operator)
+	assert.NoError(t, err)
+
+	base.DB.Delete(&models.Submission{}, "id = ?", submission.ID)
+	_, err = RemoveGradeOverride(&problemSet, user.ID, problem.ID, "test_grade_changes_reason", &operator)
+	assert.NoError(t, err)
+	assert.NoError(t, RecalculateGrades(&problemSet, nil, nil))
+
+	base.DB.Delete(&models.Submission{}, "id = ?", lowerSubmission.ID)
+	assert.NoError(t, RecalculateGrades(&problemSet, nil, nil))
+
+	change := func(oldScore, newScore uint, cause string, submissionID uint) models.GradeChange {
+		return models.GradeChange{
+			ClassID:      class.ID,
+			ProblemSetID: problemSet.ID,
+			ProblemID:    problem.ID,
+			UserID:       user.ID,
+			OldScore:     oldScore,
+			NewScore:     newScore,
+			Cause:        cause,
+			SubmissionID: submissionID,
+		}
+	}
+	assert.Equal(t, []models.GradeChange{
+		change(0, 80, models.GradeChangeCauseSubmission, submission.ID),
+		change(80, 50, models.GradeChangeCauseRejudge, submission.ID),
+		change(50, 90, models.GradeChangeCauseOverride, 0),
+		change(90, 30, models.GradeChangeCauseOverride, 0),
+		change(30, 0, models.GradeChangeCauseRefresh, 0),
+	}, getGradeChangesForTest(t, &problemSet, user.ID))
+}
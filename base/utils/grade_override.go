@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"encoding/json"
+
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/pkg/errors"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// loadGradeOverrides returns the scores overridden in a problem set, keyed by user id and problem id.
+func loadGradeOverrides(problemSetID uint) (map[uint]map[uint]uint, error) {
+	var records []models.GradeOverride
+	if err := base.DB.Order("id").Find(&records, "problem_set_id = ?", problemSetID).Error; err != nil {
+		return nil, errors.Wrap(err, "could not get grade overrides")
+	}
+	overrides := make(map[uint]map[uint]uint)
+	for _, record := range records {
+		if overrides[record.UserID] == nil {
+			overrides[record.UserID] = make(map[uint]uint)
+		}
+		if record.Removed {
+			delete(overrides[record.UserID], record.ProblemID)
+		} else {
+			overrides[record.UserID][record.ProblemID] = record.Score
+		}
+	}
+	return overrides, nil
+}
+
+// setGradeScore sets the score of a student for a problem in the grade of a problem set.
+// The caller should hold the grade lock of the class.
+func setGradeScore(problemSet *models.ProblemSet, userID, problemID, score uint) error {
+	grade := models.Grade{}
+	if err := base.DB.Where("problem_set_id = ? and user_id = ?", problemSet.ID, userID).
+		Attrs(models.Grade{
+			UserID:       userID,
+			ProblemSetID: problemSet.ID,
+			ClassID:      problemSet.ClassID,
+			Detail:       datatypes.JSON("{}"),
+		}).FirstOrInit(&grade).Error; err != nil {
+		return errors.Wrap(err, "could not get grade for setting score")
+	}
+	detail := make(map[uint]uint)
+	if err := json.Unmarshal(grade.Detail, &detail); err != nil {
+		return errors.Wrap(err, "could not unmarshal grade detail")
+	}
+	oldScore := detail[problemID]
+	detail[problemID] = score
+	grade.Total = 0
+	for _, s := range detail {
+		grade.Total += s
+	}
+	var err error
+	if grade.Detail, err = json.Marshal(detail); err != nil {
+		return errors.Wrap(err, "could not marshal grade detail")
+	}
+	if err := base.DB.Save(&grade).Error; err != nil {
+		return errors.Wrap(err, "could not save grade")
+	}
+	if err := logGradeChanges(gradeChanges(&grade, map[uint]uint{problemID: oldScore}, map[uint]uint{problemID: score},
+		models.GradeChangeCauseOverride, 0)); err != nil {
+		return err
+	}
+	return updateCourseGrade(grade.ClassID, grade.UserID)
+}
+
+// OverrideGrade overrides the score of a student for a problem in a problem set.
+func OverrideGrade(problemSet *models.ProblemSet, userID, problemID, score uint, reason string, operator *models.User) (*models.GradeOverride, error) {
+	defer lockGrades(problemSet.ClassID)()
+	override := models.GradeOverride{
+		ClassID:      problemSet.ClassID,
+		ProblemSetID: problemSet.ID,
+		ProblemID:    problemID,
+		UserID:       userID,
+		Score:        score,
+		Reason:       reason,
+		OperatorID:   operator.ID,
+		Operator:     operator,
+	}
+	if err := base.DB.Omit("User", "Operator").Create(&override).Error; err != nil {
+		return nil, errors.Wrap(err, "could not create grade override")
+	}
+	return &override, setGradeScore(problemSet, userID, problemID, score)
+}
+
+// RemoveGradeOverride removes the override of a student for a problem in a problem set,
+// and restores the score from the submissions.
+func RemoveGradeOverride(problemSet *models.ProblemSet, userID, problemID uint, reason string, operator *models.User) (*models.GradeOverride, error) {
+	defer lockGrades(problemSet.ClassID)()
+	override := models.GradeOverride{
+		ClassID:      problemSet.ClassID,
+		ProblemSetID: problemSet.ID,
+		ProblemID:    problemID,
+		UserID:       userID,
+		Removed:      true,
+		Reason:       reason,
+		OperatorID:   operator.ID,
+		Operator:     operator,
+	}
+	if err := base.DB.Omit("User", "Operator").Create(&override).Error; err != nil {
+		return nil, errors.Wrap(err, "could not create grade override")
+	}
+	score, err := bestSubmissionScore(problemSet, userID, problemID)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get submission for removing grade override")
+	}
+	fullMarks, err := problemFullMarks(problemSet.ID)
+	if err != nil {
+		return nil, err
+	}
+	score = scaleScore(score, fullMarks[problemID])
+	return &override, setGradeScore(problemSet, userID, problemID, score)
+}
+
+// IsGradeOverridden checks if the score of a student for a problem in a problem set is overridden.
+func IsGradeOverridden(problemSetID, userID, problemID uint) (bool, error) {
+	override := models.GradeOverride{}
+	err := base.DB.Order("id desc").
+		First(&override, "problem_set_id = ? and user_id = ? and problem_id = ?", problemSetID, userID, problemID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "could not get grade override")
+	}
+	return !override.Removed, nil
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGradeOverride(t *testing.T) {
+	t.Parallel()
+
+	user := models.User{
+		Username: "test_grade_override_username",
+		Nickname: "test_grade_override_nickname",
+		Email:    "test_grade_override@mail.com",
+		Password: "test_grade_override_password",
+	}
+	assert.NoError(t, base.DB.Create(&user).Error)
+	problem := models.Problem{
+		Name: "test_grade_override_name",
+	}
+	assert.NoError(t, base.DB.Create(&problem).Error)
+	class := models.Class{
+		Name:       "test_grade_override_name",
+		InviteCode: GenerateInviteCode(),
+		Students:   []*models.User{&user},
+	}
+	assert.NoError(t, base.DB.Create(&class).Error)
+	problemSet := models.ProblemSet{
+		ClassID:   class.ID,
+		Name:      "test_grade_override_name",
+		Problems:  []*models.Problem{&problem},
+		StartTime: time.Now().Add(-1 * time.Hour),
+		EndTime:   time.Now().Add(time.Hour),
+	}
+	assert.NoError(t, base.DB.Create(&problemSet).Error)
+	submission := models.Submission{
+		UserID:       user.ID,
+		ProblemID:    problem.ID,
+		ProblemSetID: problemSet.ID,
+		Score:        40,
+	}
+	assert.NoError(t, base.DB.Create(&submission).Error)
+
+	getGrade := func() models.Grade {
+		grade := models.Grade{}
+		assert.NoError(t, base.DB.First(&grade, "problem_set_id = ? and user_id = ?", problemSet.ID, user.ID).Error)
+		return grade
+	}
+
+	_, err := OverrideGrade(&problemSet, user.ID, problem.ID, 70, "test_grade_override_reason", &user)
+	assert.NoError(t, err)
+	grade := getGrade()
+	assert.Equal(t, createJSONForTest(t, map[uint]uint{problem.ID: 70}), grade.Detail)
+	assert.Equal(t, uint(70), grade.Total)
+	overridden, err := IsGradeOverridden(problemSet.ID, user.ID, problem.ID)
+	assert.NoError(t, err)
+	assert.True(t, overridden)
+
+	// New submissions don't change an overridden score.
+	assert.NoError(t, UpdateGrade(&models.Submission{
+		UserID:       user.ID,
+		ProblemID:    problem.ID,
+		ProblemSetID: problemSet.ID,
+		Score:        100,
+	}))
+	assert.Equal(t, createJSONForTest(t, map[uint]uint{problem.ID: 70}), getGrade().Detail)
+
+	_, err = RemoveGradeOverride(&problemSet, user.ID, problem.ID, "test_grade_override_reason", &user)
+	assert.NoError(t, err)
+	assert.Equal(t, createJSONForTest(t, map[uint]uint{problem.ID: 40}), getGrade().Detail)
+	overridden, err = IsGradeOverridden(problemSet.ID, user.ID, problem.ID)
+	assert.NoError(t, err)
+	assert.False(t, overridden)
+}
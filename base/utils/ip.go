@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"net"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// GetIPExtractor returns the extractor of the client ip from config server.trusted_proxies.
+// The X-Forwarded-For header is only used for requests from the trusted proxies. Without trusted proxies,
+// the ip of the connection is used, so that clients could not spoof their ips with the header.
+func GetIPExtractor() echo.IPExtractor {
+	var proxies []string
+	if err := viper.UnmarshalKey("server.trusted_proxies", &proxies); err != nil {
+		panic(errors.Wrap(err, "could not read server.trusted_proxies from config"))
+	}
+	if len(proxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			if strings.Contains(proxy, ":") {
+				proxy += "/128"
+			} else {
+				proxy += "/32"
+			}
+		}
+		_, ipRange, err := net.ParseCIDR(proxy)
+		if err != nil {
+			panic(errors.Wrap(err, "could not parse server.trusted_proxies from config"))
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"crypto/tls"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// LDAPConfig is the configuration of the LDAP directory users could login with, read from auth.ldap.
+type LDAPConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	URL      string `mapstructure:"url"`
+	StartTLS bool   `mapstructure:"start_tls"`
+	// BindDN is the template of the DN to bind as, in which {username} is replaced by the escaped username.
+	BindDN     string `mapstructure:"bind_dn"`
+	SearchBase string `mapstructure:"search_base"`
+	// SearchFilter is the template of the filter to find the entry of the user after binding,
+	// in which {username} is replaced by the escaped username.
+	SearchFilter string `mapstructure:"search_filter"`
+	Attributes   struct {
+		Username string `mapstructure:"username"`
+		Nickname string `mapstructure:"nickname"`
+		Email    string `mapstructure:"email"`
+	} `mapstructure:"attributes"`
+	// SyncProfile updates the nickname and the email of the user from the directory on each login.
+	SyncProfile bool `mapstructure:"sync_profile"`
+}
+
+// LDAPUser is the entry of a user authenticated by the directory.
+type LDAPUser struct {
+	DN       string
+	Username string
+	Nickname string
+	Email    string
+}
+
+var ErrLDAPInvalidCredentials = errors.New("invalid ldap credentials")
+
+// GetLDAPConfig returns the configuration of the LDAP directory, or nil if LDAP authentication is disabled.
+func GetLDAPConfig() (*LDAPConfig, error) {
+	if !viper.GetBool("auth.ldap.enabled") {
+		return nil, nil
+	}
+	config := LDAPConfig{}
+	if err := viper.UnmarshalKey("auth.ldap", &config); err != nil {
+		return nil, errors.Wrap(err, "could not read ldap config")
+	}
+	if config.SearchFilter == "" {
+		config.SearchFilter = "(uid={username})"
+	}
+	if config.Attributes.Username == "" {
+		config.Attributes.Username = "uid"
+	}
+	if config.Attributes.Nickname == "" {
+		config.Attributes.Nickname = "cn"
+	}
+	if config.Attributes.Email == "" {
+		config.Attributes.Email = "mail"
+	}
+	return &config, nil
+}
+
+// Authenticate binds to the directory as the user, and returns the entry of the user.
+// ErrLDAPInvalidCredentials is returned if the directory rejects the username or the password.
+func (c *LDAPConfig) Authenticate(username, password string) (*LDAPUser, error) {
+	// An empty password makes an unauthenticated bind, which always succeeds.
+	if username == "" || password == "" {
+		return nil, ErrLDAPInvalidCredentials
+	}
+	conn, err := ldap.DialURL(c.URL, ldap.DialWithDialer(&net.Dialer{Timeout: 10 * time.Second}))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to ldap server")
+	}
+	defer conn.Close()
+	conn.SetTimeout(10 * time.Second)
+	if c.StartTLS {
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not parse ldap server url")
+		}
+		if err := conn.StartTLS(&tls.Config{ServerName: u.Hostname()}); err != nil {
+			return nil, errors.Wrap(err, "could not start tls with ldap server")
+		}
+	}
+	if err := conn.Bind(strings.ReplaceAll(c.BindDN, "{username}", escapeDNValue(username)), password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrLDAPInvalidCredentials
+		}
+		return nil, errors.Wrap(err, "could not bind to ldap server")
+	}
+	result, err := conn.Search(ldap.NewSearchRequest(
+		c.SearchBase, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 10, false,
+		strings.ReplaceAll(c.SearchFilter, "{username}", ldap.EscapeFilter(username)),
+		[]string{c.Attributes.Username, c.Attributes.Nickname, c.Attributes.Email},
+		nil,
+	))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not search ldap user")
+	}
+	if len(result.Entries) != 1 {
+		return nil, errors.Errorf("expected 1 ldap entry for %s, got %d", username, len(result.Entries))
+	}
+	entry := result.Entries[0]
+	return &LDAPUser{
+		DN:       entry.DN,
+		Username: entry.GetAttributeValue(c.Attributes.Username),
+		Nickname: entry.GetAttributeValue(c.Attributes.Nickname),
+		Email:    entry.GetAttributeValue(c.Attributes.Email),
+	}, nil
+}
+
+// escapeDNValue escapes an attribute value in a DN as described in RFC 4514.
+func escapeDNValue(value string) string {
+	b := strings.Builder{}
+	for i, r := range value {
+		switch {
+		case strings.ContainsRune(`"+,;<>\`, r),
+			(i == 0 && (r == ' ' || r == '#')),
+			(i == len(value)-1 && r == ' '):
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r == 0:
+			b.WriteString(`\00`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
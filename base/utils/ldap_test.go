@@ -0,0 +1,16 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeDNValue(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "test_user", escapeDNValue("test_user"))
+	assert.Equal(t, `a\,b\+c=d`, escapeDNValue("a,b+c=d"))
+	assert.Equal(t, `\#a b\ `, escapeDNValue("#a b "))
+	assert.Equal(t, `\"a\\b\<c\>d\;`, escapeDNValue(`"a\b<c>d;`))
+	assert.Equal(t, `a\00b`, escapeDNValue("a\x00b"))
+}
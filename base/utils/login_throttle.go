@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+const (
+	LoginLimitBackoff = "BACKOFF"
+	LoginLimitLockout = "LOCKOUT"
+)
+
+func init() {
+	viper.SetDefault("auth.login_throttle.window", 3600)
+	viper.SetDefault("auth.login_throttle.backoff_base", 1)
+	viper.SetDefault("auth.login_throttle.backoff_max", 300)
+	viper.SetDefault("auth.login_throttle.lockout_duration", 900)
+	viper.SetDefault("auth.login_throttle.account.free_attempts", 3)
+	viper.SetDefault("auth.login_throttle.account.lockout_attempts", 10)
+	viper.SetDefault("auth.login_throttle.ip.free_attempts", 20)
+	viper.SetDefault("auth.login_throttle.ip.lockout_attempts", 100)
+}
+
+// loginThrottleTarget is an account or an ip whose login failures are counted.
+type loginThrottleTarget struct {
+	// Type is models.LoginLockoutAccount or models.LoginLockoutIP.
+	Type     string
+	Key      string
+	UserID   uint
+	Username string
+}
+
+func (t loginThrottleTarget) config(name string) int64 {
+	if t.Type == models.LoginLockoutAccount {
+		return viper.GetInt64("auth.login_throttle.account." + name)
+	}
+	return viper.GetInt64("auth.login_throttle.ip." + name)
+}
+
+// loginThrottleTargets returns the targets of a login attempt. The user id is 0 if the account does not exist
+// locally, in which case the account is counted by the submitted username, so that accounts only existing in
+// the LDAP directory are throttled too.
+func loginThrottleTargets(userID uint, username, ip string) []loginThrottleTarget {
+	targets := []loginThrottleTarget{{
+		Type: models.LoginLockoutIP,
+		Key:  "ip:" + ip,
+	}}
+	if userID != 0 {
+		targets = append(targets, loginThrottleTarget{
+			Type:   models.LoginLockoutAccount,
+			Key:    fmt.Sprintf("account:%d", userID),
+			UserID: userID,
+		})
+	} else if username != "" {
+		username = strings.ToLower(username)
+		targets = append(targets, loginThrottleTarget{
+			Type:     models.LoginLockoutAccount,
+			Key:      "account:name:" + username,
+			Username: username,
+		})
+	}
+	return targets
+}
+
+// loginBackoff returns the time to wait after the given count of failures, which doubles with each failure
+// after the free attempts.
+func loginBackoff(failures, freeAttempts int64) time.Duration {
+	if failures <= freeAttempts {
+		return 0
+	}
+	backoff := time.Duration(viper.GetInt64("auth.login_throttle.backoff_base")) * time.Second
+	maxBackoff := time.Duration(viper.GetInt64("auth.login_throttle.backoff_max")) * time.Second
+	for i := freeAttempts + 1; i < failures && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+// CheckLoginThrottle checks whether a login attempt to the user from the ip is allowed. If not, the limit in effect
+// and the time to wait before the next attempt are returned. The user id is 0 if the account does not exist locally,
+// in which case the submitted username is used.
+func CheckLoginThrottle(userID uint, username, ip string) (limit string, retryAfter time.Duration, err error) {
+	store := getRateLimitStore()
+	targets := loginThrottleTargets(userID, username, ip)
+	for _, target := range targets {
+		ttl, err := store.ttl("login_lockout:" + target.Key)
+		if err != nil {
+			return "", 0, errors.Wrap(err, "could not get login lockout")
+		}
+		if ttl > retryAfter {
+			limit, retryAfter = LoginLimitLockout, ttl
+		}
+	}
+	if limit != "" {
+		return
+	}
+	for _, target := range targets {
+		ttl, err := store.ttl("login_backoff:" + target.Key)
+		if err != nil {
+			return "", 0, errors.Wrap(err, "could not get login backoff")
+		}
+		if ttl > retryAfter {
+			limit, retryAfter = LoginLimitBackoff, ttl
+		}
+	}
+	return
+}
+
+// RecordLoginFailure counts a failed login attempt to the user from the ip. The next attempts are delayed after
+// the free attempts, and the account or the ip is locked out after too many failures. Lockouts are recorded
+// as models.LoginLockout.
+func RecordLoginFailure(userID uint, username, ip string) error {
+	store := getRateLimitStore()
+	window := time.Duration(viper.GetInt64("auth.login_throttle.window")) * time.Second
+	for _, target := range loginThrottleTargets(userID, username, ip) {
+		failures, _, err := store.incr("login_failures:"+target.Key, window)
+		if err != nil {
+			return errors.Wrap(err, "could not count login failures")
+		}
+		if lockoutAttempts := target.config("lockout_attempts"); lockoutAttempts > 0 && failures >= lockoutAttempts {
+			if err := lockoutLogin(target, ip, failures); err != nil {
+				return err
+			}
+			continue
+		}
+		if backoff := loginBackoff(failures, target.config("free_attempts")); backoff > 0 {
+			if err := store.set("login_backoff:"+target.Key, backoff); err != nil {
+				return errors.Wrap(err, "could not set login backoff")
+			}
+		}
+	}
+	return nil
+}
+
+func lockoutLogin(target loginThrottleTarget, ip string, failures int64) error {
+	store := getRateLimitStore()
+	duration := time.Duration(viper.GetInt64("auth.login_throttle.lockout_duration")) * time.Second
+	if err := store.set("login_lockout:"+target.Key, duration); err != nil {
+		return errors.Wrap(err, "could not set login lockout")
+	}
+	if err := resetLoginFailures(target.Key); err != nil {
+		return err
+	}
+	lockout := models.LoginLockout{
+		Type:      target.Type,
+		UserID:    target.UserID,
+		Username:  target.Username,
+		IP:        ip,
+		Failures:  failures,
+		ExpiresAt: time.Now().Add(duration),
+	}
+	return errors.Wrap(base.DB.Create(&lockout).Error, "could not record login lockout")
+}
+
+func resetLoginFailures(key string) error {
+	store := getRateLimitStore()
+	if err := store.del("login_failures:" + key); err != nil {
+		return errors.Wrap(err, "could not reset login failures")
+	}
+	return errors.Wrap(store.del("login_backoff:"+key), "could not reset login backoff")
+}
+
+// ResetLoginFailures clears the failures of an account after a successful login.
+// Failures of the ip are kept, so that they could not be cleared by logging into another account.
+func ResetLoginFailures(userID uint) error {
+	return resetLoginFailures(fmt.Sprintf("account:%d", userID))
+}
+
+// unlockLogin removes the lockouts and the failures of the keys, and marks the unexpired lockout records
+// found by the query unlocked by the admin.
+func unlockLogin(keys []string, records *gorm.DB, adminID uint) error {
+	for _, key := range keys {
+		if err := getRateLimitStore().del("login_lockout:" + key); err != nil {
+			return errors.Wrap(err, "could not remove login lockout")
+		}
+		if err := resetLoginFailures(key); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	err := records.Model(&models.LoginLockout{}).
+		Where("unlocked_at is null and expires_at > ?", now).
+		Updates(map[string]interface{}{
+			"unlocked_by_id": adminID,
+			"unlocked_at":    now,
+		}).Error
+	return errors.Wrap(err, "could not update login lockouts")
+}
+
+// UnlockAccount removes the lockout and the failures of an account, including the ones counted by its username
+// before it existed locally, and marks the unexpired lockout records unlocked by the admin.
+func UnlockAccount(user *models.User, adminID uint) error {
+	username := strings.ToLower(user.Username)
+	return unlockLogin([]string{fmt.Sprintf("account:%d", user.ID), "account:name:" + username},
+		base.DB.Where("type = ? and (user_id = ? or (user_id = 0 and username = ?))", models.LoginLockoutAccount, user.ID, username),
+		adminID)
+}
+
+// UnlockLoginLockout removes the lockout of the account or the ip of a lockout record, which could be an ip
+// or a username not existing locally, and marks the unexpired lockout records of it unlocked by the admin.
+func UnlockLoginLockout(lockout *models.LoginLockout, adminID uint) error {
+	if lockout.Type == models.LoginLockoutIP {
+		return unlockLogin([]string{"ip:" + lockout.IP},
+			base.DB.Where("type = ? and ip = ?", models.LoginLockoutIP, lockout.IP), adminID)
+	}
+	if lockout.UserID != 0 {
+		user := models.User{}
+		if err := base.DB.Unscoped().First(&user, lockout.UserID).Error; err != nil {
+			return errors.Wrap(err, "could not find locked user")
+		}
+		return UnlockAccount(&user, adminID)
+	}
+	return unlockLogin([]string{"account:name:" + lockout.Username},
+		base.DB.Where("type = ? and user_id = 0 and username = ?", models.LoginLockoutAccount, lockout.Username), adminID)
+}
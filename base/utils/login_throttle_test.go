@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginBackoff(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, time.Duration(0), loginBackoff(0, 3))
+	assert.Equal(t, time.Duration(0), loginBackoff(3, 3))
+	assert.Equal(t, time.Second, loginBackoff(4, 3))
+	assert.Equal(t, 2*time.Second, loginBackoff(5, 3))
+	assert.Equal(t, 4*time.Second, loginBackoff(6, 3))
+	assert.Equal(t, 300*time.Second, loginBackoff(100, 3))
+}
+
+func TestLoginThrottle(t *testing.T) {
+	t.Parallel()
+	const userID, ip = 1 << 30, "203.0.113.1"
+	for i := 0; i < 3; i++ {
+		limit, _, err := CheckLoginThrottle(userID, "", ip)
+		assert.NoError(t, err)
+		assert.Empty(t, limit)
+		assert.NoError(t, RecordLoginFailure(userID, "", ip))
+	}
+	limit, _, err := CheckLoginThrottle(userID, "", ip)
+	assert.NoError(t, err)
+	assert.Empty(t, limit)
+
+	assert.NoError(t, RecordLoginFailure(userID, "", ip))
+	limit, retryAfter, err := CheckLoginThrottle(userID, "", ip)
+	assert.NoError(t, err)
+	assert.Equal(t, LoginLimitBackoff, limit)
+	assert.True(t, retryAfter > 0 && retryAfter <= time.Second)
+
+	// A successful login clears the failures of the account.
+	assert.NoError(t, ResetLoginFailures(userID))
+	limit, _, err = CheckLoginThrottle(userID, "", ip)
+	assert.NoError(t, err)
+	assert.Empty(t, limit)
+}
+
+func TestLoginThrottleByUsername(t *testing.T) {
+	t.Parallel()
+	// Accounts not existing locally are counted by the username regardless of the ip.
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, RecordLoginFailure(0, "test_login_throttle_by_username", fmt.Sprintf("203.0.113.%d", 10+i)))
+	}
+	limit, _, err := CheckLoginThrottle(0, "Test_Login_Throttle_By_Username", "203.0.113.20")
+	assert.NoError(t, err)
+	assert.Equal(t, LoginLimitBackoff, limit)
+	limit, _, err = CheckLoginThrottle(0, "test_login_throttle_by_username_other", "203.0.113.20")
+	assert.NoError(t, err)
+	assert.Empty(t, limit)
+}
+
+func TestUnlockAccount(t *testing.T) {
+	t.Parallel()
+	user := models.User{
+		ID:       1 << 29,
+		Username: "Test_Unlock_Account",
+	}
+	// The account is locked out by its username before it exists locally, e.g. in the LDAP directory.
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, RecordLoginFailure(0, "test_unlock_account", fmt.Sprintf("203.0.113.%d", 30+i)))
+	}
+	limit, _, err := CheckLoginThrottle(0, user.Username, "203.0.113.50")
+	assert.NoError(t, err)
+	assert.Equal(t, LoginLimitLockout, limit)
+
+	assert.NoError(t, UnlockAccount(&user, 1))
+	limit, _, err = CheckLoginThrottle(0, user.Username, "203.0.113.50")
+	assert.NoError(t, err)
+	assert.Empty(t, limit)
+	lockout := models.LoginLockout{}
+	assert.NoError(t, base.DB.First(&lockout, "username = ?", "test_unlock_account").Error)
+	assert.NotNil(t, lockout.UnlockedAt)
+}
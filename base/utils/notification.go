@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"time"
+
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/log"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+func init() {
+	// Students are reminded of the deadline of a problem set this many seconds before it ends.
+	viper.SetDefault("notification.deadline_reminder", 86400)
+}
+
+// CreateNotifications sends a copy of the notification to each of the users,
+// and emails the ones who turned on email notification.
+func CreateNotifications(userIDs []uint, notification models.Notification) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	notifications := make([]models.Notification, len(userIDs))
+	for i, id := range userIDs {
+		notifications[i] = notification
+		notifications[i].UserID = id
+	}
+	if err := base.DB.Create(&notifications).Error; err != nil {
+		return errors.Wrap(err, "could not create notifications")
+	}
+	var users []models.User
+	if err := base.DB.Find(&users, "id in (?) and email_notification = ? and email_verified = ?", userIDs, true, true).Error; err != nil {
+		return errors.Wrap(err, "could not get users for sending notification emails")
+	}
+	if len(users) == 0 {
+		return nil
+	}
+	action := func() {
+		if viper.GetBool("email.inTest") {
+			return
+		}
+		message := fmt.Sprintf("<h3>%s</h3><pre>%s</pre>", html.EscapeString(notification.Title), html.EscapeString(notification.Content))
+		for _, user := range users {
+			if err := SendMail(user.Email, notification.Title, message); err != nil {
+				log.Errorf("%+v\n", errors.Wrap(err, "could not send notification email"))
+			}
+		}
+	}
+	if viper.GetBool("email.inTest") {
+		action()
+	} else {
+		go action()
+	}
+	return nil
+}
+
+// NotifySubmissionJudged notifies the submitter that the submission is judged.
+func NotifySubmissionJudged(submission *models.Submission) error {
+	notification := models.Notification{
+		Type:     models.NotificationTypeSubmissionJudged,
+		Title:    fmt.Sprintf("Submission #%d is judged", submission.ID),
+		Content:  fmt.Sprintf("Your submission #%d is judged: %s, score %d.", submission.ID, submission.Status, submission.Score),
+		TargetID: submission.ID,
+	}
+	if submission.ProblemSetID != 0 {
+		problemSet := models.ProblemSet{}
+		if err := base.DB.First(&problemSet, submission.ProblemSetID).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.Wrap(err, "could not get problem set for notifying submission")
+			}
+		}
+		notification.ClassID = problemSet.ClassID
+	}
+	return CreateNotifications([]uint{submission.UserID}, notification)
+}
+
+// notifyProblemSets notifies the students of each problem set which has not been notified with the type yet.
+func notifyProblemSets(query *gorm.DB, notificationType string, notification func(*models.ProblemSet) models.Notification) error {
+	var problemSets []*models.ProblemSet
+	if err := query.
+		Where("id not in (?)", base.DB.Model(&models.Notification{}).Select("target_id").Where("type = ?", notificationType)).
+		Find(&problemSets).Error; err != nil {
+		return errors.Wrap(err, "could not get problem sets for notifying")
+	}
+	for _, problemSet := range problemSets {
+		var studentIDs []uint
+		if err := base.DB.Table("user_in_classes").Where("class_id = ?", problemSet.ClassID).
+			Pluck("user_id", &studentIDs).Error; err != nil {
+			return errors.Wrap(err, "could not get students for notifying")
+		}
+		n := notification(problemSet)
+		n.Type = notificationType
+		n.ClassID = problemSet.ClassID
+		n.TargetID = problemSet.ID
+		if err := CreateNotifications(studentIDs, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unlockScript deletes a lock only if it is still held with the given value.
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// NotifyProblemSets notifies students of the problem sets which have just started or will end soon.
+// Each problem set is notified only once for each kind, so it is safe to call this periodically.
+// The notified problem sets are checked before notifying, so the instances take turns with a lock in redis.
+func NotifyProblemSets() error {
+	if base.Redis != nil {
+		value := RandStr(16)
+		ok, err := base.Redis.SetNX(context.Background(), "notifier_lock", value, 5*time.Minute).Result()
+		if err != nil {
+			return errors.Wrap(err, "could not lock notifier")
+		}
+		if !ok {
+			return nil
+		}
+		defer func() {
+			if err := unlockScript.Run(context.Background(), base.Redis, []string{"notifier_lock"}, value).Err(); err != nil {
+				log.Errorf("%+v\n", errors.Wrap(err, "could not unlock notifier"))
+			}
+		}()
+	}
+	now := time.Now()
+	// Problem sets started long before are not worth notifying.
+	if err := notifyProblemSets(base.DB.Where("start_time <= ? and start_time > ? and end_time > ?", now, now.Add(-24*time.Hour), now),
+		models.NotificationTypeProblemSetStarted, func(problemSet *models.ProblemSet) models.Notification {
+			return models.Notification{
+				Title:   fmt.Sprintf("Problem set %s has started", problemSet.Name),
+				Content: fmt.Sprintf("Problem set %s has started, and will end at %s.", problemSet.Name, problemSet.EndTime.Format(time.RFC3339)),
+			}
+		}); err != nil {
+		return err
+	}
+	reminder := time.Duration(viper.GetInt64("notification.deadline_reminder")) * time.Second
+	return notifyProblemSets(base.DB.Where("start_time <= ? and end_time > ? and end_time <= ?", now, now, now.Add(reminder)),
+		models.NotificationTypeProblemSetDeadline, func(problemSet *models.ProblemSet) models.Notification {
+			return models.Notification{
+				Title:   fmt.Sprintf("Problem set %s is ending soon", problemSet.Name),
+				Content: fmt.Sprintf("Problem set %s will end at %s.", problemSet.Name, problemSet.EndTime.Format(time.RFC3339)),
+			}
+		})
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotifySubmissionJudged(t *testing.T) {
+	t.Parallel()
+
+	user := models.User{
+		Username: "test_notify_submission_judged_username",
+		Nickname: "test_notify_submission_judged_nickname",
+		Email:    "test_notify_submission_judged@mail.com",
+		Password: "test_notify_submission_judged_password",
+	}
+	assert.NoError(t, base.DB.Create(&user).Error)
+	submission := models.Submission{
+		UserID: user.ID,
+		Status: "WRONG_ANSWER",
+		Score:  60,
+	}
+	assert.NoError(t, base.DB.Create(&submission).Error)
+	assert.NoError(t, NotifySubmissionJudged(&submission))
+
+	notification := models.Notification{}
+	assert.NoError(t, base.DB.First(&notification, "user_id = ?", user.ID).Error)
+	assert.Equal(t, models.NotificationTypeSubmissionJudged, notification.Type)
+	assert.Equal(t, submission.ID, notification.TargetID)
+	assert.Equal(t, uint(0), notification.ClassID)
+	assert.False(t, notification.Read)
+}
+
+func TestNotifyProblemSets(t *testing.T) {
+	t.Parallel()
+
+	user := models.User{
+		Username: "test_notify_problem_sets_username",
+		Nickname: "test_notify_problem_sets_nickname",
+		Email:    "test_notify_problem_sets@mail.com",
+		Password: "test_notify_problem_sets_password",
+	}
+	assert.NoError(t, base.DB.Create(&user).Error)
+	class := models.Class{
+		Name:       "test_notify_problem_sets_name",
+		InviteCode: GenerateInviteCode(),
+		Students:   []*models.User{&user},
+	}
+	assert.NoError(t, base.DB.Create(&class).Error)
+	started := models.ProblemSet{
+		ClassID:   class.ID,
+		Name:      "test_notify_problem_sets_started",
+		StartTime: time.Now().Add(-1 * time.Minute),
+		EndTime:   time.Now().Add(72 * time.Hour),
+	}
+	ending := models.ProblemSet{
+		ClassID:   class.ID,
+		Name:      "test_notify_problem_sets_ending",
+		StartTime: time.Now().Add(-72 * time.Hour),
+		EndTime:   time.Now().Add(time.Hour),
+	}
+	notStarted := models.ProblemSet{
+		ClassID:   class.ID,
+		Name:      "test_notify_problem_sets_not_started",
+		StartTime: time.Now().Add(time.Hour),
+		EndTime:   time.Now().Add(2 * time.Hour),
+	}
+	assert.NoError(t, base.DB.Create(&[]*models.ProblemSet{&started, &ending, &notStarted}).Error)
+
+	assert.NoError(t, NotifyProblemSets())
+	// Notifying again should not send duplicated notifications.
+	assert.NoError(t, NotifyProblemSets())
+
+	var notifications []models.Notification
+	assert.NoError(t, base.DB.Order("id").Find(&notifications, "user_id = ?", user.ID).Error)
+	assert.Len(t, notifications, 2)
+	assert.Equal(t, models.NotificationTypeProblemSetStarted, notifications[0].Type)
+	assert.Equal(t, started.ID, notifications[0].TargetID)
+	assert.Equal(t, class.ID, notifications[0].ClassID)
+	assert.Equal(t, models.NotificationTypeProblemSetDeadline, notifications[1].Type)
+	assert.Equal(t, ending.ID, notifications[1].TargetID)
+}
@@ -0,0 +1,340 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// OIDCProvider is an OpenID Connect identity provider users could login with.
+// The providers are configured in auth.oidc.providers.
+type OIDCProvider struct {
+	// Name identifies the provider in the APIs and the identities linked to users.
+	Name        string `mapstructure:"name"`
+	DisplayName string `mapstructure:"display_name"`
+	Issuer      string `mapstructure:"issuer"`
+	ClientID    string `mapstructure:"client_id"`
+	// ClientSecret is empty for public clients, which are secured by PKCE only.
+	ClientSecret string   `mapstructure:"client_secret"`
+	RedirectURL  string   `mapstructure:"redirect_url"`
+	Scopes       []string `mapstructure:"scopes"`
+	// UsernameClaim is the claim used as the username of auto-provisioned users.
+	UsernameClaim string `mapstructure:"username_claim"`
+	// AutoProvision creates users for identities not linked to any user.
+	AutoProvision bool `mapstructure:"auto_provision"`
+}
+
+// OIDCClaims is the claims about a user in a verified ID token.
+type OIDCClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	// Username is the value of the username claim of the provider.
+	Username string
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+var oidcClient = &http.Client{Timeout: 10 * time.Second}
+
+// oidcDiscoveries caches the discovery documents of the providers, keyed by issuer.
+var oidcDiscoveries = cache.New(time.Hour, 2*time.Hour)
+
+// oidcKeys caches the signing keys of the providers, keyed by jwks uri.
+var oidcKeys = cache.New(time.Hour, 2*time.Hour)
+
+// GetOIDCProviders returns the configured OpenID Connect providers.
+func GetOIDCProviders() ([]OIDCProvider, error) {
+	var providers []OIDCProvider
+	if err := viper.UnmarshalKey("auth.oidc.providers", &providers); err != nil {
+		return nil, errors.Wrap(err, "could not read oidc providers")
+	}
+	for i := range providers {
+		if len(providers[i].Scopes) == 0 {
+			providers[i].Scopes = []string{"openid", "email", "profile"}
+		}
+		if providers[i].UsernameClaim == "" {
+			providers[i].UsernameClaim = "preferred_username"
+		}
+		if providers[i].DisplayName == "" {
+			providers[i].DisplayName = providers[i].Name
+		}
+	}
+	return providers, nil
+}
+
+// GetOIDCProvider returns the provider of the given name, or nil if it is not configured.
+func GetOIDCProvider(name string) (*OIDCProvider, error) {
+	providers, err := GetOIDCProviders()
+	if err != nil {
+		return nil, err
+	}
+	for i := range providers {
+		if providers[i].Name == name {
+			return &providers[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// NewPKCEVerifier generates a code verifier for PKCE.
+func NewPKCEVerifier() string {
+	return RandStr(64)
+}
+
+// PKCEChallenge returns the S256 code challenge of a code verifier.
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (p *OIDCProvider) discover() (*oidcDiscovery, error) {
+	if d, ok := oidcDiscoveries.Get(p.Issuer); ok {
+		return d.(*oidcDiscovery), nil
+	}
+	resp, err := oidcClient.Get(strings.TrimSuffix(p.Issuer, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get oidc discovery document")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("could not get oidc discovery document: status %d", resp.StatusCode)
+	}
+	d := oidcDiscovery{}
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return nil, errors.Wrap(err, "could not decode oidc discovery document")
+	}
+	if d.Issuer != p.Issuer {
+		return nil, errors.Errorf("oidc issuer mismatch: expected %s, got %s", p.Issuer, d.Issuer)
+	}
+	oidcDiscoveries.SetDefault(p.Issuer, &d)
+	return &d, nil
+}
+
+// AuthCodeURL returns the url of the provider to start the authorization code flow with PKCE.
+func (p *OIDCProvider) AuthCodeURL(state, nonce, codeVerifier string) (string, error) {
+	d, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(d.AuthorizationEndpoint)
+	if err != nil {
+		return "", errors.Wrap(err, "could not parse authorization endpoint")
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.ClientID)
+	q.Set("redirect_uri", p.RedirectURL)
+	q.Set("scope", strings.Join(p.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", PKCEChallenge(codeVerifier))
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Exchange exchanges an authorization code for an ID token, and returns the claims in the verified token.
+func (p *OIDCProvider) Exchange(code, codeVerifier, nonce string) (*OIDCClaims, error) {
+	d, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("client_id", p.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	if p.ClientSecret != "" {
+		form.Set("client_secret", p.ClientSecret)
+	}
+	resp, err := oidcClient.PostForm(d.TokenEndpoint, form)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not exchange authorization code")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("could not exchange authorization code: status %d", resp.StatusCode)
+	}
+	tokenResp := struct {
+		IDToken string `json:"id_token"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, errors.Wrap(err, "could not decode token response")
+	}
+	return p.verifyIDToken(d, tokenResp.IDToken, nonce)
+}
+
+// getKey returns the signing key of the given key id. The cached keys are refetched
+// if the key id is unknown, as the provider may have rotated its keys.
+func (p *OIDCProvider) getKey(d *oidcDiscovery, kid string) (*rsa.PublicKey, error) {
+	if keys, ok := oidcKeys.Get(d.JWKSURI); ok {
+		if key, ok := keys.(map[string]*rsa.PublicKey)[kid]; ok {
+			return key, nil
+		}
+	}
+	keys, err := p.getKeys(d)
+	if err != nil {
+		return nil, err
+	}
+	oidcKeys.SetDefault(d.JWKSURI, keys)
+	key, ok := keys[kid]
+	if !ok {
+		return nil, errors.Errorf("unknown id token key %s", kid)
+	}
+	return key, nil
+}
+
+func (p *OIDCProvider) getKeys(d *oidcDiscovery) (map[string]*rsa.PublicKey, error) {
+	resp, err := oidcClient.Get(d.JWKSURI)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get oidc keys")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("could not get oidc keys: status %d", resp.StatusCode)
+	}
+	jwks := struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return nil, errors.Wrap(err, "could not decode oidc keys")
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not decode oidc key modulus")
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not decode oidc key exponent")
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+// verifyIDToken verifies the RS256 signature, the issuer, the audience, the expiry and the nonce of an ID token.
+func (p *OIDCProvider) verifyIDToken(d *oidcDiscovery, idToken, nonce string) (*OIDCClaims, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed id token")
+	}
+	header := struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}{}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, errors.Wrap(err, "could not decode id token header")
+	}
+	if header.Alg != "RS256" {
+		return nil, errors.Errorf("unsupported id token algorithm %s", header.Alg)
+	}
+	key, err := p.getKey(d, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode id token signature")
+	}
+	hashed := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signature); err != nil {
+		return nil, errors.Wrap(err, "invalid id token signature")
+	}
+	claims := make(map[string]interface{})
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, errors.Wrap(err, "could not decode id token claims")
+	}
+	if claims["iss"] != p.Issuer {
+		return nil, errors.New("id token issuer mismatch")
+	}
+	if !audienceContains(claims["aud"], p.ClientID) {
+		return nil, errors.New("id token audience mismatch")
+	}
+	if exp, ok := claims["exp"].(float64); !ok || time.Unix(int64(exp), 0).Before(time.Now()) {
+		return nil, errors.New("id token expired")
+	}
+	if claims["nonce"] != nonce {
+		return nil, errors.New("id token nonce mismatch")
+	}
+	ret := OIDCClaims{
+		Subject:  stringClaim(claims, "sub"),
+		Email:    stringClaim(claims, "email"),
+		Name:     stringClaim(claims, "name"),
+		Username: stringClaim(claims, p.UsernameClaim),
+	}
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		ret.EmailVerified = v
+	case string:
+		ret.EmailVerified = v == "true"
+	}
+	if ret.Subject == "" {
+		return nil, errors.New("id token without subject")
+	}
+	return &ret, nil
+}
+
+func decodeJWTSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func audienceContains(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, a := range v {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func stringClaim(claims map[string]interface{}, name string) string {
+	switch v := claims[name].(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprint(int64(v))
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPKCEChallenge(t *testing.T) {
+	t.Parallel()
+	// The example in RFC 7636 appendix B.
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", PKCEChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+	assert.Len(t, NewPKCEVerifier(), 64)
+}
+
+func TestAudienceContains(t *testing.T) {
+	t.Parallel()
+	assert.True(t, audienceContains("client", "client"))
+	assert.False(t, audienceContains("other", "client"))
+	assert.True(t, audienceContains([]interface{}{"other", "client"}, "client"))
+	assert.False(t, audienceContains([]interface{}{"other"}, "client"))
+	assert.False(t, audienceContains(nil, "client"))
+}
+
+func TestOIDCGetKey(t *testing.T) {
+	t.Parallel()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	var fetches int32
+	// The provider rotates its key after the first fetch, and fails from the fourth fetch.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&fetches, 1)
+		kid := "test_key_2"
+		if n == 1 {
+			kid = "test_key_1"
+		}
+		if n >= 4 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{
+				{
+					"kty": "RSA",
+					"kid": kid,
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				},
+			},
+		})
+	}))
+	defer server.Close()
+	p := OIDCProvider{}
+	d := oidcDiscovery{JWKSURI: server.URL}
+
+	k, err := p.getKey(&d, "test_key_1")
+	assert.NoError(t, err)
+	assert.Equal(t, &key.PublicKey, k)
+	// The keys are cached.
+	_, err = p.getKey(&d, "test_key_1")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+
+	// The keys are refetched for an unknown key id.
+	k, err = p.getKey(&d, "test_key_2")
+	assert.NoError(t, err)
+	assert.Equal(t, &key.PublicKey, k)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&fetches))
+
+	_, err = p.getKey(&d, "test_key_3")
+	assert.EqualError(t, err, "unknown id token key test_key_3")
+
+	_, err = p.getKey(&d, "test_key_4")
+	assert.EqualError(t, err, "could not get oidc keys: status 500")
+}
@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/pkg/errors"
+)
+
+// PersonalAccessTokenPrefix is the prefix of personal access tokens, which tells them from session tokens.
+const PersonalAccessTokenPrefix = "pat_"
+
+type tokenScope struct {
+	// Routes are the names of the routes the scope could access.
+	Routes []string
+	// Permissions are the permissions of the user the scope could use.
+	Permissions []string
+	// Includes are the scopes implied by the scope.
+	Includes []string
+}
+
+// tokenScopes are the scopes of personal access tokens. Routes not listed here could not be accessed
+// with personal access tokens.
+var tokenScopes = map[string]tokenScope{
+	"problem:read": {
+		Routes: []string{
+			"problem.getProblem",
+			"problem.getProblems",
+			"problem.getRandomProblem",
+			"problem.getProblemAttachmentFile",
+			"problem.getTestCaseInputFile",
+			"problem.getTestCaseOutputFile",
+		},
+		Permissions: []string{"read_problem_secrets"},
+	},
+	"problem:write": {
+		Routes: []string{
+			"problem.createProblem",
+			"problem.updateProblem",
+			"problem.deleteProblem",
+			"problem.createTestCase",
+			"problem.updateTestCase",
+			"problem.deleteTestCase",
+			"problem.deleteTestCases",
+		},
+		Permissions: []string{"create_problem", "update_problem", "delete_problem"},
+		Includes:    []string{"problem:read"},
+	},
+	"grades:read": {
+		Routes: []string{
+			"user.getMyGrades",
+			"user.getMyGradeChanges",
+			"class.getClassGrades",
+			"class.getCourseGrades",
+			"class.getClassStatistics",
+			"problemSet.GetProblemSetGrades",
+			"problemSet.getGradeOverrides",
+			"problemSet.getGradeChanges",
+		},
+		Permissions: []string{"read_grades"},
+	},
+	"grades:write": {
+		Routes: []string{
+			"class.updateGradeWeights",
+			"problemSet.RefreshGrades",
+			"problemSet.overrideGrade",
+			"problemSet.removeGradeOverride",
+			"problemSet.updateSubmissionFeedback",
+		},
+		Permissions: []string{"manage_grades"},
+		Includes:    []string{"grades:read"},
+	},
+	"submission:read": {
+		Routes: []string{
+			"submission.getSubmission",
+			"submission.getSubmissions",
+			"submission.getSubmissionCode",
+			"submission.getRunOutput",
+			"submission.getRunInput",
+			"submission.getRunCompilerOutput",
+			"submission.getRunComparerOutput",
+			"problemSet.getSubmission",
+			"problemSet.getSubmissions",
+			"problemSet.getSubmissionCode",
+			"problemSet.getRunOutput",
+			"problemSet.getRunInput",
+			"problemSet.getRunCompilerOutput",
+			"problemSet.getRunComparerOutput",
+		},
+		Permissions: []string{"read_answers"},
+	},
+	"submission:write": {
+		Routes: []string{
+			"submission.createSubmission",
+			"problemSet.createSubmission",
+			"problemSet.rejudgeSubmission",
+		},
+		Permissions: []string{"rejudge"},
+		Includes:    []string{"submission:read"},
+	},
+}
+
+// TokenScopes returns the names of all scopes of personal access tokens.
+func TokenScopes() []string {
+	scopes := make([]string, 0, len(tokenScopes))
+	for scope := range tokenScopes {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// IsValidTokenScope returns whether the scope is a scope of personal access tokens.
+func IsValidTokenScope(scope string) bool {
+	_, ok := tokenScopes[scope]
+	return ok
+}
+
+// expandTokenScopes returns the given scopes and the scopes implied by them.
+func expandTokenScopes(scopes []string) []tokenScope {
+	var ret []tokenScope
+	visited := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		scope, ok := tokenScopes[name]
+		if !ok || visited[name] {
+			return
+		}
+		visited[name] = true
+		ret = append(ret, scope)
+		for _, included := range scope.Includes {
+			visit(included)
+		}
+	}
+	for _, name := range scopes {
+		visit(name)
+	}
+	return ret
+}
+
+// TokenScopesAllowRoute returns whether the route of the given name could be accessed with the scopes.
+func TokenScopesAllowRoute(scopes []string, routeName string) bool {
+	for _, scope := range expandTokenScopes(scopes) {
+		for _, route := range scope.Routes {
+			if route == routeName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// TokenScopePermissions returns the permissions the scopes could use. The result is never nil.
+func TokenScopePermissions(scopes []string) []string {
+	permissions := make([]string, 0)
+	for _, scope := range expandTokenScopes(scopes) {
+		permissions = append(permissions, scope.Permissions...)
+	}
+	return permissions
+}
+
+func hashPersonalAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewPersonalAccessToken creates a personal access token for the user, and returns the token string.
+// Only the hash of the token is stored, so the token string could not be got again.
+func NewPersonalAccessToken(userID uint, name string, scopes []string, expiresAt time.Time) (string, *models.PersonalAccessToken, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, errors.Wrap(err, "could not generate personal access token")
+	}
+	tokenString := PersonalAccessTokenPrefix + hex.EncodeToString(b)
+	token := models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashPersonalAccessToken(tokenString),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err := base.DB.Create(&token).Error; err != nil {
+		return "", nil, errors.Wrap(err, "could not create personal access token")
+	}
+	return tokenString, &token, nil
+}
+
+func GetPersonalAccessToken(tokenString string) (token models.PersonalAccessToken, err error) {
+	err = base.DB.Preload("User").Where("token_hash = ?", hashPersonalAccessToken(tokenString)).First(&token).Error
+	return
+}
+
+func IsPersonalAccessTokenExpired(token models.PersonalAccessToken) bool {
+	return token.ExpiresAt.Before(time.Now())
+}
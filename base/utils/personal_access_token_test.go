@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenScopesAllowRoute(t *testing.T) {
+	t.Parallel()
+	assert.True(t, TokenScopesAllowRoute([]string{"problem:read"}, "problem.getProblem"))
+	assert.False(t, TokenScopesAllowRoute([]string{"problem:read"}, "problem.updateProblem"))
+	assert.True(t, TokenScopesAllowRoute([]string{"problem:write"}, "problem.updateProblem"))
+	// Write scopes include read scopes.
+	assert.True(t, TokenScopesAllowRoute([]string{"problem:write"}, "problem.getProblem"))
+	assert.True(t, TokenScopesAllowRoute([]string{"problem:read", "grades:read"}, "class.getClassGrades"))
+	assert.False(t, TokenScopesAllowRoute([]string{"grades:read"}, "user.getMe"))
+	assert.False(t, TokenScopesAllowRoute([]string{"non_existing"}, "problem.getProblem"))
+	assert.False(t, TokenScopesAllowRoute(nil, ""))
+}
+
+func TestTokenScopePermissions(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, []string{}, TokenScopePermissions(nil))
+	assert.Equal(t, []string{"read_grades"}, TokenScopePermissions([]string{"grades:read"}))
+	assert.ElementsMatch(t, []string{"manage_grades", "read_grades"}, TokenScopePermissions([]string{"grades:write", "grades:read"}))
+	assert.ElementsMatch(t, []string{"rejudge", "read_answers"}, TokenScopePermissions([]string{"submission:write"}))
+}
+
+func TestNewPersonalAccessToken(t *testing.T) {
+	t.Parallel()
+	user := models.User{
+		Username: "test_new_personal_access_token",
+		Nickname: "test_new_personal_access_token",
+		Email:    "test_new_personal_access_token@e.e",
+	}
+	assert.NoError(t, base.DB.Create(&user).Error)
+	expiresAt := time.Now().Add(time.Hour)
+	tokenString, token, err := NewPersonalAccessToken(user.ID, "script", []string{"problem:read"}, expiresAt)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(tokenString, PersonalAccessTokenPrefix))
+	assert.NotEqual(t, tokenString, token.TokenHash)
+
+	got, err := GetPersonalAccessToken(tokenString)
+	assert.NoError(t, err)
+	assert.Equal(t, token.ID, got.ID)
+	assert.Equal(t, user.ID, got.User.ID)
+	assert.Equal(t, []string{"problem:read"}, []string(got.Scopes))
+	assert.False(t, IsPersonalAccessTokenExpired(got))
+
+	_, err = GetPersonalAccessToken(PersonalAccessTokenPrefix + "wrong")
+	assert.Error(t, err)
+}
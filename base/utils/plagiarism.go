@@ -0,0 +1,452 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/base/log"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/minio/minio-go/v7"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	// The number of tokens in each fingerprinted k-gram.
+	viper.SetDefault("plagiarism.k", 5)
+	// The number of k-grams in each winnowing window.
+	viper.SetDefault("plagiarism.window", 4)
+	// Pairs of submissions with a similarity lower than this are not reported.
+	viper.SetDefault("plagiarism.threshold", 0.5)
+	// A running report is taken as interrupted if its server has not renewed it for this many seconds.
+	viper.SetDefault("plagiarism.lease", 60)
+}
+
+func plagiarismLease() time.Duration {
+	return time.Duration(viper.GetInt64("plagiarism.lease")) * time.Second
+}
+
+// InstanceID tells this server from the others running on the same database.
+// It is the hostname unless server.instance_id is configured.
+func InstanceID() string {
+	if id := viper.GetString("server.instance_id"); id != "" {
+		return id
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Errorf("%+v\n", errors.Wrap(err, "could not get hostname"))
+	}
+	return hostname
+}
+
+type codeToken struct {
+	text string
+	line uint
+}
+
+type languageSyntax struct {
+	lineComments  []string
+	blockComments [][2]string
+	keywords      map[string]bool
+}
+
+func keywordSet(keywords string) map[string]bool {
+	set := make(map[string]bool)
+	for _, k := range strings.Fields(keywords) {
+		set[k] = true
+	}
+	return set
+}
+
+var (
+	cLikeSyntax = languageSyntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		keywords: keywordSet(`auto break case catch char class const continue default delete do double else enum extern
+			final float for func go goto if import int interface long map namespace new package private protected public
+			range return short signed sizeof static struct switch template this throw try typedef union unsigned using
+			var virtual void volatile while bool boolean string byte true false null nullptr nil`),
+	}
+	pythonSyntax = languageSyntax{
+		lineComments:  []string{"#"},
+		blockComments: [][2]string{{`"""`, `"""`}, {`'''`, `'''`}},
+		keywords: keywordSet(`and as assert break class continue def del elif else except finally for from global if
+			import in is lambda nonlocal not or pass raise return try while with yield True False None print input
+			range len int str float list dict set`),
+	}
+)
+
+func getLanguageSyntax(language string) *languageSyntax {
+	if strings.HasPrefix(strings.ToLower(language), "py") {
+		return &pythonSyntax
+	}
+	return &cLikeSyntax
+}
+
+// tokenizeCode splits the code into tokens, dropping comments and whitespaces.
+// Identifiers, numbers and strings are normalized, so that renaming variables
+// or changing constants does not hide similarities.
+func tokenizeCode(language string, code string) (tokens []codeToken) {
+	syntax := getLanguageSyntax(language)
+	src := []rune(code)
+	line := uint(1)
+	hasPrefix := func(i int, prefix string) bool {
+		return strings.HasPrefix(string(src[i:min(len(src), i+len(prefix))]), prefix)
+	}
+	// skipUntil moves i past the end marker, counting lines on the way.
+	skipUntil := func(i int, end string) int {
+		for i < len(src) && !hasPrefix(i, end) {
+			if src[i] == '\n' {
+				line++
+			}
+			i++
+		}
+		return min(len(src), i+len([]rune(end)))
+	}
+	for i := 0; i < len(src); {
+		r := src[i]
+		if r == '\n' {
+			line++
+			i++
+			continue
+		}
+		if unicode.IsSpace(r) {
+			i++
+			continue
+		}
+		skipped := false
+		for _, c := range syntax.lineComments {
+			if hasPrefix(i, c) {
+				for i < len(src) && src[i] != '\n' {
+					i++
+				}
+				skipped = true
+				break
+			}
+		}
+		for _, c := range syntax.blockComments {
+			if !skipped && hasPrefix(i, c[0]) {
+				i = skipUntil(i+len([]rune(c[0])), c[1])
+				skipped = true
+			}
+		}
+		if skipped {
+			continue
+		}
+		start := i
+		startLine := line
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+			for i < len(src) && (src[i] == '_' || unicode.IsLetter(src[i]) || unicode.IsDigit(src[i])) {
+				i++
+			}
+			word := string(src[start:i])
+			if !syntax.keywords[word] {
+				word = "id"
+			}
+			tokens = append(tokens, codeToken{word, startLine})
+		case unicode.IsDigit(r):
+			for i < len(src) && (src[i] == '.' || src[i] == '_' || unicode.IsLetter(src[i]) || unicode.IsDigit(src[i])) {
+				i++
+			}
+			tokens = append(tokens, codeToken{"num", startLine})
+		case r == '"' || r == '\'' || r == '`':
+			i++
+			for i < len(src) && src[i] != r && src[i] != '\n' {
+				if src[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i = min(len(src), i+1)
+			tokens = append(tokens, codeToken{"str", startLine})
+		default:
+			i++
+			tokens = append(tokens, codeToken{string(r), startLine})
+		}
+	}
+	return
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+type fingerprint struct {
+	hash uint64
+	// pos is the index of the first token of the k-gram.
+	pos int
+}
+
+// winnow selects the fingerprints of the tokens using the winnowing algorithm:
+// the minimum k-gram hash of every window is selected, the rightmost one on ties.
+func winnow(tokens []codeToken, k, window int) (fingerprints []fingerprint) {
+	if len(tokens) < k {
+		return nil
+	}
+	hashes := make([]uint64, len(tokens)-k+1)
+	for i := range hashes {
+		h := fnv.New64a()
+		for _, t := range tokens[i : i+k] {
+			h.Write([]byte(t.text))
+			h.Write([]byte{0})
+		}
+		hashes[i] = h.Sum64()
+	}
+	if window > len(hashes) {
+		window = len(hashes)
+	}
+	last := -1
+	for start := 0; start+window <= len(hashes); start++ {
+		minPos := start
+		for i := start; i < start+window; i++ {
+			if hashes[i] <= hashes[minPos] {
+				minPos = i
+			}
+		}
+		if minPos != last {
+			fingerprints = append(fingerprints, fingerprint{hashes[minPos], minPos})
+			last = minPos
+		}
+	}
+	return
+}
+
+type plagiarismDocument struct {
+	submission   *models.Submission
+	tokens       []codeToken
+	fingerprints map[uint64]int
+}
+
+func newPlagiarismDocument(submission *models.Submission, code string, k, window int) *plagiarismDocument {
+	d := plagiarismDocument{
+		submission:   submission,
+		tokens:       tokenizeCode(submission.LanguageName, code),
+		fingerprints: make(map[uint64]int),
+	}
+	for _, f := range winnow(d.tokens, k, window) {
+		if _, ok := d.fingerprints[f.hash]; !ok {
+			d.fingerprints[f.hash] = f.pos
+		}
+	}
+	return &d
+}
+
+// comparePlagiarismDocuments calculates the similarity of two documents, which is the
+// proportion of the fingerprints of the smaller document found in the other one,
+// and the matched regions.
+func comparePlagiarismDocuments(a, b *plagiarismDocument, k int) (similarity float64, regions []models.PlagiarismRegion) {
+	if len(a.fingerprints) == 0 || len(b.fingerprints) == 0 {
+		return 0, nil
+	}
+	for hash, posA := range a.fingerprints {
+		posB, ok := b.fingerprints[hash]
+		if !ok {
+			continue
+		}
+		regions = append(regions, models.PlagiarismRegion{
+			FirstStartLine:  a.tokens[posA].line,
+			FirstEndLine:    a.tokens[posA+k-1].line,
+			SecondStartLine: b.tokens[posB].line,
+			SecondEndLine:   b.tokens[posB+k-1].line,
+		})
+	}
+	smaller := len(a.fingerprints)
+	if len(b.fingerprints) < smaller {
+		smaller = len(b.fingerprints)
+	}
+	similarity = float64(len(regions)) / float64(smaller)
+	return similarity, mergePlagiarismRegions(regions)
+}
+
+// mergePlagiarismRegions merges the regions which overlap or are adjacent in both submissions.
+func mergePlagiarismRegions(regions []models.PlagiarismRegion) (merged []models.PlagiarismRegion) {
+	sort.Slice(regions, func(i, j int) bool {
+		if regions[i].FirstStartLine != regions[j].FirstStartLine {
+			return regions[i].FirstStartLine < regions[j].FirstStartLine
+		}
+		return regions[i].SecondStartLine < regions[j].SecondStartLine
+	})
+	for _, r := range regions {
+		if len(merged) > 0 {
+			last := &merged[len(merged)-1]
+			if r.FirstStartLine <= last.FirstEndLine+1 &&
+				r.SecondStartLine <= last.SecondEndLine+1 && r.SecondEndLine+1 >= last.SecondStartLine {
+				if r.FirstEndLine > last.FirstEndLine {
+					last.FirstEndLine = r.FirstEndLine
+				}
+				if r.SecondStartLine < last.SecondStartLine {
+					last.SecondStartLine = r.SecondStartLine
+				}
+				if r.SecondEndLine > last.SecondEndLine {
+					last.SecondEndLine = r.SecondEndLine
+				}
+				continue
+			}
+		}
+		merged = append(merged, r)
+	}
+	return
+}
+
+// getPlagiarismSubmissions returns the submission to be analysed of each student for each problem,
+// which is the latest accepted submission, or the latest submission if none is accepted.
+// Upsolve submissions are not analysed, as they are made after the problem set ends.
+func getPlagiarismSubmissions(problemSet *models.ProblemSet) (map[uint][]*models.Submission, error) {
+	var submissions []*models.Submission
+	if err := base.DB.Order("id desc").Find(&submissions, "problem_set_id = ? and upsolve = ? and user_id in (?)", problemSet.ID, false,
+		base.DB.Table("user_in_classes").Select("user_id").Where("class_id = ?", problemSet.ClassID)).Error; err != nil {
+		return nil, errors.Wrap(err, "could not get submissions for plagiarism analysis")
+	}
+	chosen := make(map[uint]map[uint]*models.Submission)
+	for _, s := range submissions {
+		if chosen[s.ProblemID] == nil {
+			chosen[s.ProblemID] = make(map[uint]*models.Submission)
+		}
+		if c, ok := chosen[s.ProblemID][s.UserID]; !ok || (c.Status != "ACCEPTED" && s.Status == "ACCEPTED") {
+			chosen[s.ProblemID][s.UserID] = s
+		}
+	}
+	result := make(map[uint][]*models.Submission)
+	for problemID, users := range chosen {
+		for _, s := range users {
+			result[problemID] = append(result[problemID], s)
+		}
+		sort.Slice(result[problemID], func(i, j int) bool {
+			return result[problemID][i].ID < result[problemID][j].ID
+		})
+	}
+	return result, nil
+}
+
+func getSubmissionCode(submission *models.Submission) (string, error) {
+	object, err := base.Storage.GetObject(context.Background(), "submissions", fmt.Sprintf("%d/code", submission.ID), minio.GetObjectOptions{})
+	if err != nil {
+		return "", errors.Wrap(err, "could not get submission code")
+	}
+	defer object.Close()
+	code, err := ioutil.ReadAll(object)
+	if err != nil {
+		return "", errors.Wrap(err, "could not read submission code")
+	}
+	return string(code), nil
+}
+
+func analysePlagiarism(report *models.PlagiarismReport) error {
+	problemSet := models.ProblemSet{}
+	if err := base.DB.First(&problemSet, report.ProblemSetID).Error; err != nil {
+		return errors.Wrap(err, "could not get problem set for plagiarism analysis")
+	}
+	submissions, err := getPlagiarismSubmissions(&problemSet)
+	if err != nil {
+		return err
+	}
+	k := viper.GetInt("plagiarism.k")
+	window := viper.GetInt("plagiarism.window")
+	threshold := viper.GetFloat64("plagiarism.threshold")
+	var pairs []models.PlagiarismPair
+	for problemID, problemSubmissions := range submissions {
+		documents := make([]*plagiarismDocument, len(problemSubmissions))
+		for i, s := range problemSubmissions {
+			code, err := getSubmissionCode(s)
+			if err != nil {
+				return err
+			}
+			documents[i] = newPlagiarismDocument(s, code, k, window)
+		}
+		report.SubmissionCount += uint(len(documents))
+		for i, a := range documents {
+			for _, b := range documents[i+1:] {
+				similarity, regions := comparePlagiarismDocuments(a, b, k)
+				if similarity < threshold || len(regions) == 0 {
+					continue
+				}
+				pair := models.PlagiarismPair{
+					ReportID:           report.ID,
+					ProblemID:          problemID,
+					FirstSubmissionID:  a.submission.ID,
+					FirstUserID:        a.submission.UserID,
+					SecondSubmissionID: b.submission.ID,
+					SecondUserID:       b.submission.UserID,
+					Similarity:         similarity,
+				}
+				if pair.Regions, err = json.Marshal(regions); err != nil {
+					return errors.Wrap(err, "could not marshal plagiarism regions")
+				}
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	if len(pairs) > 0 {
+		if err := base.DB.Create(&pairs).Error; err != nil {
+			return errors.Wrap(err, "could not create plagiarism pairs")
+		}
+	}
+	return nil
+}
+
+// RunPlagiarismAnalysis analyses the submissions of the problem set of the report,
+// and saves the suspicious pairs and the status of the report.
+func RunPlagiarismAnalysis(report *models.PlagiarismReport) error {
+	report.Status = models.PlagiarismReportStatusRunning
+	report.Instance = InstanceID()
+	report.HeartbeatAt = time.Now()
+	if err := base.DB.Save(report).Error; err != nil {
+		return errors.Wrap(err, "could not update plagiarism report")
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(plagiarismLease() / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := base.DB.Model(&models.PlagiarismReport{}).Where("id = ?", report.ID).
+					Update("heartbeat_at", time.Now()).Error; err != nil {
+					log.Errorf("%+v\n", errors.Wrap(err, "could not renew plagiarism report"))
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	report.Status = models.PlagiarismReportStatusFinished
+	err := analysePlagiarism(report)
+	close(done)
+	if err != nil {
+		log.Errorf("%+v\n", err)
+		report.Status = models.PlagiarismReportStatusFailed
+		report.Message = err.Error()
+	}
+	now := time.Now()
+	report.FinishedAt = &now
+	report.HeartbeatAt = now
+	return errors.Wrap(base.DB.Save(report).Error, "could not update plagiarism report")
+}
+
+// FailInterruptedPlagiarismReports marks the pending or running reports of a previous process of this server,
+// and the ones whose server has stopped renewing them, as failed, so that they are not shown as running forever.
+// The reports still analysed by the other servers are left alone.
+func FailInterruptedPlagiarismReports() error {
+	err := base.DB.Model(&models.PlagiarismReport{}).
+		Where("status in ?", []string{models.PlagiarismReportStatusPending, models.PlagiarismReportStatusRunning}).
+		Where("instance = ? or heartbeat_at < ?", InstanceID(), time.Now().Add(-plagiarismLease())).
+		Updates(map[string]interface{}{
+			"status":      models.PlagiarismReportStatusFailed,
+			"message":     "interrupted by server restart",
+			"finished_at": time.Now(),
+		}).Error
+	return errors.Wrap(err, "could not fail interrupted plagiarism reports")
+}
@@ -0,0 +1,181 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenizeCode(t *testing.T) {
+	t.Parallel()
+
+	tokenTexts := func(tokens []codeToken) (texts []string) {
+		for _, token := range tokens {
+			texts = append(texts, token.text)
+		}
+		return
+	}
+	t.Run("CLike", func(t *testing.T) {
+		t.Parallel()
+		tokens := tokenizeCode("cpp", "int a = 10; // comment\n/* block\ncomment */ return \"s\\\"tr\";\n")
+		assert.Equal(t, []string{"int", "id", "=", "num", ";", "return", "str", ";"}, tokenTexts(tokens))
+		assert.Equal(t, uint(1), tokens[0].line)
+		assert.Equal(t, uint(3), tokens[5].line)
+	})
+	t.Run("Python", func(t *testing.T) {
+		t.Parallel()
+		tokens := tokenizeCode("python3", "# comment\n'''doc\n'''\ndef f(x):\n    return x + 1\n")
+		assert.Equal(t, []string{"def", "id", "(", "id", ")", ":", "return", "id", "+", "num"}, tokenTexts(tokens))
+		assert.Equal(t, uint(4), tokens[0].line)
+		assert.Equal(t, uint(5), tokens[6].line)
+	})
+}
+
+func TestComparePlagiarismDocuments(t *testing.T) {
+	t.Parallel()
+
+	code := "int main() {\n  int n;\n  scanf(\"%d\", &n);\n  for (int i = 0; i < n; i++) {\n    printf(\"%d\\n\", i * i);\n  }\n  return 0;\n}\n"
+	renamed := "// copied\nint main() {\n  int m;\n  scanf(\"%d\", &m);\n  for (int j = 0; j < m; j++) {\n    printf(\"%d\\n\", j * j);\n  }\n  return 0;\n}\n"
+	different := "#include <stdio.h>\nint main() {\n  long long a, b;\n  while (scanf(\"%lld%lld\", &a, &b) == 2) puts(a > b ? \"yes\" : \"no\");\n}\n"
+
+	original := newPlagiarismDocument(&models.Submission{LanguageName: "c"}, code, 5, 4)
+	similarity, regions := comparePlagiarismDocuments(original,
+		newPlagiarismDocument(&models.Submission{LanguageName: "c"}, renamed, 5, 4), 5)
+	assert.Equal(t, 1.0, similarity)
+	assert.Equal(t, []models.PlagiarismRegion{{
+		FirstStartLine:  1,
+		FirstEndLine:    7,
+		SecondStartLine: 2,
+		SecondEndLine:   8,
+	}}, regions)
+
+	similarity, _ = comparePlagiarismDocuments(original,
+		newPlagiarismDocument(&models.Submission{LanguageName: "c"}, different, 5, 4), 5)
+	assert.Less(t, similarity, 0.5)
+}
+
+func TestRunPlagiarismAnalysis(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, CreateBucket("submissions"))
+	class := models.Class{
+		Name:       "test_run_plagiarism_analysis_name",
+		InviteCode: GenerateInviteCode(),
+	}
+	users := make([]*models.User, 3)
+	for i := range users {
+		users[i] = &models.User{
+			Username: fmt.Sprintf("test_run_plagiarism_analysis_username_%d", i),
+			Nickname: fmt.Sprintf("test_run_plagiarism_analysis_nickname_%d", i),
+			Email:    fmt.Sprintf("test_run_plagiarism_analysis_%d@mail.com", i),
+			Password: "test_run_plagiarism_analysis_password",
+		}
+		assert.NoError(t, base.DB.Create(users[i]).Error)
+	}
+	class.Students = users
+	assert.NoError(t, base.DB.Create(&class).Error)
+	problem := models.Problem{
+		Name: "test_run_plagiarism_analysis_name",
+	}
+	assert.NoError(t, base.DB.Create(&problem).Error)
+	problemSet := models.ProblemSet{
+		ClassID:   class.ID,
+		Name:      "test_run_plagiarism_analysis_name",
+		Problems:  []*models.Problem{&problem},
+		StartTime: time.Now().Add(-1 * time.Hour),
+		EndTime:   time.Now().Add(time.Hour),
+	}
+	assert.NoError(t, base.DB.Create(&problemSet).Error)
+
+	createSubmission := func(user *models.User, status string, code string) *models.Submission {
+		submission := models.Submission{
+			UserID:       user.ID,
+			ProblemID:    problem.ID,
+			ProblemSetID: problemSet.ID,
+			LanguageName: "c",
+			Status:       status,
+		}
+		assert.NoError(t, base.DB.Create(&submission).Error)
+		_, err := base.Storage.PutObject(context.Background(), "submissions", fmt.Sprintf("%d/code", submission.ID),
+			bytes.NewReader([]byte(code)), int64(len(code)), minio.PutObjectOptions{})
+		assert.NoError(t, err)
+		return &submission
+	}
+	code := "int main() {\n  int n;\n  scanf(\"%d\", &n);\n  for (int i = 0; i < n; i++) {\n    printf(\"%d\\n\", i * i);\n  }\n  return 0;\n}\n"
+	first := createSubmission(users[0], "ACCEPTED", code)
+	// The accepted submission is analysed instead of the later wrong one.
+	createSubmission(users[0], "WRONG_ANSWER", "int main() { return 1; }")
+	second := createSubmission(users[1], "ACCEPTED", "int main() {\n  int m;\n  scanf(\"%d\", &m);\n  for (int j = 0; j < m; j++) {\n    printf(\"%d\\n\", j * j);\n  }\n  return 0;\n}\n")
+	createSubmission(users[2], "WRONG_ANSWER", "#include <stdio.h>\nint main() {\n  long long a, b;\n  while (scanf(\"%lld%lld\", &a, &b) == 2) puts(a > b ? \"yes\" : \"no\");\n}\n")
+	// Upsolve submissions are made after the problem set ends, often from the published solution.
+	upsolve := createSubmission(users[2], "ACCEPTED", code)
+	upsolve.Upsolve = true
+	assert.NoError(t, base.DB.Save(upsolve).Error)
+
+	report := models.PlagiarismReport{
+		ProblemSetID: problemSet.ID,
+		OperatorID:   users[0].ID,
+		Status:       models.PlagiarismReportStatusPending,
+	}
+	assert.NoError(t, base.DB.Create(&report).Error)
+	assert.NoError(t, RunPlagiarismAnalysis(&report))
+	assert.NoError(t, base.DB.Preload("Pairs").First(&report, report.ID).Error)
+	assert.Equal(t, models.PlagiarismReportStatusFinished, report.Status)
+	assert.NotNil(t, report.FinishedAt)
+	assert.Equal(t, uint(3), report.SubmissionCount)
+	if assert.Len(t, report.Pairs, 1) {
+		assert.Equal(t, first.ID, report.Pairs[0].FirstSubmissionID)
+		assert.Equal(t, users[0].ID, report.Pairs[0].FirstUserID)
+		assert.Equal(t, second.ID, report.Pairs[0].SecondSubmissionID)
+		assert.Equal(t, users[1].ID, report.Pairs[0].SecondUserID)
+		assert.Equal(t, 1.0, report.Pairs[0].Similarity)
+		assert.Equal(t, createJSONForTest(t, []models.PlagiarismRegion{{
+			FirstStartLine:  1,
+			FirstEndLine:    7,
+			SecondStartLine: 1,
+			SecondEndLine:   7,
+		}}), report.Pairs[0].Regions)
+	}
+}
+
+func TestFailInterruptedPlagiarismReports(t *testing.T) {
+	problemSet := models.ProblemSet{
+		Name:      "test_fail_interrupted_plagiarism_reports_name",
+		StartTime: time.Now().Add(-1 * time.Hour),
+		EndTime:   time.Now().Add(time.Hour),
+	}
+	assert.NoError(t, base.DB.Create(&problemSet).Error)
+	reports := []models.PlagiarismReport{
+		// Left by a previous process of this server.
+		{Status: models.PlagiarismReportStatusPending, Instance: InstanceID(), HeartbeatAt: time.Now()},
+		{Status: models.PlagiarismReportStatusRunning, Instance: InstanceID(), HeartbeatAt: time.Now()},
+		{Status: models.PlagiarismReportStatusFinished, Instance: InstanceID(), HeartbeatAt: time.Now()},
+		// Still analysed by another server.
+		{Status: models.PlagiarismReportStatusRunning, Instance: "another_server", HeartbeatAt: time.Now()},
+		// The other server has stopped.
+		{Status: models.PlagiarismReportStatusRunning, Instance: "another_server", HeartbeatAt: time.Now().Add(-time.Hour)},
+	}
+	for i := range reports {
+		reports[i].ProblemSetID = problemSet.ID
+		assert.NoError(t, base.DB.Create(&reports[i]).Error)
+	}
+	assert.NoError(t, FailInterruptedPlagiarismReports())
+	for i, status := range []string{
+		models.PlagiarismReportStatusFailed,
+		models.PlagiarismReportStatusFailed,
+		models.PlagiarismReportStatusFinished,
+		models.PlagiarismReportStatusRunning,
+		models.PlagiarismReportStatusFailed,
+	} {
+		report := models.PlagiarismReport{}
+		assert.NoError(t, base.DB.First(&report, reports[i].ID).Error)
+		assert.Equal(t, status, report.Status)
+	}
+}
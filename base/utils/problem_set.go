@@ -11,14 +11,23 @@ import (
 	"github.com/pkg/errors"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-var gradeLock sync.Mutex
+// gradeLocks holds a mutex for each class, so that the grades of a class are updated one at a time,
+// while the grades of different classes are updated concurrently.
+var gradeLocks sync.Map
+
+// lockGrades locks the grades of a class, and returns the function to unlock them.
+func lockGrades(classID uint) (unlock func()) {
+	l, _ := gradeLocks.LoadOrStore(classID, &sync.Mutex{})
+	mu := l.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
 
 func UpdateGrade(submission *models.Submission) error {
-	gradeLock.Lock()
-	defer gradeLock.Unlock()
-	if submission.ProblemSetID == 0 {
+	if submission.ProblemSetID == 0 || submission.Upsolve {
 		return nil
 	}
 	if submission.ProblemSet == nil {
@@ -28,23 +37,25 @@ func UpdateGrade(submission *models.Submission) error {
 		}
 		submission.ProblemSet = &problemSet
 	}
+	if submission.Rejudged {
+		// The score of a rejudged submission may decrease, so the score is recalculated from all the submissions.
+		return recalculateGrades(submission.ProblemSet, []uint{submission.UserID}, []uint{submission.ProblemID},
+			models.GradeChangeCauseRejudge, submission.ID)
+	}
 	if time.Now().After(submission.ProblemSet.EndTime) {
 		return nil
 	}
+	defer lockGrades(submission.ProblemSet.ClassID)()
 	grade := models.Grade{}
 	detail := make(map[uint]uint)
 	var err error
 	err = base.DB.First(&grade, "problem_set_id = ? and user_id = ?", submission.ProblemSetID, submission.UserID).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			problemSet := models.ProblemSet{}
-			if err := base.DB.First(&problemSet, submission.ProblemSetID).Error; err != nil {
-				return err
-			}
 			grade = models.Grade{
 				UserID:       submission.UserID,
 				ProblemSetID: submission.ProblemSetID,
-				ClassID:      problemSet.ClassID,
+				ClassID:      submission.ProblemSet.ClassID,
 				Detail:       datatypes.JSON("{}"),
 				Total:        0,
 			}
@@ -56,64 +67,318 @@ func UpdateGrade(submission *models.Submission) error {
 	if err != nil {
 		return err
 	}
-	if detail[submission.ProblemID] < submission.Score {
-		detail[submission.ProblemID] = submission.Score
+	oldDetail := make(map[uint]uint)
+	for problemID, score := range detail {
+		oldDetail[problemID] = score
+	}
+	fullMarks, err := problemFullMarks(submission.ProblemSetID)
+	if err != nil {
+		return err
+	}
+	if score := scaleScore(submission.Score, fullMarks[submission.ProblemID]); detail[submission.ProblemID] < score {
+		detail[submission.ProblemID] = score
+	}
+	overrides, err := loadGradeOverrides(submission.ProblemSetID)
+	if err != nil {
+		return err
+	}
+	if score, ok := overrides[submission.UserID][submission.ProblemID]; ok {
+		detail[submission.ProblemID] = score
 	}
 	grade.Detail, err = json.Marshal(detail)
 	if err != nil {
 		return err
 	}
-	return base.DB.Save(&grade).Error
+	if err = base.DB.Save(&grade).Error; err != nil {
+		return err
+	}
+	if err = logGradeChanges(gradeChanges(&grade, oldDetail, detail, models.GradeChangeCauseSubmission, submission.ID)); err != nil {
+		return err
+	}
+	return updateCourseGrade(grade.ClassID, grade.UserID)
 }
 
+// RefreshGrades recalculates the grades of all the students in the class of a problem set,
+// and loads the grades of the problem set into it.
 func RefreshGrades(problemSet *models.ProblemSet) error {
-	gradeLock.Lock()
-	defer gradeLock.Unlock()
-	if err := base.DB.Delete(&models.Grade{}, "problem_set_id = ?", problemSet.ID).Error; err != nil {
+	if err := RecalculateGrades(problemSet, nil, nil); err != nil {
+		return err
+	}
+	problemSet.Grades = nil
+	return errors.Wrap(base.DB.Order("id").Find(&problemSet.Grades, "problem_set_id = ?", problemSet.ID).Error,
+		"could not get grades when refreshing grades")
+}
+
+// RecalculateGrades recalculates the scores of the given students for the given problems in a problem set
+// from their submissions and the grade overrides. A nil slice of user ids means all the students in the class,
+// and the grades of the users no longer in the class are deleted then. A nil slice of problem ids means all the problems in the problem set. The scores of other problems are kept,
+// except for the problems no longer in the problem set. Only the grades that change are written.
+func RecalculateGrades(problemSet *models.ProblemSet, userIDs, problemIDs []uint) error {
+	return recalculateGrades(problemSet, userIDs, problemIDs, models.GradeChangeCauseRefresh, 0)
+}
+
+// recalculateGrades recalculates grades like RecalculateGrades, and logs the changes with the given cause.
+func recalculateGrades(problemSet *models.ProblemSet, userIDs, problemIDs []uint, cause string, submissionID uint) error {
+	defer lockGrades(problemSet.ClassID)()
+	if userIDs == nil {
+		if err := base.DB.Table("user_in_classes").Where("class_id = ?", problemSet.ClassID).
+			Pluck("user_id", &userIDs).Error; err != nil {
+			return errors.Wrap(err, "could not get students for recalculating grades")
+		}
+		// The grades of the students removed from the class are dropped.
+		query := base.DB.Where("problem_set_id = ?", problemSet.ID)
+		if len(userIDs) > 0 {
+			query = query.Where("user_id not in (?)", userIDs)
+		}
+		if err := query.Delete(&models.Grade{}).Error; err != nil {
+			return errors.Wrap(err, "could not delete grades of removed students")
+		}
+		if err := deleteRemovedCourseGrades(problemSet.ClassID, userIDs); err != nil {
+			return err
+		}
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+	fullMarks, err := problemFullMarks(problemSet.ID)
+	if err != nil {
+		return err
+	}
+	if problemIDs == nil {
+		for id := range fullMarks {
+			problemIDs = append(problemIDs, id)
+		}
+	}
+	overrides, err := loadGradeOverrides(problemSet.ID)
+	if err != nil {
+		return err
+	}
+	scores, err := bestSubmissionScores(problemSet, userIDs, problemIDs)
+	if err != nil {
 		return err
 	}
 	var grades []*models.Grade
-	for _, u := range problemSet.Class.Students {
-		grade := models.Grade{
-			UserID:       u.ID,
-			ProblemSetID: problemSet.ID,
-			ClassID:      problemSet.ClassID,
-			Detail:       nil,
-			Total:        0,
+	if err := base.DB.Find(&grades, "problem_set_id = ? and user_id in (?)", problemSet.ID, userIDs).Error; err != nil {
+		return errors.Wrap(err, "could not get grades for recalculating grades")
+	}
+	gradeMap := make(map[uint]*models.Grade)
+	for _, g := range grades {
+		gradeMap[g.UserID] = g
+	}
+	var created, updated []*models.Grade
+	var changes []*models.GradeChange
+	for _, userID := range userIDs {
+		grade, ok := gradeMap[userID]
+		if !ok {
+			grade = &models.Grade{
+				UserID:       userID,
+				ProblemSetID: problemSet.ID,
+				ClassID:      problemSet.ClassID,
+				Detail:       datatypes.JSON("{}"),
+			}
+			gradeMap[userID] = grade
 		}
 		detail := make(map[uint]uint)
-		for _, p := range problemSet.Problems {
-			var score uint = 0
-			submission := models.Submission{}
-			err := base.DB.
-				Where("user_id = ?", u.ID).
-				Where("problem_id = ?", p.ID).
-				Where("problem_set_id = ?", problemSet.ID).
-				Where("created_at < ?", problemSet.EndTime).
-				Order("score desc").
-				Order("created_at desc").
-				First(&submission).Error
-			if err != nil {
-				if !errors.Is(err, gorm.ErrRecordNotFound) {
-					return errors.Wrap(err, "could not get submission when refreshing grades")
-				}
-			} else {
-				score = submission.Score
+		if err := json.Unmarshal(grade.Detail, &detail); err != nil {
+			return errors.Wrap(err, "could not unmarshal grade detail when recalculating grades")
+		}
+		for problemID := range detail {
+			if _, inProblemSet := fullMarks[problemID]; !inProblemSet {
+				delete(detail, problemID)
+			}
+		}
+		for _, problemID := range problemIDs {
+			if _, inProblemSet := fullMarks[problemID]; !inProblemSet {
+				continue
+			}
+			score, overridden := overrides[userID][problemID]
+			if !overridden {
+				score = scaleScore(scores[userID][problemID], fullMarks[problemID])
 			}
-			detail[p.ID] = score
-			grade.Total += score
+			detail[problemID] = score
+		}
+		oldDetail := make(map[uint]uint)
+		if err := json.Unmarshal(grade.Detail, &oldDetail); err != nil {
+			return errors.Wrap(err, "could not unmarshal grade detail when recalculating grades")
 		}
-		var err error
-		grade.Detail, err = json.Marshal(detail)
+		changes = append(changes, gradeChanges(grade, oldDetail, detail, cause, submissionID)...)
+		newDetail, err := json.Marshal(detail)
 		if err != nil {
-			return errors.Wrap(err, "could not marshal grade detail when refreshing grades")
+			return errors.Wrap(err, "could not marshal grade detail when recalculating grades")
 		}
-		grades = append(grades, &grade)
+		switch {
+		case !ok:
+			grade.Detail = newDetail
+			created = append(created, grade)
+		case !jsonEqual(grade.Detail, newDetail):
+			grade.Detail = newDetail
+			updated = append(updated, grade)
+		}
+	}
+	if len(created) > 0 {
+		if err := base.DB.Create(&created).Error; err != nil {
+			return errors.Wrap(err, "could not create grades when recalculating grades")
+		}
+	}
+	if len(updated) > 0 {
+		if err := base.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"detail", "total", "updated_at"}),
+		}).Create(&updated).Error; err != nil {
+			return errors.Wrap(err, "could not update grades when recalculating grades")
+		}
+	}
+	if err := logGradeChanges(changes); err != nil {
+		return err
+	}
+	changedUserIDs := make([]uint, 0, len(created)+len(updated))
+	for _, grade := range append(created, updated...) {
+		changedUserIDs = append(changedUserIDs, grade.UserID)
+	}
+	return updateCourseGrades(problemSet.ClassID, changedUserIDs)
+}
+
+// RecalculateStudentGrades recalculates the grades of the given students in all the problem sets of a class.
+// It should be called after students join a class.
+func RecalculateStudentGrades(classID uint, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	var problemSets []*models.ProblemSet
+	if err := base.DB.Find(&problemSets, "class_id = ?", classID).Error; err != nil {
+		return errors.Wrap(err, "could not get problem sets for recalculating student grades")
+	}
+	for _, problemSet := range problemSets {
+		if err := RecalculateGrades(problemSet, userIDs, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonEqual tells if two grade details have the same scores.
+func jsonEqual(a, b datatypes.JSON) bool {
+	var x, y map[uint]uint
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil || len(x) != len(y) {
+		return false
+	}
+	for k, v := range x {
+		if s, ok := y[k]; !ok || s != v {
+			return false
+		}
+	}
+	return true
+}
+
+// problemFullMarks returns the full marks of the problems in a problem set, keyed by problem id.
+func problemFullMarks(problemSetID uint) (map[uint]uint, error) {
+	var entries []models.ProblemInProblemSet
+	if err := base.DB.Find(&entries, "problem_set_id = ?", problemSetID).Error; err != nil {
+		return nil, errors.Wrap(err, "could not get full marks of problems")
+	}
+	fullMarks := make(map[uint]uint)
+	for _, entry := range entries {
+		fullMarks[entry.ProblemID] = entry.FullMark
+	}
+	return fullMarks, nil
+}
+
+// scaleScore scales a submission score, which is out of 100, to the full mark of a problem.
+// A full mark of 0 means the problem is not in the problem set, and the score is kept.
+func scaleScore(score, fullMark uint) uint {
+	if fullMark == 0 {
+		return score
+	}
+	return (score*fullMark + models.DefaultFullMark/2) / models.DefaultFullMark
+}
+
+// bestSubmissionScore returns the highest score of the submissions of a user for a problem
+// in a problem set, submitted before the problem set ends.
+func bestSubmissionScore(problemSet *models.ProblemSet, userID, problemID uint) (uint, error) {
+	submission := models.Submission{}
+	err := base.DB.
+		Where("user_id = ?", userID).
+		Where("problem_id = ?", problemID).
+		Where("problem_set_id = ?", problemSet.ID).
+		Where("created_at < ?", problemSet.EndTime).
+		Where("upsolve = ?", false).
+		Order("score desc").
+		Order("created_at desc").
+		First(&submission).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return submission.Score, nil
+}
+
+// bestSubmissionScores returns the highest scores of the submissions of the given users for the given problems
+// in a problem set, submitted before the problem set ends. The scores are keyed by user id and problem id,
+// and are fetched in a single query.
+func bestSubmissionScores(problemSet *models.ProblemSet, userIDs, problemIDs []uint) (map[uint]map[uint]uint, error) {
+	scores := make(map[uint]map[uint]uint)
+	if len(userIDs) == 0 || len(problemIDs) == 0 {
+		return scores, nil
 	}
-	if err := base.DB.Create(&grades).Error; err != nil {
-		return errors.Wrap(err, "could not create grades when refreshing grades")
+	var records []struct {
+		UserID    uint
+		ProblemID uint
+		Score     uint
+	}
+	if err := base.DB.Model(&models.Submission{}).
+		Select("user_id, problem_id, max(score) as score").
+		Where("problem_set_id = ? and created_at < ? and upsolve = ?", problemSet.ID, problemSet.EndTime, false).
+		Where("user_id in (?) and problem_id in (?)", userIDs, problemIDs).
+		Group("user_id, problem_id").
+		Scan(&records).Error; err != nil {
+		return nil, errors.Wrap(err, "could not get best submission scores")
+	}
+	for _, record := range records {
+		if scores[record.UserID] == nil {
+			scores[record.UserID] = make(map[uint]uint)
+		}
+		scores[record.UserID][record.ProblemID] = record.Score
+	}
+	return scores, nil
+}
+
+// LoadUpsolveDetails loads the best scores of the upsolve submissions into the grades of a problem set.
+// The scores are scaled to the full marks of the problems, and are never counted in the totals.
+func LoadUpsolveDetails(problemSet *models.ProblemSet) error {
+	var records []struct {
+		UserID    uint
+		ProblemID uint
+		Score     uint
+	}
+	if err := base.DB.Model(&models.Submission{}).
+		Select("user_id, problem_id, max(score) as score").
+		Where("problem_set_id = ? and upsolve = ?", problemSet.ID, true).
+		Group("user_id, problem_id").
+		Scan(&records).Error; err != nil {
+		return errors.Wrap(err, "could not get upsolve scores")
+	}
+	fullMarks, err := problemFullMarks(problemSet.ID)
+	if err != nil {
+		return err
+	}
+	details := make(map[uint]map[uint]uint)
+	for _, record := range records {
+		if details[record.UserID] == nil {
+			details[record.UserID] = make(map[uint]uint)
+		}
+		details[record.UserID][record.ProblemID] = scaleScore(record.Score, fullMarks[record.ProblemID])
+	}
+	for _, grade := range problemSet.Grades {
+		detail, ok := details[grade.UserID]
+		if !ok {
+			continue
+		}
+		if grade.UpsolveDetail, err = json.Marshal(detail); err != nil {
+			return errors.Wrap(err, "could not marshal upsolve detail")
+		}
 	}
-	problemSet.Grades = grades
 	return nil
 }
 
@@ -121,8 +386,7 @@ func RefreshGrades(problemSet *models.ProblemSet) error {
 //
 //	for users who don't have a grade for this problem set.
 func CreateEmptyGrades(problemSet *models.ProblemSet) error {
-	gradeLock.Lock()
-	defer gradeLock.Unlock()
+	defer lockGrades(problemSet.ClassID)()
 
 	// Create empty grade JSON object
 	detail := make(map[uint]uint)
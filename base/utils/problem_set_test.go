@@ -10,6 +10,7 @@ import (
 	"github.com/EduOJ/backend/database/models"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 func createJSONForTest(t *testing.T, in interface{}) datatypes.JSON {
@@ -251,6 +252,67 @@ func TestUpdateGrade(t *testing.T) {
 			},
 		}, problemSet.Grades)
 	})
+	t.Run("ScaledToFullMark", func(t *testing.T) {
+		t.Parallel()
+		problemSet := models.ProblemSet{
+			Name:        "test_update_grade_scaled_to_full_mark_name",
+			Description: "test_update_grade_scaled_to_full_mark_description",
+			Problems: []*models.Problem{
+				&problem1,
+				&problem2,
+			},
+			StartTime: time.Now().Add(-1 * time.Hour),
+			EndTime:   time.Now().Add(time.Hour),
+		}
+		assert.NoError(t, base.DB.Create(&problemSet).Error)
+		assert.NoError(t, problemSet.SetProblemEntries(base.DB, []*models.ProblemInProblemSet{
+			{ProblemID: problem1.ID, Order: 0, Label: "A", FullMark: 30},
+			{ProblemID: problem2.ID, Order: 1, Label: "B", FullMark: 200},
+		}))
+		assert.NoError(t, UpdateGrade(&models.Submission{
+			ProblemSetID: problemSet.ID,
+			UserID:       user1.ID,
+			ProblemID:    problem1.ID,
+			Score:        50,
+		}))
+		assert.NoError(t, UpdateGrade(&models.Submission{
+			ProblemSetID: problemSet.ID,
+			UserID:       user1.ID,
+			ProblemID:    problem2.ID,
+			Score:        100,
+		}))
+		checkGrade(t, &models.Grade{
+			UserID:       user1.ID,
+			ProblemSetID: problemSet.ID,
+			Detail: createJSONForTest(t, map[uint]uint{
+				problem1.ID: 15,
+				problem2.ID: 200,
+			}),
+			Total: 215,
+		})
+	})
+	t.Run("Upsolve", func(t *testing.T) {
+		t.Parallel()
+		problemSet := models.ProblemSet{
+			Name:        "test_update_grade_upsolve_name",
+			Description: "test_update_grade_upsolve_description",
+			Problems: []*models.Problem{
+				&problem1,
+				&problem2,
+			},
+			StartTime: time.Now().Add(-1 * time.Hour),
+			EndTime:   time.Now().Add(time.Hour),
+		}
+		assert.NoError(t, base.DB.Create(&problemSet).Error)
+		assert.NoError(t, UpdateGrade(&models.Submission{
+			ProblemSetID: problemSet.ID,
+			UserID:       user2.ID,
+			ProblemID:    problem1.ID,
+			Score:        100,
+			Upsolve:      true,
+		}))
+		assert.ErrorIs(t, base.DB.First(&models.Grade{}, "problem_set_id = ?", problemSet.ID).Error, gorm.ErrRecordNotFound)
+	})
 }
 
 func checkGrade(t *testing.T, expectedGrade *models.Grade) {
@@ -447,6 +509,141 @@ func TestRefreshGrades(t *testing.T) {
 			Total:        80,
 		})
 	})
+	t.Run("Upsolve", func(t *testing.T) {
+		t.Parallel()
+		u1, u2, ps := init(3)
+		createSubmissionForTest(t, ps, u1.ID, problem1.ID, 40, "WRONG_ANSWER", time.Hour+time.Minute*1)
+		upsolve := createSubmissionForTest(t, ps, u1.ID, problem1.ID, 100, "ACCEPTED", time.Hour+time.Minute*2)
+		upsolve.Upsolve = true
+		assert.NoError(t, base.DB.Save(upsolve).Error)
+		upsolve = createSubmissionForTest(t, ps, u1.ID, problem2.ID, 70, "WRONG_ANSWER", time.Hour*2+time.Minute*3)
+		upsolve.Upsolve = true
+		assert.NoError(t, base.DB.Save(upsolve).Error)
+		assert.NoError(t, RefreshGrades(ps))
+		checkGrade(t, &models.Grade{
+			UserID:       u1.ID,
+			ProblemSetID: ps.ID,
+			Detail: createJSONForTest(t, map[uint]uint{
+				problem1.ID: 40,
+				problem2.ID: 0,
+			}),
+			Total: 40,
+		})
+		assert.NoError(t, LoadUpsolveDetails(ps))
+		for _, grade := range ps.Grades {
+			switch grade.UserID {
+			case u1.ID:
+				assert.JSONEq(t, string(createJSONForTest(t, map[uint]uint{
+					problem1.ID: 100,
+					problem2.ID: 70,
+				})), string(grade.UpsolveDetail))
+			case u2.ID:
+				assert.Nil(t, grade.UpsolveDetail)
+			}
+		}
+	})
+	t.Run("KeepExistingRows", func(t *testing.T) {
+		t.Parallel()
+		u1, u2, ps := init(4)
+		assert.NoError(t, RefreshGrades(ps))
+		gradeIDs := map[uint]uint{}
+		for _, grade := range ps.Grades {
+			gradeIDs[grade.UserID] = grade.ID
+		}
+		createSubmissionForTest(t, ps, u1.ID, problem1.ID, 50, "WRONG_ANSWER", time.Hour+time.Minute*1)
+		assert.NoError(t, RefreshGrades(ps))
+		assert.Len(t, ps.Grades, 2)
+		for _, grade := range ps.Grades {
+			assert.Equal(t, gradeIDs[grade.UserID], grade.ID)
+		}
+		checkGrade(t, &models.Grade{
+			UserID:       u1.ID,
+			ProblemSetID: ps.ID,
+			Detail: createJSONForTest(t, map[uint]uint{
+				problem1.ID: 50,
+				problem2.ID: 0,
+			}),
+			Total: 50,
+		})
+		checkGrade(t, &models.Grade{
+			UserID:       u2.ID,
+			ProblemSetID: ps.ID,
+			Detail: createJSONForTest(t, map[uint]uint{
+				problem1.ID: 0,
+				problem2.ID: 0,
+			}),
+			Total: 0,
+		})
+	})
+	t.Run("Partial", func(t *testing.T) {
+		t.Parallel()
+		u1, u2, ps := init(5)
+		assert.NoError(t, RefreshGrades(ps))
+		createSubmissionForTest(t, ps, u1.ID, problem1.ID, 50, "WRONG_ANSWER", time.Hour+time.Minute*1)
+		createSubmissionForTest(t, ps, u1.ID, problem2.ID, 60, "WRONG_ANSWER", time.Hour+time.Minute*1)
+		createSubmissionForTest(t, ps, u2.ID, problem1.ID, 70, "WRONG_ANSWER", time.Hour+time.Minute*1)
+		assert.NoError(t, RecalculateGrades(ps, []uint{u1.ID}, []uint{problem1.ID}))
+		checkGrade(t, &models.Grade{
+			UserID:       u1.ID,
+			ProblemSetID: ps.ID,
+			Detail: createJSONForTest(t, map[uint]uint{
+				problem1.ID: 50,
+				problem2.ID: 0,
+			}),
+			Total: 50,
+		})
+		checkGrade(t, &models.Grade{
+			UserID:       u2.ID,
+			ProblemSetID: ps.ID,
+			Detail: createJSONForTest(t, map[uint]uint{
+				problem1.ID: 0,
+				problem2.ID: 0,
+			}),
+			Total: 0,
+		})
+		assert.NoError(t, RecalculateStudentGrades(ps.ClassID, []uint{u2.ID}))
+		checkGrade(t, &models.Grade{
+			UserID:       u2.ID,
+			ProblemSetID: ps.ID,
+			Detail: createJSONForTest(t, map[uint]uint{
+				problem1.ID: 70,
+				problem2.ID: 0,
+			}),
+			Total: 70,
+		})
+	})
+	t.Run("DeletedProblem", func(t *testing.T) {
+		t.Parallel()
+		u1, _, ps := init(6)
+		createSubmissionForTest(t, ps, u1.ID, problem1.ID, 50, "WRONG_ANSWER", time.Hour+time.Minute*1)
+		createSubmissionForTest(t, ps, u1.ID, problem2.ID, 60, "WRONG_ANSWER", time.Hour+time.Minute*1)
+		assert.NoError(t, RefreshGrades(ps))
+		assert.NoError(t, ps.DeleteProblems([]uint{problem2.ID}))
+		assert.NoError(t, RecalculateGrades(ps, nil, []uint{}))
+		checkGrade(t, &models.Grade{
+			UserID:       u1.ID,
+			ProblemSetID: ps.ID,
+			Detail: createJSONForTest(t, map[uint]uint{
+				problem1.ID: 50,
+			}),
+			Total: 50,
+		})
+	})
+	t.Run("RemovedStudent", func(t *testing.T) {
+		t.Parallel()
+		u1, u2, ps := init(7)
+		assert.NoError(t, RefreshGrades(ps))
+		assert.NoError(t, ps.Class.DeleteStudents([]uint{u2.ID}))
+		assert.NoError(t, RefreshGrades(ps))
+		if assert.Len(t, ps.Grades, 1) {
+			assert.Equal(t, u1.ID, ps.Grades[0].UserID)
+		}
+		var courseGrades []models.CourseGrade
+		assert.NoError(t, base.DB.Find(&courseGrades, "class_id = ?", ps.ClassID).Error)
+		if assert.Len(t, courseGrades, 1) {
+			assert.Equal(t, u1.ID, courseGrades[0].UserID)
+		}
+	})
 }
 
 func TestGetGrades(t *testing.T) {
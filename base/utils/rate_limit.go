@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/patrickmn/go-cache"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+const (
+	SubmissionLimitUser     = "USER"
+	SubmissionLimitProblem  = "PROBLEM"
+	SubmissionLimitCooldown = "COOLDOWN"
+)
+
+func init() {
+	viper.SetDefault("submission.rate_limit.per_minute", 10)
+	viper.SetDefault("submission.rate_limit.per_problem", 0)
+	viper.SetDefault("submission.rate_limit.per_problem_window", 3600)
+	viper.SetDefault("submission.rate_limit.cooldown_wrong_answers", 0)
+	viper.SetDefault("submission.rate_limit.cooldown", 300)
+}
+
+// rateLimitStore keeps the counters of rate limits.
+type rateLimitStore interface {
+	// incr increases the counter of the key, and returns the count and the time before it resets.
+	incr(key string, window time.Duration) (count int64, ttl time.Duration, err error)
+	// ttl returns the time before the key expires, or 0 if the key does not exist.
+	ttl(key string) (time.Duration, error)
+	set(key string, ttl time.Duration) error
+	del(key string) error
+}
+
+type redisRateLimitStore struct{}
+
+// incrScript increases the counter and sets its expiration in one step,
+// so that the counter never lives forever if the server dies in between.
+var incrScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+func (redisRateLimitStore) incr(key string, window time.Duration) (int64, time.Duration, error) {
+	result, err := incrScript.Run(context.Background(), base.Redis, []string{key}, window.Milliseconds()).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 2 {
+		return 0, 0, errors.Errorf("unexpected result of incr script: %v", result)
+	}
+	count, _ := values[0].(int64)
+	ttl, _ := values[1].(int64)
+	return count, time.Duration(ttl) * time.Millisecond, nil
+}
+
+func (redisRateLimitStore) ttl(key string) (time.Duration, error) {
+	ttl, err := base.Redis.PTTL(context.Background(), key).Result()
+	if err != nil || ttl < 0 {
+		return 0, err
+	}
+	return ttl, nil
+}
+
+func (redisRateLimitStore) set(key string, ttl time.Duration) error {
+	return base.Redis.Set(context.Background(), key, 1, ttl).Err()
+}
+
+func (redisRateLimitStore) del(key string) error {
+	return base.Redis.Del(context.Background(), key).Err()
+}
+
+// memoryRateLimitStore is used when redis is not configured, e.g. in tests.
+// Its counters are not shared across instances.
+type memoryRateLimitStore struct {
+	sync.Mutex
+	c *cache.Cache
+}
+
+func (s *memoryRateLimitStore) incr(key string, window time.Duration) (int64, time.Duration, error) {
+	s.Lock()
+	defer s.Unlock()
+	_ = s.c.Add(key, int64(0), window)
+	count, err := s.c.IncrementInt64(key, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	_, expiration, _ := s.c.GetWithExpiration(key)
+	return count, time.Until(expiration), nil
+}
+
+func (s *memoryRateLimitStore) ttl(key string) (time.Duration, error) {
+	_, expiration, ok := s.c.GetWithExpiration(key)
+	if !ok {
+		return 0, nil
+	}
+	return time.Until(expiration), nil
+}
+
+func (s *memoryRateLimitStore) set(key string, ttl time.Duration) error {
+	s.c.Set(key, int64(1), ttl)
+	return nil
+}
+
+func (s *memoryRateLimitStore) del(key string) error {
+	s.c.Delete(key)
+	return nil
+}
+
+var memoryStore = &memoryRateLimitStore{
+	c: cache.New(time.Minute, 10*time.Minute),
+}
+
+func getRateLimitStore() rateLimitStore {
+	if base.Redis == nil {
+		return memoryStore
+	}
+	return redisRateLimitStore{}
+}
+
+func problemRateLimitKey(prefix string, userID, problemSetID, problemID uint) string {
+	return fmt.Sprintf("%s:%d:%d:%d", prefix, userID, problemSetID, problemID)
+}
+
+// CheckSubmissionRateLimit counts a submission of a user against the rate limits.
+// The problem set id is 0 for submissions out of problem sets. If the submission exceeds a limit,
+// the name of the limit and the time to wait before the next submission are returned.
+func CheckSubmissionRateLimit(userID, problemSetID, problemID uint) (limit string, retryAfter time.Duration, err error) {
+	store := getRateLimitStore()
+	cooldown, err := store.ttl(problemRateLimitKey("submission_cooldown", userID, problemSetID, problemID))
+	if err != nil {
+		return "", 0, errors.Wrap(err, "could not get submission cooldown")
+	}
+	if cooldown > 0 {
+		return SubmissionLimitCooldown, cooldown, nil
+	}
+	if perMinute := viper.GetInt64("submission.rate_limit.per_minute"); perMinute > 0 {
+		count, ttl, err := store.incr(fmt.Sprintf("submission_rate:%d", userID), time.Minute)
+		if err != nil {
+			return "", 0, errors.Wrap(err, "could not count submissions of user")
+		}
+		if count > perMinute {
+			return SubmissionLimitUser, ttl, nil
+		}
+	}
+	if perProblem := viper.GetInt64("submission.rate_limit.per_problem"); perProblem > 0 && problemSetID != 0 {
+		window := time.Duration(viper.GetInt64("submission.rate_limit.per_problem_window")) * time.Second
+		count, ttl, err := store.incr(problemRateLimitKey("submission_rate", userID, problemSetID, problemID), window)
+		if err != nil {
+			return "", 0, errors.Wrap(err, "could not count submissions of problem")
+		}
+		if count > perProblem {
+			return SubmissionLimitProblem, ttl, nil
+		}
+	}
+	return "", 0, nil
+}
+
+// RecordSubmissionResult counts the wrong answers of a user for a problem.
+// The user is put into cooldown for the problem after too many wrong answers in a row.
+// Rejudged submissions are not counted, as the user did not submit them again.
+func RecordSubmissionResult(submission *models.Submission) error {
+	wrongAnswers := viper.GetInt64("submission.rate_limit.cooldown_wrong_answers")
+	if wrongAnswers <= 0 || submission.Rejudged || submission.Status == "JUDGEMENT_FAILED" {
+		return nil
+	}
+	store := getRateLimitStore()
+	key := problemRateLimitKey("submission_wrong_answers", submission.UserID, submission.ProblemSetID, submission.ProblemID)
+	if submission.Status == "ACCEPTED" {
+		return errors.Wrap(store.del(key), "could not reset wrong answers")
+	}
+	cooldown := time.Duration(viper.GetInt64("submission.rate_limit.cooldown")) * time.Second
+	count, _, err := store.incr(key, 24*time.Hour)
+	if err != nil {
+		return errors.Wrap(err, "could not count wrong answers")
+	}
+	if count < wrongAnswers {
+		return nil
+	}
+	if err := store.del(key); err != nil {
+		return errors.Wrap(err, "could not reset wrong answers")
+	}
+	return errors.Wrap(store.set(problemRateLimitKey("submission_cooldown", submission.UserID, submission.ProblemSetID, submission.ProblemID), cooldown),
+		"could not set submission cooldown")
+}
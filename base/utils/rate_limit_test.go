@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/EduOJ/backend/database/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func checkSubmissionRateLimitForTest(t *testing.T, userID, problemSetID, problemID uint) string {
+	limit, retryAfter, err := CheckSubmissionRateLimit(userID, problemSetID, problemID)
+	assert.NoError(t, err)
+	if limit == "" {
+		assert.Zero(t, retryAfter)
+	} else {
+		assert.True(t, retryAfter > 0)
+	}
+	return limit
+}
+
+func TestCheckSubmissionRateLimit(t *testing.T) {
+	t.Parallel()
+	t.Run("PerUser", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, "", checkSubmissionRateLimitForTest(t, 1001, 0, 1))
+		assert.Equal(t, "", checkSubmissionRateLimitForTest(t, 1001, 0, 2))
+		assert.Equal(t, "", checkSubmissionRateLimitForTest(t, 1001, 0, 3))
+		assert.Equal(t, SubmissionLimitUser, checkSubmissionRateLimitForTest(t, 1001, 0, 4))
+		// other users are not affected.
+		assert.Equal(t, "", checkSubmissionRateLimitForTest(t, 1002, 0, 4))
+	})
+	t.Run("PerProblem", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, "", checkSubmissionRateLimitForTest(t, 1003, 1, 1))
+		assert.Equal(t, "", checkSubmissionRateLimitForTest(t, 1003, 1, 1))
+		assert.Equal(t, SubmissionLimitProblem, checkSubmissionRateLimitForTest(t, 1003, 1, 1))
+	})
+	t.Run("PerProblemOutOfProblemSet", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, "", checkSubmissionRateLimitForTest(t, 1004, 0, 1))
+		assert.Equal(t, "", checkSubmissionRateLimitForTest(t, 1004, 0, 1))
+		assert.Equal(t, "", checkSubmissionRateLimitForTest(t, 1004, 0, 1))
+	})
+}
+
+func TestRecordSubmissionResult(t *testing.T) {
+	t.Parallel()
+	t.Run("Cooldown", func(t *testing.T) {
+		t.Parallel()
+		submission := models.Submission{
+			UserID:       1101,
+			ProblemSetID: 1,
+			ProblemID:    1,
+			Status:       "WRONG_ANSWER",
+		}
+		assert.NoError(t, RecordSubmissionResult(&submission))
+		assert.Equal(t, "", checkSubmissionRateLimitForTest(t, 1101, 1, 1))
+		assert.NoError(t, RecordSubmissionResult(&submission))
+		limit, retryAfter, err := CheckSubmissionRateLimit(1101, 1, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, SubmissionLimitCooldown, limit)
+		assert.True(t, retryAfter > 59*time.Second && retryAfter <= time.Minute)
+		// the cooldown is for the problem only.
+		assert.Equal(t, "", checkSubmissionRateLimitForTest(t, 1101, 1, 2))
+	})
+	t.Run("AcceptedResets", func(t *testing.T) {
+		t.Parallel()
+		submission := models.Submission{
+			UserID:       1102,
+			ProblemSetID: 1,
+			ProblemID:    1,
+			Status:       "WRONG_ANSWER",
+		}
+		assert.NoError(t, RecordSubmissionResult(&submission))
+		submission.Status = "ACCEPTED"
+		assert.NoError(t, RecordSubmissionResult(&submission))
+		submission.Status = "TIME_LIMIT_EXCEEDED"
+		assert.NoError(t, RecordSubmissionResult(&submission))
+		assert.Equal(t, "", checkSubmissionRateLimitForTest(t, 1102, 1, 1))
+	})
+	t.Run("RejudgedIgnored", func(t *testing.T) {
+		t.Parallel()
+		submission := models.Submission{
+			UserID:       1103,
+			ProblemSetID: 1,
+			ProblemID:    1,
+			Status:       "WRONG_ANSWER",
+			Rejudged:     true,
+		}
+		assert.NoError(t, RecordSubmissionResult(&submission))
+		assert.NoError(t, RecordSubmissionResult(&submission))
+		assert.Equal(t, "", checkSubmissionRateLimitForTest(t, 1103, 1, 1))
+	})
+}
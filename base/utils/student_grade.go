@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/pkg/errors"
+)
+
+type problemGradeKey struct {
+	problemSetID uint
+	problemID    uint
+}
+
+// GetStudentGrades returns the standing of a student in the given problem sets.
+// The problem entries of the problem sets should be loaded. The submissions, grades
+// and overrides of all the problem sets are fetched in a few queries.
+func GetStudentGrades(userID uint, problemSets []*models.ProblemSet) ([]*models.StudentProblemSetGrade, error) {
+	ret := make([]*models.StudentProblemSetGrade, 0, len(problemSets))
+	if len(problemSets) == 0 {
+		return ret, nil
+	}
+	problemSetIDs := make([]uint, len(problemSets))
+	for i, problemSet := range problemSets {
+		problemSetIDs[i] = problemSet.ID
+	}
+
+	var records []struct {
+		ProblemSetID uint
+		ProblemID    uint
+		Upsolve      bool
+		Attempts     int64
+		Score        uint
+	}
+	if err := base.DB.Model(&models.Submission{}).
+		Select("problem_set_id, problem_id, upsolve, count(*) as attempts, max(score) as score").
+		Where("user_id = ? and problem_set_id in (?)", userID, problemSetIDs).
+		Group("problem_set_id, problem_id, upsolve").
+		Scan(&records).Error; err != nil {
+		return nil, errors.Wrap(err, "could not get submission statistics of student")
+	}
+	attempts := make(map[problemGradeKey]int64)
+	bestScores := make(map[problemGradeKey]uint)
+	upsolveScores := make(map[problemGradeKey]uint)
+	for _, record := range records {
+		key := problemGradeKey{record.ProblemSetID, record.ProblemID}
+		if record.Upsolve {
+			upsolveScores[key] = record.Score
+		} else {
+			attempts[key] = record.Attempts
+			bestScores[key] = record.Score
+		}
+	}
+
+	var accepted []models.Submission
+	if err := base.DB.Select("problem_set_id, problem_id, created_at").Order("created_at").
+		Find(&accepted, "user_id = ? and problem_set_id in (?) and status = ? and upsolve = ?",
+			userID, problemSetIDs, "ACCEPTED", false).Error; err != nil {
+		return nil, errors.Wrap(err, "could not get accepted submissions of student")
+	}
+	firstAccepted := make(map[problemGradeKey]*time.Time)
+	for i := range accepted {
+		key := problemGradeKey{accepted[i].ProblemSetID, accepted[i].ProblemID}
+		if firstAccepted[key] == nil {
+			firstAccepted[key] = &accepted[i].CreatedAt
+		}
+	}
+
+	var overrideRecords []*models.GradeOverride
+	if err := base.DB.Preload("User").Preload("Operator").Order("id").
+		Find(&overrideRecords, "user_id = ? and problem_set_id in (?)", userID, problemSetIDs).Error; err != nil {
+		return nil, errors.Wrap(err, "could not get grade overrides of student")
+	}
+	overrides := make(map[problemGradeKey]*models.GradeOverride)
+	for _, override := range overrideRecords {
+		key := problemGradeKey{override.ProblemSetID, override.ProblemID}
+		if override.Removed {
+			delete(overrides, key)
+		} else {
+			overrides[key] = override
+		}
+	}
+
+	var grades []*models.Grade
+	if err := base.DB.Find(&grades, "user_id = ? and problem_set_id in (?)", userID, problemSetIDs).Error; err != nil {
+		return nil, errors.Wrap(err, "could not get grades of student")
+	}
+	details := make(map[uint]map[uint]uint)
+	for _, grade := range grades {
+		detail := make(map[uint]uint)
+		if err := json.Unmarshal(grade.Detail, &detail); err != nil {
+			return nil, errors.Wrap(err, "could not unmarshal grade detail")
+		}
+		details[grade.ProblemSetID] = detail
+	}
+
+	for _, problemSet := range problemSets {
+		problemSetGrade := models.StudentProblemSetGrade{
+			ProblemSet: problemSet,
+			Problems:   make([]*models.StudentProblemGrade, 0, len(problemSet.ProblemEntries)),
+		}
+		for _, entry := range problemSet.ProblemEntries {
+			key := problemGradeKey{problemSet.ID, entry.ProblemID}
+			problemGrade := models.StudentProblemGrade{
+				ProblemID:       entry.ProblemID,
+				Label:           entry.Label,
+				FullMark:        entry.FullMark,
+				Score:           details[problemSet.ID][entry.ProblemID],
+				BestScore:       scaleScore(bestScores[key], entry.FullMark),
+				Attempts:        attempts[key],
+				FirstAcceptedAt: firstAccepted[key],
+				Override:        overrides[key],
+			}
+			if score, ok := upsolveScores[key]; ok {
+				score = scaleScore(score, entry.FullMark)
+				problemGrade.UpsolveScore = &score
+			}
+			problemSetGrade.Total += problemGrade.Score
+			problemSetGrade.FullMark += entry.FullMark
+			problemSetGrade.Problems = append(problemSetGrade.Problems, &problemGrade)
+		}
+		ret = append(ret, &problemSetGrade)
+	}
+	return ret, nil
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/pkg/errors"
+)
+
+// RejudgeSubmission removes the runs of a submission and creates pending runs
+// for the current test cases of its problem, so that judgers pick it up again.
+func RejudgeSubmission(submission *models.Submission) error {
+	problem := models.Problem{}
+	if err := base.DB.First(&problem, submission.ProblemID).Error; err != nil {
+		return errors.Wrap(err, "could not find problem for rejudging submission")
+	}
+	problem.LoadTestCases()
+	if err := base.DB.Delete(&models.Run{}, "submission_id = ?", submission.ID).Error; err != nil {
+		return errors.Wrap(err, "could not delete runs for rejudging submission")
+	}
+	submission.Judged = false
+	submission.Score = 0
+	submission.Status = "PENDING"
+	submission.Rejudged = true
+	submission.Runs = make([]models.Run, len(problem.TestCases))
+	for i, testCase := range problem.TestCases {
+		submission.Runs[i] = models.Run{
+			UserID:       submission.UserID,
+			ProblemID:    submission.ProblemID,
+			ProblemSetID: submission.ProblemSetID,
+			TestCaseID:   testCase.ID,
+			Sample:       testCase.Sample,
+			SubmissionID: submission.ID,
+			Priority:     submission.Priority,
+			Judged:       false,
+			Status:       "PENDING",
+		}
+	}
+	return errors.Wrap(base.DB.Save(submission).Error, "could not save submission for rejudging")
+}
@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+const (
+	totpPeriod        = 30
+	totpDigits        = 6
+	recoveryCodeCount = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func init() {
+	viper.SetDefault("auth.two_factor.issuer", "EduOJ")
+}
+
+// NewTOTPSecret generates a random base32 encoded TOTP secret.
+func NewTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "could not generate totp secret")
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// TOTPProvisioningURI returns the otpauth uri of a secret, which is encoded in the QR code scanned by authenticator apps.
+func TOTPProvisioningURI(secret, account string) string {
+	issuer := viper.GetString("auth.two_factor.issuer")
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	return (&url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: q.Encode(),
+	}).String()
+}
+
+// TOTPCode returns the code of a secret at the given time step, as described in RFC 6238.
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", errors.Wrap(err, "could not decode totp secret")
+	}
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0xf
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", code%1000000), nil
+}
+
+// TOTPStep returns the time step of the given time.
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+// VerifyTOTP checks a code against the steps around the given time, to allow for clock skew.
+// The matched step is returned, and steps not after lastUsedStep are rejected to prevent replaying.
+func VerifyTOTP(secret, code string, t time.Time, lastUsedStep int64) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	now := TOTPStep(t)
+	for step := now - 1; step <= now+1; step++ {
+		if step <= lastUsedStep {
+			continue
+		}
+		expected, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewRecoveryCodes replaces the recovery codes of a user with new ones, and returns the new codes.
+func NewRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, errors.Wrap(err, "could not generate recovery code")
+		}
+		code := hex.EncodeToString(b)
+		codes[i] = code[:5] + "-" + code[5:]
+		records[i] = models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(code),
+		}
+	}
+	err := base.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.RecoveryCode{}, "user_id = ?", userID).Error; err != nil {
+			return err
+		}
+		return tx.Create(&records).Error
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not save recovery codes")
+	}
+	return codes, nil
+}
+
+// GetTOTPCredential returns the enabled TOTP credential of a user, or nil if two-factor authentication is disabled.
+func GetTOTPCredential(userID uint) (*models.TOTPCredential, error) {
+	credential := models.TOTPCredential{}
+	err := base.DB.First(&credential, "user_id = ? and enabled = ?", userID, true).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get totp credential")
+	}
+	return &credential, nil
+}
+
+// VerifyTwoFactorCode checks a TOTP code or an unused recovery code of a user. The accepted code could not be used again.
+func VerifyTwoFactorCode(credential *models.TOTPCredential, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if step, ok := VerifyTOTP(credential.Secret, code, time.Now(), credential.LastUsedStep); ok {
+		// The condition on the last used step makes concurrent uses of a code fail.
+		result := base.DB.Model(&models.TOTPCredential{}).
+			Where("id = ? and last_used_step < ?", credential.ID, step).
+			Update("last_used_step", step)
+		if result.Error != nil {
+			return false, errors.Wrap(result.Error, "could not update totp credential")
+		}
+		credential.LastUsedStep = step
+		return result.RowsAffected == 1, nil
+	}
+	result := base.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? and code_hash = ? and used = ?", credential.UserID, hashRecoveryCode(code), false).
+		Update("used", true)
+	if result.Error != nil {
+		return false, errors.Wrap(result.Error, "could not use recovery code")
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// twoFactorManagerPermissions are the permissions making a user a manager required to use two-factor authentication.
+var twoFactorManagerPermissions = []string{"all", "manage_class", "manage_grades"}
+
+// TwoFactorRequired returns whether the user is required to enable two-factor authentication,
+// which is the case for the users with a global or class role granting twoFactorManagerPermissions
+// if auth.two_factor.required_for_managers is set. The permissions limited by personal access tokens are ignored.
+func TwoFactorRequired(user *models.User) bool {
+	if !viper.GetBool("auth.two_factor.required_for_managers") {
+		return false
+	}
+	if !user.RoleLoaded {
+		user.LoadRoles()
+	}
+	for _, role := range user.Roles {
+		if role.Role.Target != nil && *role.Role.Target != "" && *role.Role.Target != "class" {
+			continue
+		}
+		for _, perm := range role.Role.Permissions {
+			for _, name := range twoFactorManagerPermissions {
+				if perm.Name == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
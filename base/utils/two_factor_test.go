@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database/models"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// The secret used in the test vectors of RFC 6238, "12345678901234567890" in base32.
+const rfcTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	t.Parallel()
+	for _, c := range []struct {
+		time int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	} {
+		code, err := TOTPCode(rfcTOTPSecret, TOTPStep(time.Unix(c.time, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, c.code, code, c.time)
+	}
+	_, err := TOTPCode("not base32!", 0)
+	assert.Error(t, err)
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1111111109, 0)
+	step := TOTPStep(now)
+	codeAt := func(step int64) string {
+		code, err := TOTPCode(rfcTOTPSecret, step)
+		assert.NoError(t, err)
+		return code
+	}
+
+	matched, ok := VerifyTOTP(rfcTOTPSecret, codeAt(step), now, 0)
+	assert.True(t, ok)
+	assert.Equal(t, step, matched)
+	// Clock skew of one step is allowed.
+	matched, ok = VerifyTOTP(rfcTOTPSecret, codeAt(step-1), now, 0)
+	assert.True(t, ok)
+	assert.Equal(t, step-1, matched)
+	_, ok = VerifyTOTP(rfcTOTPSecret, codeAt(step+2), now, 0)
+	assert.False(t, ok)
+	// Used steps are rejected.
+	_, ok = VerifyTOTP(rfcTOTPSecret, codeAt(step), now, step)
+	assert.False(t, ok)
+	_, ok = VerifyTOTP(rfcTOTPSecret, "12345", now, 0)
+	assert.False(t, ok)
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	t.Parallel()
+	secret, err := NewTOTPSecret()
+	assert.NoError(t, err)
+	assert.Len(t, secret, 32)
+	u, err := url.Parse(TOTPProvisioningURI(secret, "test_user"))
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/EduOJ:test_user", u.Path)
+	assert.Equal(t, secret, u.Query().Get("secret"))
+	assert.Equal(t, "EduOJ", u.Query().Get("issuer"))
+}
+
+func TestVerifyTwoFactorCode(t *testing.T) {
+	t.Parallel()
+	user := models.User{
+		Username: "test_verify_two_factor_code",
+		Nickname: "test_verify_two_factor_code_nick",
+		Email:    "test_verify_two_factor_code@e.e",
+		Password: "test_verify_two_factor_code_pwd",
+	}
+	assert.NoError(t, base.DB.Create(&user).Error)
+	secret, err := NewTOTPSecret()
+	assert.NoError(t, err)
+	credential := models.TOTPCredential{
+		UserID:  user.ID,
+		Secret:  secret,
+		Enabled: true,
+	}
+	assert.NoError(t, base.DB.Create(&credential).Error)
+	found, err := GetTOTPCredential(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, credential.ID, found.ID)
+
+	code, err := TOTPCode(secret, TOTPStep(time.Now()))
+	assert.NoError(t, err)
+	ok, err := VerifyTwoFactorCode(found, code)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	// Codes could not be replayed.
+	found, err = GetTOTPCredential(user.ID)
+	assert.NoError(t, err)
+	ok, err = VerifyTwoFactorCode(found, code)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	codes, err := NewRecoveryCodes(user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, codes, recoveryCodeCount)
+	ok, err = VerifyTwoFactorCode(found, codes[0])
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = VerifyTwoFactorCode(found, codes[0])
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	// Recovery codes are case and dash insensitive.
+	ok, err = VerifyTwoFactorCode(found, " "+codes[1][:5]+codes[1][6:]+" ")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// Regenerating invalidates old codes.
+	newCodes, err := NewRecoveryCodes(user.ID)
+	assert.NoError(t, err)
+	ok, err = VerifyTwoFactorCode(found, codes[2])
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = VerifyTwoFactorCode(found, newCodes[2])
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestTwoFactorRequired(t *testing.T) {
+	admin := models.User{
+		Username: "test_two_factor_required_admin",
+		Nickname: "test_two_factor_required_admin_nick",
+		Email:    "test_two_factor_required_admin@e.e",
+		Password: "test_two_factor_required_admin_pwd",
+	}
+	assert.NoError(t, base.DB.Create(&admin).Error)
+	admin.GrantRole("admin")
+	manager := models.User{
+		Username: "test_two_factor_required_manager",
+		Nickname: "test_two_factor_required_manager_nick",
+		Email:    "test_two_factor_required_manager@e.e",
+		Password: "test_two_factor_required_manager_pwd",
+	}
+	assert.NoError(t, base.DB.Create(&manager).Error)
+	class := models.Class{
+		Name:       "test_two_factor_required_class",
+		CourseName: "test_two_factor_required_course",
+	}
+	assert.NoError(t, base.DB.Create(&class).Error)
+	manager.GrantRole("class_creator", class)
+	student := models.User{
+		Username: "test_two_factor_required_student",
+		Nickname: "test_two_factor_required_student_nick",
+		Email:    "test_two_factor_required_student@e.e",
+		Password: "test_two_factor_required_student_pwd",
+	}
+	assert.NoError(t, base.DB.Create(&student).Error)
+	student.GrantRole("class_ta", class)
+	classString := "class"
+	graderRole := models.Role{
+		Name:        "test_two_factor_required_grader",
+		Target:      &classString,
+		Permissions: []models.Permission{{Name: "manage_grades"}},
+	}
+	assert.NoError(t, base.DB.Create(&graderRole).Error)
+	grader := models.User{
+		Username: "test_two_factor_required_grader",
+		Nickname: "test_two_factor_required_grader_nick",
+		Email:    "test_two_factor_required_grader@e.e",
+		Password: "test_two_factor_required_grader_pwd",
+	}
+	assert.NoError(t, base.DB.Create(&grader).Error)
+	grader.GrantRole("test_two_factor_required_grader", class)
+
+	assert.False(t, TwoFactorRequired(&admin))
+	viper.Set("auth.two_factor.required_for_managers", true)
+	defer viper.Set("auth.two_factor.required_for_managers", false)
+	assert.True(t, TwoFactorRequired(&admin))
+	assert.True(t, TwoFactorRequired(&manager))
+	assert.True(t, TwoFactorRequired(&grader))
+	// Teaching assistants could not manage the class.
+	assert.False(t, TwoFactorRequired(&student))
+}
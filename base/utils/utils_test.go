@@ -29,6 +29,12 @@ server:
   port: 8080
   origin:
     - http://127.0.0.1:8000
+submission:
+  rate_limit:
+    per_minute: 3
+    per_problem: 2
+    cooldown_wrong_answers: 2
+    cooldown: 60
 `)
 	viper.SetConfigType("yaml")
 	if err := viper.ReadConfig(configFile); err != nil {
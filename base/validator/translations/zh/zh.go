@@ -53,6 +53,35 @@ var FieldTranslations = map[string]string{
 	"Passed":             "选取通过题目",
 	"Token":              "验证码",
 	"Sanitize":           "是否格式化换行符",
+	"DropLowest":         "去除最低分个数",
+	"ProblemSets":        "题目组数组",
+	"ProblemSetID":       "题目组ID",
+	"ProblemID":          "题目ID",
+	"Problems":           "题目数组",
+	"Weight":             "权重",
+	"Title":              "标题",
+	"Content":            "内容",
+	"Pinned":             "是否置顶",
+	"Unread":             "仅未读",
+	"IDs":                "ID数组",
+	"All":                "是否全部",
+	"EmailNotification":  "是否接收邮件通知",
+	"Score":              "分数",
+	"Reason":             "原因",
+	"Feedback":           "评语",
+	"Comments":           "是否返回评论",
+	"ParentID":           "回复的评论ID",
+	"StartLine":          "起始行",
+	"EndLine":            "结束行",
+	"TimeOffset":         "时间偏移",
+	"ExpireAt":           "过期时间",
+	"MaxUses":            "最大使用次数",
+	"RequireApproval":    "是否需要审核",
+	"Label":              "标号",
+	"FullMark":           "满分",
+	"ExamMode":           "考试模式",
+	"AllowedIPRanges":    "允许的IP段",
+	"AllowUpsolve":       "是否允许补题",
 }
 
 // RegisterDefaultTranslations registers a set of default translations
@@ -2,6 +2,7 @@ package database
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/EduOJ/backend/base"
@@ -1295,6 +1296,705 @@ func GetMigration() *gormigrate.Gormigrate {
 				return tx.Migrator().DropTable(&Tag{})
 			},
 		},
+		{
+			ID: "add_course_grade",
+			Migrate: func(tx *gorm.DB) error {
+				type Class struct {
+					DropLowest uint `json:"drop_lowest" gorm:"default:0;not null"`
+				}
+				type GradeWeight struct {
+					ID uint `gorm:"primaryKey" json:"id"`
+
+					ClassID      uint    `sql:"index" json:"class_id" gorm:"not null"`
+					ProblemSetID uint    `json:"problem_set_id" gorm:"not null"`
+					ProblemID    uint    `json:"problem_id" gorm:"default:0;not null"`
+					Weight       float64 `json:"weight" gorm:"default:1;not null"`
+
+					CreatedAt time.Time `json:"created_at"`
+					UpdatedAt time.Time `json:"-"`
+				}
+				type CourseGrade struct {
+					ID uint `gorm:"primaryKey" json:"id"`
+
+					UserID  uint `json:"user_id" gorm:"uniqueIndex:course_grade_user_class"`
+					ClassID uint `json:"class_id" gorm:"uniqueIndex:course_grade_user_class"`
+
+					Detail datatypes.JSON `json:"detail"`
+					Total  float64        `json:"total"`
+
+					CreatedAt time.Time `json:"created_at"`
+					UpdatedAt time.Time `json:"-"`
+				}
+				return tx.AutoMigrate(&Class{}, &GradeWeight{}, &CourseGrade{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				type Class struct {
+					DropLowest uint `json:"drop_lowest" gorm:"default:0;not null"`
+				}
+				if err := tx.Migrator().DropColumn(&Class{}, "drop_lowest"); err != nil {
+					return err
+				}
+				return tx.Migrator().DropTable("grade_weights", "course_grades")
+			},
+		},
+		{
+			ID: "add_class_assistants",
+			Migrate: func(tx *gorm.DB) (err error) {
+				type User struct {
+					ID uint `gorm:"primaryKey" json:"id"`
+				}
+				type Class struct {
+					ID         uint   `gorm:"primaryKey" json:"id"`
+					Assistants []User `json:"assistants" gorm:"many2many:user_assist_classes"`
+				}
+				type Permission struct {
+					ID     uint   `gorm:"primaryKey" json:"id"`
+					RoleID uint   `json:"role_id"`
+					Name   string `json:"name" gorm:"size:255"`
+				}
+				type Role struct {
+					ID          uint    `gorm:"primaryKey" json:"id"`
+					Name        string  `json:"name" gorm:"size:255"`
+					Target      *string `json:"target" gorm:"size:255"`
+					Permissions []Permission
+				}
+				if err = tx.AutoMigrate(&Class{}); err != nil {
+					return
+				}
+				classString := "class"
+				classTA := Role{
+					Name:   "class_ta",
+					Target: &classString,
+					Permissions: []Permission{
+						{Name: "read_class_secrets"},
+						{Name: "read_answers"},
+						{Name: "read_grades"},
+						{Name: "rejudge"},
+						{Name: "manage_clarifications"},
+					},
+				}
+				return tx.Create(&classTA).Error
+			},
+			Rollback: func(tx *gorm.DB) (err error) {
+				type Permission struct {
+					ID     uint   `gorm:"primaryKey" json:"id"`
+					RoleID uint   `json:"role_id"`
+					Name   string `json:"name" gorm:"size:255"`
+				}
+				type Role struct {
+					ID          uint    `gorm:"primaryKey" json:"id"`
+					Name        string  `json:"name" gorm:"size:255"`
+					Target      *string `json:"target" gorm:"size:255"`
+					Permissions []Permission
+				}
+				var classTA Role
+				err = tx.Where("name = ? and target = ? ", "class_ta", "class").First(&classTA).Error
+				if err != nil {
+					return
+				}
+				err = tx.Delete(Permission{}, "role_id = ?", classTA.ID).Error
+				if err != nil {
+					return
+				}
+				err = tx.Delete(&classTA).Error
+				if err != nil {
+					return
+				}
+				return tx.Migrator().DropTable("user_assist_classes")
+			},
+		},
+		{
+			ID: "add_announcements_and_notifications",
+			Migrate: func(tx *gorm.DB) error {
+				type User struct {
+					EmailNotification bool `json:"email_notification" gorm:"default:false;not null"`
+				}
+				type Announcement struct {
+					ID uint `gorm:"primaryKey" json:"id"`
+
+					ClassID uint `sql:"index" json:"class_id" gorm:"not null"`
+					UserID  uint `json:"user_id"`
+
+					Title   string `json:"title" gorm:"size:255;default:'';not null"`
+					Content string `json:"content"`
+					Pinned  bool   `json:"pinned" gorm:"default:false;not null"`
+
+					CreatedAt time.Time      `json:"created_at"`
+					UpdatedAt time.Time      `json:"updated_at"`
+					DeletedAt gorm.DeletedAt `json:"deleted_at"`
+				}
+				type Notification struct {
+					ID uint `gorm:"primaryKey" json:"id"`
+
+					UserID uint `sql:"index" json:"user_id" gorm:"not null"`
+
+					Type     string `json:"type" gorm:"size:255;default:'';not null"`
+					Title    string `json:"title" gorm:"size:255;default:'';not null"`
+					Content  string `json:"content"`
+					ClassID  uint   `json:"class_id"`
+					TargetID uint   `json:"target_id"`
+
+					Read bool `json:"read" gorm:"column:has_read;default:false;not null"`
+
+					CreatedAt time.Time `json:"created_at"`
+					UpdatedAt time.Time `json:"-"`
+				}
+				return tx.AutoMigrate(&User{}, &Announcement{}, &Notification{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				type User struct {
+					EmailNotification bool `json:"email_notification" gorm:"default:false;not null"`
+				}
+				if err := tx.Migrator().DropColumn(&User{}, "email_notification"); err != nil {
+					return err
+				}
+				return tx.Migrator().DropTable("announcements", "notifications")
+			},
+		},
+		{
+			ID: "add_clarifications",
+			Migrate: func(tx *gorm.DB) error {
+				type Clarification struct {
+					ID uint `gorm:"primaryKey" json:"id"`
+
+					ProblemSetID uint `sql:"index" json:"problem_set_id" gorm:"not null"`
+					ProblemID    uint `json:"problem_id"`
+					UserID       uint `json:"user_id"`
+
+					Content string `json:"content"`
+					Public  bool   `json:"public" gorm:"default:false;not null"`
+
+					CreatedAt time.Time      `json:"created_at"`
+					UpdatedAt time.Time      `json:"updated_at"`
+					DeletedAt gorm.DeletedAt `json:"deleted_at"`
+				}
+				type ClarificationReply struct {
+					ID uint `gorm:"primaryKey" json:"id"`
+
+					ClarificationID uint `sql:"index" json:"clarification_id" gorm:"not null"`
+					UserID          uint `json:"user_id"`
+
+					Content string `json:"content"`
+
+					CreatedAt time.Time `json:"created_at"`
+					UpdatedAt time.Time `json:"-"`
+				}
+				return tx.AutoMigrate(&Clarification{}, &ClarificationReply{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("clarifications", "clarification_replies")
+			},
+		},
+		{
+			ID: "add_grade_overrides_and_submission_feedback",
+			Migrate: func(tx *gorm.DB) error {
+				type Submission struct {
+					Feedback string `json:"feedback"`
+				}
+				type GradeOverride struct {
+					ID uint `gorm:"primaryKey" json:"id"`
+
+					ClassID      uint `json:"class_id"`
+					ProblemSetID uint `sql:"index" json:"problem_set_id" gorm:"not null"`
+					ProblemID    uint `json:"problem_id" gorm:"not null"`
+					UserID       uint `json:"user_id" gorm:"not null"`
+
+					Score   uint   `json:"score"`
+					Removed bool   `json:"removed" gorm:"default:false;not null"`
+					Reason  string `json:"reason" gorm:"size:255;default:'';not null"`
+
+					OperatorID uint `json:"operator_id"`
+
+					CreatedAt time.Time `json:"created_at"`
+				}
+				return tx.AutoMigrate(&Submission{}, &GradeOverride{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				type Submission struct {
+					Feedback string `json:"feedback"`
+				}
+				if err := tx.Migrator().DropColumn(&Submission{}, "feedback"); err != nil {
+					return err
+				}
+				return tx.Migrator().DropTable("grade_overrides")
+			},
+		},
+		{
+			ID: "add_submission_comments",
+			Migrate: func(tx *gorm.DB) error {
+				type SubmissionComment struct {
+					ID uint `gorm:"primaryKey" json:"id"`
+
+					SubmissionID uint `sql:"index" json:"submission_id" gorm:"not null"`
+					UserID       uint `json:"user_id"`
+					ParentID     uint `json:"parent_id"`
+
+					StartLine uint   `json:"start_line"`
+					EndLine   uint   `json:"end_line"`
+					Content   string `json:"content"`
+
+					CreatedAt time.Time      `json:"created_at"`
+					UpdatedAt time.Time      `json:"-"`
+					DeletedAt gorm.DeletedAt `json:"deleted_at"`
+				}
+				return tx.AutoMigrate(&SubmissionComment{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("submission_comments")
+			},
+		},
+		{
+			ID: "add_plagiarism_reports",
+			Migrate: func(tx *gorm.DB) error {
+				type PlagiarismReport struct {
+					ID uint `gorm:"primaryKey" json:"id"`
+
+					ProblemSetID uint `sql:"index" json:"problem_set_id" gorm:"not null"`
+					OperatorID   uint `json:"operator_id"`
+
+					Status          string `json:"status"`
+					Message         string `json:"message"`
+					SubmissionCount uint   `json:"submission_count"`
+
+					FinishedAt *time.Time `json:"finished_at"`
+					CreatedAt  time.Time  `json:"created_at"`
+					UpdatedAt  time.Time  `json:"-"`
+				}
+				type PlagiarismPair struct {
+					ID uint `gorm:"primaryKey" json:"id"`
+
+					ReportID  uint `sql:"index" json:"report_id" gorm:"not null"`
+					ProblemID uint `json:"problem_id"`
+
+					FirstSubmissionID  uint `json:"first_submission_id"`
+					FirstUserID        uint `json:"first_user_id"`
+					SecondSubmissionID uint `json:"second_submission_id"`
+					SecondUserID       uint `json:"second_user_id"`
+
+					Similarity float64        `json:"similarity"`
+					Regions    datatypes.JSON `json:"regions"`
+				}
+				return tx.AutoMigrate(&PlagiarismReport{}, &PlagiarismPair{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("plagiarism_reports", "plagiarism_pairs")
+			},
+		},
+		{
+			ID: "add_invite_code_limits_and_join_requests",
+			Migrate: func(tx *gorm.DB) error {
+				type Class struct {
+					InviteCodeExpireAt *time.Time `json:"invite_code_expire_at"`
+					InviteCodeMaxUses  uint       `json:"invite_code_max_uses" gorm:"default:0;not null"`
+					InviteCodeUses     uint       `json:"invite_code_uses" gorm:"default:0;not null"`
+					RequireApproval    bool       `json:"require_approval" gorm:"default:false;not null"`
+				}
+				type ClassJoinRequest struct {
+					ID uint `gorm:"primaryKey" json:"id"`
+
+					ClassID uint `sql:"index" json:"class_id" gorm:"not null"`
+					UserID  uint `json:"user_id" gorm:"not null"`
+
+					Status     string `json:"status" gorm:"size:255;not null"`
+					OperatorID uint   `json:"operator_id"`
+
+					CreatedAt time.Time `json:"created_at"`
+					UpdatedAt time.Time `json:"updated_at"`
+				}
+				return tx.AutoMigrate(&Class{}, &ClassJoinRequest{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				type Class struct {
+					InviteCodeExpireAt *time.Time `json:"invite_code_expire_at"`
+					InviteCodeMaxUses  uint       `json:"invite_code_max_uses" gorm:"default:0;not null"`
+					InviteCodeUses     uint       `json:"invite_code_uses" gorm:"default:0;not null"`
+					RequireApproval    bool       `json:"require_approval" gorm:"default:false;not null"`
+				}
+				for _, column := range []string{"invite_code_expire_at", "invite_code_max_uses", "invite_code_uses", "require_approval"} {
+					if err := tx.Migrator().DropColumn(&Class{}, column); err != nil {
+						return err
+					}
+				}
+				return tx.Migrator().DropTable("class_join_requests")
+			},
+		},
+		{
+			ID: "add_order_label_and_full_mark_to_problems_in_problem_sets",
+			Migrate: func(tx *gorm.DB) error {
+				type ProblemInProblemSet struct {
+					ProblemSetID uint `gorm:"primaryKey" json:"problem_set_id"`
+					ProblemID    uint `gorm:"primaryKey" json:"problem_id"`
+
+					Order    uint   `json:"order" gorm:"column:display_order;default:0;not null"`
+					Label    string `json:"label" gorm:"size:255;default:'';not null"`
+					FullMark uint   `json:"full_mark" gorm:"default:100;not null"`
+				}
+				for _, column := range []string{"Order", "Label", "FullMark"} {
+					if err := tx.Table("problems_in_problem_sets").Migrator().AddColumn(&ProblemInProblemSet{}, column); err != nil {
+						return err
+					}
+				}
+				var entries []ProblemInProblemSet
+				if err := tx.Table("problems_in_problem_sets").Order("problem_set_id, problem_id").Find(&entries).Error; err != nil {
+					return err
+				}
+				order := make(map[uint]uint)
+				for _, entry := range entries {
+					label := ""
+					for i := order[entry.ProblemSetID] + 1; i > 0; i = (i - 1) / 26 {
+						label = string(rune('A'+(i-1)%26)) + label
+					}
+					if err := tx.Table("problems_in_problem_sets").
+						Where("problem_set_id = ? and problem_id = ?", entry.ProblemSetID, entry.ProblemID).
+						Updates(map[string]interface{}{
+							"display_order": order[entry.ProblemSetID],
+							"label":         label,
+						}).Error; err != nil {
+						return err
+					}
+					order[entry.ProblemSetID]++
+				}
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				type ProblemInProblemSet struct {
+					ProblemSetID uint `gorm:"primaryKey" json:"problem_set_id"`
+					ProblemID    uint `gorm:"primaryKey" json:"problem_id"`
+
+					Order    uint   `json:"order" gorm:"column:display_order;default:0;not null"`
+					Label    string `json:"label" gorm:"size:255;default:'';not null"`
+					FullMark uint   `json:"full_mark" gorm:"default:100;not null"`
+				}
+				for _, column := range []string{"display_order", "label", "full_mark"} {
+					if err := tx.Table("problems_in_problem_sets").Migrator().DropColumn(&ProblemInProblemSet{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID: "add_exam_mode",
+			Migrate: func(tx *gorm.DB) error {
+				type ProblemSet struct {
+					ExamMode     bool   `json:"exam_mode" gorm:"default:false;not null"`
+					ExamIPRanges string `json:"exam_ip_ranges" gorm:"size:2047;default:'';not null"`
+				}
+				type ExamSession struct {
+					ID uint `gorm:"primaryKey" json:"id"`
+
+					ProblemSetID uint   `json:"problem_set_id" gorm:"not null;uniqueIndex:exam_session_user"`
+					UserID       uint   `json:"user_id" gorm:"not null;uniqueIndex:exam_session_user"`
+					TokenID      uint   `json:"token_id" gorm:"not null"`
+					IP           string `json:"ip" gorm:"size:255;default:'';not null"`
+
+					CreatedAt time.Time `json:"created_at"`
+					UpdatedAt time.Time `json:"updated_at"`
+				}
+				type ExamViolation struct {
+					ID uint `gorm:"primaryKey" json:"id"`
+
+					ProblemSetID uint `sql:"index" json:"problem_set_id" gorm:"not null"`
+					UserID       uint `json:"user_id" gorm:"not null"`
+
+					Type string `json:"type" gorm:"size:255;not null"`
+					IP   string `json:"ip" gorm:"size:255;default:'';not null"`
+					Path string `json:"path" gorm:"size:2047;default:'';not null"`
+
+					CreatedAt time.Time `json:"created_at"`
+				}
+				return tx.AutoMigrate(&ProblemSet{}, &ExamSession{}, &ExamViolation{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				type ProblemSet struct {
+					ExamMode     bool   `json:"exam_mode" gorm:"default:false;not null"`
+					ExamIPRanges string `json:"exam_ip_ranges" gorm:"size:2047;default:'';not null"`
+				}
+				for _, column := range []string{"exam_mode", "exam_ip_ranges"} {
+					if err := tx.Migrator().DropColumn(&ProblemSet{}, column); err != nil {
+						return err
+					}
+				}
+				if err := tx.Migrator().DropTable("exam_sessions"); err != nil {
+					return err
+				}
+				return tx.Migrator().DropTable("exam_violations")
+			},
+		},
+		{
+			ID: "add_upsolve",
+			Migrate: func(tx *gorm.DB) error {
+				type ProblemSet struct {
+					AllowUpsolve bool `json:"allow_upsolve" gorm:"default:false;not null"`
+				}
+				type Submission struct {
+					Upsolve bool `json:"upsolve" gorm:"default:false;not null"`
+				}
+				if err := tx.AutoMigrate(&ProblemSet{}, &Submission{}); err != nil {
+					return err
+				}
+				return tx.Model(&Submission{}).
+					Where("problem_set_id <> 0 and created_at >= (?)",
+						tx.Table("problem_sets").Select("end_time").Where("problem_sets.id = submissions.problem_set_id")).
+					Update("upsolve", true).Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				type ProblemSet struct {
+					AllowUpsolve bool `json:"allow_upsolve" gorm:"default:false;not null"`
+				}
+				type Submission struct {
+					Upsolve bool `json:"upsolve" gorm:"default:false;not null"`
+				}
+				if err := tx.Migrator().DropColumn(&ProblemSet{}, "allow_upsolve"); err != nil {
+					return err
+				}
+				return tx.Migrator().DropColumn(&Submission{}, "upsolve")
+			},
+		},
+		{
+			ID: "add_language_allowed_to_problem_sets",
+			Migrate: func(tx *gorm.DB) error {
+				type ProblemSet struct {
+					LanguageAllowed string `json:"language_allowed" gorm:"size:255;default:'';not null"`
+				}
+				return tx.AutoMigrate(&ProblemSet{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				type ProblemSet struct {
+					LanguageAllowed string `json:"language_allowed" gorm:"size:255;default:'';not null"`
+				}
+				return tx.Migrator().DropColumn(&ProblemSet{}, "language_allowed")
+			},
+		},
+		{
+			ID: "add_grade_changes",
+			Migrate: func(tx *gorm.DB) error {
+				type Submission struct {
+					Rejudged bool `json:"rejudged" gorm:"default:false;not null"`
+				}
+				type GradeChange struct {
+					ID uint `gorm:"primaryKey" json:"id"`
+
+					ClassID      uint `json:"class_id"`
+					ProblemSetID uint `sql:"index" json:"problem_set_id" gorm:"not null"`
+					ProblemID    uint `json:"problem_id" gorm:"not null"`
+					UserID       uint `sql:"index" json:"user_id" gorm:"not null"`
+
+					OldScore uint `json:"old_score"`
+					NewScore uint `json:"new_score"`
+
+					Cause        string `json:"cause" gorm:"size:31;not null"`
+					SubmissionID uint   `json:"submission_id"`
+
+					CreatedAt time.Time `json:"created_at"`
+				}
+				return tx.AutoMigrate(&Submission{}, &GradeChange{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				type Submission struct {
+					Rejudged bool `json:"rejudged" gorm:"default:false;not null"`
+				}
+				if err := tx.Migrator().DropColumn(&Submission{}, "rejudged"); err != nil {
+					return err
+				}
+				return tx.Migrator().DropTable("grade_changes")
+			},
+		},
+		{
+			ID: "add_user_identities",
+			Migrate: func(tx *gorm.DB) error {
+				type UserIdentity struct {
+					ID uint `gorm:"primaryKey" json:"id"`
+
+					UserID uint `sql:"index" json:"user_id" gorm:"not null"`
+
+					Provider string `json:"provider" gorm:"size:255;not null;uniqueIndex:user_identity_subject"`
+					Subject  string `json:"subject" gorm:"size:255;not null;uniqueIndex:user_identity_subject"`
+					Email    string `json:"email" gorm:"size:320;default:'';not null"`
+
+					CreatedAt time.Time `json:"created_at"`
+					UpdatedAt time.Time `json:"-"`
+				}
+				return tx.AutoMigrate(&UserIdentity{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("user_identities")
+			},
+		},
+		{
+			ID: "add_two_factor",
+			Migrate: func(tx *gorm.DB) error {
+				type TOTPCredential struct {
+					ID uint `gorm:"primaryKey" json:"id"`
+
+					UserID       uint   `json:"user_id" gorm:"not null;uniqueIndex"`
+					Secret       string `json:"-" gorm:"size:64;not null"`
+					Enabled      bool   `json:"enabled" gorm:"default:false;not null"`
+					LastUsedStep int64  `json:"-" gorm:"default:0;not null"`
+
+					CreatedAt time.Time `json:"created_at"`
+					UpdatedAt time.Time `json:"-"`
+				}
+				type RecoveryCode struct {
+					ID uint `gorm:"primaryKey" json:"id"`
+
+					UserID   uint   `sql:"index" json:"user_id" gorm:"not null"`
+					CodeHash string `json:"-" gorm:"size:64;not null"`
+					Used     bool   `json:"used" gorm:"default:false;not null"`
+
+					CreatedAt time.Time `json:"created_at"`
+				}
+				return tx.AutoMigrate(&TOTPCredential{}, &RecoveryCode{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("totp_credentials", "recovery_codes")
+			},
+		},
+		{
+			ID: "add_personal_access_tokens",
+			Migrate: func(tx *gorm.DB) error {
+				type PersonalAccessToken struct {
+					ID uint `gorm:"primaryKey" json:"id"`
+
+					UserID uint `sql:"index" json:"user_id" gorm:"not null"`
+
+					Name      string      `json:"name" gorm:"size:255;not null"`
+					TokenHash string      `json:"-" gorm:"size:64;not null;uniqueIndex"`
+					Scopes    StringArray `json:"scopes" gorm:"size:255;default:'';not null;type:string"`
+
+					ExpiresAt  time.Time  `json:"expires_at"`
+					LastUsedAt *time.Time `json:"last_used_at"`
+
+					CreatedAt time.Time `json:"created_at"`
+					UpdatedAt time.Time `json:"-"`
+				}
+				return tx.AutoMigrate(&PersonalAccessToken{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("personal_access_tokens")
+			},
+		},
+		{
+			ID: "add_user_agent_and_ip_to_tokens",
+			Migrate: func(tx *gorm.DB) error {
+				type Token struct {
+					UserAgent string `json:"user_agent" gorm:"size:255;default:'';not null"`
+					IP        string `json:"ip" gorm:"size:64;default:'';not null"`
+				}
+				return tx.AutoMigrate(&Token{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				type Token struct {
+					UserAgent string
+					IP        string
+				}
+				if err := tx.Migrator().DropColumn(&Token{}, "user_agent"); err != nil {
+					return err
+				}
+				return tx.Migrator().DropColumn(&Token{}, "ip")
+			},
+		},
+		{
+			ID: "add_login_lockouts",
+			Migrate: func(tx *gorm.DB) error {
+				type LoginLockout struct {
+					ID uint `gorm:"primaryKey" json:"id"`
+
+					Type     string `json:"type" gorm:"size:255;not null"`
+					UserID   uint   `sql:"index" json:"user_id" gorm:"not null"`
+					IP       string `json:"ip" gorm:"size:255;default:'';not null"`
+					Failures int64  `json:"failures" gorm:"not null"`
+
+					ExpiresAt    time.Time  `json:"expires_at"`
+					UnlockedByID *uint      `json:"unlocked_by_id"`
+					UnlockedAt   *time.Time `json:"unlocked_at"`
+
+					CreatedAt time.Time `json:"created_at"`
+				}
+				return tx.AutoMigrate(&LoginLockout{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("login_lockouts")
+			},
+		},
+		{
+			ID: "add_username_to_login_lockouts",
+			Migrate: func(tx *gorm.DB) error {
+				type LoginLockout struct {
+					Username string `json:"username" gorm:"size:255;default:'';not null"`
+				}
+				return tx.AutoMigrate(&LoginLockout{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				type LoginLockout struct {
+					Username string
+				}
+				return tx.Migrator().DropColumn(&LoginLockout{}, "username")
+			},
+		},
+		{
+			ID: "add_lease_to_plagiarism_reports",
+			Migrate: func(tx *gorm.DB) error {
+				type PlagiarismReport struct {
+					Instance    string `gorm:"size:255;default:'';not null"`
+					HeartbeatAt time.Time
+				}
+				return tx.AutoMigrate(&PlagiarismReport{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				type PlagiarismReport struct {
+					Instance    string
+					HeartbeatAt time.Time
+				}
+				if err := tx.Migrator().DropColumn(&PlagiarismReport{}, "instance"); err != nil {
+					return err
+				}
+				return tx.Migrator().DropColumn(&PlagiarismReport{}, "heartbeat_at")
+			},
+		},
+		{
+			ID: "convert_language_allowed_of_problem_sets_to_string_array",
+			Migrate: func(tx *gorm.DB) error {
+				type ProblemSet struct {
+					ID              uint
+					LanguageAllowed string
+				}
+				var problemSets []ProblemSet
+				if err := tx.Where("language_allowed <> ?", "").Find(&problemSets).Error; err != nil {
+					return err
+				}
+				for _, problemSet := range problemSets {
+					languages := StringArray{}
+					for _, name := range strings.Split(problemSet.LanguageAllowed, ",") {
+						if name = strings.TrimSpace(name); name != "" {
+							languages = append(languages, name)
+						}
+					}
+					if err := tx.Model(&ProblemSet{}).Where("id = ?", problemSet.ID).
+						Update("language_allowed", languages).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				type ProblemSet struct {
+					ID              uint
+					LanguageAllowed StringArray `gorm:"type:string"`
+				}
+				var problemSets []ProblemSet
+				if err := tx.Where("language_allowed <> ?", "").Find(&problemSets).Error; err != nil {
+					return err
+				}
+				for _, problemSet := range problemSets {
+					if err := tx.Model(&ProblemSet{}).Where("id = ?", problemSet.ID).
+						Update("language_allowed", strings.Join(problemSet.LanguageAllowed, ",")).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
 	})
 }
 
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Announcement struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	ClassID uint   `sql:"index" json:"class_id" gorm:"not null"`
+	Class   *Class `json:"class"`
+	UserID  uint   `json:"user_id"`
+	User    *User  `json:"user"`
+
+	Title   string `json:"title" gorm:"size:255;default:'';not null"`
+	Content string `json:"content"` // markdown
+	Pinned  bool   `json:"pinned" gorm:"default:false;not null"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at"`
+}
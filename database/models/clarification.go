@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Clarification is a question asked by a user during a problem set.
+// It is only visible to the asker and the managers of the class, unless it is public.
+type Clarification struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	ProblemSetID uint        `sql:"index" json:"problem_set_id" gorm:"not null"`
+	ProblemSet   *ProblemSet `json:"problem_set"`
+	// ProblemID is 0 if the clarification is not about a specific problem.
+	ProblemID uint  `json:"problem_id"`
+	UserID    uint  `json:"user_id"`
+	User      *User `json:"user"`
+
+	Content string `json:"content"`
+	Public  bool   `json:"public" gorm:"default:false;not null"`
+
+	Replies []ClarificationReply `json:"replies"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at"`
+}
+
+type ClarificationReply struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	ClarificationID uint  `sql:"index" json:"clarification_id" gorm:"not null"`
+	UserID          uint  `json:"user_id"`
+	User            *User `json:"user"`
+
+	Content string `json:"content"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"-"`
+}
@@ -15,9 +15,21 @@ type Class struct {
 	InviteCode  string  `json:"invite_code" gorm:"size:255;default:'';not null"`
 	Managers    []*User `json:"managers" gorm:"many2many:user_manage_classes"`
 	Students    []*User `json:"students" gorm:"many2many:user_in_classes"`
+	Assistants  []*User `json:"assistants" gorm:"many2many:user_assist_classes"`
 
 	ProblemSets []*ProblemSet `json:"problem_sets"`
 
+	// InviteCodeExpireAt is the time after which the invite code could not be used. Nil means never.
+	InviteCodeExpireAt *time.Time `json:"invite_code_expire_at"`
+	// InviteCodeMaxUses is the number of times the invite code could be used. 0 means unlimited.
+	InviteCodeMaxUses uint `json:"invite_code_max_uses" gorm:"default:0;not null"`
+	InviteCodeUses    uint `json:"invite_code_uses" gorm:"default:0;not null"`
+	// RequireApproval makes joining the class create a join request to be approved by the managers.
+	RequireApproval bool `json:"require_approval" gorm:"default:false;not null"`
+
+	// DropLowest is the number of lowest problem set scores dropped from the course grade.
+	DropLowest uint `json:"drop_lowest" gorm:"default:0;not null"`
+
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"-"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
@@ -61,6 +73,51 @@ func (c *Class) DeleteStudents(ids []uint) error {
 	return base.DB.Model(c).Association("Students").Delete(&users)
 }
 
+func (c *Class) AddAssistants(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	existingIds := make([]uint, len(c.Assistants))
+	for i, a := range c.Assistants {
+		existingIds[i] = a.ID
+	}
+	var users []*User
+	query := base.DB
+	if len(existingIds) != 0 {
+		query = base.DB.Where("id not in (?)", existingIds)
+	}
+	if err := query.Find(&users, ids).Error; err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return nil
+	}
+	if err := base.DB.Model(c).Association("Assistants").Append(users); err != nil {
+		return err
+	}
+	for _, u := range users {
+		u.GrantRole("class_ta", c)
+	}
+	return nil
+}
+
+func (c *Class) DeleteAssistants(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var users []*User
+	if err := base.DB.Find(&users, ids).Error; err != nil {
+		return err
+	}
+	if err := base.DB.Model(c).Association("Assistants").Delete(users); err != nil {
+		return err
+	}
+	for _, u := range users {
+		u.DeleteRole("class_ta", c)
+	}
+	return nil
+}
+
 func (c *Class) AfterDelete(tx *gorm.DB) error {
 	var problemSets []ProblemSet
 	err := tx.Find(&problemSets, "class_id = ?", c.ID).Error
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+const (
+	ClassJoinRequestStatusPending  = "PENDING"
+	ClassJoinRequestStatusApproved = "APPROVED"
+	ClassJoinRequestStatusRejected = "REJECTED"
+)
+
+// ClassJoinRequest is created when a user joins a class which requires approval.
+type ClassJoinRequest struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	ClassID uint  `sql:"index" json:"class_id" gorm:"not null"`
+	UserID  uint  `json:"user_id" gorm:"not null"`
+	User    *User `json:"user"`
+
+	// PENDING / APPROVED / REJECTED
+	Status     string `json:"status" gorm:"size:255;not null"`
+	OperatorID uint   `json:"operator_id"`
+	Operator   *User  `json:"operator"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
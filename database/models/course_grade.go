@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// GradeWeight is the weight of a problem set in the course grade of a class.
+// When ProblemID is not 0, it is the weight of that problem inside the problem set instead.
+// Problem sets and problems without a GradeWeight have a weight of 1.
+type GradeWeight struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	ClassID      uint    `sql:"index" json:"class_id" gorm:"not null"`
+	ProblemSetID uint    `json:"problem_set_id" gorm:"not null"`
+	ProblemID    uint    `json:"problem_id" gorm:"default:0;not null"`
+	Weight       float64 `json:"weight" gorm:"default:1;not null"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+type CourseGrade struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	UserID  uint   `json:"user_id" gorm:"uniqueIndex:course_grade_user_class"`
+	User    *User  `json:"user"`
+	ClassID uint   `json:"class_id" gorm:"uniqueIndex:course_grade_user_class"`
+	Class   *Class `json:"class"`
+
+	// Detail is a JSON array of CourseGradeItem.
+	Detail datatypes.JSON `json:"detail"`
+	Total  float64        `json:"total"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+// CourseGradeItem is the breakdown of a course grade for one problem set.
+type CourseGradeItem struct {
+	ProblemSetID uint    `json:"problem_set_id"`
+	Score        float64 `json:"score"` // percentage of the weighted full mark
+	Weight       float64 `json:"weight"`
+	Dropped      bool    `json:"dropped"`
+}
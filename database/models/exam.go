@@ -0,0 +1,56 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	ExamViolationIPNotAllowed    = "IP_NOT_ALLOWED"
+	ExamViolationSessionConflict = "SESSION_CONFLICT"
+	ExamViolationOutOfExamAccess = "OUT_OF_EXAM_ACCESS"
+)
+
+// ExamSession binds a student to the first token used to access a running exam.
+// Requests using other tokens are rejected until a teacher resets the session.
+type ExamSession struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	ProblemSetID uint   `json:"problem_set_id" gorm:"not null;uniqueIndex:exam_session_user"`
+	UserID       uint   `json:"user_id" gorm:"not null;uniqueIndex:exam_session_user"`
+	TokenID      uint   `json:"token_id" gorm:"not null"`
+	IP           string `json:"ip" gorm:"size:255;default:'';not null"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ExamViolation records a request rejected by the restrictions of a running exam.
+type ExamViolation struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	ProblemSetID uint  `sql:"index" json:"problem_set_id" gorm:"not null"`
+	UserID       uint  `json:"user_id" gorm:"not null"`
+	User         *User `json:"user"`
+
+	// IP_NOT_ALLOWED / SESSION_CONFLICT / OUT_OF_EXAM_ACCESS
+	Type string `json:"type" gorm:"size:255;not null"`
+	IP   string `json:"ip" gorm:"size:255;default:'';not null"`
+	Path string `json:"path" gorm:"size:2047;default:'';not null"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AllowedIPRanges returns the CIDR ranges the exam accepts requests from.
+// An empty slice means requests from any address are accepted.
+func (p *ProblemSet) AllowedIPRanges() []string {
+	if p.ExamIPRanges == "" {
+		return []string{}
+	}
+	return strings.Split(p.ExamIPRanges, ",")
+}
+
+// ExamRunning tells if the problem set is an exam taking place at the given time.
+func (p *ProblemSet) ExamRunning(now time.Time) bool {
+	return p.ExamMode && !now.Before(p.StartTime) && now.Before(p.EndTime)
+}
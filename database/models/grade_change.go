@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+const (
+	GradeChangeCauseSubmission = "SUBMISSION"
+	GradeChangeCauseRefresh    = "REFRESH"
+	GradeChangeCauseOverride   = "OVERRIDE"
+	GradeChangeCauseRejudge    = "REJUDGE"
+)
+
+// GradeChange records a change of the score of a student for a problem in a problem set.
+// Changes are never modified or deleted, so that they form the history of the grades.
+type GradeChange struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	ClassID      uint  `json:"class_id"`
+	ProblemSetID uint  `sql:"index" json:"problem_set_id" gorm:"not null"`
+	ProblemID    uint  `json:"problem_id" gorm:"not null"`
+	UserID       uint  `sql:"index" json:"user_id" gorm:"not null"`
+	User         *User `json:"user"`
+
+	OldScore uint `json:"old_score"`
+	NewScore uint `json:"new_score"`
+
+	// SUBMISSION / REFRESH / OVERRIDE / REJUDGE
+	Cause string `json:"cause" gorm:"size:31;not null"`
+	// SubmissionID is the submission which causes the change, for changes caused by submissions and rejudges.
+	SubmissionID uint `json:"submission_id"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// GradeOverride overrides the score of a student for a problem in a problem set.
+// Overrides are never modified or deleted, so that they form an audit trail.
+// The latest record of a student and a problem takes effect, unless it is Removed.
+type GradeOverride struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	ClassID      uint  `json:"class_id"`
+	ProblemSetID uint  `sql:"index" json:"problem_set_id" gorm:"not null"`
+	ProblemID    uint  `json:"problem_id" gorm:"not null"`
+	UserID       uint  `json:"user_id" gorm:"not null"`
+	User         *User `json:"user"`
+
+	Score   uint   `json:"score"`
+	Removed bool   `json:"removed" gorm:"default:false;not null"`
+	Reason  string `json:"reason" gorm:"size:255;default:'';not null"`
+
+	OperatorID uint  `json:"operator_id"`
+	Operator   *User `json:"operator"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
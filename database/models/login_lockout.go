@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+const (
+	LoginLockoutAccount = "ACCOUNT"
+	LoginLockoutIP      = "IP"
+)
+
+// LoginLockout records an account or an ip locked out from logging in after repeated failures.
+// The records are kept after the lockouts expire as an audit log.
+type LoginLockout struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	// ACCOUNT / IP
+	Type string `json:"type" gorm:"size:255;not null"`
+	// UserID is the locked user for account lockouts, or 0 for ip lockouts.
+	UserID uint  `sql:"index" json:"user_id" gorm:"not null"`
+	User   *User `json:"user"`
+	// Username is the submitted username for account lockouts of users not existing locally,
+	// e.g. users in the LDAP directory before their first login.
+	Username string `json:"username" gorm:"size:255;default:'';not null"`
+	// IP is the locked ip for ip lockouts, or the ip of the last failure for account lockouts.
+	IP       string `json:"ip" gorm:"size:255;default:'';not null"`
+	Failures int64  `json:"failures" gorm:"not null"`
+
+	ExpiresAt time.Time `json:"expires_at"`
+	// UnlockedByID is the admin unlocking the lockout before it expires, or nil.
+	UnlockedByID *uint      `json:"unlocked_by_id"`
+	UnlockedAt   *time.Time `json:"unlocked_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+const (
+	NotificationTypeAnnouncement       = "announcement"
+	NotificationTypeSubmissionJudged   = "submission_judged"
+	NotificationTypeProblemSetStarted  = "problem_set_started"
+	NotificationTypeProblemSetDeadline = "problem_set_deadline"
+	NotificationTypeClarification      = "clarification"
+	NotificationTypeSubmissionComment  = "submission_comment"
+)
+
+type Notification struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	UserID uint  `sql:"index" json:"user_id" gorm:"not null"`
+	User   *User `json:"user"`
+
+	Type    string `json:"type" gorm:"size:255;default:'';not null"`
+	Title   string `json:"title" gorm:"size:255;default:'';not null"`
+	Content string `json:"content"`
+	// ClassID and TargetID point to the class and the announcement, submission or problem set
+	// the notification is about.
+	ClassID  uint `json:"class_id"`
+	TargetID uint `json:"target_id"`
+
+	Read bool `json:"read" gorm:"column:has_read;default:false;not null"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"-"`
+}
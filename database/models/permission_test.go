@@ -89,6 +89,6 @@ func TestUserHasRoleMarshalJSON(t *testing.T) {
 	}
 	j, err := json.Marshal(user)
 	assert.NoError(t, err)
-	expected := `{"id":4001,"username":"test_marshal_json_username","nickname":"test_marshal_json_nickname","email":"test_marshal_json@mail.com","email_verified":false,"roles":[{"id":2001,"name":"role1","target":"test_class","Permissions":[{"id":1001,"role_id":2001,"name":"perm1"},{"id":1003,"role_id":2001,"name":"perm2"}],"target_id":5001},{"id":2002,"name":"role2","target":"test_class","Permissions":[{"id":1002,"role_id":2002,"name":"perm1"}],"target_id":5002},{"id":2001,"name":"role1","target":"test_class","Permissions":[{"id":1001,"role_id":2001,"name":"perm1"},{"id":1003,"role_id":2001,"name":"perm2"}],"target_id":0}],"class_managing":null,"class_taking":null,"grades":null,"created_at":"2020-08-18T13:24:25.031972138+08:00","deleted_at":"2020-08-18T13:29:24.031972138+08:00","Credentials":null}`
+	expected := `{"id":4001,"username":"test_marshal_json_username","nickname":"test_marshal_json_nickname","email":"test_marshal_json@mail.com","email_verified":false,"email_notification":false,"roles":[{"id":2001,"name":"role1","target":"test_class","Permissions":[{"id":1001,"role_id":2001,"name":"perm1"},{"id":1003,"role_id":2001,"name":"perm2"}],"target_id":5001},{"id":2002,"name":"role2","target":"test_class","Permissions":[{"id":1002,"role_id":2002,"name":"perm1"}],"target_id":5002},{"id":2001,"name":"role1","target":"test_class","Permissions":[{"id":1001,"role_id":2001,"name":"perm1"},{"id":1003,"role_id":2001,"name":"perm2"}],"target_id":0}],"class_managing":null,"class_taking":null,"grades":null,"created_at":"2020-08-18T13:24:25.031972138+08:00","deleted_at":"2020-08-18T13:29:24.031972138+08:00","Credentials":null}`
 	assert.Equal(t, expected, string(j))
 }
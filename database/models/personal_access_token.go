@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/EduOJ/backend/database"
+)
+
+// PersonalAccessToken is a long-lived token for scripts. It could only access the APIs covered by its scopes.
+type PersonalAccessToken struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	UserID uint  `sql:"index" json:"user_id" gorm:"not null"`
+	User   *User `json:"user"`
+
+	Name string `json:"name" gorm:"size:255;not null"`
+	// TokenHash is the hex encoded SHA-256 hash of the token. The token itself is only shown once on creation.
+	TokenHash string               `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	Scopes    database.StringArray `json:"scopes" gorm:"size:255;default:'';not null;type:string"`
+
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"-"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+const (
+	PlagiarismReportStatusPending  = "PENDING"
+	PlagiarismReportStatusRunning  = "RUNNING"
+	PlagiarismReportStatusFinished = "FINISHED"
+	PlagiarismReportStatusFailed   = "FAILED"
+)
+
+// PlagiarismReport is the result of a similarity analysis of the submissions in a problem set.
+type PlagiarismReport struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	ProblemSetID uint        `sql:"index" json:"problem_set_id" gorm:"not null"`
+	ProblemSet   *ProblemSet `json:"problem_set"`
+	OperatorID   uint        `json:"operator_id"`
+	Operator     *User       `json:"operator"`
+
+	// PENDING / RUNNING / FINISHED / FAILED
+	Status          string `json:"status"`
+	Message         string `json:"message"`
+	SubmissionCount uint   `json:"submission_count"`
+	// Instance is the server analysing the report, which renews HeartbeatAt while the analysis runs.
+	Instance    string    `json:"-" gorm:"size:255;default:'';not null"`
+	HeartbeatAt time.Time `json:"-"`
+
+	Pairs []PlagiarismPair `json:"pairs" gorm:"foreignKey:ReportID"`
+
+	FinishedAt *time.Time `json:"finished_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"-"`
+}
+
+// PlagiarismPair is a pair of suspiciously similar submissions for the same problem.
+type PlagiarismPair struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	ReportID  uint `sql:"index" json:"report_id" gorm:"not null"`
+	ProblemID uint `json:"problem_id"`
+
+	FirstSubmissionID  uint  `json:"first_submission_id"`
+	FirstUserID        uint  `json:"first_user_id"`
+	FirstUser          *User `json:"first_user"`
+	SecondSubmissionID uint  `json:"second_submission_id"`
+	SecondUserID       uint  `json:"second_user_id"`
+	SecondUser         *User `json:"second_user"`
+
+	Similarity float64 `json:"similarity"`
+	// Regions is a json array of PlagiarismRegion.
+	Regions datatypes.JSON `json:"regions"`
+}
+
+// PlagiarismRegion is a pair of line ranges that match in the two submissions of a pair.
+type PlagiarismRegion struct {
+	FirstStartLine  uint `json:"first_start_line"`
+	FirstEndLine    uint `json:"first_end_line"`
+	SecondStartLine uint `json:"second_start_line"`
+	SecondEndLine   uint `json:"second_end_line"`
+}
@@ -2,9 +2,11 @@ package models
 
 import (
 	"encoding/json"
+	"sort"
 	"time"
 
 	"github.com/EduOJ/backend/base"
+	"github.com/EduOJ/backend/database"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
@@ -20,14 +22,79 @@ type ProblemSet struct {
 	Problems []*Problem `json:"problems" gorm:"many2many:problems_in_problem_sets"`
 	Grades   []*Grade   `json:"grades"`
 
+	ProblemEntries []*ProblemInProblemSet `json:"problem_entries" gorm:"foreignKey:ProblemSetID"`
+
 	StartTime time.Time `json:"start_time"`
 	EndTime   time.Time `json:"end_time"`
 
+	// ExamMode restricts the students of the class while the problem set is running.
+	ExamMode bool `json:"exam_mode" gorm:"default:false;not null"`
+	// ExamIPRanges is a comma separated list of CIDR ranges accepted during the exam. Empty means any.
+	ExamIPRanges string `json:"exam_ip_ranges" gorm:"size:2047;default:'';not null"`
+
+	// AllowUpsolve accepts submissions after the problem set ends. They never change the grades.
+	AllowUpsolve bool `json:"allow_upsolve" gorm:"default:false;not null"`
+
+	// LanguageAllowed restricts the languages allowed by the problems. Empty means no restriction.
+	LanguageAllowed database.StringArray `json:"language_allowed" gorm:"size:255;default:'';not null;type:string"` // E.g.    c,python
+
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"-"`
 	DeletedAt gorm.DeletedAt `json:"deleted_at"`
 }
 
+// DefaultFullMark is the full mark of a problem in a problem set unless specified.
+const DefaultFullMark = 100
+
+// ProblemInProblemSet is a row of the join table between problem sets and problems.
+type ProblemInProblemSet struct {
+	ProblemSetID uint `gorm:"primaryKey" json:"problem_set_id"`
+	ProblemID    uint `gorm:"primaryKey" json:"problem_id"`
+
+	Order    uint   `json:"order" gorm:"column:display_order;default:0;not null"`
+	Label    string `json:"label" gorm:"size:255;default:'';not null"`
+	FullMark uint   `json:"full_mark" gorm:"default:100;not null"`
+}
+
+func (ProblemInProblemSet) TableName() string {
+	return "problems_in_problem_sets"
+}
+
+// Languages returns the languages allowed by the problem set. An empty slice means no restriction.
+func (p *ProblemSet) Languages() []string {
+	if p.LanguageAllowed == nil {
+		return []string{}
+	}
+	return p.LanguageAllowed
+}
+
+// AllowedLanguages returns the languages allowed for a problem in the problem set,
+// which is the intersection of the languages allowed by the problem and the problem set.
+func (p *ProblemSet) AllowedLanguages(problem *Problem) []string {
+	if len(p.LanguageAllowed) == 0 {
+		return problem.LanguageAllowed
+	}
+	languages := make([]string, 0, len(problem.LanguageAllowed))
+	for _, language := range problem.LanguageAllowed {
+		for _, allowed := range p.LanguageAllowed {
+			if language == allowed {
+				languages = append(languages, language)
+				break
+			}
+		}
+	}
+	return languages
+}
+
+// ProblemLabel returns the default label of the i-th problem in a problem set, e.g. A, B, ..., Z, AA, AB.
+func ProblemLabel(i int) string {
+	label := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		label = string(rune('A'+(i-1)%26)) + label
+	}
+	return label
+}
+
 type Grade struct {
 	ID uint `gorm:"primaryKey" json:"id"`
 
@@ -41,6 +108,10 @@ type Grade struct {
 	Detail datatypes.JSON `json:"detail"`
 	Total  uint           `json:"total"`
 
+	// UpsolveDetail is the best scores of the upsolve submissions, keyed by problem id.
+	// It is not stored, and should be loaded by utils.LoadUpsolveDetails.
+	UpsolveDetail datatypes.JSON `json:"upsolve_detail" gorm:"-"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"-"`
 }
@@ -61,7 +132,29 @@ func (p *ProblemSet) AddProblems(ids []uint) error {
 	if err := query.Find(&problems, ids).Error; err != nil {
 		return err
 	}
-	return base.DB.Model(p).Association("Problems").Append(&problems)
+	if len(problems) == 0 {
+		return nil
+	}
+	// New problems are placed after the existing ones.
+	var next int
+	if err := base.DB.Model(&ProblemInProblemSet{}).Where("problem_set_id = ?", p.ID).
+		Select("coalesce(max(display_order) + 1, 0)").Scan(&next).Error; err != nil {
+		return err
+	}
+	if err := base.DB.Model(p).Association("Problems").Append(&problems); err != nil {
+		return err
+	}
+	for i, problem := range problems {
+		if err := base.DB.Model(&ProblemInProblemSet{}).
+			Where("problem_set_id = ? and problem_id = ?", p.ID, problem.ID).
+			Updates(map[string]interface{}{
+				"display_order": next + i,
+				"label":         ProblemLabel(next + i),
+			}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (p *ProblemSet) DeleteProblems(ids []uint) error {
@@ -75,6 +168,78 @@ func (p *ProblemSet) DeleteProblems(ids []uint) error {
 	return base.DB.Model(p).Association("Problems").Delete(&problems)
 }
 
+// LoadProblemEntries loads the order, label and full mark of the problems in the problem set,
+// and sorts the problems.
+func (p *ProblemSet) LoadProblemEntries() error {
+	p.ProblemEntries = nil
+	if err := base.DB.Find(&p.ProblemEntries, "problem_set_id = ?", p.ID).Error; err != nil {
+		return err
+	}
+	p.SortProblems()
+	return nil
+}
+
+// SetProblemEntries updates the order, label and full mark of the problems in the problem set.
+// Problems not in the problem set are ignored.
+func (p *ProblemSet) SetProblemEntries(tx *gorm.DB, entries []*ProblemInProblemSet) error {
+	for _, entry := range entries {
+		if err := tx.Model(&ProblemInProblemSet{}).
+			Where("problem_set_id = ? and problem_id = ?", p.ID, entry.ProblemID).
+			Updates(map[string]interface{}{
+				"display_order": entry.Order,
+				"label":         entry.Label,
+				"full_mark":     entry.FullMark,
+			}).Error; err != nil {
+			return err
+		}
+	}
+	p.ProblemEntries = nil
+	if err := tx.Find(&p.ProblemEntries, "problem_set_id = ?", p.ID).Error; err != nil {
+		return err
+	}
+	p.SortProblems()
+	return nil
+}
+
+// BeforeSave keeps the column not null for problem sets without a language restriction.
+func (p *ProblemSet) BeforeSave(tx *gorm.DB) error {
+	if p.LanguageAllowed == nil {
+		p.LanguageAllowed = database.StringArray{}
+	}
+	return nil
+}
+
+func (p *ProblemSet) AfterFind(tx *gorm.DB) error {
+	p.SortProblems()
+	return nil
+}
+
+// SortProblems sorts the problems in the problem set by their display order.
+// ProblemEntries should be loaded.
+func (p *ProblemSet) SortProblems() {
+	order := make(map[uint]uint)
+	for _, entry := range p.ProblemEntries {
+		order[entry.ProblemID] = entry.Order
+	}
+	sort.SliceStable(p.ProblemEntries, func(i, j int) bool {
+		return p.ProblemEntries[i].Order < p.ProblemEntries[j].Order
+	})
+	sort.SliceStable(p.Problems, func(i, j int) bool {
+		return order[p.Problems[i].ID] < order[p.Problems[j].ID]
+	})
+}
+
+// FullMark returns the full mark of a problem in the problem set.
+// ProblemEntries should be loaded.
+func (p *ProblemSet) FullMark(problemID uint) uint {
+	for _, entry := range p.ProblemEntries {
+		if entry.ProblemID == problemID {
+			return entry.FullMark
+		}
+	}
+	return DefaultFullMark
+}
+
 func (g *Grade) BeforeSave(tx *gorm.DB) (err error) {
 	detail := make(map[uint]uint)
 	err = json.Unmarshal(g.Detail, &detail)
@@ -77,10 +77,11 @@ func TestAddProblemsAndDeleteProblemsByID(t *testing.T) {
 				problem3,
 				problem4,
 			},
-			StartTime: hashStringToTime("test_add_students_success_problem_set_start_time"),
-			EndTime:   hashStringToTime("test_add_students_success_problem_set_end_time"),
-			CreatedAt: problemSet.CreatedAt,
-			UpdatedAt: problemSet.UpdatedAt,
+			StartTime:       hashStringToTime("test_add_students_success_problem_set_start_time"),
+			EndTime:         hashStringToTime("test_add_students_success_problem_set_end_time"),
+			LanguageAllowed: database.StringArray{},
+			CreatedAt:       problemSet.CreatedAt,
+			UpdatedAt:       problemSet.UpdatedAt,
 		}, problemSet)
 	})
 	t.Run("AddInEmptySet", func(t *testing.T) {
@@ -107,10 +108,11 @@ func TestAddProblemsAndDeleteProblemsByID(t *testing.T) {
 				problem1,
 				problem2,
 			},
-			StartTime: hashStringToTime("test_add_students_in_empty_set_problem_set_start_time"),
-			EndTime:   hashStringToTime("test_add_students_in_empty_set_problem_set_end_time"),
-			CreatedAt: problemSet.CreatedAt,
-			UpdatedAt: problemSet.UpdatedAt,
+			StartTime:       hashStringToTime("test_add_students_in_empty_set_problem_set_start_time"),
+			EndTime:         hashStringToTime("test_add_students_in_empty_set_problem_set_end_time"),
+			LanguageAllowed: database.StringArray{},
+			CreatedAt:       problemSet.CreatedAt,
+			UpdatedAt:       problemSet.UpdatedAt,
 		}, problemSet)
 	})
 	t.Run("AddNothing", func(t *testing.T) {
@@ -137,10 +139,11 @@ func TestAddProblemsAndDeleteProblemsByID(t *testing.T) {
 				problem1,
 				problem2,
 			},
-			StartTime: hashStringToTime("test_add_students_nothing_problem_set_start_time"),
-			EndTime:   hashStringToTime("test_add_students_nothing_problem_set_end_time"),
-			CreatedAt: problemSet.CreatedAt,
-			UpdatedAt: problemSet.UpdatedAt,
+			StartTime:       hashStringToTime("test_add_students_nothing_problem_set_start_time"),
+			EndTime:         hashStringToTime("test_add_students_nothing_problem_set_end_time"),
+			LanguageAllowed: database.StringArray{},
+			CreatedAt:       problemSet.CreatedAt,
+			UpdatedAt:       problemSet.UpdatedAt,
 		}, problemSet)
 	})
 	t.Run("AddExistingInSet", func(t *testing.T) {
@@ -172,10 +175,11 @@ func TestAddProblemsAndDeleteProblemsByID(t *testing.T) {
 				problem2,
 				problem3,
 			},
-			StartTime: hashStringToTime("test_add_students_existing_in_set_problem_set_start_time"),
-			EndTime:   hashStringToTime("test_add_students_existing_in_set_problem_set_end_time"),
-			CreatedAt: problemSet.CreatedAt,
-			UpdatedAt: problemSet.UpdatedAt,
+			StartTime:       hashStringToTime("test_add_students_existing_in_set_problem_set_start_time"),
+			EndTime:         hashStringToTime("test_add_students_existing_in_set_problem_set_end_time"),
+			LanguageAllowed: database.StringArray{},
+			CreatedAt:       problemSet.CreatedAt,
+			UpdatedAt:       problemSet.UpdatedAt,
 		}, problemSet)
 	})
 	t.Run("AddNonExisting", func(t *testing.T) {
@@ -207,10 +211,11 @@ func TestAddProblemsAndDeleteProblemsByID(t *testing.T) {
 				problem2,
 				problem3,
 			},
-			StartTime: hashStringToTime("test_add_students_non_existing_problem_set_start_time"),
-			EndTime:   hashStringToTime("test_add_students_non_existing_problem_set_end_time"),
-			CreatedAt: problemSet.CreatedAt,
-			UpdatedAt: problemSet.UpdatedAt,
+			StartTime:       hashStringToTime("test_add_students_non_existing_problem_set_start_time"),
+			EndTime:         hashStringToTime("test_add_students_non_existing_problem_set_end_time"),
+			LanguageAllowed: database.StringArray{},
+			CreatedAt:       problemSet.CreatedAt,
+			UpdatedAt:       problemSet.UpdatedAt,
 		}, problemSet)
 	})
 	t.Run("DeleteSuccess", func(t *testing.T) {
@@ -241,10 +246,11 @@ func TestAddProblemsAndDeleteProblemsByID(t *testing.T) {
 			Problems: []*Problem{
 				problem1,
 			},
-			StartTime: hashStringToTime("test_delete_students_success_problem_set_start_time"),
-			EndTime:   hashStringToTime("test_delete_students_success_problem_set_end_time"),
-			CreatedAt: problemSet.CreatedAt,
-			UpdatedAt: problemSet.UpdatedAt,
+			StartTime:       hashStringToTime("test_delete_students_success_problem_set_start_time"),
+			EndTime:         hashStringToTime("test_delete_students_success_problem_set_end_time"),
+			LanguageAllowed: database.StringArray{},
+			CreatedAt:       problemSet.CreatedAt,
+			UpdatedAt:       problemSet.UpdatedAt,
 		}, problemSet)
 	})
 	t.Run("DeleteNothing", func(t *testing.T) {
@@ -274,10 +280,11 @@ func TestAddProblemsAndDeleteProblemsByID(t *testing.T) {
 				problem2,
 				problem3,
 			},
-			StartTime: hashStringToTime("test_delete_students_nothing_problem_set_start_time"),
-			EndTime:   hashStringToTime("test_delete_students_nothing_problem_set_end_time"),
-			CreatedAt: problemSet.CreatedAt,
-			UpdatedAt: problemSet.UpdatedAt,
+			StartTime:       hashStringToTime("test_delete_students_nothing_problem_set_start_time"),
+			EndTime:         hashStringToTime("test_delete_students_nothing_problem_set_end_time"),
+			LanguageAllowed: database.StringArray{},
+			CreatedAt:       problemSet.CreatedAt,
+			UpdatedAt:       problemSet.UpdatedAt,
 		}, problemSet)
 	})
 	t.Run("DeleteInEmptySet", func(t *testing.T) {
@@ -297,14 +304,15 @@ func TestAddProblemsAndDeleteProblemsByID(t *testing.T) {
 			problem2.ID,
 		}))
 		assert.Equal(t, ProblemSet{
-			ID:          problemSet.ID,
-			Name:        "test_delete_students_in_empty_set_problem_set_name",
-			Description: "test_delete_students_in_empty_set_problem_set_description",
-			Problems:    nil,
-			StartTime:   hashStringToTime("test_delete_students_in_empty_set_problem_set_start_time"),
-			EndTime:     hashStringToTime("test_delete_students_in_empty_set_problem_set_end_time"),
-			CreatedAt:   problemSet.CreatedAt,
-			UpdatedAt:   problemSet.UpdatedAt,
+			ID:              problemSet.ID,
+			Name:            "test_delete_students_in_empty_set_problem_set_name",
+			Description:     "test_delete_students_in_empty_set_problem_set_description",
+			Problems:        nil,
+			StartTime:       hashStringToTime("test_delete_students_in_empty_set_problem_set_start_time"),
+			EndTime:         hashStringToTime("test_delete_students_in_empty_set_problem_set_end_time"),
+			LanguageAllowed: database.StringArray{},
+			CreatedAt:       problemSet.CreatedAt,
+			UpdatedAt:       problemSet.UpdatedAt,
 		}, problemSet)
 	})
 	t.Run("DeleteNotBelongTo", func(t *testing.T) {
@@ -334,10 +342,49 @@ func TestAddProblemsAndDeleteProblemsByID(t *testing.T) {
 			Problems: []*Problem{
 				problem1,
 			},
-			StartTime: hashStringToTime("test_delete_students_not_belong_to_problem_set_start_time"),
-			EndTime:   hashStringToTime("test_delete_students_not_belong_to_problem_set_end_time"),
-			CreatedAt: problemSet.CreatedAt,
-			UpdatedAt: problemSet.UpdatedAt,
+			StartTime:       hashStringToTime("test_delete_students_not_belong_to_problem_set_start_time"),
+			EndTime:         hashStringToTime("test_delete_students_not_belong_to_problem_set_end_time"),
+			LanguageAllowed: database.StringArray{},
+			CreatedAt:       problemSet.CreatedAt,
+			UpdatedAt:       problemSet.UpdatedAt,
 		}, problemSet)
 	})
 }
+
+func TestProblemSetAllowedLanguages(t *testing.T) {
+	t.Parallel()
+	problem := Problem{
+		LanguageAllowed: database.StringArray([]string{"c", "cpp", "python"}),
+	}
+	tests := []struct {
+		name            string
+		languageAllowed database.StringArray
+		expected        []string
+	}{
+		{
+			name:            "NoRestriction",
+			languageAllowed: database.StringArray{},
+			expected:        []string{"c", "cpp", "python"},
+		},
+		{
+			name:            "Intersection",
+			languageAllowed: database.StringArray{"python", "c", "java"},
+			expected:        []string{"c", "python"},
+		},
+		{
+			name:            "Disjoint",
+			languageAllowed: database.StringArray{"java"},
+			expected:        []string{},
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			problemSet := ProblemSet{
+				LanguageAllowed: test.languageAllowed,
+			}
+			assert.Equal(t, test.expected, problemSet.AllowedLanguages(&problem))
+		})
+	}
+}
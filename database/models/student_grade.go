@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// StudentProblemGrade is the standing of a student for a problem in a problem set.
+// It is not stored, and should be loaded by utils.GetStudentGrades.
+type StudentProblemGrade struct {
+	ProblemID uint
+	Label     string
+	FullMark  uint
+
+	// Score is the score counted in the grade, after the override if any.
+	Score uint
+	// BestScore is the scaled best score of the submissions counted in the grade.
+	BestScore       uint
+	Attempts        int64
+	FirstAcceptedAt *time.Time
+	// Override is the grade override in effect, or nil if the score is not overridden.
+	Override *GradeOverride
+	// UpsolveScore is the scaled best score of the upsolve submissions, or nil if there is none.
+	UpsolveScore *uint
+}
+
+// StudentProblemSetGrade is the standing of a student in a problem set.
+type StudentProblemSetGrade struct {
+	ProblemSet *ProblemSet
+	Total      uint
+	FullMark   uint
+	Problems   []*StudentProblemGrade
+}
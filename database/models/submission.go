@@ -32,6 +32,15 @@ type Submission struct {
 	*/
 	Status string `json:"status"`
 
+	// Feedback is the written feedback from teachers, shown to the submitter.
+	Feedback string `json:"feedback"`
+
+	// Upsolve marks the submissions created after the problem set ends, which never change the grades.
+	Upsolve bool `json:"upsolve" gorm:"default:false;not null"`
+
+	// Rejudged marks the submissions which are rejudged. The grades are recalculated when they are judged again.
+	Rejudged bool `json:"rejudged" gorm:"default:false;not null"`
+
 	Runs []Run `json:"runs"`
 
 	CreatedAt time.Time      `sql:"index" json:"created_at"`
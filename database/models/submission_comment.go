@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SubmissionComment is a code review comment on a range of lines of a submission.
+// A reply has the id of the comment it replies to as ParentID, and shares the lines of its parent.
+type SubmissionComment struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	SubmissionID uint  `sql:"index" json:"submission_id" gorm:"not null"`
+	UserID       uint  `json:"user_id"`
+	User         *User `json:"user"`
+	ParentID     uint  `json:"parent_id"`
+
+	StartLine uint   `json:"start_line"`
+	EndLine   uint   `json:"end_line"`
+	Content   string `json:"content"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"-"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at"`
+}
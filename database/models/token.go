@@ -7,7 +7,11 @@ type Token struct {
 	Token      string `gorm:"unique_index" json:"token"`
 	UserID     uint
 	User       User
-	RememberMe bool      `json:"remember_me"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	RememberMe bool `json:"remember_me"`
+	// UserAgent is of the request creating the token, and IP is of the request last using the token.
+	UserAgent string    `json:"user_agent" gorm:"size:255;default:'';not null"`
+	IP        string    `json:"ip" gorm:"size:64;default:'';not null"`
+	CreatedAt time.Time `json:"created_at"`
+	// UpdatedAt is the time the token is last used, from which the token expires.
+	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// TOTPCredential is the TOTP secret of a user. Two-factor authentication is enabled after the user
+// confirms the credential with a code.
+type TOTPCredential struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	UserID uint `json:"user_id" gorm:"not null;uniqueIndex"`
+	// Secret is the base32 encoded secret shared with the authenticator app.
+	Secret  string `json:"-" gorm:"size:64;not null"`
+	Enabled bool   `json:"enabled" gorm:"default:false;not null"`
+	// LastUsedStep is the time step of the last accepted code. Codes of this and earlier steps are rejected.
+	LastUsedStep int64 `json:"-" gorm:"default:0;not null"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+// RecoveryCode is a single-use code to pass two-factor authentication without the authenticator app.
+type RecoveryCode struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	UserID uint `sql:"index" json:"user_id" gorm:"not null"`
+	// CodeHash is the hex encoded SHA-256 hash of the code.
+	CodeHash string `json:"-" gorm:"size:64;not null"`
+	Used     bool   `json:"used" gorm:"default:false;not null"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -19,9 +19,14 @@ type User struct {
 	Password string `json:"-"`
 
 	EmailVerified bool `json:"email_verified"`
+	// EmailNotification is whether notifications are also sent to the user by email.
+	EmailNotification bool `json:"email_notification" gorm:"default:false;not null"`
 
 	Roles      []UserHasRole `json:"roles"`
 	RoleLoaded bool          `gorm:"-" json:"-"`
+	// AllowedPermissions limits the permissions the user could use, which is set when the user is authenticated
+	// with a personal access token. Nil means no limit.
+	AllowedPermissions []string `gorm:"-" json:"-"`
 
 	ClassesManaging []*Class `json:"class_managing" gorm:"many2many:user_manage_classes"`
 	ClassesTaking   []*Class `json:"class_taking" gorm:"many2many:user_in_classes"`
@@ -153,6 +158,18 @@ func (u *User) Can(permission string, target ...HasRole) bool {
 	if len(target) > 1 {
 		panic(errors.New("target length should be one!"))
 	}
+	if u.AllowedPermissions != nil {
+		allowed := false
+		for _, perm := range u.AllowedPermissions {
+			if perm == permission {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
 	if !u.RoleLoaded {
 		u.LoadRoles()
 	}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// UserIdentityProviderLDAP is the provider of identities in the LDAP directory, whose subjects are DNs.
+const UserIdentityProviderLDAP = "ldap"
+
+// UserIdentity links a user to an identity of an external OpenID Connect provider, or the LDAP directory.
+type UserIdentity struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	UserID uint  `sql:"index" json:"user_id" gorm:"not null"`
+	User   *User `json:"user"`
+
+	// Provider is the name of the provider in auth.oidc.providers, or UserIdentityProviderLDAP.
+	Provider string `json:"provider" gorm:"size:255;not null;uniqueIndex:user_identity_subject"`
+	// Subject is the "sub" claim of the identity, which is unique in the provider.
+	Subject string `json:"subject" gorm:"size:255;not null;uniqueIndex:user_identity_subject"`
+	Email   string `json:"email" gorm:"size:320;default:'';not null"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"-"`
+}
@@ -145,6 +145,16 @@ func TestCan(t *testing.T) {
 		thisAssert.True(testUser1.Can("global_permission"))
 		thisAssert.True(testUser1.Can("non_existing_permission"))
 	})
+	t.Run("allowedPermissions", func(t *testing.T) {
+		thisAssert := assert.New(t)
+		limited := testUser1
+		limited.AllowedPermissions = []string{"permission_teacher"}
+		thisAssert.True(limited.Can("permission_teacher", classB))
+		thisAssert.False(limited.Can("permission_both", classB))
+		thisAssert.False(limited.Can("global_permission"))
+		limited.AllowedPermissions = []string{}
+		thisAssert.False(limited.Can("permission_teacher", classB))
+	})
 	assert.Panics(t, func() {
 		testUser0.Can("xxx", classA, classB)
 	})
@@ -9,3 +9,13 @@ func UpdateGrade(r EventArgs) EventRst {
 	err := utils.UpdateGrade(r)
 	return errors.Wrap(err, "could not update grade")
 }
+
+func RecordSubmissionResult(r EventArgs) EventRst {
+	err := utils.RecordSubmissionResult(r)
+	return errors.Wrap(err, "could not record submission result")
+}
+
+func SendNotification(r EventArgs) EventRst {
+	err := utils.NotifySubmissionJudged(r)
+	return errors.Wrap(err, "could not send submission notification")
+}
@@ -5,7 +5,9 @@ go 1.15
 require (
 	github.com/fatih/color v1.10.0
 	github.com/gabriel-vasile/mimetype v1.1.2
+	github.com/go-asn1-ber/asn1-ber v1.5.4
 	github.com/go-gormigrate/gormigrate/v2 v2.0.0
+	github.com/go-ldap/ldap/v3 v3.4.4
 	github.com/go-mail/mail v2.3.1+incompatible
 	github.com/go-playground/locales v0.13.0
 	github.com/go-playground/universal-translator v0.17.0
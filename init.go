@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"html/template"
 	"os"
+	"time"
 
 	"github.com/EduOJ/backend/app"
 	"github.com/EduOJ/backend/base"
@@ -58,6 +59,8 @@ func initEvent() {
 	log.Debug("Initializing Event System.")
 	event.RegisterListener("run", runEvent.NotifyGetSubmissionPoll)
 	event.RegisterListener("submission", submissionEvent.UpdateGrade)
+	event.RegisterListener("submission", submissionEvent.SendNotification)
+	event.RegisterListener("submission", submissionEvent.RecordSubmissionResult)
 	event.RegisterListener("register", register.SendVerificationEmail)
 }
 
@@ -96,6 +99,33 @@ func startEcho() {
 	}()
 }
 
+func startNotifier() {
+	log.Debug("Starting notifier.")
+	exit.QuitWG.Add(1)
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := utils.NotifyProblemSets(); err != nil {
+					log.Errorf("%+v\n", errors.Wrap(err, "could not notify problem sets"))
+				}
+			case <-exit.BaseContext.Done():
+				exit.QuitWG.Done()
+				return
+			}
+		}
+	}()
+}
+
+func recoverPlagiarismReports() {
+	log.Debug("Recovering plagiarism reports.")
+	if err := utils.FailInterruptedPlagiarismReports(); err != nil {
+		log.Errorf("%+v\n", err)
+	}
+}
+
 func initRedis() {
 	log.Debug("Starting redis client.")
 	base.Redis = redis.NewClient(&redis.Options{
@@ -153,7 +183,7 @@ func initMail() {
 		d.StartTLSPolicy = mail.MandatoryStartTLS
 	}
 	base.Mail = *d
-	base.Template, err = template.ParseFiles("template/email_verification.html")
+	base.Template, err = template.ParseFiles("template/email_verification.html", "template/account_created.html")
 	if err != nil {
 		log.Fatal(err)
 	}
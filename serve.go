@@ -18,7 +18,9 @@ func serve() {
 	initWebAuthn()
 	initMail()
 	initEvent()
+	recoverPlagiarismReports()
 	startEcho()
+	startNotifier()
 	s := make(chan os.Signal, 1)
 	signal.Notify(s, syscall.SIGHUP,
 		syscall.SIGINT,